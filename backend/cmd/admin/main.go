@@ -0,0 +1,59 @@
+// Command admin runs maintenance tasks against the database configured in
+// .env, such as granting the admin role to the first administrator:
+//
+//	go run ./cmd/admin promote <username>
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"notes/internal/configs"
+	"notes/internal/db"
+	"notes/internal/repositories"
+
+	"github.com/joho/godotenv"
+	"github.com/lmittmann/tint"
+)
+
+const usage = `usage: admin <command> [arguments]
+
+commands:
+  promote <username>   grant the admin role to an existing user`
+
+func main() {
+	logger := slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelWarn}))
+	if err := run(logger, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(logger *slog.Logger, args []string) error {
+	if len(args) != 2 || args[0] != "promote" {
+		return fmt.Errorf("%s", usage)
+	}
+	username := args[1]
+
+	if err := godotenv.Load(".env"); err != nil {
+		return err
+	}
+	conf := configs.New()
+	gormDB, err := db.New(logger, conf.DB_DRIVER, conf.DB_URI, conf.DB_NAME)
+	if err != nil {
+		return err
+	}
+
+	userRepo := repositories.NewUserRepository(gormDB, conf)
+	found, err := userRepo.PromoteToAdmin(context.Background(), username)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no user named %q, register it first", username)
+	}
+	fmt.Printf("%s is now an admin\n", username)
+	return nil
+}
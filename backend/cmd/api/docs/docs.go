@@ -15,37 +15,64 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/notes": {
+        "/admin/users": {
             "get": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Fetches all notes created by the authenticated user.",
+                "description": "Returns users ordered by ID with the number of notes each has outside the trash. Pages are chained with next_cursor from the metadata.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "admin"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, 1 to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Retrieve all notes",
                 "responses": {
                     "200": {
-                        "description": "List of notes",
+                        "description": "List of users",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.GetNoteResponse"
+                                "$ref": "#/definitions/handlers.AdminUserResponse"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Invalid limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized access",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Admin role required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -53,44 +80,41 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/users/{userId}": {
+            "delete": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Adds a new note for the authenticated user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Permanently deletes the user with their notes, including the trash, categories, sessions and tokens. Admins cannot delete themselves.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "admin"
                 ],
-                "summary": "Create a new note",
+                "summary": "Delete a user",
                 "parameters": [
                     {
-                        "description": "Note data",
-                        "name": "note",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CreateNoteRequest"
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Note created successfully",
+                    "200": {
+                        "description": "User deleted",
                         "schema": {
                             "$ref": "#/definitions/handlers.APIResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid input data",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -100,52 +124,61 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Admin role required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/notes/filter": {
-            "get": {
+        "/admin/users/{userId}/disable": {
+            "post": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Filters notes based on categories and archived status. Both parameters are optional.",
+                "description": "Locks the user out: their sessions end, their access tokens are revoked and they cannot log in until enabled again. Admins cannot disable themselves.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "notes"
+                    "admin"
                 ],
-                "summary": "Filter notes by categories and archived status",
+                "summary": "Disable a user",
                 "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "Filter by archived status (optional)",
-                        "name": "isArchived",
-                        "in": "query"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Filter notes by categories (optional)",
-                        "name": "categories",
-                        "in": "query"
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Filtered notes",
+                        "description": "User disabled",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.GetNoteResponse"
-                            }
+                            "$ref": "#/definitions/handlers.APIResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid query parameters",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -156,6 +189,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Admin role required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -165,39 +210,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/notes/{noteId}": {
-            "get": {
+        "/admin/users/{userId}/enable": {
+            "post": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Fetches a single note by its unique ID for the authenticated user.",
+                "description": "Lets a disabled user log in again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "admin"
                 ],
-                "summary": "Retrieve a specific note by ID",
+                "summary": "Enable a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Note retrieved successfully",
+                        "description": "User enabled",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                            "$ref": "#/definitions/handlers.APIResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid note ID",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -208,8 +253,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Admin role required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "Note not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -221,51 +272,41 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/users/{userId}/password-reset": {
+            "post": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Updates the note for the authenticated user by its unique ID.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Ends the user's sessions, revokes their access tokens and refuses their logins until they set a new password at /user/password-reset with the returned reset token, valid for 24 hours. Hand the token to the user; it is not shown again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "admin"
                 ],
-                "summary": "Update a specific note by ID",
+                "summary": "Force a password reset",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Updated note data",
-                        "name": "note",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateNoteRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Note ID of updated note",
+                        "description": "Reset token",
                         "schema": {
-                            "$ref": "#/definitions/handlers.APIResponse"
+                            "$ref": "#/definitions/handlers.PasswordResetResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid note ID or request data",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -276,8 +317,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Admin role required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "Note not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -289,44 +336,31 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/categories": {
+            "get": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Deletes a note by its unique ID for the authenticated user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns every category owned by the authenticated user, sorted by name.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
-                ],
-                "summary": "Delete a specific note by ID",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "categories"
                 ],
+                "summary": "List categories",
                 "responses": {
                     "200": {
-                        "description": "Note ID of the deleted note",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.APIResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid note ID",
+                        "description": "List of categories",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GetCategory"
+                            }
                         }
                     },
                     "401": {
@@ -335,12 +369,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Note not found",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -348,16 +376,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/notes/{noteId}/archive": {
-            "put": {
+            },
+            "post": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Toggles whether a note is archived or not for the authenticated user.",
+                "description": "Creates a new category owned by the authenticated user.",
                 "consumes": [
                     "application/json"
                 ],
@@ -365,27 +391,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "categories"
                 ],
-                "summary": "Toggle the archive status of a note",
+                "summary": "Create a category",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
-                        "in": "path",
-                        "required": true
+                        "description": "Category data",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateCategoryRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Note with updated archive status",
+                    "201": {
+                        "description": "Category created successfully",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                            "$ref": "#/definitions/handlers.GetCategory"
                         }
                     },
                     "400": {
-                        "description": "Invalid note ID",
+                        "description": "Invalid category name",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -396,8 +424,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Note not found",
+                    "409": {
+                        "description": "Category already exists",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -411,49 +439,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/notes/{noteId}/categories/{categoryName}": {
-            "post": {
+        "/categories/{categoryId}": {
+            "get": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Adds a category to a note for the authenticated user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Fetches a single category owned by the authenticated user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "categories"
                 ],
-                "summary": "Add a category to a specific note by ID",
+                "summary": "Retrieve a category by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Category name",
-                        "name": "categoryName",
+                        "description": "Category ID",
+                        "name": "categoryId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated note with added category",
+                        "description": "Category retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                            "$ref": "#/definitions/handlers.GetCategory"
                         }
                     },
                     "400": {
-                        "description": "Invalid note ID or category name",
+                        "description": "Invalid category ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -465,7 +483,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Note or category not found",
+                        "description": "Category not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -478,13 +496,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
+            "put": {
                 "security": [
                     {
                         "notes_jwt": []
                     }
                 ],
-                "description": "Removes a category from a note for the authenticated user.",
+                "description": "Renames a category; every note tagged with it shows the new name and gets a new version, so its previous ETag no longer matches.",
                 "consumes": [
                     "application/json"
                 ],
@@ -492,34 +510,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "notes"
+                    "categories"
                 ],
-                "summary": "Remove a category from a specific note by ID",
+                "summary": "Rename a category",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Note ID",
-                        "name": "noteId",
+                        "description": "Category ID",
+                        "name": "categoryId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Category name",
-                        "name": "categoryName",
-                        "in": "path",
-                        "required": true
+                        "description": "New category name",
+                        "name": "category",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateCategoryRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated note with removed category",
+                        "description": "Category renamed successfully",
                         "schema": {
-                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                            "$ref": "#/definitions/handlers.GetCategory"
                         }
                     },
                     "400": {
-                        "description": "Invalid note ID or category name",
+                        "description": "Invalid category ID or name",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -531,7 +551,13 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Note or category not found",
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Category name already in use",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -543,43 +569,72 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/status": {
-            "get": {
-                "description": "Returns the status of the API to confirm it's running correctly.",
-                "consumes": [
-                    "application/json"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
                 ],
+                "description": "Deletes a category and detaches it from its notes. Refused when a note would be left without categories. The notes it is detached from get a new version.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "categories"
+                ],
+                "summary": "Delete a category",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "categoryId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Check API status",
                 "responses": {
                     "200": {
-                        "description": "Status OK",
+                        "description": "ID of the deleted category",
                         "schema": {
-                            "$ref": "#/definitions/handlers.StatusResponse"
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid category ID or category still required by a note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/user/login": {
+        "/categories/{categoryId}/merge": {
             "post": {
-                "description": "Log in with username and password",
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Re-points every note from the category in the path to the target category, then deletes the merged category. Runs in a single transaction; the re-pointed notes get a new version.",
                 "consumes": [
                     "application/json"
                 ],
@@ -587,41 +642,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "categories"
                 ],
-                "summary": "Authenticate user",
+                "summary": "Merge a category into another",
                 "parameters": [
                     {
-                        "description": " ",
-                        "name": "credentials",
+                        "type": "integer",
+                        "description": "ID of the category to merge and delete",
+                        "name": "categoryId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target category",
+                        "name": "merge",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserRequest"
+                            "$ref": "#/definitions/handlers.MergeCategoryRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Target category after the merge",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
+                            "$ref": "#/definitions/handlers.GetCategory"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid category IDs",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -629,9 +697,104 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/logout": {
+        "/metrics": {
+            "get": {
+                "description": "Returns Prometheus metrics for monitoring",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Get application metrics",
+                "responses": {
+                    "200": {
+                        "description": "Metrics data",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Fetches the notes created by the authenticated user, one page at a time, or with scope=shared the notes other users shared with them. Pass metadata.next_cursor back as cursor to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Retrieve all notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "own (default) or shared",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-100, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "position (manual order, pinned first), created_at, updated_at or title, prefixed with - for descending (default position, or -created_at with scope=shared)",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of notes, with PageMetadata under metadata",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GetNoteResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid scope or paging parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "logout",
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Adds a new note for the authenticated user.",
                 "consumes": [
                     "application/json"
                 ],
@@ -639,15 +802,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "notes"
                 ],
-                "summary": "logout connected user",
-                "responses": {}
+                "summary": "Create a new note",
+                "parameters": [
+                    {
+                        "description": "Note data",
+                        "name": "note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateNoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Note created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
             }
         },
-        "/user/register": {
+        "/notes/bulk": {
             "post": {
-                "description": "Create user account with username and password",
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Archives, unarchives, deletes, or adds or removes a category on up to 100 notes. By default the notes are changed in one transaction that is rolled back if any of them fails; with best_effort the successful changes are kept. Every note gets its own result: ok, failed, or rolled_back when it succeeded but the transaction was rolled back.",
                 "consumes": [
                     "application/json"
                 ],
@@ -655,102 +853,3856 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "notes"
                 ],
-                "summary": "Register new user",
+                "summary": "Apply an operation to several notes",
                 "parameters": [
                     {
-                        "description": " ",
-                        "name": "request",
+                        "description": "Operation, note IDs and, for category operations, the category",
+                        "name": "bulk",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserRequest"
+                            "$ref": "#/definitions/handlers.BulkNotesRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Per-note results",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
+                            "$ref": "#/definitions/handlers.BulkNotesResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid operation, note IDs or category",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/events": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Server-Sent Events stream of the user's note changes: created, updated, archived, unarchived, deleted, restored and category-changed. The data of each event is a NoteEventResponse; deleted events carry no note. Idle streams receive a heartbeat comment. Reconnecting with Last-Event-ID replays the events missed since then; when they can no longer be replayed, or the client fell behind and events were dropped, a reset event is sent and the client should reload its notes.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Stream note events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received, to resume the stream",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NoteEventResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.APIResponse": {
+        },
+        "/notes/export": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Streams a zip archive with one markdown file per note outside the trash. Each file starts with YAML front matter holding the title, categories, archived flag and timestamps.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Export notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format, only markdown is supported (default markdown)",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Zip archive of markdown files",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/filter": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Filters notes based on categories and archived status, one page at a time. Both filters are optional.",
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Filter notes by categories and archived status",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Filter by archived status (optional)",
+                        "name": "isArchived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Filter notes by categories (optional)",
+                        "name": "categories",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-100, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "position (manual order, pinned first), created_at, updated_at or title, prefixed with - for descending (default position)",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Filtered notes, with PageMetadata under metadata",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GetNoteResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/import": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Accepts a zip archive in the format produced by the export, up to 10 MB and 500 files. Every markdown file is validated like a new note; categories are created when missing. Invalid files are skipped and files whose title is already taken are reported as conflicts.",
+                "consumes": [
+                    "application/zip"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Import notes",
+                "parameters": [
+                    {
+                        "description": "Zip archive of markdown files",
+                        "name": "archive",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Created, skipped and conflicting files",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImportNotesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Body is not a valid zip archive or has too many files",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/search": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Searches title, content and category names of the authenticated user's notes and of the notes shared with them, ordered by relevance with highlighted snippets. Shared notes carry their owner's user_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Full-text search over notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query, supports quoted phrases, OR and -exclusions",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (1-50, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching notes ordered by relevance",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SearchNoteResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters or no matches",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/trash": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns the notes in the trash of the authenticated user, most recently deleted first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "List trashed notes",
+                "responses": {
+                    "200": {
+                        "description": "Trashed notes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.TrashedNoteResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Permanently deletes all trashed notes of the authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Empty the trash",
+                "responses": {
+                    "200": {
+                        "description": "Number of purged notes",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EmptyTrashResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Fetches a single note by its unique ID for the authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Retrieve a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note retrieved successfully, with its version in the ETag header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Updates the note for the authenticated user by its unique ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Update a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the note version being edited, or * to overwrite unconditionally",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated note data",
+                        "name": "note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateNoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note ID of updated note, with the new version in the ETag header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID, If-Match header or request data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers may not edit the note, nor editors archive it",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Note changed since it was fetched; data holds its current state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Missing If-Match header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Moves a note to the trash. It can be restored until the trash is emptied or the retention period runs out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Delete a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note ID of the deleted note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the owner may delete the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 merge patch to the note. Only the fields present are validated and saved; a categories array replaces the whole list. Fields cannot be removed with null. A patch that changes nothing returns the note as it is.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Partially update a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the note version being edited",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PatchNoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Patched note, with its version in the ETag header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID, If-Match header or patch",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers may not edit the note, nor editors archive it",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Note changed since it was fetched; data holds its current state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Patch not sent as application/merge-patch+json",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/archive": {
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Toggles whether a note is archived or not for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Toggle the archive status of a note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the note version being edited",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note with updated archive status",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or If-Match header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the owner may archive the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Note changed since it was fetched; data holds its current state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/categories/{categoryName}": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Adds a category to a note for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Add a category to a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "categoryName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the note version being edited",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated note with added category",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID, category name or If-Match header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers may not edit the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note or category not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Note changed since it was fetched; data holds its current state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Removes a category from a note for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Remove a category from a specific note by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "categoryName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the note version being edited",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated note with removed category",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID, category name or If-Match header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers may not edit the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note or category not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Note changed since it was fetched; data holds its current state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/move": {
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Puts the note right before or right after another of the user's notes in the order GET /notes lists them. Both notes must be pinned, or both unpinned. Only the owner of a note can move it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Move a note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Note to move the note before or after",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MoveNoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note moved",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or target, or note not shared with the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the owner may move the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/permissions": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns the users the note is shared with and their roles, in username order. Only the owner of a note can list them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "List who a note is shared with",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of permissions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.NotePermissionResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or note not shared with the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is not the owner of the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/permissions/{username}": {
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Gives the user viewer or editor access to the note, or changes the role they already have. Viewers may read the note and its revisions; editors may also change its title, content and categories. Only the owner of a note can share it, archive it or delete it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Share a note with a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username of the user to share the note with",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role of the user",
+                        "name": "permission",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SetNotePermissionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note shared",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotePermissionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or role, note shared with its owner, or note not shared with the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is not the owner of the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Takes away the access the user has to the note.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Stop sharing a note with a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username of the user the note is shared with",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Access taken away",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or note not shared with the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is not the owner of the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found, or the note is not shared with them",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/pin": {
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Pinned notes are listed before the others by GET /notes, in the same manual order. A note keeps its position when pinned, so it goes back to where it was when unpinned. Only the owner of a note can pin it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Pin or unpin a note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Whether the note is pinned",
+                        "name": "pin",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PinNoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note pinned or unpinned",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or request data, or note not shared with the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the owner may pin the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Moves a note of the authenticated user out of the trash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore a trashed note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or title already in use",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Note not found in the trash",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns every recorded revision of a note owned by the authenticated user, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "List note revisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Revisions of the note",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.NoteRevisionResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/revisions/diff": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns a line diff of title and content plus category and archive changes between two revisions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Diff two note revisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Base revision",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to compare against the base",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes between the revisions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NoteRevisionDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or revision",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Revision not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/revisions/{revision}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Brings the note back to the state of the given revision. The restore is recorded as a new revision.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Restore a note revision",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to restore",
+                        "name": "revision",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Note after the restore",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or revision, or note already matches it",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers may not edit the note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Revision not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/share": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Creates a link anyone can open without an account to read the note. The token is only returned by this call. expires_in_days is at most 365; without it the link works until it is revoked. With a password, the link asks for it before showing the note. Only the owner of a note can share it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Share a note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Expiry and password of the link",
+                        "name": "share",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Link created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatedShareResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID, expiry or password, or note not owned by the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/shares": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns the links to the note that have not been revoked, newest first, with how often each was opened. The tokens themselves are never returned again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "List the share links of a note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of links",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ShareResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note ID or note not owned by the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/{noteId}/shares/{shareId}": {
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Revokes one of the links to the note; it cannot be opened from then on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Revoke a share link",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Note ID",
+                        "name": "noteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Share ID",
+                        "name": "shareId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid note or share ID, or note not owned by the user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/shared/{token}": {
+            "get": {
+                "description": "Shows the shared note without authentication: as a minimal HTML page to browsers, whose Accept header asks for text/html, and as JSON otherwise. Links with a password need it in the password form field of a POST; the HTML page asks for it. Repeated incorrect passwords from one address hold its attempts on the link back for a while. Revoked and expired links, and links to notes in the trash, are not found.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Open a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of the link",
+                        "name": "password",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Shared note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SharedNoteResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many incorrect passwords, retry after the time in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Shows the shared note without authentication: as a minimal HTML page to browsers, whose Accept header asks for text/html, and as JSON otherwise. Links with a password need it in the password form field of a POST; the HTML page asks for it. Repeated incorrect passwords from one address hold its attempts on the link back for a while. Revoked and expired links, and links to notes in the trash, are not found.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Open a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of the link",
+                        "name": "password",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Shared note",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SharedNoteResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many incorrect passwords, retry after the time in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "Returns the status of the API to confirm it's running correctly.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Check API status",
+                "responses": {
+                    "200": {
+                        "description": "Status OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StatusResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sync": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Lists the notes and categories created or changed, and those deleted, since the token of a previous sync, oldest change first. Without a token every note and category is listed. Notes moved to the trash are listed as deleted. Pass the returned token to the next sync; while has_more is true there are more changes to fetch right away. Deletions are remembered for SYNC_TOMBSTONE_RETENTION_DAYS: older tokens get 410 and the client must sync again without a token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Pull note changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token returned by the previous sync",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of changes per page, from 1 to 1000 (default 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes since the token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SyncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid token or limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Token too old, sync again without one",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Applies up to 100 changes made offline, in order and each on its own. A change made to the current version of its note is applied. One made to an older version is a conflict unless it already matches the note, in which case it is unchanged; conflicts carry the change and the server's note, null when the note was deleted, for the client to merge and send again against the server's version. Changes that fail validation are rejected with the reason. Sending a batch again is safe.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Push note changes",
+                "parameters": [
+                    {
+                        "description": "Changes made offline",
+                        "name": "changes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-change results",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SyncChangesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payload or number of changes",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Permanently deletes the authenticated user with their notes, categories, sessions and access tokens after checking their password, and their two-factor code when enabled. Wrong two-factor codes are throttled per user. The auth cookies are cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete the account",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Enables two-factor authentication with a code from the authenticator app and returns ten one-time recovery codes. The codes are not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Confirm two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorConfirmResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Turns two-factor authentication off, given a current code or a recovery code. The secret and the remaining recovery codes are deleted. Wrong codes are throttled per user, answering 429 with a Retry-After header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Generates a TOTP secret for the authenticated user. Two-factor authentication is enabled only after /user/2fa/confirm receives a valid code; enrolling again replaces an unconfirmed secret.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Start two-factor enrollment",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorEnrollResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/export": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Downloads everything stored about the authenticated user as a JSON file: the profile, every note including those in the trash, the categories and the metadata of the note revisions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Export personal data",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Log in with username and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Authenticate user",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "two-factor authentication required, complete the login at /user/login/2fa",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/2fa": {
+            "post": {
+                "description": "Second step of the login for accounts with two-factor authentication. Takes the challenge token returned by /user/login, which is valid for 5 minutes, and a TOTP code or a recovery code, then sets the session cookies.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Complete a two-factor login",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginTwoFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/logout": {
+            "post": {
+                "description": "Revokes the session of the refresh token cookie and clears the auth cookies.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "logout connected user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/logout-all": {
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Revokes every session of the authenticated user; their access and refresh tokens stop working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Log out all devices",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password": {
+            "put": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Replaces the password of the authenticated user after checking the current one. The new password must follow the password policy. Every other session of the user is logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change the password",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password-reset": {
+            "post": {
+                "description": "Completes a password reset requested by an admin, using the reset token they handed over. Log in with the new password afterwards.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set a new password after a forced reset",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/refresh": {
+            "post": {
+                "description": "Exchanges the refresh token cookie for a new access token and a new refresh token. A refresh token works once; reusing one revokes its session.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Refresh the access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/register": {
+            "post": {
+                "description": "Create user account with username and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register new user",
+                "parameters": [
+                    {
+                        "description": " ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Returns the authenticated user's tokens that have not been revoked, newest first. The token values themselves are never returned again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "List personal access tokens",
+                "responses": {
+                    "200": {
+                        "description": "List of tokens",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AccessTokenResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Called with an access token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Issues a token for scripts and CLI clients, sent as \"Authorization: Bearer \u003ctoken\u003e\". The token is only returned by this call. expires_in_days defaults to 30 and is at most 365.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Create a personal access token",
+                "parameters": [
+                    {
+                        "description": "Token name, scopes and lifetime",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAccessTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Token created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatedAccessTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid name, scopes or expiry",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Called with an access token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/tokens/{tokenId}": {
+            "delete": {
+                "security": [
+                    {
+                        "notes_jwt": []
+                    }
+                ],
+                "description": "Revokes one of the authenticated user's tokens; requests made with it fail from then on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Revoke a personal access token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Token ID",
+                        "name": "tokenId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid token ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Called with an access token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Token not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "diff.Line": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "$ref": "#/definitions/diff.Op"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "diff.Op": {
+            "type": "string",
+            "enum": [
+                "equal",
+                "insert",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "Equal",
+                "Insert",
+                "Delete"
+            ]
+        },
+        "handlers.APIResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "string",
+                    "example": "Error message if any"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "handlers.AccessTokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "description": "LastUsedAt is recorded at most once a minute.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.AdminUserResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "is_disabled": {
+                    "type": "boolean"
+                },
+                "note_count": {
+                    "type": "integer"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BulkNoteResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "handlers.BulkNotesRequest": {
+            "type": "object",
+            "properties": {
+                "best_effort": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "category": {
+                    "type": "string",
+                    "example": "work"
+                },
+                "note_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "archive"
+                }
+            }
+        },
+        "handlers.BulkNotesResponse": {
+            "type": "object",
+            "properties": {
+                "best_effort": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "committed": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "archive"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BulkNoteResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateAccessTokenRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "name": {
+                    "type": "string",
+                    "example": "backup script"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "notes:read"
+                    ]
+                }
+            }
+        },
+        "handlers.CreateCategoryRequest": {
+            "description": "Payload for creating a new category",
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateNoteRequest": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"Work\"",
+                        " \"Personal\"]"
+                    ]
+                },
+                "content": {
+                    "type": "string",
+                    "example": "This is the content of the note."
+                },
+                "title": {
+                    "type": "string",
+                    "example": "My Note Title"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.CreateShareRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "password": {
+                    "type": "string",
+                    "example": "correct horse"
+                }
+            }
+        },
+        "handlers.CreatedAccessTokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "description": "LastUsedAt is recorded at most once a minute.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "type": "string",
+                    "example": "notes_pat_3f9c..."
+                }
+            }
+        },
+        "handlers.CreatedShareResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "has_password": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_viewed_at": {
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "3f9c..."
+                },
+                "url": {
+                    "type": "string",
+                    "example": "http://localhost:8025/shared/3f9c..."
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EmptyTrashResponse": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "No data - null\nExample: nil"
+                },
+                "error": {
+                    "description": "Error message\nExample: Invalid input data",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status code\nExample: 400",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.GetCategory": {
+            "description": "Response containing details of a category",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.GetNoteResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
+                "content": {
+                    "type": "string",
+                    "example": "Sample note content."
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-02-01T12:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "is_archived": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "pinned": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "position": {
+                    "type": "number",
+                    "example": 1024
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Sample Note Title"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.ImportNoteEntry": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "type": "string",
+                    "example": "42-groceries.md"
+                },
+                "note_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Groceries"
+                }
+            }
+        },
+        "handlers.ImportNotesResponse": {
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImportNoteEntry"
+                    }
+                },
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImportNoteEntry"
+                    }
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImportNoteEntry"
+                    }
+                }
+            }
+        },
+        "handlers.LoginChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "two_factor_required": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.LoginTwoFactorRequest": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "example": "a1b2c-3d4e5"
+                }
+            }
+        },
+        "handlers.MergeCategoryRequest": {
+            "description": "Payload naming the category that absorbs the merged one",
+            "type": "object",
+            "properties": {
+                "target_id": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "handlers.MoveNoteRequest": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "type": "integer"
+                },
+                "before": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "handlers.NoteEventResponse": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string",
+                    "example": "2025-02-01T12:00:00Z"
+                },
+                "note": {
+                    "$ref": "#/definitions/handlers.GetNoteResponse"
+                },
+                "note_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.NoteEventType"
+                        }
+                    ],
+                    "example": "updated"
+                }
+            }
+        },
+        "handlers.NoteHighlights": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "buy milk at the \u003cmark\u003eshop\u003c/mark\u003e"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "\u003cmark\u003eShopping\u003c/mark\u003e List"
+                }
+            }
+        },
+        "handlers.NotePermissionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.NoteRevisionDiffResponse": {
+            "type": "object",
+            "properties": {
+                "archived_from": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "archived_to": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "categories_added": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Work"
+                    ]
+                },
+                "categories_removed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Personal"
+                    ]
+                },
+                "content": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diff.Line"
+                    }
+                },
+                "from": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diff.Line"
+                    }
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.NoteRevisionResponse": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Work",
+                        "Personal"
+                    ]
+                },
+                "content": {
+                    "type": "string",
+                    "example": "Sample note content."
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-02-01T12:00:00Z"
+                },
+                "is_archived": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "revision": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Sample Note Title"
+                }
+            }
+        },
+        "handlers.PasswordResetResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "reset_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PatchNoteRequest": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
+                "content": {
+                    "type": "string"
+                },
+                "is_archived": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PinNoteRequest": {
+            "type": "object",
+            "properties": {
+                "pinned": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "reset_token": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SearchNoteResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
+                "content": {
+                    "type": "string",
+                    "example": "Sample note content."
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-02-01T12:00:00Z"
+                },
+                "highlights": {
+                    "$ref": "#/definitions/handlers.NoteHighlights"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "is_archived": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "pinned": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "position": {
+                    "type": "number",
+                    "example": 1024
+                },
+                "rank": {
+                    "type": "number",
+                    "example": 0.6079
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Sample Note Title"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.SetNotePermissionRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "editor"
+                }
+            }
+        },
+        "handlers.ShareResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "has_password": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_viewed_at": {
+                    "type": "string"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SharedNoteResponse": {
             "type": "object",
             "properties": {
-                "data": {},
-                "error": {
-                    "type": "string",
-                    "example": "Error message if any"
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
                 },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.StatusResponse": {
+            "type": "object",
+            "properties": {
                 "status": {
-                    "type": "integer",
-                    "example": 200
+                    "type": "string",
+                    "example": "OK"
                 }
             }
         },
-        "handlers.CreateNoteRequest": {
+        "handlers.SyncChangeRequest": {
             "type": "object",
             "properties": {
+                "base_version": {
+                    "type": "integer",
+                    "example": 3
+                },
                 "categories": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     },
                     "example": [
-                        "[\"Work\"",
-                        " \"Personal\"]"
+                        "Work",
+                        "Personal"
                     ]
                 },
                 "content": {
                     "type": "string",
-                    "example": "This is the content of the note."
+                    "example": "Written offline."
+                },
+                "deleted": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "is_archived": {
+                    "type": "boolean",
+                    "example": false
                 },
                 "title": {
                     "type": "string",
                     "example": "My Note Title"
-                },
-                "user_id": {
-                    "type": "integer",
-                    "example": 1
                 }
             }
         },
-        "handlers.ErrorResponse": {
+        "handlers.SyncChangeResult": {
             "type": "object",
             "properties": {
-                "data": {
-                    "description": "No data - null\nExample: nil"
+                "conflict": {
+                    "$ref": "#/definitions/handlers.SyncConflict"
                 },
                 "error": {
-                    "description": "Error message\nExample: Invalid input data",
                     "type": "string"
                 },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "note": {
+                    "$ref": "#/definitions/handlers.GetNoteResponse"
+                },
                 "status": {
-                    "description": "HTTP status code\nExample: 400",
-                    "type": "integer"
+                    "type": "string",
+                    "example": "applied"
                 }
             }
         },
-        "handlers.GetNoteResponse": {
+        "handlers.SyncChangesResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SyncChangeResult"
+                    }
+                }
+            }
+        },
+        "handlers.SyncConflict": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "$ref": "#/definitions/handlers.SyncChangeRequest"
+                },
+                "server": {
+                    "$ref": "#/definitions/handlers.GetNoteResponse"
+                }
+            }
+        },
+        "handlers.SyncDeletedEntry": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string",
+                    "example": "2025-02-03T09:30:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "type": {
+                    "type": "string",
+                    "example": "note"
+                }
+            }
+        },
+        "handlers.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SyncChangeRequest"
+                    }
+                }
+            }
+        },
+        "handlers.SyncResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.GetCategory"
+                    }
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SyncDeletedEntry"
+                    }
+                },
+                "has_more": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.GetNoteResponse"
+                    }
+                },
+                "token": {
+                    "type": "string",
+                    "example": "MTI4"
+                }
+            }
+        },
+        "handlers.TrashedNoteResponse": {
             "type": "object",
             "properties": {
                 "categories": {
@@ -767,6 +4719,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2025-02-01T12:00:00Z"
                 },
+                "deleted_at": {
+                    "type": "string",
+                    "example": "2025-02-03T09:30:00Z"
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
@@ -775,6 +4731,14 @@ const docTemplate = `{
                     "type": "boolean",
                     "example": false
                 },
+                "pinned": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "position": {
+                    "type": "number",
+                    "example": 1024
+                },
                 "title": {
                     "type": "string",
                     "example": "Sample Note Title"
@@ -785,15 +4749,56 @@ const docTemplate = `{
                 "user_id": {
                     "type": "integer",
                     "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
-        "handlers.StatusResponse": {
+        "handlers.TwoFactorCodeRequest": {
             "type": "object",
             "properties": {
-                "status": {
+                "code": {
                     "type": "string",
-                    "example": "OK"
+                    "example": "123456"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TwoFactorConfirmResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.TwoFactorEnrollResponse": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/Notes:alice?secret=JBSWY3DPEHPK3PXP\u0026issuer=Notes"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "handlers.UpdateCategoryRequest": {
+            "description": "Payload for renaming a category",
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Groceries"
                 }
             }
         },
@@ -858,6 +4863,58 @@ const docTemplate = `{
                 }
             }
         },
+        "models.AccountExport": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Note"
+                    }
+                },
+                "profile": {
+                    "$ref": "#/definitions/models.AccountProfile"
+                },
+                "revisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RevisionMetadata"
+                    }
+                }
+            }
+        },
+        "models.AccountProfile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Category": {
             "type": "object",
             "properties": {
@@ -872,6 +4929,9 @@ const docTemplate = `{
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
@@ -890,12 +4950,25 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the note sits in the trash; gorm skips such rows\nin every query unless Unscoped is used.",
+                    "type": "string",
+                    "format": "date-time"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "is_archived": {
                     "type": "boolean"
                 },
+                "pinned": {
+                    "description": "Pinned notes are listed before the others.",
+                    "type": "boolean"
+                },
+                "position": {
+                    "description": "Position orders the owner's notes by hand, lowest first. A moved note\nis put halfway between its new neighbours, so positions are fractional.",
+                    "type": "number"
+                },
                 "title": {
                     "type": "string"
                 },
@@ -904,9 +4977,82 @@ const docTemplate = `{
                 },
                 "user_id": {
                     "type": "integer"
+                },
+                "version": {
+                    "description": "Version increases with every saved change and is exposed as the ETag\nclients must send back in If-Match to update the note.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NoteEventType": {
+            "type": "string",
+            "enum": [
+                "created",
+                "updated",
+                "archived",
+                "unarchived",
+                "deleted",
+                "restored",
+                "category-changed",
+                "pinned",
+                "unpinned",
+                "moved"
+            ],
+            "x-enum-varnames": [
+                "NoteCreated",
+                "NoteUpdated",
+                "NoteArchived",
+                "NoteUnarchived",
+                "NoteDeleted",
+                "NoteRestored",
+                "NoteCategoryChanged",
+                "NotePinned",
+                "NoteUnpinned",
+                "NoteMoved"
+            ]
+        },
+        "models.RevisionMetadata": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "integer"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "is_archived": {
+                    "type": "boolean"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "notes_jwt": {
+            "type": "apiKey",
+            "name": "notes_jwt",
+            "in": "cookies"
+        },
+        "notes_pat": {
+            "description": "Personal access token, sent as \"Bearer notes_pat_...\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	_ "notes/cmd/api/docs"
 	"notes/internal/api/handlers"
+	"notes/internal/models"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -12,7 +13,7 @@ import (
 func (app *application) routes() http.Handler {
 	mx := mux.NewRouter()
 	app.logger.Debug("INTIALIZING ROUTES")
-	const GET, POST, PUT, DELETE, OPTIONS = "GET", "POST", "PUT", "DELETE", "OPTIONS"
+	const GET, POST, PUT, PATCH, DELETE, OPTIONS = "GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"
 
 	// Rate Limiter
 	rateLimiter := handlers.NewRateLimiter(5, 10)
@@ -21,7 +22,7 @@ func (app *application) routes() http.Handler {
 	mx.Use(app.handlers.RecoverPanic)
 	mx.Use(app.handlers.AddHeadersWithCSP)
 	mx.Use(app.handlers.MetricsMiddleware)
-	mx.Use(app.handlers.WithTimeout(time.Second * 20))
+	mx.Use(app.handlers.WithTimeout(time.Second*20, "notes:events"))
 	mx.Use(app.handlers.LimitMiddleware(rateLimiter))
 	mx.Use(app.handlers.LogAccess)
 
@@ -34,6 +35,10 @@ func (app *application) routes() http.Handler {
 	// @securityDefinitions.apikey notes_jwt
 	// @in cookies
 	// @name notes_jwt
+	// @securityDefinitions.apikey notes_pat
+	// @in header
+	// @name Authorization
+	// @description Personal access token, sent as "Bearer notes_pat_..."
 	app.handlers.RegisterSwaggerHandler(mx)
 
 	// Routes
@@ -46,25 +51,97 @@ func (app *application) routes() http.Handler {
 
 	userRouter := mx.PathPrefix("/user").Subrouter()
 	noteRouter := mx.PathPrefix("/notes").Subrouter()
+	categoryRouter := mx.PathPrefix("/categories").Subrouter()
+	syncRouter := mx.PathPrefix("/sync").Subrouter()
+	adminRouter := mx.PathPrefix("/admin").Subrouter()
+
+	// Access tokens are limited to their scopes; session-only routes manage
+	// the account itself and cannot be called with an access token.
+	read := app.handlers.RequireScope(models.ScopeNotesRead)
+	write := app.handlers.RequireScope(models.ScopeNotesWrite)
+	session := func(h http.HandlerFunc) http.Handler {
+		return app.handlers.PROTECT(app.handlers.RequireSession(h))
+	}
 
 	// USER ROUTES
 	userRouter.HandleFunc("/register", app.handlers.UserHandler.RegisterUserHandler).Methods(POST, OPTIONS).Name("user:register")
 	userRouter.HandleFunc("/login", app.handlers.UserHandler.LoginUserHandler).Methods(POST, OPTIONS).Name("user:login")
+	userRouter.HandleFunc("/login/2fa", app.handlers.UserHandler.LoginTwoFactorHandler).Methods(POST, OPTIONS).Name("user:login-2fa")
+	userRouter.HandleFunc("/password-reset", app.handlers.UserHandler.ResetPasswordHandler).Methods(POST, OPTIONS).Name("user:password-reset")
+	userRouter.HandleFunc("/refresh", app.handlers.UserHandler.RefreshHandler).Methods(POST, OPTIONS).Name("user:refresh")
 	userRouter.HandleFunc("/logout", app.handlers.UserHandler.LogoutUserHandler).Methods(POST, OPTIONS).Name("user:logout")
+	userRouter.Handle("/logout-all", session(app.handlers.UserHandler.LogoutAllHandler)).Methods(POST, OPTIONS).Name("user:logout-all")
+	userRouter.Handle("/2fa/enroll", session(app.handlers.UserHandler.EnrollTwoFactorHandler)).Methods(POST, OPTIONS).Name("user:2fa-enroll")
+	userRouter.Handle("/2fa/confirm", session(app.handlers.UserHandler.ConfirmTwoFactorHandler)).Methods(POST, OPTIONS).Name("user:2fa-confirm")
+	userRouter.Handle("/2fa/disable", session(app.handlers.UserHandler.DisableTwoFactorHandler)).Methods(POST, OPTIONS).Name("user:2fa-disable")
+	userRouter.Handle("/export", session(app.handlers.UserHandler.ExportAccountHandler)).Methods(GET, OPTIONS).Name("user:export")
+	userRouter.Handle("", session(app.handlers.UserHandler.DeleteAccountHandler)).Methods(DELETE, OPTIONS).Name("user:delete")
+	userRouter.Handle("/password", session(app.handlers.UserHandler.ChangePasswordHandler)).Methods(PUT, OPTIONS).Name("user:password")
+	userRouter.Handle("/tokens", session(app.handlers.TokenHandler.ListAccessTokensHandler)).Methods(GET, OPTIONS).Name("tokens:list")
+	userRouter.Handle("/tokens", session(app.handlers.TokenHandler.CreateAccessTokenHandler)).Methods(POST, OPTIONS).Name("tokens:create")
+	userRouter.Handle("/tokens/{tokenId}", session(app.handlers.TokenHandler.RevokeAccessTokenHandler)).Methods(DELETE, OPTIONS).Name("tokens:revoke")
 	userRouter.Handle("/auth-check", app.handlers.PROTECT(http.HandlerFunc(app.handlers.UserHandler.AuthCheckHandler))).Methods(GET, OPTIONS).Name("user:auth-check")
 
 	// NOTES (PROTECTED) ROUTES
 	noteRouter.Use(app.handlers.PROTECT)
-	noteRouter.HandleFunc("/filter", app.handlers.UserHandler.FilterNotesForUserHandler).Methods(GET, OPTIONS).Name("notes:filter")
-	noteRouter.HandleFunc("", app.handlers.UserHandler.GetAllNotesByUserHandler).Methods(GET, OPTIONS).Name("notes:list")
-	noteRouter.HandleFunc("", app.handlers.UserHandler.CreateNoteHandler).Methods(POST, OPTIONS).Name("notes:create")
-	noteRouter.HandleFunc("/{noteId}", app.handlers.UserHandler.GetNoteByIdHandler).Methods(GET, OPTIONS).Name("notes:get")
-	noteRouter.HandleFunc("/{noteId}", app.handlers.UserHandler.UpdateNoteHandler).Methods(PUT, OPTIONS).Name("notes:update")
-	noteRouter.HandleFunc("/{noteId}", app.handlers.UserHandler.DeleteNoteHandler).Methods(DELETE, OPTIONS).Name("notes:delete")
-	noteRouter.HandleFunc("/{noteId}/archive-toggle", app.handlers.UserHandler.ToggleArchiveStatusHandler).Methods(PUT, OPTIONS).Name("archive-toggle")
-
-	noteRouter.HandleFunc("/{noteId}/categories/{categoryName}", app.handlers.UserHandler.AddCategoryToNoteHandler).Methods(POST, OPTIONS).Name("category:add")
-	noteRouter.HandleFunc("/{noteId}/categories/{categoryName}", app.handlers.UserHandler.RemoveCategoryFromNoteHandler).Methods(DELETE, OPTIONS).Name("category:remove")
+	noteRouter.Handle("/filter", read(http.HandlerFunc(app.handlers.UserHandler.FilterNotesForUserHandler))).Methods(GET, OPTIONS).Name("notes:filter")
+	noteRouter.Handle("/search", read(http.HandlerFunc(app.handlers.UserHandler.SearchNotesForUserHandler))).Methods(GET, OPTIONS).Name("notes:search")
+	noteRouter.Handle("/events", read(http.HandlerFunc(app.handlers.EventsHandler.NoteEventsHandler))).Methods(GET, OPTIONS).Name("notes:events")
+	noteRouter.Handle("/bulk", write(http.HandlerFunc(app.handlers.UserHandler.BulkNotesHandler))).Methods(POST, OPTIONS).Name("notes:bulk")
+	noteRouter.Handle("/export", read(http.HandlerFunc(app.handlers.UserHandler.ExportNotesHandler))).Methods(GET, OPTIONS).Name("notes:export")
+	noteRouter.Handle("/import", write(http.HandlerFunc(app.handlers.UserHandler.ImportNotesHandler))).Methods(POST, OPTIONS).Name("notes:import")
+	noteRouter.Handle("", read(http.HandlerFunc(app.handlers.UserHandler.GetAllNotesByUserHandler))).Methods(GET, OPTIONS).Name("notes:list")
+	noteRouter.Handle("", write(http.HandlerFunc(app.handlers.UserHandler.CreateNoteHandler))).Methods(POST, OPTIONS).Name("notes:create")
+	noteRouter.Handle("/trash", read(http.HandlerFunc(app.handlers.UserHandler.GetTrashHandler))).Methods(GET, OPTIONS).Name("trash:list")
+	noteRouter.Handle("/trash", write(http.HandlerFunc(app.handlers.UserHandler.EmptyTrashHandler))).Methods(DELETE, OPTIONS).Name("trash:empty")
+	noteRouter.Handle("/{noteId}", read(http.HandlerFunc(app.handlers.UserHandler.GetNoteByIdHandler))).Methods(GET, OPTIONS).Name("notes:get")
+	noteRouter.Handle("/{noteId}", write(http.HandlerFunc(app.handlers.UserHandler.UpdateNoteHandler))).Methods(PUT, OPTIONS).Name("notes:update")
+	noteRouter.Handle("/{noteId}", write(http.HandlerFunc(app.handlers.UserHandler.PatchNoteHandler))).Methods(PATCH, OPTIONS).Name("notes:patch")
+	noteRouter.Handle("/{noteId}", write(http.HandlerFunc(app.handlers.UserHandler.DeleteNoteHandler))).Methods(DELETE, OPTIONS).Name("notes:delete")
+	noteRouter.Handle("/{noteId}/archive-toggle", write(http.HandlerFunc(app.handlers.UserHandler.ToggleArchiveStatusHandler))).Methods(PUT, OPTIONS).Name("archive-toggle")
+	noteRouter.Handle("/{noteId}/pin", write(http.HandlerFunc(app.handlers.UserHandler.PinNoteHandler))).Methods(PUT, OPTIONS).Name("notes:pin")
+	noteRouter.Handle("/{noteId}/move", write(http.HandlerFunc(app.handlers.UserHandler.MoveNoteHandler))).Methods(PUT, OPTIONS).Name("notes:move")
+
+	noteRouter.Handle("/{noteId}/share", write(http.HandlerFunc(app.handlers.ShareHandler.CreateShareHandler))).Methods(POST, OPTIONS).Name("shares:create")
+	noteRouter.Handle("/{noteId}/shares", read(http.HandlerFunc(app.handlers.ShareHandler.ListSharesHandler))).Methods(GET, OPTIONS).Name("shares:list")
+	noteRouter.Handle("/{noteId}/shares/{shareId}", write(http.HandlerFunc(app.handlers.ShareHandler.RevokeShareHandler))).Methods(DELETE, OPTIONS).Name("shares:revoke")
+
+	noteRouter.Handle("/{noteId}/permissions", read(http.HandlerFunc(app.handlers.UserHandler.ListNotePermissionsHandler))).Methods(GET, OPTIONS).Name("permissions:list")
+	noteRouter.Handle("/{noteId}/permissions/{username}", write(http.HandlerFunc(app.handlers.UserHandler.SetNotePermissionHandler))).Methods(PUT, OPTIONS).Name("permissions:set")
+	noteRouter.Handle("/{noteId}/permissions/{username}", write(http.HandlerFunc(app.handlers.UserHandler.DeleteNotePermissionHandler))).Methods(DELETE, OPTIONS).Name("permissions:delete")
+
+	noteRouter.Handle("/{noteId}/restore", write(http.HandlerFunc(app.handlers.UserHandler.RestoreNoteHandler))).Methods(POST, OPTIONS).Name("trash:restore")
+	noteRouter.Handle("/{noteId}/revisions", read(http.HandlerFunc(app.handlers.UserHandler.GetNoteRevisionsHandler))).Methods(GET, OPTIONS).Name("revisions:list")
+	noteRouter.Handle("/{noteId}/revisions/diff", read(http.HandlerFunc(app.handlers.UserHandler.DiffNoteRevisionsHandler))).Methods(GET, OPTIONS).Name("revisions:diff")
+	noteRouter.Handle("/{noteId}/revisions/{revision}/restore", write(http.HandlerFunc(app.handlers.UserHandler.RestoreNoteRevisionHandler))).Methods(POST, OPTIONS).Name("revisions:restore")
+
+	noteRouter.Handle("/{noteId}/categories/{categoryName}", write(http.HandlerFunc(app.handlers.UserHandler.AddCategoryToNoteHandler))).Methods(POST, OPTIONS).Name("category:add")
+	noteRouter.Handle("/{noteId}/categories/{categoryName}", write(http.HandlerFunc(app.handlers.UserHandler.RemoveCategoryFromNoteHandler))).Methods(DELETE, OPTIONS).Name("category:remove")
+
+	// CATEGORIES (PROTECTED) ROUTES
+	categoryRouter.Use(app.handlers.PROTECT)
+	categoryRouter.Handle("", read(http.HandlerFunc(app.handlers.CategoryHandler.GetCategoriesHandler))).Methods(GET, OPTIONS).Name("categories:list")
+	categoryRouter.Handle("", write(http.HandlerFunc(app.handlers.CategoryHandler.CreateCategoryHandler))).Methods(POST, OPTIONS).Name("categories:create")
+	categoryRouter.Handle("/{categoryId}", read(http.HandlerFunc(app.handlers.CategoryHandler.GetCategoryByIdHandler))).Methods(GET, OPTIONS).Name("categories:get")
+	categoryRouter.Handle("/{categoryId}", write(http.HandlerFunc(app.handlers.CategoryHandler.UpdateCategoryHandler))).Methods(PUT, OPTIONS).Name("categories:update")
+	categoryRouter.Handle("/{categoryId}", write(http.HandlerFunc(app.handlers.CategoryHandler.DeleteCategoryHandler))).Methods(DELETE, OPTIONS).Name("categories:delete")
+	categoryRouter.Handle("/{categoryId}/merge", write(http.HandlerFunc(app.handlers.CategoryHandler.MergeCategoryHandler))).Methods(POST, OPTIONS).Name("categories:merge")
+
+	// SYNC (PROTECTED) ROUTES
+	syncRouter.Use(app.handlers.PROTECT)
+	syncRouter.Handle("", read(http.HandlerFunc(app.handlers.UserHandler.SyncHandler))).Methods(GET, OPTIONS).Name("sync:pull")
+	syncRouter.Handle("", write(http.HandlerFunc(app.handlers.UserHandler.ApplySyncHandler))).Methods(POST, OPTIONS).Name("sync:push")
+
+	// SHARED NOTES (PUBLIC) ROUTES
+	mx.HandleFunc("/shared/{token}", app.handlers.ShareHandler.SharedNoteHandler).Methods(GET, POST, OPTIONS).Name("shared:open")
+
+	// ADMIN (PROTECTED) ROUTES
+	adminRouter.Use(app.handlers.PROTECT, app.handlers.RequireSession, app.handlers.ADMIN)
+	adminRouter.HandleFunc("/users", app.handlers.AdminHandler.ListUsersHandler).Methods(GET, OPTIONS).Name("admin:users-list")
+	adminRouter.HandleFunc("/users/{userId}", app.handlers.AdminHandler.DeleteUserHandler).Methods(DELETE, OPTIONS).Name("admin:users-delete")
+	adminRouter.HandleFunc("/users/{userId}/disable", app.handlers.AdminHandler.DisableUserHandler).Methods(POST, OPTIONS).Name("admin:users-disable")
+	adminRouter.HandleFunc("/users/{userId}/enable", app.handlers.AdminHandler.EnableUserHandler).Methods(POST, OPTIONS).Name("admin:users-enable")
+	adminRouter.HandleFunc("/users/{userId}/password-reset", app.handlers.AdminHandler.ForcePasswordResetHandler).Methods(POST, OPTIONS).Name("admin:users-password-reset")
 
 	// Default Fallback Handling
 	mx.PathPrefix("/").HandlerFunc(app.handlers.HttpErrs.NotFound).Name("fallback")
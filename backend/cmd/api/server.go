@@ -12,6 +12,10 @@ import (
 	"notes/internal/db"
 	"notes/internal/repositories"
 	"notes/internal/services"
+	"notes/pkg/password"
+	"notes/pkg/request"
+	"notes/pkg/throttle"
+	"notes/pkg/validations"
 	"os"
 	"os/signal"
 	"runtime/debug"
@@ -33,10 +37,12 @@ const (
 var logger *slog.Logger
 
 type application struct {
-	logger   *slog.Logger
-	wg       sync.WaitGroup
-	confs    *configs.Config
-	handlers *handlers.Handlers
+	logger      *slog.Logger
+	wg          sync.WaitGroup
+	confs       *configs.Config
+	handlers    *handlers.Handlers
+	trashPurger *services.TrashPurger
+	events      *services.EventBus
 }
 
 func Init() {
@@ -62,33 +68,70 @@ func (app *application) serveHttp() error {
 		},
 	}
 
+	// Shutdown waits for active requests, which event streams never finish
+	// on their own; closing the bus ends them.
+	srv.RegisterOnShutdown(app.events.Close)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	app.background(func() { app.trashPurger.Run(backgroundCtx) })
+
 	shutDownErrChan := make(chan error)
-	app.gracefulShutdown(srv, shutDownErrChan)
+	app.gracefulShutdown(srv, shutDownErrChan, stopBackground)
 	app.logger.Info("starting server ...", slog.Group("server", "addr", srv.Addr, "environment", app.confs.ENV))
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
+	if err := <-shutDownErrChan; err != nil {
+		return err
+	}
 	app.logger.Info("server stopped ...", slog.Group("server", "addr", srv.Addr))
 	app.wg.Wait()
 	return nil
 }
 
-func (app *application) gracefulShutdown(srv *http.Server, shutdownErrChan chan error) {
+func (app *application) gracefulShutdown(srv *http.Server, shutdownErrChan chan error, stopBackground context.CancelFunc) {
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownPeriod)
 		quitChannel := make(chan os.Signal, 1)
 		signal.Notify(quitChannel, syscall.SIGTERM, syscall.SIGINT)
 		<-quitChannel
 		defer cancel()
+		stopBackground()
 		shutdownErrChan <- srv.Shutdown(ctx)
 	}()
 }
 
+// background runs fn in a goroutine tracked by the application wait group,
+// so serveHttp only returns once it has finished.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err), "trace", string(debug.Stack()))
+			}
+		}()
+		fn()
+	}()
+}
+
 func run(logger *slog.Logger) error {
 	conf := configs.New()
+	// time.NewTicker panics on intervals that are not positive.
+	if conf.TRASH_PURGE_INTERVAL_MINUTES < 1 {
+		return errors.New("TRASH_PURGE_INTERVAL_MINUTES must be at least 1")
+	}
+	if conf.TRASH_RETENTION_HOURS < 0 {
+		return errors.New("TRASH_RETENTION_HOURS must not be negative")
+	}
+	if conf.SYNC_TOMBSTONE_RETENTION_DAYS < 1 {
+		return errors.New("SYNC_TOMBSTONE_RETENTION_DAYS must be at least 1")
+	}
 
-	db, err := db.New(logger, conf.DB_URI, conf.DB_NAME)
+	db, err := db.New(logger, conf.DB_DRIVER, conf.DB_URI, conf.DB_NAME)
 	if err != nil {
 		trace := string(debug.Stack())
 		logger.Error(err.Error(), "trace", trace)
@@ -100,20 +143,121 @@ func run(logger *slog.Logger) error {
 	categoryRepo := repositories.NewCategoryRepository(db, conf)
 	noteRepo := repositories.NewNoteRepository(db, conf, categoryRepo)
 	userRepo := repositories.NewUserRepository(db, conf)
+	sessionRepo := repositories.NewSessionRepository(db, conf)
+	accessTokenRepo := repositories.NewAccessTokenRepository(db, conf)
+	shareRepo := repositories.NewShareRepository(db, conf)
 	categoryService := services.NewCategoryService(categoryRepo)
-	noteService := services.NewNoteService(noteRepo, categoryService)
-	userService := services.NewUserService(userRepo, noteService)
+	dropPolicy, err := services.ParseDropPolicy(conf.EVENTS_DROP_POLICY)
+	if err != nil {
+		return err
+	}
+	if conf.EVENTS_HEARTBEAT_SECONDS < 1 {
+		return errors.New("EVENTS_HEARTBEAT_SECONDS must be at least 1")
+	}
+	eventBus := services.NewEventBus(conf.EVENTS_BUFFER_SIZE, conf.EVENTS_REPLAY_SIZE, dropPolicy)
+	noteService := services.NewNoteService(noteRepo, categoryService, eventBus)
+	sessionService := services.NewSessionService(sessionRepo)
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo)
+	loginThrottle := services.NewLoginThrottle(throttle.NewMemoryStore(), userLoginPolicy(conf), ipLoginPolicy(conf), logger)
+	passwordPolicy, err := password.NewPolicy(conf.PASSWORD_MIN_LENGTH, conf.PASSWORD_MAX_LENGTH, conf.PASSWORD_MIN_CLASSES, conf.PASSWORD_DENYLIST_FILE)
+	if err != nil {
+		return err
+	}
+	passwordHasher, err := newPasswordHasher(conf)
+	if err != nil {
+		return err
+	}
+	codeThrottle := services.NewAttemptThrottle(throttle.NewMemoryStore(), secondFactorPolicy(conf), validations.ErrTwoFactorThrottled, logger)
+	userService := services.NewUserService(userRepo, noteService, sessionService, loginThrottle, codeThrottle, passwordPolicy, passwordHasher)
+	sharePasswordThrottle := services.NewAttemptThrottle(throttle.NewMemoryStore(), sharePasswordPolicy(conf), validations.ErrShareThrottled, logger)
+	shareService := services.NewShareService(shareRepo, noteService, passwordHasher, sharePasswordThrottle)
+	adminService := services.NewAdminService(userRepo, sessionService, accessTokenService)
+	trashPurger := services.NewTrashPurger(
+		noteService,
+		time.Duration(conf.TRASH_RETENTION_HOURS)*time.Hour,
+		time.Duration(conf.SYNC_TOMBSTONE_RETENTION_DAYS)*24*time.Hour,
+		time.Duration(conf.TRASH_PURGE_INTERVAL_MINUTES)*time.Minute,
+		logger,
+	)
 	noteHandler := handlers.NewNoteHandler(noteService, httpErrs)
-	categoryHandler := handlers.NewCategoryHandler(categoryService, httpErrs)
-	userHandler := handlers.NewUserHandler(userService, httpErrs)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, noteService, httpErrs)
+	clientIP, err := request.NewClientIP(conf.TRUSTED_PROXIES)
+	if err != nil {
+		return err
+	}
+	userHandler := handlers.NewUserHandler(userService, clientIP, httpErrs)
+	tokenHandler := handlers.NewAccessTokenHandler(accessTokenService, httpErrs)
+	shareHandler := handlers.NewShareHandler(shareService, conf.API_URL, clientIP, httpErrs)
+	adminHandler := handlers.NewAdminHandler(adminService, httpErrs)
+	eventsHandler := handlers.NewEventsHandler(eventBus, time.Duration(conf.EVENTS_HEARTBEAT_SECONDS)*time.Second, httpErrs)
 
-	hdls := handlers.New(noteHandler, categoryHandler, userHandler, logger, httpErrs)
+	hdls := handlers.New(noteHandler, categoryHandler, userHandler, tokenHandler, shareHandler, adminHandler, eventsHandler, sessionService, accessTokenService, logger, httpErrs)
 
 	app := &application{
-		logger:   logger,
-		confs:    conf,
-		handlers: hdls,
+		logger:      logger,
+		confs:       conf,
+		handlers:    hdls,
+		trashPurger: trashPurger,
+		events:      eventBus,
 	}
 
 	return app.serveHttp()
 }
+
+// userLoginPolicy and ipLoginPolicy build the login throttling policies from
+// the configuration. Addresses get more free attempts than usernames since
+// many users can share one behind a NAT.
+func userLoginPolicy(conf *configs.Config) throttle.Policy {
+	policy := loginPolicy(conf)
+	policy.FreeAttempts = conf.LOGIN_USER_FREE_ATTEMPTS
+	policy.LockoutAfter = conf.LOGIN_USER_MAX_FAILURES
+	return policy
+}
+
+func ipLoginPolicy(conf *configs.Config) throttle.Policy {
+	policy := loginPolicy(conf)
+	policy.FreeAttempts = conf.LOGIN_IP_FREE_ATTEMPTS
+	policy.LockoutAfter = conf.LOGIN_IP_MAX_FAILURES
+	return policy
+}
+
+// sharePasswordPolicy throttles the passwords of share links, per link and
+// address, as strictly as the logins of a username.
+func sharePasswordPolicy(conf *configs.Config) throttle.Policy {
+	return userLoginPolicy(conf)
+}
+
+// secondFactorPolicy throttles the two-factor codes a signed in user gives
+// to disable two-factor authentication or delete the account, per user, as
+// strictly as the logins of a username.
+func secondFactorPolicy(conf *configs.Config) throttle.Policy {
+	return userLoginPolicy(conf)
+}
+
+func loginPolicy(conf *configs.Config) throttle.Policy {
+	return throttle.Policy{
+		BaseDelay:  time.Duration(conf.LOGIN_BACKOFF_BASE_SECONDS) * time.Second,
+		MaxDelay:   time.Duration(conf.LOGIN_BACKOFF_MAX_SECONDS) * time.Second,
+		LockoutFor: time.Duration(conf.LOGIN_LOCKOUT_MINUTES) * time.Minute,
+		Window:     time.Duration(conf.LOGIN_FAILURE_WINDOW_MINUTES) * time.Minute,
+	}
+}
+
+func newPasswordHasher(conf *configs.Config) (*password.Hasher, error) {
+	switch conf.PASSWORD_HASH_ALGORITHM {
+	case password.Argon2id, password.Bcrypt:
+	default:
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be %s or %s", password.Argon2id, password.Bcrypt)
+	}
+	return &password.Hasher{
+		Algorithm:  conf.PASSWORD_HASH_ALGORITHM,
+		BcryptCost: conf.PASSWORD_BCRYPT_COST,
+		Argon2: password.Argon2Params{
+			Memory:      uint32(conf.PASSWORD_ARGON2_MEMORY_KIB),
+			Iterations:  uint32(conf.PASSWORD_ARGON2_ITERATIONS),
+			Parallelism: uint8(conf.PASSWORD_ARGON2_PARALLELISM),
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}, nil
+}
@@ -0,0 +1,128 @@
+// Command migrate manages the schema of the database configured in .env.
+// The server no longer migrates on start, so apply pending migrations before
+// deploying a new version:
+//
+//	go run ./cmd/migrate up
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"notes/internal/configs"
+	"notes/internal/db"
+	"notes/internal/db/migrations"
+
+	"github.com/joho/godotenv"
+	"github.com/lmittmann/tint"
+)
+
+// sourceDir is where create writes new migrations, relative to backend.
+const sourceDir = "internal/db/migrations"
+
+const usage = `usage: migrate <command> [arguments]
+
+commands:
+  up              apply every pending migration
+  down [steps]    revert the latest applied migrations, one by default
+  status          list the migrations and when they were applied
+  create <name>   add an empty up and down migration for every driver to ` + sourceDir
+
+func main() {
+	logger := slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelInfo}))
+	if err := run(logger, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	switch command := args[0]; {
+	case command == "create" && len(args) == 2:
+		paths, err := migrations.Create(sourceDir, args[1])
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Printf("created %s\n", path)
+		}
+		return nil
+	case command == "up" && len(args) == 1,
+		command == "status" && len(args) == 1,
+		command == "down" && len(args) <= 2:
+	default:
+		return fmt.Errorf("%s", usage)
+	}
+
+	steps := 1
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("down: steps must be a positive number, got %q", args[1])
+		}
+		steps = n
+	}
+
+	migrator, err := newMigrator(logger)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", ran)
+	case "down":
+		ran, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", ran)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-30s  %s\n", s.Version, s.Name, applied)
+		}
+	}
+	return nil
+}
+
+func newMigrator(logger *slog.Logger) (*migrations.Migrator, error) {
+	if err := godotenv.Load(".env"); err != nil {
+		return nil, err
+	}
+	conf := configs.New()
+	gormDB, err := db.New(logger, conf.DB_DRIVER, conf.DB_URI, conf.DB_NAME)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := migrations.All(conf.DB_DRIVER)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB, conf.DB_DRIVER, all, logger), nil
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"notes/internal/services"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	AdminService *services.AdminService
+	HttpErrs     *HttpErrors
+}
+
+func NewAdminHandler(as *services.AdminService, httpErrs *HttpErrors) *AdminHandler {
+	return &AdminHandler{
+		AdminService: as,
+		HttpErrs:     httpErrs,
+	}
+}
+
+// ListUsersHandler lists every user.
+// @Summary List users
+// @Description Returns users ordered by ID with the number of notes each has outside the trash. Pages are chained with next_cursor from the metadata.
+// @Tags admin
+// @Security notes_jwt
+// @Produce json
+// @Param limit query int false "Page size, 1 to 100" default(50)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {array} AdminUserResponse "List of users"
+// @Failure 400 {object} ErrorResponse "Invalid limit or cursor"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/users [get]
+func (ah *AdminHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := pageRequestFromQuery(r)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	userPage, err := ah.AdminService.ListUsers(r.Context(), page)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	res := make([]AdminUserResponse, 0, len(userPage.Users))
+	for _, user := range userPage.Users {
+		res = append(res, AdminUserResponse{
+			ID:         user.ID,
+			UserName:   user.UserName,
+			IsAdmin:    user.IsAdmin,
+			IsDisabled: user.IsDisabled,
+			NoteCount:  user.NoteCount,
+			CreatedAt:  user.CreatedAt,
+		})
+	}
+	metadata := PageMetadata{Limit: page.Limit, Sort: "id"}
+	if userPage.NextCursor != "" {
+		metadata.NextCursor = &userPage.NextCursor
+	}
+	if err := response.JSONWithMetadata(w, http.StatusOK, res, metadata); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DisableUserHandler disables a user.
+// @Summary Disable a user
+// @Description Locks the user out: their sessions end, their access tokens are revoked and they cannot log in until enabled again. Admins cannot disable themselves.
+// @Tags admin
+// @Security notes_jwt
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} APIResponse "User disabled"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/users/{userId}/disable [post]
+func (ah *AdminHandler) DisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	ah.setDisabled(w, r, true)
+}
+
+// EnableUserHandler enables a disabled user.
+// @Summary Enable a user
+// @Description Lets a disabled user log in again.
+// @Tags admin
+// @Security notes_jwt
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} APIResponse "User enabled"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/users/{userId}/enable [post]
+func (ah *AdminHandler) EnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	ah.setDisabled(w, r, false)
+}
+
+func (ah *AdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	adminID, userID, err := adminAndTargetIDs(r)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := ah.AdminService.SetDisabled(r.Context(), adminID, userID, disabled); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	message := "User enabled"
+	if disabled {
+		message = "User disabled"
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": message}); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ForcePasswordResetHandler forces a user to choose a new password.
+// @Summary Force a password reset
+// @Description Ends the user's sessions, revokes their access tokens and refuses their logins until they set a new password at /user/password-reset with the returned reset token, valid for 24 hours. Hand the token to the user; it is not shown again.
+// @Tags admin
+// @Security notes_jwt
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} PasswordResetResponse "Reset token"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/users/{userId}/password-reset [post]
+func (ah *AdminHandler) ForcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	_, userID, err := adminAndTargetIDs(r)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	token, expiresAt, err := ah.AdminService.ForcePasswordReset(r.Context(), userID)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, PasswordResetResponse{ResetToken: token, ExpiresAt: expiresAt}); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DeleteUserHandler deletes a user and all their data.
+// @Summary Delete a user
+// @Description Permanently deletes the user with their notes, including the trash, categories, sessions and tokens. Admins cannot delete themselves.
+// @Tags admin
+// @Security notes_jwt
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} APIResponse "User deleted"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/users/{userId} [delete]
+func (ah *AdminHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, userID, err := adminAndTargetIDs(r)
+	if err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := ah.AdminService.DeleteUser(r.Context(), adminID, userID); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "User deleted"}); err != nil {
+		ah.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+func adminAndTargetIDs(r *http.Request) (uint, uint, error) {
+	userID, err := strconv.ParseUint(mux.Vars(r)["userId"], 10, 32)
+	if err != nil {
+		return 0, 0, validations.ErrInlvalidId
+	}
+	adminID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		return 0, 0, err
+	}
+	return *adminID, uint(userID), nil
+}
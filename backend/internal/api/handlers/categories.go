@@ -1,15 +1,268 @@
 package handlers
 
-import "notes/internal/services"
+import (
+	"net/http"
+	"notes/internal/models"
+	"notes/internal/services"
+	"notes/pkg/request"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+	"strconv"
 
+	"github.com/gorilla/mux"
+)
+
+// CategoryHandler serves the category endpoints. Renames, merges and
+// deletions go through the NoteService, as they change the user's notes too.
 type CategoryHandler struct {
-	CatService *services.CategoryService
-	HttpErrs   *HttpErrors
+	CatService  *services.CategoryService
+	NoteService *services.NoteService
+	HttpErrs    *HttpErrors
 }
 
-func NewCategoryHandler(cs *services.CategoryService, httpErrs *HttpErrors) *CategoryHandler {
+func NewCategoryHandler(cs *services.CategoryService, ns *services.NoteService, httpErrs *HttpErrors) *CategoryHandler {
 	return &CategoryHandler{
-		CatService: cs,
-		HttpErrs:   httpErrs,
+		CatService:  cs,
+		NoteService: ns,
+		HttpErrs:    httpErrs,
+	}
+}
+
+func toGetCategory(category *models.Category) GetCategory {
+	return GetCategory{
+		ID:        category.ID,
+		Name:      category.Name,
+		CreatedAt: category.CreatedAt,
+		UpdatedAt: category.UpdatedAt,
+	}
+}
+
+// GetCategoriesHandler lists the authenticated user's categories.
+// @Summary List categories
+// @Description Returns every category owned by the authenticated user, sorted by name.
+// @Tags categories
+// @Security notes_jwt
+// @Produce json
+// @Success 200 {array} GetCategory "List of categories"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories [get]
+func (ch *CategoryHandler) GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	categories, err := ch.CatService.GetAll(r.Context(), *userID)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	res := make([]GetCategory, 0, len(categories))
+	for i := range categories {
+		res = append(res, toGetCategory(&categories[i]))
+	}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// CreateCategoryHandler creates a category for the authenticated user.
+// @Summary Create a category
+// @Description Creates a new category owned by the authenticated user.
+// @Tags categories
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param category body CreateCategoryRequest true "Category data"
+// @Success 201 {object} GetCategory "Category created successfully"
+// @Failure 400 {object} ErrorResponse "Invalid category name"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 409 {object} ErrorResponse "Category already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories [post]
+func (ch *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	var req CreateCategoryRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		ch.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	category, err := ch.CatService.Create(r.Context(), *userID, req.Name)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusCreated, toGetCategory(category)); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// GetCategoryByIdHandler retrieves one of the authenticated user's categories.
+// @Summary Retrieve a category by ID
+// @Description Fetches a single category owned by the authenticated user.
+// @Tags categories
+// @Security notes_jwt
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Success 200 {object} GetCategory "Category retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Invalid category ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{categoryId} [get]
+func (ch *CategoryHandler) GetCategoryByIdHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseUint(mux.Vars(r)["categoryId"], 10, 32)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	category, err := ch.CatService.GetById(r.Context(), *userID, uint(categoryID))
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, toGetCategory(category)); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// UpdateCategoryHandler renames one of the authenticated user's categories.
+// @Summary Rename a category
+// @Description Renames a category; every note tagged with it shows the new name and gets a new version, so its previous ETag no longer matches.
+// @Tags categories
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Param category body UpdateCategoryRequest true "New category name"
+// @Success 200 {object} GetCategory "Category renamed successfully"
+// @Failure 400 {object} ErrorResponse "Invalid category ID or name"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 409 {object} ErrorResponse "Category name already in use"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{categoryId} [put]
+func (ch *CategoryHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseUint(mux.Vars(r)["categoryId"], 10, 32)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		ch.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	category, err := ch.NoteService.RenameCategory(r.Context(), *userID, uint(categoryID), req.Name)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, toGetCategory(category)); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DeleteCategoryHandler deletes one of the authenticated user's categories.
+// @Summary Delete a category
+// @Description Deletes a category and detaches it from its notes. Refused when a note would be left without categories. The notes it is detached from get a new version.
+// @Tags categories
+// @Security notes_jwt
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Success 200 {object} APIResponse "ID of the deleted category"
+// @Failure 400 {object} ErrorResponse "Invalid category ID or category still required by a note"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{categoryId} [delete]
+func (ch *CategoryHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseUint(mux.Vars(r)["categoryId"], 10, 32)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	deletedID, err := ch.NoteService.DeleteCategory(r.Context(), *userID, uint(categoryID))
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, deletedID); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// MergeCategoryHandler merges one category into another.
+// @Summary Merge a category into another
+// @Description Re-points every note from the category in the path to the target category, then deletes the merged category. Runs in a single transaction; the re-pointed notes get a new version.
+// @Tags categories
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param categoryId path int true "ID of the category to merge and delete"
+// @Param merge body MergeCategoryRequest true "Target category"
+// @Success 200 {object} GetCategory "Target category after the merge"
+// @Failure 400 {object} ErrorResponse "Invalid category IDs"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{categoryId}/merge [post]
+func (ch *CategoryHandler) MergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseUint(mux.Vars(r)["categoryId"], 10, 32)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	var req MergeCategoryRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		ch.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	if req.TargetID == 0 {
+		ch.HttpErrs.CheckErrType(w, r, validations.ErrMissingParameters)
+		return
+	}
+
+	target, err := ch.NoteService.MergeCategories(r.Context(), *userID, uint(categoryID), req.TargetID)
+	if err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, toGetCategory(target)); err != nil {
+		ch.HttpErrs.CheckErrType(w, r, err)
 	}
 }
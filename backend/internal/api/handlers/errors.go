@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"notes/internal/services"
 	"notes/pkg/response"
 	"notes/pkg/validations"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 )
 
@@ -58,10 +61,14 @@ func (h *HttpErrors) reportServerError(r *http.Request, err error) {
 }
 
 func (h *HttpErrors) errorMessage(w http.ResponseWriter, r *http.Request, status int, key CustomErrKey, message string, headers http.Header) {
+	h.errorMessageWithData(w, r, status, key, message, nil, headers)
+}
+
+func (h *HttpErrors) errorMessageWithData(w http.ResponseWriter, r *http.Request, status int, key CustomErrKey, message string, data any, headers http.Header) {
 	errObject := map[CustomErrKey]string{
 		key: message,
 	}
-	err := response.ErrJSONWithHeaders(w, status, errObject, headers)
+	err := response.ErrJSONWithData(w, status, errObject, data, headers)
 	if err != nil {
 		h.reportServerError(r, err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -104,11 +111,54 @@ func (h *HttpErrors) Unauthorized(w http.ResponseWriter, r *http.Request, key Cu
 	h.errorMessage(w, r, http.StatusUnauthorized, key, message, nil)
 }
 
+func (h *HttpErrors) forbidden(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusForbidden, key, err.Error(), nil)
+}
+
 // REQUEST/CLIENT - DB
 func (h *HttpErrors) badRequest(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
 	h.errorMessage(w, r, http.StatusBadRequest, key, err.Error(), nil)
 }
 
+// REQUEST/CLIENT - RESOURCE
+func (h *HttpErrors) notFound(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusNotFound, key, err.Error(), nil)
+}
+
+func (h *HttpErrors) conflict(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusConflict, key, err.Error(), nil)
+}
+
+func (h *HttpErrors) gone(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusGone, key, err.Error(), nil)
+}
+
+func (h *HttpErrors) unsupportedMediaType(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusUnsupportedMediaType, key, err.Error(), nil)
+}
+
+// REQUEST/CLIENT - CONDITIONAL
+// preconditionFailed reports a stale If-Match and sends the current state of
+// the resource so the client can reconcile without another request.
+func (h *HttpErrors) preconditionFailed(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey, current any, headers http.Header) {
+	h.errorMessageWithData(w, r, http.StatusPreconditionFailed, key, err.Error(), current, headers)
+}
+
+func (h *HttpErrors) preconditionRequired(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	h.errorMessage(w, r, http.StatusPreconditionRequired, key, err.Error(), nil)
+}
+
+// tooManyRequests tells the client when it may retry, if the error says so.
+func (h *HttpErrors) tooManyRequests(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
+	var headers http.Header
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		headers = http.Header{"Retry-After": []string{strconv.Itoa(max(seconds, 1))}}
+	}
+	h.errorMessage(w, r, http.StatusTooManyRequests, key, err.Error(), headers)
+}
+
 // REQUEST/CLIENT - API
 func (h *HttpErrors) gatewayTimeout(w http.ResponseWriter, r *http.Request, err error, key CustomErrKey) {
 	h.errorMessage(w, r, http.StatusGatewayTimeout, key, err.Error(), nil)
@@ -148,20 +198,90 @@ func (h *HttpErrors) CheckErrType(w http.ResponseWriter, r *http.Request, err er
 		errors.Is(err, validations.ErrRepeatedLetters),
 		errors.Is(err, validations.ErrNoNotesFound),
 		errors.Is(err, validations.ErreEmptyTitle),
+		errors.Is(err, validations.ErrSearchQuery),
+		errors.Is(err, validations.ErrInvalidLimit),
+		errors.Is(err, validations.ErrInvalidPageLimit),
+		errors.Is(err, validations.ErrInvalidCursor),
+		errors.Is(err, validations.ErrInvalidSort),
+		errors.Is(err, validations.ErrCatInUse),
+		errors.Is(err, validations.ErrCatMergeSelf),
+		errors.Is(err, validations.ErrInvalidRevision),
+		errors.Is(err, validations.ErrInvalidIfMatch),
+		errors.Is(err, validations.ErrPatchRemoveField),
+		errors.Is(err, validations.ErrBulkOperation),
+		errors.Is(err, validations.ErrBulkNoteIds),
+		errors.Is(err, validations.ErrExportFormat),
+		errors.Is(err, validations.ErrSyncToken),
+		errors.Is(err, validations.ErrSyncLimit),
+		errors.Is(err, validations.ErrSyncChanges),
+		errors.Is(err, validations.ErrImportArchive),
+		errors.Is(err, validations.ErrImportTooLarge),
+		errors.Is(err, validations.ErrTOTPNotEnrolled),
+		errors.Is(err, validations.ErrAccessTokenName),
+		errors.Is(err, validations.ErrAccessTokenScopes),
+		errors.Is(err, validations.ErrAccessTokenExpiry),
+		errors.Is(err, validations.ErrShareExpiry),
+		errors.Is(err, validations.ErrSharePasswordLength),
+		errors.Is(err, validations.ErrNoteRole),
+		errors.Is(err, validations.ErrPermissionSelf),
+		errors.Is(err, validations.ErrNoteScope),
+		errors.Is(err, validations.ErrNoteMove),
+		errors.Is(err, validations.ErrMoveTarget),
+		errors.Is(err, validations.ErrMovePinned),
+		errors.Is(err, validations.ErrAdminSelf),
+		errors.Is(err, validations.ErrPasswordLength),
+		errors.Is(err, validations.ErrPasswordClasses),
+		errors.Is(err, validations.ErrPasswordCommon),
+		errors.Is(err, validations.ErrPasswordUsername),
+		errors.Is(err, validations.ErrPasswordUnchanged),
+		errors.Is(err, validations.ErrCurrentPassword),
+		errors.Is(err, validations.ErrTOTPNotEnabled),
+		errors.Is(err, validations.ErrZeroCategory),
 		errors.Is(err, validations.ErrTooManyCat):
 		h.badRequest(w, r, err, ReqErrKey)
 		return
 
+	case errors.Is(err, validations.ErrCatNotFound),
+		errors.Is(err, validations.ErrRevisionNotFound),
+		errors.Is(err, validations.ErrTrashedNoteNotFound),
+		errors.Is(err, validations.ErrAccessTokenNotFound),
+		errors.Is(err, validations.ErrShareNotFound),
+		errors.Is(err, validations.ErrPermissionUser),
+		errors.Is(err, validations.ErrPermissionNotFound),
+		errors.Is(err, validations.ErrUserNotFound):
+		h.notFound(w, r, err, ReqErrKey)
+		return
+
+	case errors.Is(err, validations.ErrCatAlreadyExist),
+		errors.Is(err, validations.ErrTOTPEnabled):
+		h.conflict(w, r, err, ReqErrKey)
+		return
+
+	case errors.Is(err, validations.ErrSyncTokenExpired):
+		h.gone(w, r, err, ReqErrKey)
+		return
+
+	case errors.Is(err, validations.ErrVersionMismatch):
+		h.preconditionFailed(w, r, err, ReqErrKey, nil, nil)
+		return
+
+	case errors.Is(err, validations.ErrIfMatchRequired):
+		h.preconditionRequired(w, r, err, ReqErrKey)
+		return
+
+	case errors.Is(err, validations.ErrPatchContentType):
+		h.unsupportedMediaType(w, r, err, ReqErrKey)
+		return
+
 	// DATABASE
 	case errors.Is(err, validations.ErrFetchingCategory),
 		errors.Is(err, validations.ErrUserIdNotSet),
 		errors.Is(err, validations.ErrFetchingNotes),
 		errors.Is(err, validations.ErrFilterDB),
-		errors.Is(err, validations.ErrCatAlreadyExist),
 		errors.Is(err, validations.ErrCatCreate),
-		errors.Is(err, validations.ErrCatNotFound),
 		errors.Is(err, validations.ErrCatUpdate),
 		errors.Is(err, validations.ErrCatDelete),
+		errors.Is(err, validations.ErrCatMerge),
 		errors.Is(err, validations.ErrNotTitle),
 		errors.Is(err, validations.ErrFetchingCategories),
 		errors.Is(err, validations.ErrNoteCreate),
@@ -169,6 +289,20 @@ func (h *HttpErrors) CheckErrType(w http.ResponseWriter, r *http.Request, err er
 		errors.Is(err, validations.ErrFetchingNote),
 		errors.Is(err, validations.ErrAddNewCatToNote),
 		errors.Is(err, validations.ErrNoteUpdate),
+		errors.Is(err, validations.ErrSearchDB),
+		errors.Is(err, validations.ErrRevisionCreate),
+		errors.Is(err, validations.ErrFetchingRevisions),
+		errors.Is(err, validations.ErrTrashDB),
+		errors.Is(err, validations.ErrNoteRestore),
+		errors.Is(err, validations.ErrSessionDB),
+		errors.Is(err, validations.ErrTwoFactorDB),
+		errors.Is(err, validations.ErrAccessTokenDB),
+		errors.Is(err, validations.ErrShareDB),
+		errors.Is(err, validations.ErrPermissionDB),
+		errors.Is(err, validations.ErrUserDB),
+		errors.Is(err, validations.ErrAccountExport),
+		errors.Is(err, validations.ErrAccountDelete),
+		errors.Is(err, validations.ErrSyncDB),
 		errors.Is(err, validations.ErrNoteDelete):
 		h.ServerError(w, r, err, DBErrKey)
 
@@ -203,6 +337,33 @@ func (h *HttpErrors) CheckErrType(w http.ResponseWriter, r *http.Request, err er
 		h.Unauthorized(w, r, AUTH, validations.ErrInvalidUserID.Error())
 	case errors.Is(err, validations.ErrUnauthorized):
 		h.Unauthorized(w, r, AUTH, validations.ErrUnauthorized.Error())
+	case errors.Is(err, validations.ErrRefreshToken):
+		h.Unauthorized(w, r, AUTH, validations.ErrRefreshToken.Error())
+	case errors.Is(err, validations.ErrRefreshTokenReuse):
+		h.Unauthorized(w, r, AUTH, validations.ErrRefreshTokenReuse.Error())
+	case errors.Is(err, validations.ErrSessionRevoked):
+		h.Unauthorized(w, r, AUTH, validations.ErrSessionRevoked.Error())
+	case errors.Is(err, validations.ErrInvalidTOTPCode):
+		h.Unauthorized(w, r, AUTH, validations.ErrInvalidTOTPCode.Error())
+	case errors.Is(err, validations.ErrChallengeToken):
+		h.Unauthorized(w, r, AUTH, validations.ErrChallengeToken.Error())
+	case errors.Is(err, validations.ErrAccessToken):
+		h.Unauthorized(w, r, AUTH, validations.ErrAccessToken.Error())
+	case errors.Is(err, validations.ErrSharePassword):
+		h.Unauthorized(w, r, AUTH, validations.ErrSharePassword.Error())
+	case errors.Is(err, validations.ErrLoginThrottled),
+		errors.Is(err, validations.ErrShareThrottled),
+		errors.Is(err, validations.ErrTwoFactorThrottled):
+		h.tooManyRequests(w, r, err, AUTH)
+	case errors.Is(err, validations.ErrResetToken):
+		h.Unauthorized(w, r, AUTH, validations.ErrResetToken.Error())
+	case errors.Is(err, validations.ErrInsufficientScope),
+		errors.Is(err, validations.ErrSessionRequired),
+		errors.Is(err, validations.ErrAdminRequired),
+		errors.Is(err, validations.ErrNotePermission),
+		errors.Is(err, validations.ErrUserDisabled),
+		errors.Is(err, validations.ErrPasswordReset):
+		h.forbidden(w, r, err, AUTH)
 
 	// DEFAULT
 	default:
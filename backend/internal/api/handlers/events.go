@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"notes/internal/models"
+	"notes/internal/services"
+	"time"
+)
+
+// eventWriteWait bounds each write to an event stream. The server
+// WriteTimeout would otherwise end every stream after a few seconds, so the
+// deadline is pushed forward before each write instead.
+const eventWriteWait = 10 * time.Second
+
+type EventsHandler struct {
+	Events    *services.EventBus
+	Heartbeat time.Duration
+	HttpErrs  *HttpErrors
+}
+
+func NewEventsHandler(events *services.EventBus, heartbeat time.Duration, httpErrs *HttpErrors) *EventsHandler {
+	return &EventsHandler{
+		Events:    events,
+		Heartbeat: heartbeat,
+		HttpErrs:  httpErrs,
+	}
+}
+
+// NoteEventsHandler streams changes to the authenticated user's notes.
+// @Summary Stream note events
+// @Description Server-Sent Events stream of the user's note changes: created, updated, archived, unarchived, deleted, restored and category-changed. The data of each event is a NoteEventResponse; deleted events carry no note. Idle streams receive a heartbeat comment. Reconnecting with Last-Event-ID replays the events missed since then; when they can no longer be replayed, or the client fell behind and events were dropped, a reset event is sent and the client should reload its notes.
+// @Tags notes
+// @Security notes_jwt
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received, to resume the stream"
+// @Success 200 {object} NoteEventResponse "Event stream"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/events [get]
+func (eh *EventsHandler) NoteEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		eh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	sub, missed, complete := eh.Events.Subscribe(*userID, r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: http.NewResponseController(w), wait: eh.Heartbeat + eventWriteWait}
+	if !complete {
+		stream.send(eh.Events.FormatID(sub.Start()), "reset", "{}")
+	}
+	for _, event := range missed {
+		stream.event(eh.Events, event)
+	}
+	stream.flush()
+
+	// The stream outlives the checks PROTECT made when it opened: it ends
+	// when the credentials expire, and every heartbeat makes them again so a
+	// logout, revocation or disabled account closes it too.
+	var expired <-chan time.Time
+	if expiresAt := authExpiry(r.Context()); !expiresAt.IsZero() {
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+	heartbeat := time.NewTicker(eh.Heartbeat)
+	defer heartbeat.Stop()
+	for stream.err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case <-expired:
+			return
+		case <-heartbeat.C:
+			if err := checkAuth(r.Context()); err != nil {
+				return
+			}
+			stream.write(": heartbeat\n\n")
+		case event := <-sub.Events():
+			if sub.Lagged() {
+				stream.send("", "reset", "{}")
+			}
+			stream.event(eh.Events, event)
+		}
+		stream.flush()
+	}
+	if errors.Is(stream.err, http.ErrNotSupported) {
+		// Nothing reaches the client when the writer cannot flush.
+		eh.HttpErrs.reportServerError(r, stream.err)
+	}
+}
+
+// eventStream writes Server-Sent Events, remembering the first error so the
+// stream can stop at the next check.
+type eventStream struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	wait time.Duration
+	err  error
+}
+
+func (s *eventStream) event(events *services.EventBus, event models.NoteEvent) {
+	data := NoteEventResponse{Type: event.Type, NoteID: event.NoteID, At: event.At}
+	if event.Note != nil {
+		note := toGetNoteResponse(event.Note)
+		data.Note = &note
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.send(events.FormatID(event.ID), string(event.Type), string(payload))
+}
+
+func (s *eventStream) send(id, name, data string) {
+	if id != "" {
+		s.write("id: " + id + "\n")
+	}
+	s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", name, data))
+}
+
+func (s *eventStream) write(text string) {
+	if s.err != nil {
+		return
+	}
+	err := s.rc.SetWriteDeadline(time.Now().Add(eventWriteWait))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.err = err
+		return
+	}
+	_, s.err = s.w.Write([]byte(text))
+}
+
+// flush sends what was written and leaves the connection enough time to
+// sit idle until the next heartbeat.
+func (s *eventStream) flush() {
+	if s.err != nil {
+		return
+	}
+	if s.err = s.rc.Flush(); s.err != nil {
+		return
+	}
+	if err := s.rc.SetWriteDeadline(time.Now().Add(s.wait)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.err = err
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/services"
+)
+
+// openEventStream serves NoteEventsHandler for user 1, with the given
+// context values set as PROTECT would, and opens a stream to it.
+func openEventStream(t *testing.T, bus *services.EventBus, lastEventID string, values map[contextKey]any) *bufio.Reader {
+	t.Helper()
+	eh := NewEventsHandler(bus, 50*time.Millisecond, NewHttpErrors(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), userIDKey, uint(1))
+		for key, value := range values {
+			ctx = context.WithValue(ctx, key, value)
+		}
+		eh.NoteEventsHandler(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	// Cleanups run last in first out: the request ends before the server
+	// waits for its handlers.
+	t.Cleanup(func() {
+		cancel()
+		res.Body.Close()
+	})
+	if got := res.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type %q, want text/event-stream", got)
+	}
+	return bufio.NewReader(res.Body)
+}
+
+// readFrame reads the lines of the next frame of the stream, up to the blank
+// line ending it.
+func readFrame(t *testing.T, stream *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the stream: %v after %q", err, lines)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestNoteEventsHandler(t *testing.T) {
+	note := &models.Note{ID: 7, UserID: 1, Title: "shopping", Content: "buy milk"}
+
+	t.Run("reset and live events", func(t *testing.T) {
+		bus := services.NewEventBus(8, 10, services.Disconnect)
+		stream := openEventStream(t, bus, "0-1", nil)
+
+		frame := readFrame(t, stream)
+		if len(frame) != 3 || frame[1] != "event: reset" || frame[2] != "data: {}" {
+			t.Fatalf("resuming from another epoch: got %q, want a reset event", frame)
+		}
+
+		bus.Publish(models.NewNoteEvent(models.NoteCreated, note))
+		frame = readFrame(t, stream)
+		for frame[0] == ": heartbeat" {
+			frame = readFrame(t, stream)
+		}
+		if len(frame) != 3 || frame[0] != "id: "+bus.FormatID(1) || frame[1] != "event: created" ||
+			!strings.Contains(frame[2], `"note_id":7`) || !strings.Contains(frame[2], `"title":"shopping"`) {
+			t.Errorf("got %q, want the created event of note 7", frame)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		bus := services.NewEventBus(8, 10, services.Disconnect)
+		bus.Publish(models.NewNoteEvent(models.NoteCreated, note))
+		bus.Publish(models.NewNoteEvent(models.NoteArchived, note))
+		bus.Publish(models.NewNoteEvent(models.NoteCreated, &models.Note{ID: 8, UserID: 2}))
+
+		stream := openEventStream(t, bus, bus.FormatID(1), nil)
+		frame := readFrame(t, stream)
+		if len(frame) != 3 || frame[0] != "id: "+bus.FormatID(2) || frame[1] != "event: archived" {
+			t.Fatalf("got %q, want the archived event replayed", frame)
+		}
+		if frame = readFrame(t, stream); frame[0] != ": heartbeat" {
+			t.Errorf("got %q after the replay, want a heartbeat", frame)
+		}
+	})
+
+	t.Run("revoked credentials", func(t *testing.T) {
+		bus := services.NewEventBus(8, 10, services.Disconnect)
+		stream := openEventStream(t, bus, "", map[contextKey]any{
+			authKey: &authCheck{recheck: func(context.Context) error { return errors.New("session revoked") }},
+		})
+		if _, err := stream.ReadString('\n'); err != io.EOF {
+			t.Errorf("stream still open after the credentials were revoked: %v", err)
+		}
+	})
+
+	t.Run("expired credentials", func(t *testing.T) {
+		bus := services.NewEventBus(8, 10, services.Disconnect)
+		stream := openEventStream(t, bus, "", map[contextKey]any{
+			authKey: &authCheck{
+				expiresAt: time.Now().Add(20 * time.Millisecond),
+				recheck:   func(context.Context) error { return nil },
+			},
+		})
+		if _, err := stream.ReadString('\n'); err != io.EOF {
+			t.Errorf("stream still open after the credentials expired: %v", err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"log/slog"
 	"net/http"
+	"notes/internal/services"
 	"notes/pkg/response"
 
 	"github.com/gorilla/mux"
@@ -15,13 +16,19 @@ type Handlers struct {
 	NoteHandler       *NoteHandler
 	CategoryHandler   *CategoryHandler
 	UserHandler       *UserHandler
+	TokenHandler      *AccessTokenHandler
+	ShareHandler      *ShareHandler
+	AdminHandler      *AdminHandler
+	EventsHandler     *EventsHandler
+	Sessions          *services.SessionService
+	AccessTokens      *services.AccessTokenService
 	Logger            *slog.Logger
 	HttpErrs          *HttpErrors
 	HttpRequestsTotal *prometheus.CounterVec
 	HttpDuration      *prometheus.HistogramVec
 }
 
-func New(nh *NoteHandler, ch *CategoryHandler, uh *UserHandler, logger *slog.Logger, httpErrs *HttpErrors) *Handlers {
+func New(nh *NoteHandler, ch *CategoryHandler, uh *UserHandler, th *AccessTokenHandler, sh *ShareHandler, ah *AdminHandler, eh *EventsHandler, sessions *services.SessionService, accessTokens *services.AccessTokenService, logger *slog.Logger, httpErrs *HttpErrors) *Handlers {
 	requestsTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
@@ -39,11 +46,17 @@ func New(nh *NoteHandler, ch *CategoryHandler, uh *UserHandler, logger *slog.Log
 	)
 
 	// Register metrics
-	prometheus.MustRegister(requestsTotal, requestDuration)
+	prometheus.MustRegister(requestsTotal, requestDuration, sh.Views)
 	return &Handlers{
 		NoteHandler:       nh,
 		CategoryHandler:   ch,
 		UserHandler:       uh,
+		TokenHandler:      th,
+		ShareHandler:      sh,
+		AdminHandler:      ah,
+		EventsHandler:     eh,
+		Sessions:          sessions,
+		AccessTokens:      accessTokens,
 		Logger:            logger,
 		HttpErrs:          httpErrs,
 		HttpRequestsTotal: requestsTotal,
@@ -6,6 +6,7 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"notes/internal/configs"
@@ -17,7 +18,6 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
-	"github.com/tomasen/realip"
 )
 
 func (h *Handlers) RecoverPanic(next http.Handler) http.Handler {
@@ -37,7 +37,7 @@ func (h *Handlers) LogAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		mw := response.NewMetricsWriter(w)
 		var (
-			ip     = realip.FromRequest(r)
+			ip     = h.UserHandler.ClientIP.FromRequest(r)
 			method = r.Method
 			url    = r.URL.String()
 			proto  = r.Proto
@@ -56,8 +56,9 @@ func (h *Handlers) AddHeadersWithCSP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After")
 
 		if r.Method == http.MethodOptions {
 			if handlePreflight(w, origin, conf) {
@@ -108,6 +109,11 @@ func isValidOrigin(origin string, conf *configs.Config) bool {
 		return true
 	}
 
+	// Pages the API serves itself, such as shared notes, post back to it.
+	if origin == strings.TrimRight(conf.API_URL, "/") {
+		return true
+	}
+
 	allowedOrigins := strings.Split(strings.ReplaceAll(conf.ALLOWED_ORIGINS, " ", ""), ",")
 	for _, allowed := range allowedOrigins {
 		if allowed == origin {
@@ -130,6 +136,15 @@ func addContentSecurityPolicy(w http.ResponseWriter, r *http.Request, origin str
 			"default-src 'self' 'unsafe-inline' 'unsafe-eval'; "+
 				"img-src data: https://cdn.ngrok.com; "+
 				"connect-src 'self' "+origin)
+	} else if strings.HasPrefix(r.URL.Path, "/shared/") {
+		// Shared notes are plain pages showing other people's text: no
+		// scripts, no external content, and forms only post back here.
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'none'; "+
+				"style-src 'unsafe-inline'; "+
+				"form-action 'self'; "+
+				"frame-ancestors 'none'; "+
+				"base-uri 'none'")
 	} else {
 		w.Header().Set("Content-Security-Policy",
 			"default-src 'self'; "+
@@ -140,9 +155,16 @@ func addContentSecurityPolicy(w http.ResponseWriter, r *http.Request, origin str
 }
 
 // DEBUG END
-func (h *Handlers) WithTimeout(duration time.Duration) func(http.Handler) http.Handler {
+
+// WithTimeout cancels the request context after duration, except on the
+// named streaming routes, which stay open for as long as the client listens.
+func (h *Handlers) WithTimeout(duration time.Duration, streams ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && slices.Contains(streams, route.GetName()) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), duration)
 			defer cancel()
 			r = r.WithContext(ctx)
@@ -153,42 +175,191 @@ func (h *Handlers) WithTimeout(duration time.Duration) func(http.Handler) http.H
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey contextKey = "userID"
+	scopesKey contextKey = "scopes"
+	adminKey  contextKey = "admin"
+
+	sessionIDKey contextKey = "sessionID"
+	authKey      contextKey = "auth"
+)
 
 type CustomClaims struct {
 	UserID uint `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
+// PROTECT authenticates the request with the session cookie or, for scripts
+// and CLI clients, with a personal access token in the Authorization header.
+// Disabled users are rejected even while their tokens are still valid.
 func (h *Handlers) PROTECT(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(configs.GetString("JWT_NAME", ""))
-		if err != nil || strings.TrimSpace(cookie.Value) == "" {
-			h.HttpErrs.CheckErrType(w, r, validations.ErrJWT)
+		creds, err := h.authenticate(r)
+		if err != nil {
+			h.HttpErrs.CheckErrType(w, r, err)
 			return
 		}
 
-		tokenStr := cookie.Value
-
-		claims := &CustomClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(configs.GetString("JWT_STRING", "")), nil
+		ctx := context.WithValue(r.Context(), userIDKey, creds.userID)
+		ctx = context.WithValue(ctx, adminKey, creds.isAdmin)
+		if creds.scopes != nil {
+			ctx = context.WithValue(ctx, scopesKey, creds.scopes)
+		} else {
+			ctx = context.WithValue(ctx, sessionIDKey, creds.sessionID)
+		}
+		ctx = context.WithValue(ctx, authKey, &authCheck{
+			expiresAt: creds.expiresAt,
+			recheck: func(ctx context.Context) error {
+				_, err := h.authenticate(r.WithContext(ctx))
+				return err
+			},
 		})
-		if err != nil || !token.Valid {
-			h.HttpErrs.CheckErrType(w, r, validations.ErrJWT)
-			return
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type credentials struct {
+	userID    uint
+	sessionID string
+	scopes    []string
+	isAdmin   bool
+	expiresAt time.Time
+}
+
+// authenticate runs the checks of PROTECT: a valid access token cookie with
+// a live session, or an unrevoked personal access token, of an active user.
+func (h *Handlers) authenticate(r *http.Request) (*credentials, error) {
+	creds := &credentials{}
+	if raw, ok := bearerToken(r); ok {
+		token, err := h.AccessTokens.Authenticate(r.Context(), raw)
+		if err != nil {
+			return nil, err
 		}
+		creds.userID, creds.scopes, creds.expiresAt = token.UserID, token.ScopeList(), token.ExpiresAt
+	} else {
+		claims, err := h.sessionClaims(r)
+		if err != nil {
+			return nil, err
+		}
+		creds.userID, creds.sessionID = claims.UserID, claims.ID
+		if claims.ExpiresAt != nil {
+			creds.expiresAt = claims.ExpiresAt.Time
+		}
+	}
+
+	user, err := h.UserHandler.UserService.CheckActive(r.Context(), creds.userID)
+	if err != nil {
+		return nil, err
+	}
+	creds.isAdmin = user.IsAdmin
+	return creds, nil
+}
+
+// authCheck lets long-lived requests repeat the authentication of PROTECT,
+// since a session can be revoked or a user disabled while they are open.
+type authCheck struct {
+	expiresAt time.Time
+	recheck   func(ctx context.Context) error
+}
 
-		if claims.ExpiresAt != nil && date.ArgentinaTimeNow().After(claims.ExpiresAt.Time) {
-			h.HttpErrs.CheckErrType(w, r, validations.ErrTokenExpired)
+// checkAuth fails once the credentials of the request have expired or no
+// longer pass PROTECT. Requests that did not go through PROTECT always pass.
+func checkAuth(ctx context.Context) error {
+	check, ok := ctx.Value(authKey).(*authCheck)
+	if !ok {
+		return nil
+	}
+	if !check.expiresAt.IsZero() && date.ArgentinaTimeNow().After(check.expiresAt) {
+		return validations.ErrTokenExpired
+	}
+	return check.recheck(ctx)
+}
+
+// authExpiry is when the credentials of the request expire, zero when they
+// do not or the request did not go through PROTECT.
+func authExpiry(ctx context.Context) time.Time {
+	if check, ok := ctx.Value(authKey).(*authCheck); ok {
+		return check.expiresAt
+	}
+	return time.Time{}
+}
+
+// sessionClaims validates the access token cookie and its session.
+func (h *Handlers) sessionClaims(r *http.Request) (*CustomClaims, error) {
+	cookie, err := r.Cookie(configs.GetString("JWT_NAME", ""))
+	if err != nil || strings.TrimSpace(cookie.Value) == "" {
+		return nil, validations.ErrJWT
+	}
+
+	claims := &CustomClaims{}
+	token, err := jwt.ParseWithClaims(cookie.Value, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(configs.GetString("JWT_STRING", "")), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, validations.ErrJWT
+	}
+
+	if claims.ExpiresAt != nil && date.ArgentinaTimeNow().After(claims.ExpiresAt.Time) {
+		return nil, validations.ErrTokenExpired
+	}
+
+	// The jti is the session; a revoked session invalidates its tokens.
+	active, err := h.Sessions.IsActive(r.Context(), claims.ID, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, validations.ErrSessionRevoked
+	}
+	return claims, nil
+}
+
+// ADMIN rejects users without the admin role. It must run after PROTECT.
+func (h *Handlers) ADMIN(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAdmin, _ := r.Context().Value(adminKey).(bool); !isAdmin {
+			h.HttpErrs.CheckErrType(w, r, validations.ErrAdminRequired)
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+// RequireScope rejects requests made with an access token that lacks the
+// scope. Session requests are not scoped and always pass. It must run after
+// PROTECT.
+func (h *Handlers) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value(scopesKey).([]string); ok && !slices.Contains(scopes, scope) {
+				h.HttpErrs.CheckErrType(w, r, validations.ErrInsufficientScope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests made with an access token, for routes that
+// manage the account itself. It must run after PROTECT.
+func (h *Handlers) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			h.HttpErrs.CheckErrType(w, r, validations.ErrSessionRequired)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
 func GetUserIDFromContext(ctx context.Context) (*uint, error) {
 	if userID, ok := ctx.Value(userIDKey).(uint); ok {
 		return &userID, nil
@@ -196,6 +367,15 @@ func GetUserIDFromContext(ctx context.Context) (*uint, error) {
 	return nil, validations.ErrUnauthorized
 }
 
+// GetSessionIDFromContext returns the session of a request authenticated
+// with the session cookie.
+func GetSessionIDFromContext(ctx context.Context) (string, error) {
+	if sessionID, ok := ctx.Value(sessionIDKey).(string); ok && sessionID != "" {
+		return sessionID, nil
+	}
+	return "", validations.ErrUnauthorized
+}
+
 func (h *Handlers) LimitMiddleware(rl *RateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/internal/services"
+)
+
+// newAuthHandlers builds the handlers PROTECT needs on a SQLite database
+// holding one user, and returns them with the ID of the user.
+func newAuthHandlers(t *testing.T) (*Handlers, uint) {
+	t.Helper()
+	t.Setenv("JWT_STRING", "test-secret")
+	t.Setenv("JWT_NAME", "notes_jwt")
+	gormDB := dbtest.SQLite(t)
+	conf := &configs.Config{}
+	users := repositories.NewUserRepository(gormDB, conf)
+	user := models.NewUser("alice", "hash", nil)
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sessions := services.NewSessionService(repositories.NewSessionRepository(gormDB, conf))
+	httpErrs := NewHttpErrors(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return &Handlers{
+		UserHandler:  NewUserHandler(services.NewUserService(users, nil, sessions, nil, nil, nil, nil), nil, httpErrs),
+		Sessions:     sessions,
+		AccessTokens: services.NewAccessTokenService(repositories.NewAccessTokenRepository(gormDB, conf)),
+		HttpErrs:     httpErrs,
+	}, user.ID
+}
+
+func TestScopedAccess(t *testing.T) {
+	h, userId := newAuthHandlers(t)
+	ctx := context.Background()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	// The chains the routes of notes and of the account itself use.
+	routes := map[string]http.Handler{
+		"read":    h.PROTECT(h.RequireScope(models.ScopeNotesRead)(ok)),
+		"write":   h.PROTECT(h.RequireScope(models.ScopeNotesWrite)(ok)),
+		"session": h.PROTECT(h.RequireSession(ok)),
+	}
+
+	_, readToken, err := h.AccessTokens.Create(ctx, userId, "reader", []string{models.ScopeNotesRead}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, writeToken, err := h.AccessTokens.Create(ctx, userId, "writer", []string{models.ScopeNotesWrite}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	started, err := h.Sessions.Start(ctx, userId, "test agent")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name, route string
+		auth        func(r *http.Request)
+		want        int
+	}{
+		{"read token reads", "read", bearer(readToken), http.StatusNoContent},
+		{"read token writes", "write", bearer(readToken), http.StatusForbidden},
+		{"read token manages the account", "session", bearer(readToken), http.StatusForbidden},
+		{"write token reads", "read", bearer(writeToken), http.StatusNoContent},
+		{"write token writes", "write", bearer(writeToken), http.StatusNoContent},
+		{"write token manages the account", "session", bearer(writeToken), http.StatusForbidden},
+		{"session reads", "read", sessionCookie(started.AccessToken), http.StatusNoContent},
+		{"session writes", "write", sessionCookie(started.AccessToken), http.StatusNoContent},
+		{"session manages the account", "session", sessionCookie(started.AccessToken), http.StatusNoContent},
+		{"unknown token", "read", bearer(services.AccessTokenPrefix + "unknown"), http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tc.auth(r)
+			w := httptest.NewRecorder()
+			routes[tc.route].ServeHTTP(w, r)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
+
+func bearer(token string) func(r *http.Request) {
+	return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+}
+
+func sessionCookie(token string) func(r *http.Request) {
+	return func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "notes_jwt", Value: token}) }
+}
@@ -24,6 +24,15 @@ func NewNoteHandler(noteService *services.NoteService, httpErr *HttpErrors) *Not
 	}
 }
 
+// requestActor returns the authenticated user recorded as the author of a
+// change, or 0 when the route is not protected.
+func requestActor(r *http.Request) uint {
+	if userID, err := GetUserIDFromContext(r.Context()); err == nil {
+		return *userID
+	}
+	return 0
+}
+
 func (nh *NoteHandler) CreateNoteHandler(w http.ResponseWriter, r *http.Request) {
 	var req *CreateNoteRequest
 	err := request.DecodeJSONStrict(w, r, &req)
@@ -77,7 +86,7 @@ func (nh *NoteHandler) UpdateNoteHandler(w http.ResponseWriter, r *http.Request)
 		UserID:     req.UserID,
 	}
 
-	ider, err := nh.NoteService.UpdateNote(r.Context(), uintID, &updatedNote)
+	ider, err := nh.NoteService.UpdateNote(r.Context(), uintID, &updatedNote, req.UserID)
 	if err != nil {
 		nh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -197,7 +206,7 @@ func (nh *NoteHandler) RemoveCategoryFromNoteHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	updatedNote, err := nh.NoteService.RemoveCategoryFromNote(r.Context(), uint(noteId), categoryName)
+	updatedNote, err := nh.NoteService.RemoveCategoryFromNote(r.Context(), uint(noteId), categoryName, 0, requestActor(r))
 	if err != nil {
 		nh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -235,7 +244,7 @@ func (nh *NoteHandler) AddCategoryToNoteHandler(w http.ResponseWriter, r *http.R
 	}
 	uintID := uint(intId)
 
-	note, err := nh.NoteService.AddCategoryToNote(r.Context(), uintID, category)
+	note, err := nh.NoteService.AddCategoryToNote(r.Context(), uintID, category, 0, requestActor(r))
 	if err != nil {
 		nh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -273,7 +282,7 @@ func (nh *NoteHandler) ToggleArchiveStatusHandler(w http.ResponseWriter, r *http
 	}
 	uintID := uint(intId)
 
-	note, err := nh.NoteService.ToggleArchiveStatus(r.Context(), uintID)
+	note, err := nh.NoteService.ToggleArchiveStatus(r.Context(), uintID, 0, requestActor(r))
 	if err != nil {
 		nh.HttpErrs.CheckErrType(w, r, err)
 		return
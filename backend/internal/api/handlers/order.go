@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"notes/pkg/request"
+	"notes/pkg/validations"
+
+	"github.com/gorilla/mux"
+)
+
+// PinNoteHandler pins or unpins a note.
+// @Summary Pin or unpin a note
+// @Description Pinned notes are listed before the others by GET /notes, in the same manual order. A note keeps its position when pinned, so it goes back to where it was when unpinned. Only the owner of a note can pin it.
+// @Tags notes
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param pin body PinNoteRequest true "Whether the note is pinned"
+// @Success 200 {object} GetNoteResponse "Note pinned or unpinned"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or request data, or note not shared with the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Only the owner may pin the note"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/pin [put]
+func (uh *UserHandler) PinNoteHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req PinNoteRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	note, err := uh.UserService.PinNoteForUser(r.Context(), *userID, uint(noteID), req.Pinned)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	uh.writeNote(w, r, http.StatusOK, note)
+}
+
+// MoveNoteHandler moves a note in the manual order.
+// @Summary Move a note
+// @Description Puts the note right before or right after another of the user's notes in the order GET /notes lists them. Both notes must be pinned, or both unpinned. Only the owner of a note can move it.
+// @Tags notes
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param move body MoveNoteRequest true "Note to move the note before or after"
+// @Success 200 {object} GetNoteResponse "Note moved"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or target, or note not shared with the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Only the owner may move the note"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/move [put]
+func (uh *UserHandler) MoveNoteHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req MoveNoteRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	if (req.Before == nil) == (req.After == nil) {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrNoteMove)
+		return
+	}
+	targetID, after := req.Before, false
+	if req.After != nil {
+		targetID, after = req.After, true
+	}
+
+	note, err := uh.UserService.MoveNoteForUser(r.Context(), *userID, uint(noteID), *targetID, after)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	uh.writeNote(w, r, http.StatusOK, note)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"notes/internal/models"
+	"notes/pkg/request"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+
+	"github.com/gorilla/mux"
+)
+
+func toNotePermissionResponse(permission *models.NotePermission) NotePermissionResponse {
+	return NotePermissionResponse{
+		NoteID:    permission.NoteID,
+		UserName:  permission.UserName,
+		Role:      string(permission.Role),
+		CreatedAt: permission.CreatedAt,
+	}
+}
+
+// ListNotePermissionsHandler lists the users a note is shared with.
+// @Summary List who a note is shared with
+// @Description Returns the users the note is shared with and their roles, in username order. Only the owner of a note can list them.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Success 200 {array} NotePermissionResponse "List of permissions"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or note not shared with the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "The user is not the owner of the note"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/permissions [get]
+func (uh *UserHandler) ListNotePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	permissions, err := uh.UserService.ListNotePermissionsForUser(r.Context(), *userID, uint(noteID))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := make([]NotePermissionResponse, 0, len(permissions))
+	for i := range permissions {
+		res = append(res, toNotePermissionResponse(&permissions[i]))
+	}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// SetNotePermissionHandler shares a note with another user.
+// @Summary Share a note with a user
+// @Description Gives the user viewer or editor access to the note, or changes the role they already have. Viewers may read the note and its revisions; editors may also change its title, content and categories. Only the owner of a note can share it, archive it or delete it.
+// @Tags notes
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param username path string true "Username of the user to share the note with"
+// @Param permission body SetNotePermissionRequest true "Role of the user"
+// @Success 200 {object} NotePermissionResponse "Note shared"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or role, note shared with its owner, or note not shared with the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "The user is not the owner of the note"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/permissions/{username} [put]
+func (uh *UserHandler) SetNotePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req SetNotePermissionRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	permission, err := uh.UserService.ShareNoteWithUser(r.Context(), *userID, uint(noteID), vars["username"], models.NoteRole(req.Role))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, toNotePermissionResponse(permission)); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DeleteNotePermissionHandler stops sharing a note with another user.
+// @Summary Stop sharing a note with a user
+// @Description Takes away the access the user has to the note.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param username path string true "Username of the user the note is shared with"
+// @Success 200 {object} APIResponse "Access taken away"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or note not shared with the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "The user is not the owner of the note"
+// @Failure 404 {object} ErrorResponse "User not found, or the note is not shared with them"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/permissions/{username} [delete]
+func (uh *UserHandler) DeleteNotePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := uh.UserService.UnshareNoteWithUser(r.Context(), *userID, uint(noteID), vars["username"]); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Note no longer shared with the user"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
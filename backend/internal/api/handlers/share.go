@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"notes/internal/models"
+	"notes/internal/services"
+	"notes/pkg/request"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxShareFormBytes caps the body of the password form of a shared note.
+const maxShareFormBytes = 4 << 10
+
+type ShareHandler struct {
+	ShareService *services.ShareService
+	// APIURL is the public address of the API, which share URLs start with.
+	APIURL   string
+	ClientIP *request.ClientIP
+	Views    *prometheus.CounterVec
+	HttpErrs *HttpErrors
+}
+
+func NewShareHandler(ss *services.ShareService, apiURL string, clientIP *request.ClientIP, httpErrs *HttpErrors) *ShareHandler {
+	return &ShareHandler{
+		ShareService: ss,
+		APIURL:       strings.TrimRight(apiURL, "/"),
+		ClientIP:     clientIP,
+		Views: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "note_share_views_total",
+				Help: "Total number of shared notes opened through a share link",
+			},
+			[]string{"format"},
+		),
+		HttpErrs: httpErrs,
+	}
+}
+
+func toShareResponse(share *models.NoteShare) ShareResponse {
+	return ShareResponse{
+		ID:           share.ID,
+		NoteID:       share.NoteID,
+		HasPassword:  share.HasPassword(),
+		CreatedAt:    share.CreatedAt,
+		ExpiresAt:    share.ExpiresAt,
+		Views:        share.Views,
+		LastViewedAt: share.LastViewedAt,
+	}
+}
+
+func toSharedNoteResponse(note *models.Note) SharedNoteResponse {
+	categories := make([]string, 0, len(note.Categories))
+	for _, c := range note.Categories {
+		categories = append(categories, c.Name)
+	}
+	return SharedNoteResponse{
+		Title:      note.Title,
+		Content:    note.Content,
+		Categories: categories,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+	}
+}
+
+// CreateShareHandler creates a read-only link to a note.
+// @Summary Share a note
+// @Description Creates a link anyone can open without an account to read the note. The token is only returned by this call. expires_in_days is at most 365; without it the link works until it is revoked. With a password, the link asks for it before showing the note. Only the owner of a note can share it.
+// @Tags shares
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param share body CreateShareRequest false "Expiry and password of the link"
+// @Success 201 {object} CreatedShareResponse "Link created"
+// @Failure 400 {object} ErrorResponse "Invalid note ID, expiry or password, or note not owned by the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/share [post]
+func (sh *ShareHandler) CreateShareHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req CreateShareRequest
+	if r.ContentLength != 0 {
+		if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+			sh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+			return
+		}
+	}
+
+	share, raw, err := sh.ShareService.Create(r.Context(), *userID, uint(noteID), req.ExpiresInDays, req.Password)
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := CreatedShareResponse{
+		ShareResponse: toShareResponse(share),
+		Token:         raw,
+		URL:           sh.APIURL + "/shared/" + raw,
+	}
+	if err := response.JSON(w, http.StatusCreated, res); err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ListSharesHandler lists the links to a note.
+// @Summary List the share links of a note
+// @Description Returns the links to the note that have not been revoked, newest first, with how often each was opened. The tokens themselves are never returned again.
+// @Tags shares
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Success 200 {array} ShareResponse "List of links"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or note not owned by the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/shares [get]
+func (sh *ShareHandler) ListSharesHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	shares, err := sh.ShareService.List(r.Context(), *userID, uint(noteID))
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := make([]ShareResponse, 0, len(shares))
+	for i := range shares {
+		res = append(res, toShareResponse(&shares[i]))
+	}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// RevokeShareHandler revokes a link to a note.
+// @Summary Revoke a share link
+// @Description Revokes one of the links to the note; it cannot be opened from then on.
+// @Tags shares
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param shareId path int true "Share ID"
+// @Success 200 {object} APIResponse "Link revoked"
+// @Failure 400 {object} ErrorResponse "Invalid note or share ID, or note not owned by the user"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Link not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/shares/{shareId} [delete]
+func (sh *ShareHandler) RevokeShareHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	shareID, err := strconv.ParseUint(vars["shareId"], 10, 32)
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := sh.ShareService.Revoke(r.Context(), *userID, uint(noteID), uint(shareID)); err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Share link revoked"}); err != nil {
+		sh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// SharedNoteHandler shows the note a share link points to.
+// @Summary Open a share link
+// @Description Shows the shared note without authentication: as a minimal HTML page to browsers, whose Accept header asks for text/html, and as JSON otherwise. Links with a password need it in the password form field of a POST; the HTML page asks for it. Repeated incorrect passwords from one address hold its attempts on the link back for a while. Revoked and expired links, and links to notes in the trash, are not found.
+// @Tags shares
+// @Accept x-www-form-urlencoded
+// @Produce json,html
+// @Param token path string true "Share token"
+// @Param password formData string false "Password of the link"
+// @Success 200 {object} SharedNoteResponse "Shared note"
+// @Failure 401 {object} ErrorResponse "Password missing or incorrect"
+// @Failure 404 {object} ErrorResponse "Link not found"
+// @Failure 429 {object} ErrorResponse "Too many incorrect passwords, retry after the time in the Retry-After header"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /shared/{token} [get]
+// @Router /shared/{token} [post]
+func (sh *ShareHandler) SharedNoteHandler(w http.ResponseWriter, r *http.Request) {
+	// The token is in the URL: keep it out of other sites' logs, caches and
+	// search engines.
+	w.Header().Set("Referrer-Policy", "same-origin")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Cache-Control", "no-store")
+
+	format := "json"
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		format = "html"
+	}
+
+	var pw string
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxShareFormBytes)
+		if err := r.ParseForm(); err != nil {
+			sh.sharedNoteError(w, r, format, err)
+			return
+		}
+		pw = r.PostForm.Get("password")
+	}
+
+	note, _, err := sh.ShareService.Open(r.Context(), mux.Vars(r)["token"], pw, sh.ClientIP.FromRequest(r))
+	if err != nil {
+		sh.sharedNoteError(w, r, format, err)
+		return
+	}
+	sh.Views.WithLabelValues(format).Inc()
+
+	res := toSharedNoteResponse(note)
+	if format == "json" {
+		if err := response.JSON(w, http.StatusOK, res); err != nil {
+			sh.HttpErrs.CheckErrType(w, r, err)
+		}
+		return
+	}
+	sh.renderSharedPage(w, r, http.StatusOK, sharedPage{Note: &res})
+}
+
+// sharedNoteError reports a failure to open a link in the requested format.
+// The HTML page asks again for the password when it is missing or wrong.
+func (sh *ShareHandler) sharedNoteError(w http.ResponseWriter, r *http.Request, format string, err error) {
+	if format == "json" {
+		sh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	switch {
+	case errors.Is(err, validations.ErrSharePassword):
+		page := sharedPage{AskPassword: true}
+		if r.Method == http.MethodPost {
+			page.Message = "Incorrect password."
+		}
+		sh.renderSharedPage(w, r, http.StatusUnauthorized, page)
+	case errors.Is(err, validations.ErrShareThrottled):
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		}
+		sh.renderSharedPage(w, r, http.StatusTooManyRequests, sharedPage{Message: "Too many incorrect passwords. Try again later."})
+	case errors.Is(err, validations.ErrShareNotFound):
+		sh.renderSharedPage(w, r, http.StatusNotFound, sharedPage{Message: "This link does not exist, has expired or was revoked."})
+	default:
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			sh.renderSharedPage(w, r, http.StatusBadRequest, sharedPage{Message: "The request is too large."})
+			return
+		}
+		sh.HttpErrs.reportServerError(r, err)
+		sh.renderSharedPage(w, r, http.StatusInternalServerError, sharedPage{Message: "The note cannot be shown right now."})
+	}
+}
+
+type sharedPage struct {
+	Note        *SharedNoteResponse
+	AskPassword bool
+	Message     string
+}
+
+var sharedPageTemplate = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{if .Note}}{{.Note.Title}}{{else}}Shared note{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.content { white-space: pre-wrap; overflow-wrap: anywhere; }
+.categories, .meta { color: #666; font-size: 0.9rem; }
+</style>
+</head>
+<body>
+{{- if .Note}}
+<h1>{{.Note.Title}}</h1>
+{{- if .Note.Categories}}
+<p class="categories">{{range $i, $c := .Note.Categories}}{{if $i}}, {{end}}{{$c}}{{end}}</p>
+{{- end}}
+<div class="content">{{.Note.Content}}</div>
+<p class="meta">Last updated {{if .Note.UpdatedAt}}{{.Note.UpdatedAt.Format "2006-01-02 15:04"}}{{else}}{{.Note.CreatedAt.Format "2006-01-02 15:04"}}{{end}}</p>
+{{- else if .AskPassword}}
+<h1>Shared note</h1>
+<p>This note is protected by a password.</p>
+{{- if .Message}}
+<p role="alert">{{.Message}}</p>
+{{- end}}
+<form method="post">
+<label>Password <input type="password" name="password" autocomplete="off" required autofocus></label>
+<button type="submit">Open</button>
+</form>
+{{- else}}
+<h1>Shared note</h1>
+<p>{{.Message}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+func (sh *ShareHandler) renderSharedPage(w http.ResponseWriter, r *http.Request, status int, page sharedPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := sharedPageTemplate.Execute(w, page); err != nil {
+		sh.HttpErrs.reportServerError(r, err)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"notes/internal/models"
+	"notes/pkg/request"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+	"strconv"
+)
+
+// defaultSyncLimit is the number of changes a sync page lists unless the
+// client asks for another.
+const defaultSyncLimit = 500
+
+// SyncHandler lists what changed in the authenticated user's notes since a sync token.
+// @Summary Pull note changes
+// @Description Lists the notes and categories created or changed, and those deleted, since the token of a previous sync, oldest change first. Without a token every note and category is listed. Notes moved to the trash are listed as deleted. Pass the returned token to the next sync; while has_more is true there are more changes to fetch right away. Deletions are remembered for SYNC_TOMBSTONE_RETENTION_DAYS: older tokens get 410 and the client must sync again without a token.
+// @Tags sync
+// @Security notes_jwt
+// @Produce json
+// @Param since query string false "Token returned by the previous sync"
+// @Param limit query int false "Number of changes per page, from 1 to 1000 (default 500)"
+// @Success 200 {object} SyncResponse "Changes since the token"
+// @Failure 400 {object} ErrorResponse "Invalid token or limit"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 410 {object} ErrorResponse "Token too old, sync again without one"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /sync [get]
+func (uh *UserHandler) SyncHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	limit := defaultSyncLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil {
+			uh.HttpErrs.CheckErrType(w, r, validations.ErrSyncLimit)
+			return
+		}
+	}
+
+	page, err := uh.UserService.SyncChangesForUser(r.Context(), *userID, r.URL.Query().Get("since"), limit)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	resp := SyncResponse{
+		Notes:      make([]GetNoteResponse, 0, len(page.Notes)),
+		Categories: make([]GetCategory, 0, len(page.Categories)),
+		Deleted:    make([]SyncDeletedEntry, 0, len(page.Deleted)),
+		Token:      page.Token,
+		HasMore:    page.HasMore,
+	}
+	for _, note := range page.Notes {
+		resp.Notes = append(resp.Notes, toGetNoteResponse(&note))
+	}
+	for _, category := range page.Categories {
+		resp.Categories = append(resp.Categories, toGetCategory(&category))
+	}
+	for _, deleted := range page.Deleted {
+		resp.Deleted = append(resp.Deleted, SyncDeletedEntry{
+			Type:      string(deleted.Kind),
+			ID:        deleted.EntityID,
+			DeletedAt: deleted.DeletedAt,
+		})
+	}
+
+	if err := response.JSON(w, http.StatusOK, resp); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ApplySyncHandler applies changes made offline to the authenticated user's notes.
+// @Summary Push note changes
+// @Description Applies up to 100 changes made offline, in order and each on its own. A change made to the current version of its note is applied. One made to an older version is a conflict unless it already matches the note, in which case it is unchanged; conflicts carry the change and the server's note, null when the note was deleted, for the client to merge and send again against the server's version. Changes that fail validation are rejected with the reason. Sending a batch again is safe.
+// @Tags sync
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param changes body SyncRequest true "Changes made offline"
+// @Success 200 {object} SyncChangesResponse "Per-change results"
+// @Failure 400 {object} ErrorResponse "Invalid payload or number of changes"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /sync [post]
+func (uh *UserHandler) ApplySyncHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	var req SyncRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	changes := make([]models.SyncChange, 0, len(req.Changes))
+	for _, change := range req.Changes {
+		categories := make([]models.Category, 0, len(change.Categories))
+		for _, name := range change.Categories {
+			categories = append(categories, models.Category{Name: name})
+		}
+		changes = append(changes, models.SyncChange{
+			NoteID:      change.ID,
+			BaseVersion: change.BaseVersion,
+			Deleted:     change.Deleted,
+			Title:       change.Title,
+			Content:     change.Content,
+			Categories:  categories,
+			IsArchived:  change.IsArchived,
+		})
+	}
+
+	results, err := uh.UserService.ApplySyncChangesForUser(r.Context(), *userID, changes)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	resp := SyncChangesResponse{Results: make([]SyncChangeResult, 0, len(results))}
+	for i, result := range results {
+		item := SyncChangeResult{Index: i, Status: string(result.Status)}
+		var note *GetNoteResponse
+		if result.Note != nil {
+			n := toGetNoteResponse(result.Note)
+			note = &n
+		}
+		switch result.Status {
+		case models.SyncConflict:
+			item.Conflict = &SyncConflict{Client: req.Changes[i], Server: note}
+		case models.SyncRejected:
+			item.Error = result.Err.Error()
+		default:
+			item.Note = note
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	if err := response.JSON(w, http.StatusOK, resp); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"notes/internal/models"
+	"notes/internal/services"
+	"notes/pkg/request"
+	"notes/pkg/response"
+	"notes/pkg/validations"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AccessTokenHandler struct {
+	TokenService *services.AccessTokenService
+	HttpErrs     *HttpErrors
+}
+
+func NewAccessTokenHandler(ts *services.AccessTokenService, httpErrs *HttpErrors) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		TokenService: ts,
+		HttpErrs:     httpErrs,
+	}
+}
+
+func toAccessTokenResponse(token *models.AccessToken) AccessTokenResponse {
+	return AccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.ScopeList(),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+// CreateAccessTokenHandler issues a personal access token.
+// @Summary Create a personal access token
+// @Description Issues a token for scripts and CLI clients, sent as "Authorization: Bearer <token>". The token is only returned by this call. expires_in_days defaults to 30 and is at most 365.
+// @Tags tokens
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param token body CreateAccessTokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} CreatedAccessTokenResponse "Token created"
+// @Failure 400 {object} ErrorResponse "Invalid name, scopes or expiry"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Called with an access token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /user/tokens [post]
+func (th *AccessTokenHandler) CreateAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req CreateAccessTokenRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		th.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	token, raw, err := th.TokenService.Create(r.Context(), *userID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := CreatedAccessTokenResponse{
+		AccessTokenResponse: toAccessTokenResponse(token),
+		Token:               raw,
+	}
+	if err := response.JSON(w, http.StatusCreated, res); err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ListAccessTokensHandler lists the user's personal access tokens.
+// @Summary List personal access tokens
+// @Description Returns the authenticated user's tokens that have not been revoked, newest first. The token values themselves are never returned again.
+// @Tags tokens
+// @Security notes_jwt
+// @Produce json
+// @Success 200 {array} AccessTokenResponse "List of tokens"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Called with an access token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /user/tokens [get]
+func (th *AccessTokenHandler) ListAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	tokens, err := th.TokenService.List(r.Context(), *userID)
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := make([]AccessTokenResponse, 0, len(tokens))
+	for i := range tokens {
+		res = append(res, toAccessTokenResponse(&tokens[i]))
+	}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// RevokeAccessTokenHandler revokes a personal access token.
+// @Summary Revoke a personal access token
+// @Description Revokes one of the authenticated user's tokens; requests made with it fail from then on.
+// @Tags tokens
+// @Security notes_jwt
+// @Produce json
+// @Param tokenId path int true "Token ID"
+// @Success 200 {object} APIResponse "Token revoked"
+// @Failure 400 {object} ErrorResponse "Invalid token ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Called with an access token"
+// @Failure 404 {object} ErrorResponse "Token not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /user/tokens/{tokenId} [delete]
+func (th *AccessTokenHandler) RevokeAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.ParseUint(mux.Vars(r)["tokenId"], 10, 32)
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := th.TokenService.Revoke(r.Context(), *userID, uint(tokenID)); err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Token revoked"}); err != nil {
+		th.HttpErrs.CheckErrType(w, r, err)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"notes/pkg/request"
+	"notes/pkg/response"
+)
+
+// EnrollTwoFactorHandler godoc
+// @Summary     Start two-factor enrollment
+// @Description Generates a TOTP secret for the authenticated user. Two-factor authentication is enabled only after /user/2fa/confirm receives a valid code; enrolling again replaces an unconfirmed secret.
+// @Tags        users
+// @Security    notes_jwt
+// @Produce     json
+// @Success     200 {object} TwoFactorEnrollResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     409 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/2fa/enroll [post]
+func (uh *UserHandler) EnrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	enrollment, err := uh.UserService.EnrollTOTP(r.Context(), *userID)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := TwoFactorEnrollResponse{Secret: enrollment.Secret, URI: enrollment.URI}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ConfirmTwoFactorHandler godoc
+// @Summary     Confirm two-factor enrollment
+// @Description Enables two-factor authentication with a code from the authenticator app and returns ten one-time recovery codes. The codes are not shown again.
+// @Tags        users
+// @Security    notes_jwt
+// @Accept      json
+// @Produce     json
+// @Param       request body TwoFactorCodeRequest true " "
+// @Success     200 {object} TwoFactorConfirmResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     409 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/2fa/confirm [post]
+func (uh *UserHandler) ConfirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	codes, err := uh.UserService.ConfirmTOTP(r.Context(), *userID, req.Code)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, TwoFactorConfirmResponse{RecoveryCodes: codes}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DisableTwoFactorHandler godoc
+// @Summary     Disable two-factor authentication
+// @Description Turns two-factor authentication off, given a current code or a recovery code. The secret and the remaining recovery codes are deleted. Wrong codes are throttled per user, answering 429 with a Retry-After header.
+// @Tags        users
+// @Security    notes_jwt
+// @Accept      json
+// @Produce     json
+// @Param       request body TwoFactorCodeRequest true " "
+// @Success     200 {object} APIResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     429 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/2fa/disable [post]
+func (uh *UserHandler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	if err := uh.UserService.DisableTOTP(r.Context(), *userID, req.Code, req.RecoveryCode); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// LoginTwoFactorHandler godoc
+// @Summary     Complete a two-factor login
+// @Description Second step of the login for accounts with two-factor authentication. Takes the challenge token returned by /user/login, which is valid for 5 minutes, and a TOTP code or a recovery code, then sets the session cookies.
+// @Tags        users
+// @Accept      json
+// @Produce     json
+// @Param       request body LoginTwoFactorRequest true " "
+// @Success     200 {object} UserResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     429 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/login/2fa [post]
+func (uh *UserHandler) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginTwoFactorRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	usr, err := uh.UserService.CompleteLogin(r.Context(), w, req.ChallengeToken, req.Code, req.RecoveryCode, r.UserAgent(), uh.ClientIP.FromRequest(r))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := UserResponse{
+		ID:        &usr.ID,
+		UserName:  usr.UserName,
+		Notes:     usr.Notes,
+		IsAdmin:   usr.IsAdmin,
+		CreatedAt: usr.CreatedAt,
+		UpdatedAt: usr.UpdatedAt,
+	}
+	if err := response.JSON(w, http.StatusOK, &res); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
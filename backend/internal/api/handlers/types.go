@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"notes/internal/models"
+	"notes/pkg/diff"
 	"time"
 )
 
@@ -22,11 +23,75 @@ type GetNoteResponse struct {
 	Content    string            `json:"content" example:"Sample note content."`
 	Categories []models.Category `json:"categories"`
 	IsArchived bool              `json:"is_archived" example:"false"`
+	Pinned     bool              `json:"pinned" example:"false"`
+	Position   float64           `json:"position" example:"1024"`
+	Version    uint              `json:"version" example:"3"`
 	CreatedAt  time.Time         `json:"created_at" example:"2025-02-01T12:00:00Z"`
 	UpdatedAt  *time.Time        `json:"updated_at,omitempty"`
 	UserID     uint              `json:"user_id" example:"1"`
 }
 
+// PageMetadata describes the position of a page in a paginated listing
+// @swagger:model PageMetadata
+type PageMetadata struct {
+	NextCursor *string `json:"next_cursor" example:"eyJzIjoiLWNyZWF0ZWRfYXQiLCJ2IjoiMjAyNS0wMi0wMVQxMjowMDowMFoiLCJpZCI6NDJ9"`
+	Limit      int     `json:"limit" example:"50"`
+	Sort       string  `json:"sort" example:"position"`
+}
+
+// SearchNoteResponse represents a note matched by full-text search
+// @swagger:model SearchNoteResponse
+type SearchNoteResponse struct {
+	GetNoteResponse
+	Rank       float64        `json:"rank" example:"0.6079"`
+	Highlights NoteHighlights `json:"highlights"`
+}
+
+// NoteHighlights holds HTML-escaped note fields with the matched terms wrapped
+// in <mark> tags; the content is cut down to the fragments around the matches
+type NoteHighlights struct {
+	Title   string `json:"title" example:"<mark>Shopping</mark> List"`
+	Content string `json:"content" example:"buy milk at the <mark>shop</mark>"`
+}
+
+// TrashedNoteResponse represents a note sitting in the trash
+// @swagger:model TrashedNoteResponse
+type TrashedNoteResponse struct {
+	GetNoteResponse
+	DeletedAt time.Time `json:"deleted_at" example:"2025-02-03T09:30:00Z"`
+}
+
+// EmptyTrashResponse represents the outcome of emptying the trash
+// @swagger:model EmptyTrashResponse
+type EmptyTrashResponse struct {
+	Purged int64 `json:"purged" example:"3"`
+}
+
+// NoteRevisionResponse represents a stored revision of a note
+// @swagger:model NoteRevisionResponse
+type NoteRevisionResponse struct {
+	Revision   uint      `json:"revision" example:"3"`
+	Title      string    `json:"title" example:"Sample Note Title"`
+	Content    string    `json:"content" example:"Sample note content."`
+	Categories []string  `json:"categories" example:"Work,Personal"`
+	IsArchived bool      `json:"is_archived" example:"false"`
+	ActorID    uint      `json:"actor_id" example:"1"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-02-01T12:00:00Z"`
+}
+
+// NoteRevisionDiffResponse represents the changes between two revisions of a note
+// @swagger:model NoteRevisionDiffResponse
+type NoteRevisionDiffResponse struct {
+	From              uint        `json:"from" example:"1"`
+	To                uint        `json:"to" example:"3"`
+	Title             []diff.Line `json:"title"`
+	Content           []diff.Line `json:"content"`
+	CategoriesAdded   []string    `json:"categories_added" example:"Work"`
+	CategoriesRemoved []string    `json:"categories_removed" example:"Personal"`
+	ArchivedFrom      bool        `json:"archived_from" example:"false"`
+	ArchivedTo        bool        `json:"archived_to" example:"true"`
+}
+
 // UpdateNoteRequest represents the payload for updating a note
 // @swagger:model
 type UpdateNoteRequest struct {
@@ -37,12 +102,142 @@ type UpdateNoteRequest struct {
 	UserID     uint              `json:"user_id"`
 }
 
+// PatchNoteRequest represents a JSON merge patch for a note; every field is optional
+// @swagger:model
+type PatchNoteRequest struct {
+	Title      *string           `json:"title,omitempty"`
+	Content    *string           `json:"content,omitempty"`
+	Categories []models.Category `json:"categories,omitempty"`
+	IsArchived *bool             `json:"is_archived,omitempty"`
+}
+
+// BulkNotesRequest represents a bulk operation over several notes
+// @swagger:model
+type BulkNotesRequest struct {
+	Operation  string `json:"operation" example:"archive"`
+	NoteIDs    []uint `json:"note_ids" example:"1,2,3"`
+	Category   string `json:"category,omitempty" example:"work"`
+	BestEffort bool   `json:"best_effort" example:"false"`
+}
+
+// BulkNoteResult reports the outcome of a bulk operation for one note
+// @swagger:model
+type BulkNoteResult struct {
+	ID     uint   `json:"id" example:"1"`
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkNotesResponse reports the outcome of a bulk operation
+// @swagger:model
+type BulkNotesResponse struct {
+	Operation  string           `json:"operation" example:"archive"`
+	BestEffort bool             `json:"best_effort" example:"false"`
+	Committed  bool             `json:"committed" example:"true"`
+	Succeeded  int              `json:"succeeded" example:"3"`
+	Failed     int              `json:"failed" example:"0"`
+	Results    []BulkNoteResult `json:"results"`
+}
+
+// ImportNoteEntry reports what happened to one file of an imported archive
+// @swagger:model
+type ImportNoteEntry struct {
+	File   string `json:"file" example:"42-groceries.md"`
+	Title  string `json:"title,omitempty" example:"Groceries"`
+	NoteID uint   `json:"note_id,omitempty" example:"42"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ImportNotesResponse reports the outcome of a markdown import
+// @swagger:model
+type ImportNotesResponse struct {
+	Created   []ImportNoteEntry `json:"created"`
+	Skipped   []ImportNoteEntry `json:"skipped"`
+	Conflicts []ImportNoteEntry `json:"conflicts"`
+}
+
+// SyncResponse lists what changed in the user's notes and categories since a
+// sync token
+// @swagger:model
+type SyncResponse struct {
+	Notes      []GetNoteResponse  `json:"notes"`
+	Categories []GetCategory      `json:"categories"`
+	Deleted    []SyncDeletedEntry `json:"deleted"`
+	Token      string             `json:"token" example:"MTI4"`
+	HasMore    bool               `json:"has_more" example:"false"`
+}
+
+// SyncDeletedEntry reports a note moved to the trash or purged, or a deleted
+// category
+// @swagger:model
+type SyncDeletedEntry struct {
+	Type      string    `json:"type" example:"note"`
+	ID        uint      `json:"id" example:"42"`
+	DeletedAt time.Time `json:"deleted_at" example:"2025-02-03T09:30:00Z"`
+}
+
+// SyncRequest carries changes made offline, applied in order
+// @swagger:model
+type SyncRequest struct {
+	Changes []SyncChangeRequest `json:"changes"`
+}
+
+// SyncChangeRequest is a change made offline to one note. Without an id it
+// creates a note; otherwise base_version is the version the change was made to
+// @swagger:model
+type SyncChangeRequest struct {
+	ID          uint     `json:"id,omitempty" example:"42"`
+	BaseVersion uint     `json:"base_version,omitempty" example:"3"`
+	Deleted     bool     `json:"deleted,omitempty" example:"false"`
+	Title       string   `json:"title,omitempty" example:"My Note Title"`
+	Content     string   `json:"content,omitempty" example:"Written offline."`
+	Categories  []string `json:"categories,omitempty" example:"Work,Personal"`
+	IsArchived  bool     `json:"is_archived,omitempty" example:"false"`
+}
+
+// SyncChangeResult reports the outcome of one change: applied, unchanged,
+// conflict or rejected
+// @swagger:model
+type SyncChangeResult struct {
+	Index    int              `json:"index" example:"0"`
+	Status   string           `json:"status" example:"applied"`
+	Note     *GetNoteResponse `json:"note,omitempty"`
+	Conflict *SyncConflict    `json:"conflict,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// SyncConflict holds both sides of a conflicting change; server is null when
+// the note was deleted on the server
+// @swagger:model
+type SyncConflict struct {
+	Client SyncChangeRequest `json:"client"`
+	Server *GetNoteResponse  `json:"server"`
+}
+
+// SyncChangesResponse reports the outcome of every change of a sync request
+// @swagger:model
+type SyncChangesResponse struct {
+	Results []SyncChangeResult `json:"results"`
+}
+
 // CreateCategoryRequest represents the payload for creating a new category.
 // @Description Payload for creating a new category
 type CreateCategoryRequest struct {
 	Name string `json:"name"`
 }
 
+// UpdateCategoryRequest represents the payload for renaming a category.
+// @Description Payload for renaming a category
+type UpdateCategoryRequest struct {
+	Name string `json:"name" example:"Groceries"`
+}
+
+// MergeCategoryRequest represents the payload for merging a category into another one.
+// @Description Payload naming the category that absorbs the merged one
+type MergeCategoryRequest struct {
+	TargetID uint `json:"target_id" example:"2"`
+}
+
 // GetCategory represents the response when fetching a category.
 // @Description Response containing details of a category
 type GetCategory struct {
@@ -59,6 +254,141 @@ type UserRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginChallengeResponse is returned by /user/login when the account has
+// two-factor authentication enabled. No session is started yet.
+type LoginChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required" example:"true"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// LoginTwoFactorRequest completes a login with a TOTP code or, when the
+// authenticator is lost, one of the recovery codes.
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty" example:"123456"`
+	RecoveryCode   string `json:"recovery_code,omitempty" example:"a1b2c-3d4e5"`
+}
+
+// TwoFactorCodeRequest carries the code that confirms or disables
+// two-factor authentication.
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code,omitempty" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// TwoFactorEnrollResponse holds what an authenticator app needs: the otpauth
+// URI, usually shown as a QR code, and the secret for manual entry.
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"otpauth_uri" example:"otpauth://totp/Notes:alice?secret=JBSWY3DPEHPK3PXP&issuer=Notes"`
+}
+
+// TwoFactorConfirmResponse lists the recovery codes. They are only shown
+// once and each can be used a single time.
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// CreateAccessTokenRequest describes a new personal access token.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" example:"backup script"`
+	Scopes        []string `json:"scopes" example:"notes:read"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" example:"30"`
+}
+
+// AccessTokenResponse describes a personal access token without its value.
+type AccessTokenResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// LastUsedAt is recorded at most once a minute.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedAccessTokenResponse is returned once, when a token is created: it
+// is the only response that includes the token itself.
+type CreatedAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token" example:"notes_pat_3f9c..."`
+}
+
+// CreateShareRequest describes a new share link. expires_in_days of 0, the
+// default, makes a link that works until it is revoked; an empty password
+// makes one anyone holding the link can open.
+type CreateShareRequest struct {
+	ExpiresInDays int    `json:"expires_in_days,omitempty" example:"7"`
+	Password      string `json:"password,omitempty" example:"correct horse"`
+}
+
+// ShareResponse describes a share link without its token.
+type ShareResponse struct {
+	ID           uint       `json:"id"`
+	NoteID       uint       `json:"note_id"`
+	HasPassword  bool       `json:"has_password"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Views        int64      `json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+}
+
+// CreatedShareResponse is returned once, when a link is created: it is the
+// only response that includes the token and the URL built from it.
+type CreatedShareResponse struct {
+	ShareResponse
+	Token string `json:"token" example:"3f9c..."`
+	URL   string `json:"url" example:"http://localhost:8025/shared/3f9c..."`
+}
+
+// SharedNoteResponse is a note as shown to whoever opens a share link.
+type SharedNoteResponse struct {
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Categories []string   `json:"categories"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// AdminUserResponse is a user as listed to admins.
+type AdminUserResponse struct {
+	ID         uint      `json:"id"`
+	UserName   string    `json:"user_name"`
+	IsAdmin    bool      `json:"is_admin"`
+	IsDisabled bool      `json:"is_disabled"`
+	NoteCount  int64     `json:"note_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PasswordResetResponse holds the token a user needs to set a new password
+// after an admin forced a reset.
+type PasswordResetResponse struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ChangePasswordRequest replaces the authenticated user's password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// DeleteAccountRequest confirms the deletion of the authenticated user's
+// account. A two-factor code is required when two-factor authentication is
+// enabled.
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// ResetPasswordRequest sets a new password with an admin-issued reset token.
+type ResetPasswordRequest struct {
+	UserName    string `json:"user_name"`
+	ResetToken  string `json:"reset_token"`
+	NewPassword string `json:"new_password"`
+}
+
 // StatusResponse represents the response for the /status endpoint.
 type StatusResponse struct {
 	Status string `json:"status" example:"OK"`
@@ -109,3 +439,38 @@ type LogOut struct {
 	Error  string      `json:"error,omitempty"`
 	Status int         `json:"status"`
 }
+
+// NoteEventResponse is the data of a note event sent on the event stream
+// @swagger:model NoteEventResponse
+type NoteEventResponse struct {
+	Type   models.NoteEventType `json:"type" example:"updated"`
+	NoteID uint                 `json:"note_id" example:"1"`
+	Note   *GetNoteResponse     `json:"note,omitempty"`
+	At     time.Time            `json:"at" example:"2025-02-01T12:00:00Z"`
+}
+
+// SetNotePermissionRequest is the role given to a user a note is shared
+// with: viewer or editor.
+type SetNotePermissionRequest struct {
+	Role string `json:"role" example:"editor"`
+}
+
+// NotePermissionResponse describes a user a note is shared with.
+type NotePermissionResponse struct {
+	NoteID    uint      `json:"note_id"`
+	UserName  string    `json:"user_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PinNoteRequest pins or unpins a note.
+type PinNoteRequest struct {
+	Pinned bool `json:"pinned" example:"true"`
+}
+
+// MoveNoteRequest names the note a note is moved next to: exactly one of
+// before and after must be set.
+type MoveNoteRequest struct {
+	Before *uint `json:"before,omitempty" example:"12"`
+	After  *uint `json:"after,omitempty"`
+}
@@ -1,26 +1,41 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"notes/internal/models"
 	"notes/internal/services"
+	"notes/pkg/pagination"
 	"notes/pkg/request"
 	"notes/pkg/response"
 	"notes/pkg/utils"
 	"notes/pkg/validations"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
+// maxLoginPasswordBytes bounds the passwords accepted at login, well above
+// any length the password policy allows.
+const maxLoginPasswordBytes = 256
+
 type UserHandler struct {
 	UserService *services.UserService
+	ClientIP    *request.ClientIP
 	HttpErrs    *HttpErrors
 }
 
-func NewUserHandler(userService *services.UserService, httpErr *HttpErrors) *UserHandler {
+func NewUserHandler(userService *services.UserService, clientIP *request.ClientIP, httpErr *HttpErrors) *UserHandler {
 	return &UserHandler{
 		UserService: userService,
+		ClientIP:    clientIP,
 		HttpErrs:    httpErr,
 	}
 }
@@ -45,12 +60,8 @@ func (uh *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Reques
 		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
 		return
 	}
-	if len(req.Password) < 5 || len(req.Password) > 20 {
-		uh.HttpErrs.CheckErrType(w, r, validations.ErrUserNamePassLength)
-		return
-	}
 
-	user, err := uh.UserService.RegisterUser(r.Context(), w, req.UserName, req.Password)
+	user, err := uh.UserService.RegisterUser(r.Context(), w, req.UserName, req.Password, r.UserAgent())
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -76,6 +87,7 @@ func (uh *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Reques
 // @Produce     json
 // @Param       credentials body UserRequest true " "
 // @Success     200 {object} UserResponse
+// @Success     202 {object} LoginChallengeResponse "two-factor authentication required, complete the login at /user/login/2fa"
 // @Failure     400 {object} ErrorResponse
 // @Failure     401 {object} ErrorResponse
 // @Failure     500 {object} ErrorResponse
@@ -87,11 +99,13 @@ func (uh *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request)
 		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
 		return
 	}
-	if len(req.Password) < 5 || len(req.Password) > 20 {
-		uh.HttpErrs.CheckErrType(w, r, validations.ErrUserNamePassLength)
+	// Passwords are checked against the policy when they are set; here only
+	// bound the input so hashing stays cheap.
+	if req.Password == "" || len(req.Password) > maxLoginPasswordBytes {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidCredentials)
 		return
 	}
-	usr, err := uh.UserService.LoginUser(r.Context(), w, req.UserName, req.Password)
+	usr, challenge, err := uh.UserService.LoginUser(r.Context(), w, req.UserName, req.Password, r.UserAgent(), uh.ClientIP.FromRequest(r))
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -100,6 +114,13 @@ func (uh *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request)
 		uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidCredentials)
 		return
 	}
+	if challenge != "" {
+		res := LoginChallengeResponse{TwoFactorRequired: true, ChallengeToken: challenge}
+		if err := response.JSON(w, http.StatusAccepted, res); err != nil {
+			uh.HttpErrs.CheckErrType(w, r, err)
+		}
+		return
+	}
 	var res UserResponse
 	res.ID = &usr.ID
 	res.UserName = usr.UserName
@@ -156,13 +177,15 @@ func (uh *UserHandler) AuthCheckHandler(w http.ResponseWriter, r *http.Request)
 
 // Logout godoc
 // @Summary     logout connected user
-// @Description logout
+// @Description Revokes the session of the refresh token cookie and clears the auth cookies.
 // @Tags        users
 // @Accept      json
 // @Produce     json
+// @Success     200 {object} APIResponse
+// @Failure     500 {object} ErrorResponse
 // @Router      /user/logout [post]
 func (uh *UserHandler) LogoutUserHandler(w http.ResponseWriter, r *http.Request) {
-	err := uh.UserService.LogoutUser(w)
+	err := uh.UserService.LogoutUser(r.Context(), w, refreshTokenFromCookie(r))
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
@@ -173,6 +196,200 @@ func (uh *UserHandler) LogoutUserHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// LogoutAllHandler godoc
+// @Summary     Log out all devices
+// @Description Revokes every session of the authenticated user; their access and refresh tokens stop working immediately.
+// @Tags        users
+// @Security    notes_jwt
+// @Produce     json
+// @Success     200 {object} APIResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/logout-all [post]
+func (uh *UserHandler) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := uh.UserService.LogoutAllSessions(r.Context(), w, *userID); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Logged out of all devices"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ResetPasswordHandler godoc
+// @Summary     Set a new password after a forced reset
+// @Description Completes a password reset requested by an admin, using the reset token they handed over. Log in with the new password afterwards.
+// @Tags        users
+// @Accept      json
+// @Produce     json
+// @Param       request body ResetPasswordRequest true " "
+// @Success     200 {object} APIResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/password-reset [post]
+func (uh *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	if err := uh.UserService.ResetPassword(r.Context(), req.UserName, req.ResetToken, req.NewPassword); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Password updated, log in with the new password"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ChangePasswordHandler godoc
+// @Summary     Change the password
+// @Description Replaces the password of the authenticated user after checking the current one. The new password must follow the password policy. Every other session of the user is logged out.
+// @Tags        users
+// @Security    notes_jwt
+// @Accept      json
+// @Produce     json
+// @Param       request body ChangePasswordRequest true " "
+// @Success     200 {object} APIResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     403 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/password [put]
+func (uh *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	sessionID, err := GetSessionIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req ChangePasswordRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	if err := uh.UserService.ChangePassword(r.Context(), *userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Password changed, other sessions were logged out"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// ExportAccountHandler godoc
+// @Summary     Export personal data
+// @Description Downloads everything stored about the authenticated user as a JSON file: the profile, every note including those in the trash, the categories and the metadata of the note revisions.
+// @Tags        users
+// @Security    notes_jwt
+// @Produce     json
+// @Success     200 {object} models.AccountExport
+// @Failure     401 {object} ErrorResponse
+// @Failure     403 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/export [get]
+func (uh *UserHandler) ExportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	export, err := uh.UserService.ExportAccount(r.Context(), *userID)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	body, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrJsonResponse)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="account.json"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// DeleteAccountHandler godoc
+// @Summary     Delete the account
+// @Description Permanently deletes the authenticated user with their notes, categories, sessions and access tokens after checking their password, and their two-factor code when enabled. Wrong two-factor codes are throttled per user. The auth cookies are cleared.
+// @Tags        users
+// @Security    notes_jwt
+// @Accept      json
+// @Produce     json
+// @Param       request body DeleteAccountRequest true " "
+// @Success     200 {object} APIResponse
+// @Failure     400 {object} ErrorResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     403 {object} ErrorResponse
+// @Failure     429 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user [delete]
+func (uh *UserHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	var req DeleteAccountRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	if err := uh.UserService.DeleteAccount(r.Context(), w, *userID, req.Password, req.Code, req.RecoveryCode); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, map[string]string{"message": "Account deleted"}); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// RefreshHandler godoc
+// @Summary     Refresh the access token
+// @Description Exchanges the refresh token cookie for a new access token and a new refresh token. A refresh token works once; reusing one revokes its session.
+// @Tags        users
+// @Produce     json
+// @Success     200 {object} APIResponse
+// @Failure     401 {object} ErrorResponse
+// @Failure     500 {object} ErrorResponse
+// @Router      /user/refresh [post]
+func (uh *UserHandler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uh.UserService.RefreshSession(r.Context(), w, refreshTokenFromCookie(r))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	res := map[string]any{
+		"authenticated": true,
+		"user_id":       userID,
+	}
+	if err := response.JSON(w, http.StatusOK, res); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+func refreshTokenFromCookie(r *http.Request) string {
+	cookie, err := r.Cookie(utils.RefreshCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
 // CreateNoteHandler creates a new note for the user.
 // @Summary Create a new note
 // @Description Adds a new note for the authenticated user.
@@ -204,21 +421,7 @@ func (uh *UserHandler) CreateNoteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	noteResponse := GetNoteResponse{
-		ID:         note.ID,
-		Title:      note.Title,
-		Content:    note.Content,
-		Categories: note.Categories,
-		IsArchived: note.IsArchived,
-		CreatedAt:  note.CreatedAt,
-		UpdatedAt:  note.UpdatedAt,
-		UserID:     note.UserID,
-	}
-
-	if err := response.JSON(w, http.StatusCreated, noteResponse); err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
-		return
-	}
+	uh.writeNote(w, r, http.StatusCreated, note)
 }
 
 // GetNoteByIdHandler retrieves a specific note by its ID for the authenticated user.
@@ -228,7 +431,7 @@ func (uh *UserHandler) CreateNoteHandler(w http.ResponseWriter, r *http.Request)
 // @Security notes_jwt
 // @Produce json
 // @Param noteId path int true "Note ID"
-// @Success 200 {object} GetNoteResponse "Note retrieved successfully"
+// @Success 200 {object} GetNoteResponse "Note retrieved successfully, with its version in the ETag header"
 // @Failure 400 {object} ErrorResponse "Invalid note ID"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
 // @Failure 404 {object} ErrorResponse "Note not found"
@@ -255,20 +458,7 @@ func (uh *UserHandler) GetNoteByIdHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	noteResponse := GetNoteResponse{
-		ID:         note.ID,
-		Title:      note.Title,
-		Content:    note.Content,
-		Categories: note.Categories,
-		IsArchived: note.IsArchived,
-		CreatedAt:  note.CreatedAt,
-		UpdatedAt:  note.UpdatedAt,
-		UserID:     note.UserID,
-	}
-
-	if err := response.JSON(w, http.StatusOK, noteResponse); err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
-	}
+	uh.writeNote(w, r, http.StatusOK, note)
 }
 
 // UpdateNoteHandler updates a specific note for the authenticated user.
@@ -279,11 +469,15 @@ func (uh *UserHandler) GetNoteByIdHandler(w http.ResponseWriter, r *http.Request
 // @Accept json
 // @Produce json
 // @Param noteId path int true "Note ID"
+// @Param If-Match header string true "ETag of the note version being edited, or * to overwrite unconditionally"
 // @Param note body UpdateNoteRequest true "Updated note data"
-// @Success 200 {object} APIResponse "Note ID of updated note"
-// @Failure 400 {object} ErrorResponse "Invalid note ID or request data"
+// @Success 200 {object} APIResponse "Note ID of updated note, with the new version in the ETag header"
+// @Failure 400 {object} ErrorResponse "Invalid note ID, If-Match header or request data"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Viewers may not edit the note, nor editors archive it"
 // @Failure 404 {object} ErrorResponse "Note not found"
+// @Failure 412 {object} ErrorResponse "Note changed since it was fetched; data holds its current state"
+// @Failure 428 {object} ErrorResponse "Missing If-Match header"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /notes/{noteId} [put]
 func (uh *UserHandler) UpdateNoteHandler(w http.ResponseWriter, r *http.Request) {
@@ -300,6 +494,16 @@ func (uh *UserHandler) UpdateNoteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	version, present, err := ifMatchVersion(r)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if !present {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrIfMatchRequired)
+		return
+	}
+
 	var req UpdateNoteRequest
 	err = request.DecodeJSONStrict(w, r, &req)
 	if err != nil {
@@ -308,172 +512,442 @@ func (uh *UserHandler) UpdateNoteHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	updated := models.NewNote(req.Title, req.Content, req.Categories, *userID)
+	updated.Version = version
 	updatedNoteId, err := uh.UserService.UpdateNoteForUser(r.Context(), *userID, uint(noteID), updated)
 	if err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
+		uh.noteUpdateFailed(w, r, err, *userID, uint(noteID))
 		return
 	}
-	if err := response.JSON(w, http.StatusOK, updatedNoteId); err != nil {
+	if err := response.JSONWithHeaders(w, http.StatusOK, updatedNoteId, noteETagHeader(updated.Version)); err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 	}
 }
 
-// DeleteNoteHandler deletes a specific note by its ID for the authenticated user.
-// @Summary Delete a specific note by ID
-// @Description Deletes a note by its unique ID for the authenticated user.
+// PatchNoteHandler partially updates a specific note for the authenticated user.
+// @Summary Partially update a specific note by ID
+// @Description Applies an RFC 7396 merge patch to the note. Only the fields present are validated and saved; a categories array replaces the whole list. Fields cannot be removed with null. A patch that changes nothing returns the note as it is.
 // @Tags notes
 // @Security notes_jwt
-// @Accept json
+// @Accept application/merge-patch+json
 // @Produce json
 // @Param noteId path int true "Note ID"
-// @Success 200 {object} APIResponse "Note ID of the deleted note"
-// @Failure 400 {object} ErrorResponse "Invalid note ID"
+// @Param If-Match header string false "ETag of the note version being edited"
+// @Param patch body PatchNoteRequest true "Fields to change"
+// @Success 200 {object} GetNoteResponse "Patched note, with its version in the ETag header"
+// @Failure 400 {object} ErrorResponse "Invalid note ID, If-Match header or patch"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Viewers may not edit the note, nor editors archive it"
 // @Failure 404 {object} ErrorResponse "Note not found"
+// @Failure 412 {object} ErrorResponse "Note changed since it was fetched; data holds its current state"
+// @Failure 415 {object} ErrorResponse "Patch not sent as application/merge-patch+json"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /notes/{noteId} [delete]
-func (uh *UserHandler) DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+// @Router /notes/{noteId} [patch]
+func (uh *UserHandler) PatchNoteHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
 		return
 	}
+
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	_, err = uh.UserService.DeleteNoteForUser(r.Context(), *userID, uint(noteID))
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrPatchContentType)
+		return
+	}
+
+	version, _, err := ifMatchVersion(r)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	if err := response.JSON(w, http.StatusOK, noteID); err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
+	doc, err := request.DecodeMergePatch(w, r)
+	if err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+	patch, err := notePatchFromDocument(doc)
+	if err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	note, err := uh.UserService.PatchNoteForUser(r.Context(), *userID, uint(noteID), patch, version)
+	if err != nil {
+		uh.noteUpdateFailed(w, r, err, *userID, uint(noteID))
+		return
+	}
+
+	uh.writeNote(w, r, http.StatusOK, note)
+}
+
+// notePatchFromDocument maps the members of a merge patch onto a NotePatch.
+// Every note field is required, so null, which would remove the member, is
+// refused rather than applied.
+func notePatchFromDocument(doc map[string]json.RawMessage) (*models.NotePatch, error) {
+	patch := &models.NotePatch{}
+	for key, raw := range doc {
+		if string(raw) == "null" {
+			return nil, validations.ErrPatchRemoveField
+		}
+		var target any
+		switch key {
+		case "title":
+			target = &patch.Title
+		case "content":
+			target = &patch.Content
+		case "categories":
+			target = &patch.Categories
+		case "is_archived":
+			target = &patch.IsArchived
+		default:
+			return nil, fmt.Errorf("unknown json key %q", key)
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return nil, fmt.Errorf("incorrect json key type at: %q", key)
+		}
 	}
+	return patch, nil
 }
 
-// GetAllNotesByUserHandler retrieves all notes for the authenticated user.
-// @Summary Retrieve all notes
-// @Description Fetches all notes created by the authenticated user.
+// BulkNotesHandler applies one operation to several of the authenticated user's notes.
+// @Summary Apply an operation to several notes
+// @Description Archives, unarchives, deletes, or adds or removes a category on up to 100 notes. By default the notes are changed in one transaction that is rolled back if any of them fails; with best_effort the successful changes are kept. Every note gets its own result: ok, failed, or rolled_back when it succeeded but the transaction was rolled back.
 // @Tags notes
 // @Security notes_jwt
+// @Accept json
 // @Produce json
-// @Success 200 {array} GetNoteResponse "List of notes"
+// @Param bulk body BulkNotesRequest true "Operation, note IDs and, for category operations, the category"
+// @Success 200 {object} BulkNotesResponse "Per-note results"
+// @Failure 400 {object} ErrorResponse "Invalid operation, note IDs or category"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /notes [get]
-func (uh *UserHandler) GetAllNotesByUserHandler(w http.ResponseWriter, r *http.Request) {
-
+// @Router /notes/bulk [post]
+func (uh *UserHandler) BulkNotesHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	notes, err := uh.UserService.GetAllNotesByUserID(r.Context(), *userID)
+	var req BulkNotesRequest
+	if err := request.DecodeJSONStrict(w, r, &req); err != nil {
+		uh.HttpErrs.badRequest(w, r, err, ReqErrKey)
+		return
+	}
+
+	results, committed, err := uh.UserService.BulkNotesForUser(r.Context(), *userID, services.BulkOperation(req.Operation), req.NoteIDs, req.Category, req.BestEffort)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	noteResponses := make([]GetNoteResponse, 0, len(notes))
-	for _, note := range notes {
-		noteResponses = append(noteResponses, GetNoteResponse{
-			ID:         note.ID,
-			Title:      note.Title,
-			Content:    note.Content,
-			Categories: note.Categories,
-			IsArchived: note.IsArchived,
-			CreatedAt:  note.CreatedAt,
-			UpdatedAt:  note.UpdatedAt,
-			UserID:     note.UserID,
-		})
+	resp := BulkNotesResponse{
+		Operation:  req.Operation,
+		BestEffort: req.BestEffort,
+		Committed:  committed,
+		Results:    make([]BulkNoteResult, 0, len(results)),
+	}
+	for _, result := range results {
+		item := BulkNoteResult{ID: result.NoteID, Status: "ok"}
+		switch {
+		case result.Err != nil:
+			item.Status = "failed"
+			item.Error = result.Err.Error()
+			resp.Failed++
+		case !committed:
+			item.Status = "rolled_back"
+		default:
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, item)
 	}
 
-	if err := response.JSON(w, http.StatusOK, noteResponses); err != nil {
+	if err := response.JSON(w, http.StatusOK, resp); err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 	}
 }
 
-// AddCategoryToNoteHandler adds a category to a specific note for the authenticated user.
-// @Summary Add a category to a specific note by ID
-// @Description Adds a category to a note for the authenticated user.
+// maxImportBytes caps the size of an uploaded import archive.
+const maxImportBytes = 10 << 20
+
+// ExportNotesHandler streams the authenticated user's notes as a zip archive.
+// @Summary Export notes
+// @Description Streams a zip archive with one markdown file per note outside the trash. Each file starts with YAML front matter holding the title, categories, archived flag and timestamps.
 // @Tags notes
 // @Security notes_jwt
-// @Accept json
-// @Produce json
-// @Param noteId path int true "Note ID"
-// @Param categoryName path string true "Category name"
-// @Success 200 {object} GetNoteResponse "Updated note with added category"
-// @Failure 400 {object} ErrorResponse "Invalid note ID or category name"
+// @Produce application/zip
+// @Param format query string false "Export format, only markdown is supported (default markdown)"
+// @Success 200 {file} file "Zip archive of markdown files"
+// @Failure 400 {object} ErrorResponse "Unsupported format"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
-// @Failure 404 {object} ErrorResponse "Note or category not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /notes/{noteId}/categories/{categoryName} [post]
-func (uh *UserHandler) AddCategoryToNoteHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+// @Router /notes/export [get]
+func (uh *UserHandler) ExportNotesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	categoryName := vars["categoryName"]
-	if categoryName == "" {
-		uh.HttpErrs.CheckErrType(w, r, validations.ErrCategoryName)
+	if format := r.URL.Query().Get("format"); format != "" && format != "markdown" {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrExportFormat)
 		return
 	}
-	valid, formmatedCat, err := utils.ValidateAndFormatCategory(categoryName)
-	if !valid {
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="notes.zip"`)
+	out := &writeTracker{w: w}
+	if err := uh.UserService.ExportNotesForUser(r.Context(), *userID, out); err != nil {
+		if out.written {
+			// The status line is gone; the client gets a truncated archive.
+			uh.HttpErrs.reportServerError(r, err)
+			return
+		}
+		w.Header().Del("Content-Disposition")
 		uh.HttpErrs.CheckErrType(w, r, err)
-		return
 	}
+}
+
+// writeTracker records whether anything was written to the response.
+type writeTracker struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (wt *writeTracker) Write(p []byte) (int, error) {
+	wt.written = true
+	return wt.w.Write(p)
+}
 
+// ImportNotesHandler creates notes from a zip archive of markdown files.
+// @Summary Import notes
+// @Description Accepts a zip archive in the format produced by the export, up to 10 MB and 500 files. Every markdown file is validated like a new note; categories are created when missing. Invalid files are skipped and files whose title is already taken are reported as conflicts.
+// @Tags notes
+// @Security notes_jwt
+// @Accept application/zip
+// @Produce json
+// @Param archive body string true "Zip archive of markdown files"
+// @Success 200 {object} ImportNotesResponse "Created, skipped and conflicting files"
+// @Failure 400 {object} ErrorResponse "Body is not a valid zip archive or has too many files"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/import [post]
+func (uh *UserHandler) ImportNotesHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	updatedNote, err := uh.UserService.AddCategoryToNoteForUser(r.Context(), *userID, uint(noteID), formmatedCat)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
 	if err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrImportArchive)
+		return
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrImportArchive)
 		return
 	}
 
-	resp := GetNoteResponse{
-		ID:         updatedNote.ID,
-		Title:      updatedNote.Title,
-		Content:    updatedNote.Content,
-		Categories: updatedNote.Categories,
-		IsArchived: updatedNote.IsArchived,
-		CreatedAt:  updatedNote.CreatedAt,
-		UpdatedAt:  updatedNote.UpdatedAt,
-		UserID:     updatedNote.UserID,
+	report, err := uh.UserService.ImportNotesForUser(r.Context(), *userID, archive)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
 	}
 
+	resp := ImportNotesResponse{
+		Created:   toImportNoteEntries(report.Created),
+		Skipped:   toImportNoteEntries(report.Skipped),
+		Conflicts: toImportNoteEntries(report.Conflicts),
+	}
 	if err := response.JSON(w, http.StatusOK, resp); err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 	}
 }
 
-// RemoveCategoryFromNoteHandler removes a category from a specific note for the authenticated user.
-// @Summary Remove a category from a specific note by ID
-// @Description Removes a category from a note for the authenticated user.
+func toImportNoteEntries(entries []models.NoteImportEntry) []ImportNoteEntry {
+	res := make([]ImportNoteEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, ImportNoteEntry{
+			File:   e.File,
+			Title:  e.Title,
+			NoteID: e.NoteID,
+			Reason: e.Reason,
+		})
+	}
+	return res
+}
+
+// DeleteNoteHandler moves a specific note to the trash for the authenticated user.
+// @Summary Delete a specific note by ID
+// @Description Moves a note to the trash. It can be restored until the trash is emptied or the retention period runs out.
 // @Tags notes
 // @Security notes_jwt
 // @Accept json
 // @Produce json
 // @Param noteId path int true "Note ID"
-// @Param categoryName path string true "Category name"
-// @Success 200 {object} GetNoteResponse "Updated note with removed category"
-// @Failure 400 {object} ErrorResponse "Invalid note ID or category name"
+// @Success 200 {object} APIResponse "Note ID of the deleted note"
+// @Failure 400 {object} ErrorResponse "Invalid note ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Only the owner may delete the note"
+// @Failure 404 {object} ErrorResponse "Note not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId} [delete]
+func (uh *UserHandler) DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	_, err = uh.UserService.DeleteNoteForUser(r.Context(), *userID, uint(noteID))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := response.JSON(w, http.StatusOK, noteID); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// GetAllNotesByUserHandler retrieves a page of notes for the authenticated user.
+// @Summary Retrieve all notes
+// @Description Fetches the notes created by the authenticated user, one page at a time, or with scope=shared the notes other users shared with them. Pass metadata.next_cursor back as cursor to get the next page.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param scope query string false "own (default) or shared"
+// @Param limit query int false "Page size (1-100, default 50)"
+// @Param cursor query string false "Opaque cursor from the previous page"
+// @Param sort query string false "position (manual order, pinned first), created_at, updated_at or title, prefixed with - for descending (default position, or -created_at with scope=shared)"
+// @Success 200 {array} GetNoteResponse "List of notes, with PageMetadata under metadata"
+// @Failure 400 {object} ErrorResponse "Invalid scope or paging parameters"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes [get]
+func (uh *UserHandler) GetAllNotesByUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	page, err := pageRequestFromQuery(r)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	var notePage *models.NotePage
+	switch r.URL.Query().Get("scope") {
+	case "", "own":
+		notePage, err = uh.UserService.GetAllNotesByUserID(r.Context(), *userID, page)
+	case "shared":
+		notePage, err = uh.UserService.GetSharedNotesForUser(r.Context(), *userID, page)
+	default:
+		err = validations.ErrNoteScope
+	}
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	if err := response.JSONWithMetadata(w, http.StatusOK, noteResponsesFromPage(notePage), pageMetadata(notePage, page)); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// AddCategoryToNoteHandler adds a category to a specific note for the authenticated user.
+// @Summary Add a category to a specific note by ID
+// @Description Adds a category to a note for the authenticated user.
+// @Tags notes
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param categoryName path string true "Category name"
+// @Param If-Match header string false "ETag of the note version being edited"
+// @Success 200 {object} GetNoteResponse "Updated note with added category"
+// @Failure 400 {object} ErrorResponse "Invalid note ID, category name or If-Match header"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Viewers may not edit the note"
 // @Failure 404 {object} ErrorResponse "Note or category not found"
+// @Failure 412 {object} ErrorResponse "Note changed since it was fetched; data holds its current state"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/categories/{categoryName} [post]
+func (uh *UserHandler) AddCategoryToNoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+
+	categoryName := vars["categoryName"]
+	if categoryName == "" {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrCategoryName)
+		return
+	}
+	valid, formmatedCat, err := utils.ValidateAndFormatCategory(categoryName)
+	if !valid {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	version, _, err := ifMatchVersion(r)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	updatedNote, err := uh.UserService.AddCategoryToNoteForUser(r.Context(), *userID, uint(noteID), formmatedCat, version)
+	if err != nil {
+		uh.noteUpdateFailed(w, r, err, *userID, uint(noteID))
+		return
+	}
+
+	uh.writeNote(w, r, http.StatusOK, updatedNote)
+}
+
+// RemoveCategoryFromNoteHandler removes a category from a specific note for the authenticated user.
+// @Summary Remove a category from a specific note by ID
+// @Description Removes a category from a note for the authenticated user.
+// @Tags notes
+// @Security notes_jwt
+// @Accept json
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param categoryName path string true "Category name"
+// @Param If-Match header string false "ETag of the note version being edited"
+// @Success 200 {object} GetNoteResponse "Updated note with removed category"
+// @Failure 400 {object} ErrorResponse "Invalid note ID, category name or If-Match header"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Viewers may not edit the note"
+// @Failure 404 {object} ErrorResponse "Note or category not found"
+// @Failure 412 {object} ErrorResponse "Note changed since it was fetched; data holds its current state"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /notes/{noteId}/categories/{categoryName} [delete]
 func (uh *UserHandler) RemoveCategoryFromNoteHandler(w http.ResponseWriter, r *http.Request) {
@@ -502,26 +976,19 @@ func (uh *UserHandler) RemoveCategoryFromNoteHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	updatedNote, err := uh.UserService.RemoveCategoryFromNoteForUser(r.Context(), *userID, uint(noteID), formmatedCat)
+	version, _, err := ifMatchVersion(r)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	resp := GetNoteResponse{
-		ID:         updatedNote.ID,
-		Title:      updatedNote.Title,
-		Content:    updatedNote.Content,
-		Categories: updatedNote.Categories,
-		IsArchived: updatedNote.IsArchived,
-		CreatedAt:  updatedNote.CreatedAt,
-		UpdatedAt:  updatedNote.UpdatedAt,
-		UserID:     updatedNote.UserID,
+	updatedNote, err := uh.UserService.RemoveCategoryFromNoteForUser(r.Context(), *userID, uint(noteID), formmatedCat, version)
+	if err != nil {
+		uh.noteUpdateFailed(w, r, err, *userID, uint(noteID))
+		return
 	}
 
-	if err := response.JSON(w, http.StatusOK, resp); err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
-	}
+	uh.writeNote(w, r, http.StatusOK, updatedNote)
 }
 
 // ToggleArchiveStatusHandler toggles the archive status of a specific note for the authenticated user.
@@ -532,10 +999,13 @@ func (uh *UserHandler) RemoveCategoryFromNoteHandler(w http.ResponseWriter, r *h
 // @Accept json
 // @Produce json
 // @Param noteId path int true "Note ID"
+// @Param If-Match header string false "ETag of the note version being edited"
 // @Success 200 {object} GetNoteResponse "Note with updated archive status"
-// @Failure 400 {object} ErrorResponse "Invalid note ID"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or If-Match header"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Only the owner may archive the note"
 // @Failure 404 {object} ErrorResponse "Note not found"
+// @Failure 412 {object} ErrorResponse "Note changed since it was fetched; data holds its current state"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /notes/{noteId}/archive [put]
 func (uh *UserHandler) ToggleArchiveStatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -552,36 +1022,32 @@ func (uh *UserHandler) ToggleArchiveStatusHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	updatedNote, err := uh.UserService.ToggleArchiveStatusForUser(r.Context(), *userID, uint(noteID))
+	version, _, err := ifMatchVersion(r)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	resp := GetNoteResponse{
-		ID:         updatedNote.ID,
-		Title:      updatedNote.Title,
-		Content:    updatedNote.Content,
-		Categories: updatedNote.Categories,
-		IsArchived: updatedNote.IsArchived,
-		CreatedAt:  updatedNote.CreatedAt,
-		UpdatedAt:  updatedNote.UpdatedAt,
-		UserID:     updatedNote.UserID,
+	updatedNote, err := uh.UserService.ToggleArchiveStatusForUser(r.Context(), *userID, uint(noteID), version)
+	if err != nil {
+		uh.noteUpdateFailed(w, r, err, *userID, uint(noteID))
+		return
 	}
 
-	if err := response.JSON(w, http.StatusOK, resp); err != nil {
-		uh.HttpErrs.CheckErrType(w, r, err)
-	}
+	uh.writeNote(w, r, http.StatusOK, updatedNote)
 }
 
 // FilterNotesForUserHandler filters notes by categories and archived status.
 // @Summary Filter notes by categories and archived status
-// @Description Filters notes based on categories and archived status. Both parameters are optional.
+// @Description Filters notes based on categories and archived status, one page at a time. Both filters are optional.
 // @Tags notes
 // @Security notes_jwt
 // @Param isArchived query bool false "Filter by archived status (optional)"
 // @Param categories query []string false "Filter notes by categories (optional)" "List of categories"
-// @Success 200 {array} GetNoteResponse "Filtered notes"
+// @Param limit query int false "Page size (1-100, default 50)"
+// @Param cursor query string false "Opaque cursor from the previous page"
+// @Param sort query string false "position (manual order, pinned first), created_at, updated_at or title, prefixed with - for descending (default position)"
+// @Success 200 {array} GetNoteResponse "Filtered notes, with PageMetadata under metadata"
 // @Failure 400 {object} ErrorResponse "Invalid query parameters"
 // @Failure 401 {object} ErrorResponse "Unauthorized access"
 // @Failure 500 {object} ErrorResponse "Internal server error"
@@ -604,27 +1070,419 @@ func (uh *UserHandler) FilterNotesForUserHandler(w http.ResponseWriter, r *http.
 
 	categories := r.URL.Query()["categories"]
 
-	notes, err := uh.UserService.FilterNotesForUser(r.Context(), *userID, archived, categories)
+	page, err := pageRequestFromQuery(r)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	notePage, err := uh.UserService.FilterNotesForUser(r.Context(), *userID, archived, categories, page)
 	if err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 		return
 	}
 
-	noteResponses := make([]GetNoteResponse, 0, len(notes))
+	if err := response.JSONWithMetadata(w, http.StatusOK, noteResponsesFromPage(notePage), pageMetadata(notePage, page)); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// pageRequestFromQuery reads the limit, cursor and sort query parameters.
+func pageRequestFromQuery(r *http.Request) (pagination.Request, error) {
+	query := r.URL.Query()
+	page := pagination.Request{
+		Limit:  50,
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+	}
+	if limitParam := query.Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil {
+			return page, validations.ErrInvalidPageLimit
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+func pageMetadata(notePage *models.NotePage, page pagination.Request) PageMetadata {
+	metadata := PageMetadata{
+		Limit: page.Limit,
+		Sort:  notePage.Sort,
+	}
+	if notePage.NextCursor != "" {
+		metadata.NextCursor = &notePage.NextCursor
+	}
+	return metadata
+}
+
+func toGetNoteResponse(note *models.Note) GetNoteResponse {
+	return GetNoteResponse{
+		ID:         note.ID,
+		Title:      note.Title,
+		Content:    note.Content,
+		Categories: note.Categories,
+		IsArchived: note.IsArchived,
+		Pinned:     note.Pinned,
+		Position:   note.Position,
+		Version:    note.Version,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+		UserID:     note.UserID,
+	}
+}
+
+func noteETagHeader(version uint) http.Header {
+	headers := http.Header{}
+	headers.Set("ETag", `"`+strconv.FormatUint(uint64(version), 10)+`"`)
+	return headers
+}
+
+// ifMatchVersion reads the note version the client expects from If-Match.
+// It returns 0, which skips the version check, when the header is missing or
+// "*"; present reports whether it was sent at all. Weak tags are accepted
+// since the version is the only thing compared.
+func ifMatchVersion(r *http.Request) (version uint, present bool, err error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, false, nil
+	}
+	if value == "*" {
+		return 0, true, nil
+	}
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, true, validations.ErrInvalidIfMatch
+	}
+	parsed, err := strconv.ParseUint(value[1:len(value)-1], 10, 32)
+	if err != nil || parsed == 0 {
+		return 0, true, validations.ErrInvalidIfMatch
+	}
+	return uint(parsed), true, nil
+}
+
+// writeNote responds with the note and its version as the ETag.
+func (uh *UserHandler) writeNote(w http.ResponseWriter, r *http.Request, status int, note *models.Note) {
+	if err := response.JSONWithHeaders(w, status, toGetNoteResponse(note), noteETagHeader(note.Version)); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// noteUpdateFailed reports an error from a note update. When the client's
+// If-Match is stale the current note is sent along, so it can reconcile its
+// copy without fetching it again.
+func (uh *UserHandler) noteUpdateFailed(w http.ResponseWriter, r *http.Request, err error, userId uint, noteId uint) {
+	if errors.Is(err, validations.ErrVersionMismatch) {
+		if current, getErr := uh.UserService.GetNoteById(r.Context(), noteId, userId); getErr == nil {
+			uh.HttpErrs.preconditionFailed(w, r, err, ReqErrKey, toGetNoteResponse(current), noteETagHeader(current.Version))
+			return
+		}
+	}
+	uh.HttpErrs.CheckErrType(w, r, err)
+}
+
+func noteResponsesFromPage(notePage *models.NotePage) []GetNoteResponse {
+	noteResponses := make([]GetNoteResponse, 0, len(notePage.Notes))
+	for _, note := range notePage.Notes {
+		noteResponses = append(noteResponses, toGetNoteResponse(&note))
+	}
+	return noteResponses
+}
+
+// SearchNotesForUserHandler ranks the authenticated user's notes, and the notes shared with them, by relevance to a query.
+// @Summary Full-text search over notes
+// @Description Searches title, content and category names of the authenticated user's notes and of the notes shared with them, ordered by relevance with highlighted snippets. Shared notes carry their owner's user_id.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param q query string true "Search query, supports quoted phrases, OR and -exclusions"
+// @Param limit query int false "Maximum number of results (1-50, default 20)"
+// @Success 200 {array} SearchNoteResponse "Matching notes ordered by relevance"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters or no matches"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/search [get]
+func (uh *UserHandler) SearchNotesForUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	limit := 20
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidLimit)
+			return
+		}
+	}
+
+	results, err := uh.UserService.SearchNotesForUser(r.Context(), *userID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	searchResponses := make([]SearchNoteResponse, 0, len(results))
+	for _, result := range results {
+		note := result.Note
+		searchResponses = append(searchResponses, SearchNoteResponse{
+			GetNoteResponse: toGetNoteResponse(&note),
+			Rank:            result.Rank,
+			Highlights: NoteHighlights{
+				Title:   result.TitleSnippet,
+				Content: result.ContentSnippet,
+			},
+		})
+	}
+
+	if err := response.JSON(w, http.StatusOK, searchResponses); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// GetNoteRevisionsHandler lists the revision history of a note.
+// @Summary List note revisions
+// @Description Returns every recorded revision of a note owned by the authenticated user, newest first.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Success 200 {array} NoteRevisionResponse "Revisions of the note"
+// @Failure 400 {object} ErrorResponse "Invalid note ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/revisions [get]
+func (uh *UserHandler) GetNoteRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	revisions, err := uh.UserService.GetNoteRevisionsForUser(r.Context(), *userID, uint(noteID))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	revisionResponses := make([]NoteRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionResponses = append(revisionResponses, NoteRevisionResponse{
+			Revision:   revision.Revision,
+			Title:      revision.Title,
+			Content:    revision.Content,
+			Categories: revision.Categories,
+			IsArchived: revision.IsArchived,
+			ActorID:    revision.ActorID,
+			CreatedAt:  revision.CreatedAt,
+		})
+	}
+
+	if err := response.JSON(w, http.StatusOK, revisionResponses); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// DiffNoteRevisionsHandler compares two revisions of a note.
+// @Summary Diff two note revisions
+// @Description Returns a line diff of title and content plus category and archive changes between two revisions.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param from query int true "Base revision"
+// @Param to query int true "Revision to compare against the base"
+// @Success 200 {object} NoteRevisionDiffResponse "Changes between the revisions"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or revision"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Revision not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/revisions/diff [get]
+func (uh *UserHandler) DiffNoteRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidRevision)
+		return
+	}
+	to, err := strconv.ParseUint(r.URL.Query().Get("to"), 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidRevision)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	revisionDiff, err := uh.UserService.DiffNoteRevisionsForUser(r.Context(), *userID, uint(noteID), uint(from), uint(to))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	resp := NoteRevisionDiffResponse{
+		From:              revisionDiff.From,
+		To:                revisionDiff.To,
+		Title:             revisionDiff.Title,
+		Content:           revisionDiff.Content,
+		CategoriesAdded:   revisionDiff.CategoriesAdded,
+		CategoriesRemoved: revisionDiff.CategoriesRemoved,
+		ArchivedFrom:      revisionDiff.ArchivedFrom,
+		ArchivedTo:        revisionDiff.ArchivedTo,
+	}
+	if err := response.JSON(w, http.StatusOK, resp); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// RestoreNoteRevisionHandler restores a note to a previous revision.
+// @Summary Restore a note revision
+// @Description Brings the note back to the state of the given revision. The restore is recorded as a new revision.
+// @Tags notes
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Param revision path int true "Revision to restore"
+// @Success 200 {object} GetNoteResponse "Note after the restore"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or revision, or note already matches it"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 403 {object} ErrorResponse "Viewers may not edit the note"
+// @Failure 404 {object} ErrorResponse "Revision not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/revisions/{revision}/restore [post]
+func (uh *UserHandler) RestoreNoteRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.ParseUint(vars["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	revision, err := strconv.ParseUint(vars["revision"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInvalidRevision)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	note, err := uh.UserService.RestoreNoteRevisionForUser(r.Context(), *userID, uint(noteID), uint(revision))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	uh.writeNote(w, r, http.StatusOK, note)
+}
+
+// GetTrashHandler lists the notes in the authenticated user's trash.
+// @Summary List trashed notes
+// @Description Returns the notes in the trash of the authenticated user, most recently deleted first.
+// @Tags trash
+// @Security notes_jwt
+// @Produce json
+// @Success 200 {array} TrashedNoteResponse "Trashed notes"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/trash [get]
+func (uh *UserHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	notes, err := uh.UserService.GetTrashForUser(r.Context(), *userID)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	trashResponses := make([]TrashedNoteResponse, 0, len(notes))
 	for _, note := range notes {
-		noteResponses = append(noteResponses, GetNoteResponse{
-			ID:         note.ID,
-			Title:      note.Title,
-			Content:    note.Content,
-			Categories: note.Categories,
-			IsArchived: note.IsArchived,
-			CreatedAt:  note.CreatedAt,
-			UpdatedAt:  note.UpdatedAt,
-			UserID:     note.UserID,
+		trashResponses = append(trashResponses, TrashedNoteResponse{
+			GetNoteResponse: toGetNoteResponse(&note),
+			DeletedAt:       note.DeletedAt.Time,
 		})
 	}
 
-	if err := response.JSON(w, http.StatusOK, noteResponses); err != nil {
+	if err := response.JSON(w, http.StatusOK, trashResponses); err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+	}
+}
+
+// RestoreNoteHandler moves a note out of the trash.
+// @Summary Restore a trashed note
+// @Description Moves a note of the authenticated user out of the trash.
+// @Tags trash
+// @Security notes_jwt
+// @Produce json
+// @Param noteId path int true "Note ID"
+// @Success 200 {object} GetNoteResponse "Restored note"
+// @Failure 400 {object} ErrorResponse "Invalid note ID or title already in use"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 404 {object} ErrorResponse "Note not found in the trash"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/{noteId}/restore [post]
+func (uh *UserHandler) RestoreNoteHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseUint(mux.Vars(r)["noteId"], 10, 32)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, validations.ErrInlvalidId)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	note, err := uh.UserService.RestoreNoteForUser(r.Context(), *userID, uint(noteID))
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	uh.writeNote(w, r, http.StatusOK, note)
+}
+
+// EmptyTrashHandler permanently deletes every note in the authenticated user's trash.
+// @Summary Empty the trash
+// @Description Permanently deletes all trashed notes of the authenticated user.
+// @Tags trash
+// @Security notes_jwt
+// @Produce json
+// @Success 200 {object} EmptyTrashResponse "Number of purged notes"
+// @Failure 401 {object} ErrorResponse "Unauthorized access"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /notes/trash [delete]
+func (uh *UserHandler) EmptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+
+	purged, err := uh.UserService.EmptyTrashForUser(r.Context(), *userID)
+	if err != nil {
+		uh.HttpErrs.CheckErrType(w, r, err)
+		return
+	}
+	if err := response.JSON(w, http.StatusOK, EmptyTrashResponse{Purged: purged}); err != nil {
 		uh.HttpErrs.CheckErrType(w, r, err)
 	}
 }
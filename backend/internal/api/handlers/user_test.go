@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/internal/services"
+	"notes/pkg/pagination"
+	"notes/pkg/validations"
+)
+
+// newNotesUserHandler builds a UserHandler on a SQLite database holding the
+// users alice and bob, and returns it with their IDs.
+func newNotesUserHandler(t *testing.T) (*UserHandler, uint, uint) {
+	t.Helper()
+	gormDB := dbtest.SQLite(t)
+	conf := &configs.Config{}
+	users := repositories.NewUserRepository(gormDB, conf)
+	var ids []uint
+	for _, name := range []string{"alice", "bob"} {
+		user := models.NewUser(name, "hash", nil)
+		if err := users.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+	categories := repositories.NewCategoryRepository(gormDB, conf)
+	ns := services.NewNoteService(repositories.NewNoteRepository(gormDB, conf, categories), services.NewCategoryService(categories), nil)
+	us := services.NewUserService(users, ns, nil, nil, nil, nil, nil)
+	return NewUserHandler(us, nil, NewHttpErrors(slog.New(slog.NewTextHandler(io.Discard, nil)))), ids[0], ids[1]
+}
+
+// serveAs serves the handler for the user, as PROTECT would let it through.
+func serveAs(handler http.HandlerFunc, userId uint, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userId)))
+	return w
+}
+
+// decodeData decodes the data of the standard response envelope.
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, data any) {
+	t.Helper()
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding the response: %v", err)
+	}
+}
+
+func TestBulkNotesHandler(t *testing.T) {
+	for _, bestEffort := range []bool{false, true} {
+		name := "transactional"
+		if bestEffort {
+			name = "best effort"
+		}
+		t.Run(name, func(t *testing.T) {
+			uh, alice, bob := newNotesUserHandler(t)
+			ctx := context.Background()
+			var ids []uint
+			for _, owner := range []struct {
+				id    uint
+				title string
+			}{{alice, "first note"}, {bob, "bob's note"}, {alice, "second note"}} {
+				note, err := uh.UserService.CreateNote(ctx, owner.title, "content of "+owner.title, []string{"work"}, owner.id)
+				if err != nil {
+					t.Fatalf("CreateNote: %v", err)
+				}
+				ids = append(ids, note.ID)
+			}
+
+			body, _ := json.Marshal(BulkNotesRequest{Operation: "archive", NoteIDs: ids, BestEffort: bestEffort})
+			w := serveAs(uh.BulkNotesHandler, alice, httptest.NewRequest(http.MethodPost, "/notes/bulk", bytes.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+			}
+			var res BulkNotesResponse
+			decodeData(t, w, &res)
+
+			// Bob's note fails either way; alice's are rolled back with it
+			// unless the run is best effort.
+			wantStatus, wantArchived, wantSucceeded := "rolled_back", false, 0
+			if bestEffort {
+				wantStatus, wantArchived, wantSucceeded = "ok", true, 2
+			}
+			if res.Committed != bestEffort || res.Failed != 1 || res.Succeeded != wantSucceeded || len(res.Results) != 3 {
+				t.Fatalf("response is %+v, want committed %v with one failure", res, bestEffort)
+			}
+			for i, want := range []string{wantStatus, "failed", wantStatus} {
+				if got := res.Results[i]; got.ID != ids[i] || got.Status != want {
+					t.Fatalf("result %d is %+v, want note %d %s", i, got, ids[i], want)
+				}
+			}
+
+			for i, owner := range []uint{alice, bob, alice} {
+				note, err := uh.UserService.GetNoteById(ctx, ids[i], owner)
+				if err != nil {
+					t.Fatalf("GetNoteById: %v", err)
+				}
+				if want := wantArchived && owner == alice; note.IsArchived != want {
+					t.Fatalf("note %d is archived: %v, want %v", ids[i], note.IsArchived, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	uh, alice, bob := newNotesUserHandler(t)
+	ctx := context.Background()
+	if _, err := uh.UserService.CreateNote(ctx, "groceries", "milk, eggs and bread", []string{"home", "errands"}, alice); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	trip, err := uh.UserService.CreateNote(ctx, "trip plan", "pack the tent", []string{"travel"}, alice)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if _, err := uh.UserService.ToggleArchiveStatusForUser(ctx, alice, trip.ID, 0); err != nil {
+		t.Fatalf("ToggleArchiveStatusForUser: %v", err)
+	}
+
+	w := serveAs(uh.ExportNotesHandler, alice, httptest.NewRequest(http.MethodGet, "/notes/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export status %d, want 200: %s", w.Code, w.Body)
+	}
+	w = serveAs(uh.ImportNotesHandler, bob, httptest.NewRequest(http.MethodPost, "/notes/import", bytes.NewReader(w.Body.Bytes())))
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status %d, want 200: %s", w.Code, w.Body)
+	}
+	var report ImportNotesResponse
+	decodeData(t, w, &report)
+	if len(report.Created) != 2 || len(report.Skipped) != 0 || len(report.Conflicts) != 0 {
+		t.Fatalf("import report is %+v, want both notes created", report)
+	}
+
+	exported := notesByTitle(t, uh, alice)
+	imported := notesByTitle(t, uh, bob)
+	if len(exported) != 2 || len(imported) != 2 {
+		t.Fatalf("alice has %d notes and bob %d, want 2 each", len(exported), len(imported))
+	}
+	if exported["Trip Plan"].UpdatedAt == nil {
+		t.Fatalf("archiving did not update the note")
+	}
+	for title, want := range exported {
+		got, ok := imported[title]
+		if !ok {
+			t.Fatalf("note %q was not imported", title)
+		}
+		if got.Content != want.Content || got.IsArchived != want.IsArchived || categoryNames(got) != categoryNames(want) {
+			t.Fatalf("imported note is %+v, want %+v", got, want)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) || (got.UpdatedAt == nil) != (want.UpdatedAt == nil) ||
+			(want.UpdatedAt != nil && !got.UpdatedAt.Equal(*want.UpdatedAt)) {
+			t.Fatalf("imported note %q was created %v and updated %v, want %v and %v", title, got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+		}
+	}
+}
+
+// notesByTitle returns the user's notes, archived ones included, by title.
+func notesByTitle(t *testing.T, uh *UserHandler, userId uint) map[string]models.Note {
+	t.Helper()
+	notes := map[string]models.Note{}
+	for _, archived := range []bool{false, true} {
+		page, err := uh.UserService.FilterNotesForUser(context.Background(), userId, &archived, nil, pagination.Request{Limit: 10})
+		if err != nil && !errors.Is(err, validations.ErrNoNotesFound) {
+			t.Fatalf("GetNotesForUser: %v", err)
+		}
+		if page != nil {
+			for _, n := range page.Notes {
+				notes[n.Title] = n
+			}
+		}
+	}
+	return notes
+}
+
+func categoryNames(note models.Note) string {
+	names := make([]string, 0, len(note.Categories))
+	for _, c := range note.Categories {
+		names = append(names, c.Name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+func TestImportNotesRejects(t *testing.T) {
+	uh, alice, _ := newNotesUserHandler(t)
+
+	w := serveAs(uh.ImportNotesHandler, alice, httptest.NewRequest(http.MethodPost, "/notes/import", bytes.NewReader(make([]byte, maxImportBytes+1))))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("import of an archive over the limit: status %d, want 400", w.Code)
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, doc := range map[string]string{
+		"broken.md":   "---\ntitle: [unclosed\ncategories: [work]\n---\n\nsome content here\n",
+		"unclosed.md": "---\ntitle: no end\ncategories: [work]\n\nsome content here\n",
+		"good.md":     "---\ntitle: good note\ncategories: [work]\n---\n\nsome content here\n",
+	} {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip Create: %v", err)
+		}
+		io.WriteString(f, doc)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+	w = serveAs(uh.ImportNotesHandler, alice, httptest.NewRequest(http.MethodPost, "/notes/import", &archive))
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status %d, want 200: %s", w.Code, w.Body)
+	}
+	var report ImportNotesResponse
+	decodeData(t, w, &report)
+	if len(report.Created) != 1 || report.Created[0].Title != "Good Note" || len(report.Skipped) != 2 {
+		t.Fatalf("import report is %+v, want the good note created and the others skipped", report)
+	}
+	for _, skipped := range report.Skipped {
+		if skipped.Reason != validations.ErrImportFrontMatter.Error() {
+			t.Fatalf("%s was skipped for %q, want the front matter error", skipped.File, skipped.Reason)
+		}
+	}
+}
@@ -7,39 +7,167 @@ import (
 
 const (
 	env            = "local"
+	dbDriver       = "postgres"
+	dbHost         = "localhost"
 	dbPORT         = 5400
 	dbName         = "note_rev"
 	httpPort       = 8025
 	apiUrl         = "http://localhost:8025"
 	allowedOrigins = ""
+	trustedProxies = ""
+
+	trashRetentionHours       = 720
+	trashPurgeIntervalMinutes = 60
+
+	syncTombstoneRetentionDays = 90
+
+	eventsBufferSize       = 64
+	eventsReplaySize       = 1000
+	eventsDropPolicy       = "disconnect"
+	eventsHeartbeatSeconds = 15
+
+	loginFailureWindowMinutes = 15
+	loginLockoutMinutes       = 15
+	loginBackoffBaseSeconds   = 1
+	loginBackoffMaxSeconds    = 60
+	loginUserFreeAttempts     = 3
+	loginUserMaxFailures      = 10
+	loginIPFreeAttempts       = 10
+	loginIPMaxFailures        = 50
+
+	passwordMinLength         = 8
+	passwordMaxLength         = 64
+	passwordMinClasses        = 2
+	passwordHashAlgorithm     = "argon2id"
+	passwordBcryptCost        = 12
+	passwordArgon2MemoryKiB   = 19456
+	passwordArgon2Iterations  = 2
+	passwordArgon2Parallelism = 1
 )
 
 func New() *Config {
-	dbUser := GetString("DB_USER", "")
-	dbPassword := GetString("DB_PASSWORD", "")
-	dbURI := "postgres://" + dbUser + ":" + dbPassword + "@localhost:" + strconv.Itoa(dbPORT) + "/" + dbName + "?sslmode=disable"
+	driver := GetString("DB_DRIVER", dbDriver)
+	name := GetString("DB_NAME", dbName)
+	host := GetString("DB_HOST", dbHost)
+	port := GetInt("DB_PORT", dbPORT)
+	dbURI := name + ".db"
+	if driver == dbDriver {
+		dbUser := GetString("DB_USER", "")
+		dbPassword := GetString("DB_PASSWORD", "")
+		dbURI = "postgres://" + dbUser + ":" + dbPassword + "@" + host + ":" + strconv.Itoa(port) + "/" + name + "?sslmode=disable"
+	}
 	return &Config{
+		DB_DRIVER:       driver,
 		DB_URI:          GetString("DB_URI", dbURI),
-		DB_NAME:         GetString("DB_NAME", dbName),
-		DB_PORT:         GetInt("DB_PORT", dbPORT),
+		DB_NAME:         name,
+		DB_HOST:         host,
+		DB_PORT:         port,
 		ENV:             GetString("ENV", env),
 		API_URL:         GetString("API_URL", apiUrl),
 		HTTP_PORT:       GetInt("HTTP_PORT", httpPort),
 		ALLOWED_ORIGINS: GetString("ALLOWED_ORIGINS", allowedOrigins),
+		TRUSTED_PROXIES: GetString("TRUSTED_PROXIES", trustedProxies),
+
+		TRASH_RETENTION_HOURS:        GetInt("TRASH_RETENTION_HOURS", trashRetentionHours),
+		TRASH_PURGE_INTERVAL_MINUTES: GetInt("TRASH_PURGE_INTERVAL_MINUTES", trashPurgeIntervalMinutes),
+
+		SYNC_TOMBSTONE_RETENTION_DAYS: GetInt("SYNC_TOMBSTONE_RETENTION_DAYS", syncTombstoneRetentionDays),
+
+		EVENTS_BUFFER_SIZE:       GetInt("EVENTS_BUFFER_SIZE", eventsBufferSize),
+		EVENTS_REPLAY_SIZE:       GetInt("EVENTS_REPLAY_SIZE", eventsReplaySize),
+		EVENTS_DROP_POLICY:       GetString("EVENTS_DROP_POLICY", eventsDropPolicy),
+		EVENTS_HEARTBEAT_SECONDS: GetInt("EVENTS_HEARTBEAT_SECONDS", eventsHeartbeatSeconds),
+
+		LOGIN_FAILURE_WINDOW_MINUTES: GetInt("LOGIN_FAILURE_WINDOW_MINUTES", loginFailureWindowMinutes),
+		LOGIN_LOCKOUT_MINUTES:        GetInt("LOGIN_LOCKOUT_MINUTES", loginLockoutMinutes),
+		LOGIN_BACKOFF_BASE_SECONDS:   GetInt("LOGIN_BACKOFF_BASE_SECONDS", loginBackoffBaseSeconds),
+		LOGIN_BACKOFF_MAX_SECONDS:    GetInt("LOGIN_BACKOFF_MAX_SECONDS", loginBackoffMaxSeconds),
+		LOGIN_USER_FREE_ATTEMPTS:     GetInt("LOGIN_USER_FREE_ATTEMPTS", loginUserFreeAttempts),
+		LOGIN_USER_MAX_FAILURES:      GetInt("LOGIN_USER_MAX_FAILURES", loginUserMaxFailures),
+		LOGIN_IP_FREE_ATTEMPTS:       GetInt("LOGIN_IP_FREE_ATTEMPTS", loginIPFreeAttempts),
+		LOGIN_IP_MAX_FAILURES:        GetInt("LOGIN_IP_MAX_FAILURES", loginIPMaxFailures),
+
+		PASSWORD_MIN_LENGTH:         GetInt("PASSWORD_MIN_LENGTH", passwordMinLength),
+		PASSWORD_MAX_LENGTH:         GetInt("PASSWORD_MAX_LENGTH", passwordMaxLength),
+		PASSWORD_MIN_CLASSES:        GetInt("PASSWORD_MIN_CLASSES", passwordMinClasses),
+		PASSWORD_DENYLIST_FILE:      GetString("PASSWORD_DENYLIST_FILE", ""),
+		PASSWORD_HASH_ALGORITHM:     GetString("PASSWORD_HASH_ALGORITHM", passwordHashAlgorithm),
+		PASSWORD_BCRYPT_COST:        GetInt("PASSWORD_BCRYPT_COST", passwordBcryptCost),
+		PASSWORD_ARGON2_MEMORY_KIB:  GetInt("PASSWORD_ARGON2_MEMORY_KIB", passwordArgon2MemoryKiB),
+		PASSWORD_ARGON2_ITERATIONS:  GetInt("PASSWORD_ARGON2_ITERATIONS", passwordArgon2Iterations),
+		PASSWORD_ARGON2_PARALLELISM: GetInt("PASSWORD_ARGON2_PARALLELISM", passwordArgon2Parallelism),
 	}
 }
 
 type Config struct {
 	//DATABASE
-	DB_URI  string
-	DB_NAME string
-	DB_PORT int
+	// DB_DRIVER is postgres or sqlite. Unless DB_URI is set, PostgreSQL is
+	// reached on DB_HOST:DB_PORT and SQLite uses the file DB_NAME.db.
+	DB_DRIVER string
+	DB_URI    string
+	DB_NAME   string
+	DB_HOST   string
+	DB_PORT   int
 
 	//SERVER
 	ENV             string
 	API_URL         string
 	HTTP_PORT       int
 	ALLOWED_ORIGINS string
+	// Comma separated addresses or CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed.
+	TRUSTED_PROXIES string
+
+	//TRASH
+	TRASH_RETENTION_HOURS        int
+	TRASH_PURGE_INTERVAL_MINUTES int
+
+	//SYNC
+	// Deletions are reported to syncing clients for RETENTION_DAYS; clients
+	// that last synced before that must sync again from scratch. Tombstones
+	// are pruned on the trash purge interval.
+	SYNC_TOMBSTONE_RETENTION_DAYS int
+
+	//NOTE EVENTS
+	// Each event stream buffers BUFFER_SIZE events; when a client falls
+	// behind the DROP_POLICY (drop-oldest, drop-newest or disconnect)
+	// applies. The last REPLAY_SIZE events can be replayed to clients
+	// resuming with Last-Event-ID. Idle streams get a heartbeat comment
+	// every HEARTBEAT_SECONDS.
+	EVENTS_BUFFER_SIZE       int
+	EVENTS_REPLAY_SIZE       int
+	EVENTS_DROP_POLICY       string
+	EVENTS_HEARTBEAT_SECONDS int
+
+	//LOGIN THROTTLING
+	// Failed logins are counted per username and per IP address. Past the
+	// free attempts each failure doubles the wait before the next attempt,
+	// from the base up to the max delay; at max failures the key is locked
+	// out. Counters are forgotten after the window without failures.
+	LOGIN_FAILURE_WINDOW_MINUTES int
+	LOGIN_LOCKOUT_MINUTES        int
+	LOGIN_BACKOFF_BASE_SECONDS   int
+	LOGIN_BACKOFF_MAX_SECONDS    int
+	LOGIN_USER_FREE_ATTEMPTS     int
+	LOGIN_USER_MAX_FAILURES      int
+	LOGIN_IP_FREE_ATTEMPTS       int
+	LOGIN_IP_MAX_FAILURES        int
+
+	//PASSWORDS
+	// New passwords need MIN to MAX characters mixing MIN_CLASSES of
+	// lowercase, uppercase, digits and symbols, and must not appear in the
+	// deny-list file (a built-in list of common passwords when unset).
+	// Hashes made with another algorithm or a lower cost are upgraded when
+	// their owner logs in.
+	PASSWORD_MIN_LENGTH         int
+	PASSWORD_MAX_LENGTH         int
+	PASSWORD_MIN_CLASSES        int
+	PASSWORD_DENYLIST_FILE      string
+	PASSWORD_HASH_ALGORITHM     string
+	PASSWORD_BCRYPT_COST        int
+	PASSWORD_ARGON2_MEMORY_KIB  int
+	PASSWORD_ARGON2_ITERATIONS  int
+	PASSWORD_ARGON2_PARALLELISM int
 }
 
 func GetString(key, defaultValue string) string {
@@ -2,9 +2,9 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
-	"notes/internal/models"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -12,6 +12,12 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// The supported database drivers, selected by DB_DRIVER.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
 const (
 	defaultTimeout     = time.Second * 3
 	maxOpenConnections = 10
@@ -28,12 +34,26 @@ var newLogger = logger.New(
 	},
 )
 
-func New(logger *slog.Logger, dsn, dbName string) (*gorm.DB, error) {
+// New connects to the database of the given driver. It leaves the schema
+// alone: apply the migrations of internal/db/migrations first, with
+// cmd/migrate.
+func New(logger *slog.Logger, driver, dsn, dbName string) (*gorm.DB, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
-	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+
+	var dialector gorm.Dialector
+	switch driver {
+	case Postgres:
+		dialector = postgres.Open(dsn)
+	case SQLite:
+		dialector = openSQLite(dsn)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q, use %s or %s", driver, Postgres, SQLite)
+	}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         newLogger,
+		TranslateError: true,
 	})
 	if err != nil {
 		logger.Error("GormDB Open failed", "error: ", err)
@@ -46,22 +66,21 @@ func New(logger *slog.Logger, dsn, dbName string) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	sqlDB.SetConnMaxLifetime(defaultTimeout)
-	sqlDB.SetMaxOpenConns(maxOpenConnections)
-	sqlDB.SetMaxIdleConns(maxIdleConnections)
+	if driver == SQLite {
+		// SQLite allows a single writer; one long-lived connection also
+		// keeps in-memory databases alive.
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetConnMaxLifetime(defaultTimeout)
+		sqlDB.SetMaxOpenConns(maxOpenConnections)
+		sqlDB.SetMaxIdleConns(maxIdleConnections)
+	}
 
 	if err := sqlDB.PingContext(ctx); err != nil {
 		logger.Error("DB Ping Test Failed", "error: ", err)
 		return nil, err
 	}
 
-	logger.Info("Running migrations...")
-	if err := gormDB.AutoMigrate(&models.User{}, &models.Note{}, &models.Category{}); err != nil {
-		logger.Error("AutoMigrate failed", "error", err)
-		return nil, err
-	}
-
-	logger.Info("Migrations completed successfully.")
 	logger.Info("Successfully connected to DB: " + dbName)
 
 	return gormDB, nil
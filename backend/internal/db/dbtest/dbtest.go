@@ -0,0 +1,65 @@
+// Package dbtest opens migrated databases for tests.
+package dbtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notes/internal/db"
+	"notes/internal/db/migrations"
+
+	"gorm.io/gorm"
+)
+
+// tables lists every table of the schema.
+var tables = []string{"users", "notes", "categories", "note_categories", "note_revisions",
+	"sessions", "refresh_tokens", "recovery_codes", "access_tokens", "sync_tombstones", "note_shares", "note_permissions"}
+
+// Postgres opens the disposable database named by NOTES_TEST_DB_URI,
+// migrated up and emptied. The test is skipped when the variable is unset.
+func Postgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("NOTES_TEST_DB_URI")
+	if dsn == "" {
+		t.Skip("NOTES_TEST_DB_URI is not set")
+	}
+	gormDB := open(t, db.Postgres, dsn)
+	if err := gormDB.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+		t.Fatalf("emptying the test database: %v", err)
+	}
+	return gormDB
+}
+
+// SQLite opens a new migrated database in a temporary file.
+func SQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	return open(t, db.SQLite, filepath.Join(t.TempDir(), "notes.db"))
+}
+
+func open(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	gormDB, err := db.New(logger, driver, dsn, "test")
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	all, err := migrations.All(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.New(sqlDB, driver, all, logger).Up(context.Background()); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+	return gormDB
+}
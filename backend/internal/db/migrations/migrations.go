@@ -0,0 +1,320 @@
+// Package migrations applies versioned SQL files to the database. Every
+// driver has its own directory of files, with the same versions. Each version
+// has an up and a down file, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, that run in a transaction. Applied versions are
+// recorded in the schema_migrations table; on PostgreSQL an advisory lock
+// keeps concurrent instances from migrating at once.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes/internal/db"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Drivers lists the drivers with a directory of migrations.
+var Drivers = []string{db.Postgres, db.SQLite}
+
+// lockKey identifies the advisory lock held while migrating.
+const lockKey int64 = 7_352_019_019
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var ErrUnknownVersion = errors.New("applied migration has no file")
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, nil while pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All returns the embedded migrations of the driver in order.
+func All(driver string) ([]Migration, error) {
+	dir, err := fs.Sub(files, driver)
+	if err != nil || !slices.Contains(Drivers, driver) {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+	return Load(dir)
+}
+
+// Load reads the migrations of fsys in order. Every version needs both its
+// up and its down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.(up|down).sql", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[m.Version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is also named %s", entry.Name(), version, m.Name)
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: both the up and the down file are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Create writes an empty up and down file for the next version to the
+// directory of every driver under dir and returns their paths.
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+	var version uint = 1
+	for _, driver := range Drivers {
+		existing, err := Load(os.DirFS(filepath.Join(dir, driver)))
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			version = max(version, existing[len(existing)-1].Version+1)
+		}
+	}
+
+	var paths []string
+	for _, driver := range Drivers {
+		base := filepath.Join(dir, driver, fmt.Sprintf("%04d_%s", version, name))
+		up, down := base+".up.sql", base+".down.sql"
+		if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(down, []byte("-- Revert "+name+"\n"), 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, up, down)
+	}
+	return paths, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+func New(db *sql.DB, driver string, migrations []Migration, logger *slog.Logger) *Migrator {
+	return &Migrator{db: db, driver: driver, migrations: migrations, logger: logger}
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	ran := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			m.logger.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+			err := inTx(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, time.Now())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran++
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	ran := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]uint, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("version %d: %w", version, ErrUnknownVersion)
+			}
+			m.logger.Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+			err := inTx(ctx, conn, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran++
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Status lists every known migration with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if at, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		for version := range applied {
+			if _, ok := m.find(version); !ok {
+				return fmt.Errorf("version %d: %w", version, ErrUnknownVersion)
+			}
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) find(version uint) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked runs fn on a dedicated connection holding the migration lock, after
+// making sure the schema_migrations table exists. Session-level advisory
+// locks belong to a connection, hence the dedicated one. SQLite has no such
+// lock, but it serializes writers and a version can only be recorded once.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timeType := "datetime"
+	if m.driver == db.Postgres {
+		timeType = "timestamptz"
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+			return err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+				m.logger.Error("Releasing the migration lock failed", "error", err)
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at `+timeType+` NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[uint]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[uint]time.Time{}
+	for rows.Next() {
+		var version uint
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// inTx runs the migration script and the bookkeeping statement in one
+// transaction.
+func inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
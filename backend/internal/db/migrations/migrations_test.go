@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	var want []Migration
+	for _, driver := range Drivers {
+		all, err := All(driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range all {
+			if m.Version != uint(i+1) {
+				t.Errorf("%s migration %d has version %d, versions must have no gaps", driver, i, m.Version)
+			}
+		}
+		if want == nil {
+			want = all
+			continue
+		}
+		if len(all) != len(want) {
+			t.Fatalf("%s has %d migrations, %s has %d", driver, len(all), Drivers[0], len(want))
+		}
+		for i := range all {
+			if all[i].Name != want[i].Name {
+				t.Errorf("%s migration %d is %s, %s has %s", driver, all[i].Version, all[i].Name, Drivers[0], want[i].Name)
+			}
+		}
+	}
+	if _, err := All("oracle"); err == nil {
+		t.Error("got migrations for an unknown driver")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_tags.up.sql":   {Data: []byte("CREATE TABLE tags ();")},
+		"0002_add_tags.down.sql": {Data: []byte("DROP TABLE tags;")},
+		"0001_initial.up.sql":    {Data: []byte("CREATE TABLE users ();")},
+		"0001_initial.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"migrations.go":          {Data: []byte("package migrations")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "initial" || migrations[1].Version != 2 {
+		t.Fatalf("got %+v, want initial then add_tags", migrations)
+	}
+	if migrations[1].Up != "CREATE TABLE tags ();" || migrations[1].Down != "DROP TABLE tags;" {
+		t.Errorf("add_tags scripts are %q and %q", migrations[1].Up, migrations[1].Down)
+	}
+}
+
+func TestLoadRejectsInvalidSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"initial.up.sql":   {Data: []byte("SELECT 1;")},
+			"initial.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"version zero": {
+			"0000_initial.up.sql":   {Data: []byte("SELECT 1;")},
+			"0000_initial.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"version reused": {
+			"0001_initial.up.sql":   {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql":   {Data: []byte("SELECT 1;")},
+			"0001_other.up.sql":     {Data: []byte("SELECT 1;")},
+			"0001_initial.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(fsys); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, driver := range Drivers {
+		if err := os.Mkdir(filepath.Join(dir, driver), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"0001_initial.up.sql", "0001_initial.down.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, Drivers[0], name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := Create(dir, "Add note Tags!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2*len(Drivers) {
+		t.Fatalf("created %v, want an up and a down file per driver", paths)
+	}
+	for _, driver := range Drivers {
+		migrations, err := Load(os.DirFS(filepath.Join(dir, driver)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := migrations[len(migrations)-1]
+		if last.Version != 2 || last.Name != "add_note_tags" || !strings.Contains(last.Up, "add_note_tags") {
+			t.Errorf("%s: got %+v after create, want version 2 add_note_tags", driver, last)
+		}
+	}
+
+	if _, err := Create(dir, "!!"); err == nil {
+		t.Error("created a migration without a name")
+	}
+}
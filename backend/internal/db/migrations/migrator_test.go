@@ -0,0 +1,243 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"notes/internal/db"
+	"notes/internal/db/migrations"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// openSQLite opens an empty database in a temporary file.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	return open(t, db.SQLite, filepath.Join(t.TempDir(), "notes.db"))
+}
+
+// openPostgres opens the database named by NOTES_TEST_DB_URI in a schema of
+// its own, dropped after the test, so that migrating up and down leaves the
+// tables other tests use alone. The test is skipped when the variable is
+// unset.
+func openPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("NOTES_TEST_DB_URI")
+	if dsn == "" {
+		t.Skip("NOTES_TEST_DB_URI is not set")
+	}
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+	admin := open(t, db.Postgres, dsn)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	if strings.Contains(dsn, "://") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&search_path=" + schema
+		} else {
+			dsn += "?search_path=" + schema
+		}
+	} else {
+		dsn += " search_path=" + schema
+	}
+	return open(t, db.Postgres, dsn)
+}
+
+func open(t *testing.T, driver, dsn string) *sql.DB {
+	t.Helper()
+	gormDB, err := db.New(logger, driver, dsn, "test")
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func newMigrator(t *testing.T, sqlDB *sql.DB, driver string) (*migrations.Migrator, []migrations.Migration) {
+	t.Helper()
+	all, err := migrations.All(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations.New(sqlDB, driver, all, logger), all
+}
+
+// applied returns how many migrations Status reports as applied.
+func applied(t *testing.T, m *migrations.Migrator, total int) int {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != total {
+		t.Fatalf("Status lists %d migrations, want %d", len(statuses), total)
+	}
+	n := 0
+	for i, status := range statuses {
+		if status.AppliedAt == nil {
+			continue
+		}
+		if i != n {
+			t.Fatalf("migration %04d_%s is applied after a pending one", status.Version, status.Name)
+		}
+		n++
+	}
+	return n
+}
+
+// hasTables reports whether every table of the schema can be queried.
+func hasTables(sqlDB *sql.DB) bool {
+	for _, table := range []string{"users", "notes", "categories", "note_categories", "note_revisions",
+		"sessions", "refresh_tokens", "recovery_codes", "access_tokens", "sync_tombstones", "note_shares", "note_permissions"} {
+		if _, err := sqlDB.Exec("SELECT count(*) FROM " + table); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func testRoundTrip(t *testing.T, sqlDB *sql.DB, driver string) {
+	ctx := context.Background()
+	m, all := newMigrator(t, sqlDB, driver)
+
+	if n := applied(t, m, len(all)); n != 0 {
+		t.Fatalf("%d migrations applied to an empty database", n)
+	}
+	if ran, err := m.Up(ctx); err != nil || ran != len(all) {
+		t.Fatalf("Up = %d, %v, want %d", ran, err, len(all))
+	}
+	if n := applied(t, m, len(all)); n != len(all) || !hasTables(sqlDB) {
+		t.Fatalf("after Up: %d of %d applied, tables present %v", n, len(all), hasTables(sqlDB))
+	}
+	if ran, err := m.Up(ctx); err != nil || ran != 0 {
+		t.Fatalf("second Up = %d, %v, want nothing to run", ran, err)
+	}
+
+	if ran, err := m.Down(ctx, 1); err != nil || ran != 1 {
+		t.Fatalf("Down(1) = %d, %v, want 1", ran, err)
+	}
+	if n := applied(t, m, len(all)); n != len(all)-1 {
+		t.Fatalf("after Down(1): %d applied, want %d", n, len(all)-1)
+	}
+	if ran, err := m.Down(ctx, len(all)+1); err != nil || ran != len(all)-1 {
+		t.Fatalf("Down(all) = %d, %v, want %d", ran, err, len(all)-1)
+	}
+	if n := applied(t, m, len(all)); n != 0 {
+		t.Fatalf("after Down(all): %d applied, want none", n)
+	}
+	if _, err := sqlDB.Exec("SELECT count(*) FROM users"); err == nil {
+		t.Fatal("users still exists after reverting every migration")
+	}
+
+	if ran, err := m.Up(ctx); err != nil || ran != len(all) {
+		t.Fatalf("Up after Down = %d, %v, want %d", ran, err, len(all))
+	}
+	if !hasTables(sqlDB) {
+		t.Fatal("tables missing after migrating up again")
+	}
+}
+
+func TestRoundTripSQLite(t *testing.T) {
+	testRoundTrip(t, openSQLite(t), db.SQLite)
+}
+
+func TestRoundTripPostgres(t *testing.T) {
+	testRoundTrip(t, openPostgres(t), db.Postgres)
+}
+
+func TestStatusRejectsUnknownVersions(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openSQLite(t)
+	m, all := newMigrator(t, sqlDB, db.SQLite)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	older := migrations.New(sqlDB, db.SQLite, all[:len(all)-1], logger)
+	if _, err := older.Status(ctx); err == nil {
+		t.Error("Status accepted a database migrated by a newer release")
+	}
+}
+
+// TestAdoptBaselinePostgres migrates a database created by AutoMigrate in the
+// first release.
+func TestAdoptBaselinePostgres(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := openPostgres(t)
+	baseline, err := os.ReadFile(filepath.Join("testdata", "baseline_postgres.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(string(baseline)); err != nil {
+		t.Fatalf("creating the baseline schema: %v", err)
+	}
+
+	m, all := newMigrator(t, sqlDB, db.Postgres)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up from the baseline: %v", err)
+	}
+	if n := applied(t, m, len(all)); n != len(all) || !hasTables(sqlDB) {
+		t.Fatalf("after Up: %d of %d applied, tables present %v", n, len(all), hasTables(sqlDB))
+	}
+
+	var notes, versioned int
+	if err := sqlDB.QueryRow(`SELECT count(*), count(*) FILTER (WHERE version = 1 AND deleted_at IS NULL) FROM notes`).
+		Scan(&notes, &versioned); err != nil || notes != 2 || versioned != 2 {
+		t.Errorf("notes after adoption: %d, %d at version 1 outside the trash, %v", notes, versioned, err)
+	}
+	var users int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM users WHERE NOT is_disabled AND NOT totp_enabled`).
+		Scan(&users); err != nil || users != 2 {
+		t.Errorf("users after adoption: %d enabled without two-factor authentication, %v", users, err)
+	}
+
+	// Each user gets their own "work"; the unreferenced category goes.
+	rows, err := sqlDB.Query(`SELECT n.user_id, c.user_id, c.name FROM note_categories nc
+		JOIN notes n ON n.id = nc.note_id JOIN categories c ON c.id = nc.category_id ORDER BY n.user_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var links []string
+	for rows.Next() {
+		var noteUser, categoryUser int
+		var name string
+		if err := rows.Scan(&noteUser, &categoryUser, &name); err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, fmt.Sprintf("%d:%d:%s", noteUser, categoryUser, name))
+	}
+	if got := strings.Join(links, " "); got != "1:1:work 2:2:work" {
+		t.Errorf("note categories after adoption %q, want each note on its owner's work", got)
+	}
+	var categories int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM categories`).Scan(&categories); err != nil || categories != 2 {
+		t.Errorf("%d categories after adoption, want one work per user: %v", categories, err)
+	}
+
+	// Titles are only unique per user now.
+	if _, err := sqlDB.Exec(`INSERT INTO notes (title, user_id) VALUES ('standup', 2)`); err != nil {
+		t.Errorf("another user's note with the same title: %v", err)
+	}
+
+	if _, err := m.Down(ctx, len(all)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if ran, err := m.Up(ctx); err != nil || ran != len(all) {
+		t.Fatalf("Up after Down = %d, %v, want %d", ran, err, len(all))
+	}
+}
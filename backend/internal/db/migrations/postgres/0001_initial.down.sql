@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS access_tokens;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS note_revisions;
+DROP TABLE IF EXISTS note_categories;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,175 @@
+-- The schema as AutoMigrate last left it. Every statement is idempotent so
+-- that databases created by earlier releases are adopted as they are: their
+-- tables are kept, and the columns added since the first release are added to
+-- them before anything indexes those columns.
+
+-- Categories used to be a global table with a unique name. Give every user
+-- their own copy of the categories their notes reference, re-point the notes
+-- to those copies and drop the unowned originals.
+DO $$
+BEGIN
+	IF to_regclass('categories') IS NULL THEN
+		RETURN;
+	END IF;
+	ALTER TABLE categories ADD COLUMN IF NOT EXISTS user_id bigint;
+	ALTER TABLE categories DROP CONSTRAINT IF EXISTS uni_categories_name;
+	DROP INDEX IF EXISTS idx_categories_name;
+	INSERT INTO categories (name, user_id, created_at)
+		SELECT DISTINCT c.name, n.user_id, c.created_at
+		FROM categories c
+		JOIN note_categories nc ON nc.category_id = c.id
+		JOIN notes n ON n.id = nc.note_id
+		WHERE c.user_id IS NULL;
+	UPDATE note_categories nc SET category_id = owned.id
+		FROM notes n, categories legacy, categories owned
+		WHERE nc.note_id = n.id
+			AND nc.category_id = legacy.id
+			AND legacy.user_id IS NULL
+			AND owned.user_id = n.user_id
+			AND owned.name = legacy.name;
+	DELETE FROM categories WHERE user_id IS NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS users (
+	id bigserial,
+	user_name varchar(30) NOT NULL,
+	password text NOT NULL,
+	is_admin boolean DEFAULT false,
+	is_disabled boolean DEFAULT false,
+	password_reset_hash varchar(64),
+	password_reset_expires_at timestamptz,
+	totp_secret varchar(64),
+	totp_enabled boolean DEFAULT false,
+	totp_last_step bigint DEFAULT 0,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT uni_users_user_name UNIQUE (user_name)
+);
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS is_disabled boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS password_reset_hash varchar(64),
+	ADD COLUMN IF NOT EXISTS password_reset_expires_at timestamptz,
+	ADD COLUMN IF NOT EXISTS totp_secret varchar(64),
+	ADD COLUMN IF NOT EXISTS totp_enabled boolean DEFAULT false,
+	ADD COLUMN IF NOT EXISTS totp_last_step bigint DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS notes (
+	id bigserial,
+	title varchar(50) NOT NULL,
+	content text,
+	user_id bigint NOT NULL,
+	is_archived boolean DEFAULT false,
+	version bigint NOT NULL DEFAULT 1,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_notes FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+ALTER TABLE notes
+	ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+-- Titles are unique per user among notes outside the trash. Drop the legacy
+-- global constraint, whichever name the gorm version gave it, and the index
+-- that also covered the trash.
+ALTER TABLE notes DROP CONSTRAINT IF EXISTS uni_notes_title;
+DROP INDEX IF EXISTS idx_notes_title;
+DROP INDEX IF EXISTS idx_notes_user_title;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notes_user_title_live ON notes (user_id, title) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes (deleted_at);
+-- Full-text search: title weighs more than content.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(content, '')), 'B')
+	) STORED;
+CREATE INDEX IF NOT EXISTS idx_notes_search_vector ON notes USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id bigserial,
+	name varchar(30) NOT NULL,
+	user_id bigint NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_categories FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- Backfilled categories.user_id starts out nullable.
+ALTER TABLE categories ALTER COLUMN user_id SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_user_name ON categories (user_id, name);
+
+CREATE TABLE IF NOT EXISTS note_categories (
+	note_id bigint,
+	category_id bigint,
+	PRIMARY KEY (note_id, category_id),
+	CONSTRAINT fk_note_categories_note FOREIGN KEY (note_id) REFERENCES notes(id),
+	CONSTRAINT fk_note_categories_category FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE TABLE IF NOT EXISTS note_revisions (
+	id bigserial,
+	note_id bigint NOT NULL,
+	revision bigint NOT NULL,
+	title varchar(50) NOT NULL,
+	content text,
+	categories text,
+	is_archived boolean DEFAULT false,
+	actor_id bigint NOT NULL,
+	created_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_notes_revisions FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_revisions_note_revision ON note_revisions (note_id, revision);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id varchar(32),
+	user_id bigint NOT NULL,
+	user_agent varchar(255),
+	created_at timestamptz,
+	last_used_at timestamptz,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_sessions FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id bigserial,
+	session_id varchar(32) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	created_at timestamptz,
+	used_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_sessions_refresh_tokens FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id bigserial,
+	user_id bigint NOT NULL,
+	code_hash varchar(64) NOT NULL,
+	created_at timestamptz,
+	used_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_recovery_codes FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+	id bigserial,
+	user_id bigint NOT NULL,
+	name varchar(50) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	scopes varchar(255) NOT NULL,
+	created_at timestamptz,
+	expires_at timestamptz NOT NULL,
+	last_used_at timestamptz,
+	revoked_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_access_tokens FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_access_tokens_token_hash ON access_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_access_tokens_user_id ON access_tokens (user_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sync_tombstones;
+DROP INDEX IF EXISTS idx_categories_user_change_seq;
+DROP INDEX IF EXISTS idx_notes_user_change_seq;
+ALTER TABLE categories DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE notes DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE users DROP COLUMN IF EXISTS sync_pruned_seq, DROP COLUMN IF EXISTS change_seq;
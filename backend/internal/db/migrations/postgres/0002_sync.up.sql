@@ -0,0 +1,47 @@
+-- Delta sync. Every change to a user's notes and categories takes the next
+-- number of the user's change sequence, users.change_seq, and stores it on the
+-- changed row. Incrementing the counter locks the user's row until the
+-- transaction ends, so changes commit in the order of their numbers and a
+-- client that has seen number n never misses a smaller one committed later.
+ALTER TABLE users
+	ADD COLUMN change_seq bigint NOT NULL DEFAULT 0,
+	-- The largest number of the user's tombstones pruned so far. Clients that
+	-- last synced before it may have missed deletions and must sync afresh.
+	ADD COLUMN sync_pruned_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN change_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN change_seq bigint NOT NULL DEFAULT 0;
+
+-- Number the existing rows of every user, notes before categories.
+UPDATE notes SET change_seq = (
+	SELECT count(*) FROM notes older
+	WHERE older.user_id = notes.user_id AND older.id <= notes.id
+);
+UPDATE categories SET change_seq = (
+	SELECT count(*) FROM notes WHERE notes.user_id = categories.user_id
+) + (
+	SELECT count(*) FROM categories older
+	WHERE older.user_id = categories.user_id AND older.id <= categories.id
+);
+UPDATE users SET change_seq = (
+	SELECT count(*) FROM notes WHERE notes.user_id = users.id
+) + (
+	SELECT count(*) FROM categories WHERE categories.user_id = users.id
+);
+
+CREATE INDEX idx_notes_user_change_seq ON notes (user_id, change_seq);
+CREATE INDEX idx_categories_user_change_seq ON categories (user_id, change_seq);
+
+-- Notes purged from the trash and deleted categories, kept for
+-- SYNC_TOMBSTONE_RETENTION_DAYS so that clients learn about the deletion.
+CREATE TABLE sync_tombstones (
+	id bigserial,
+	user_id bigint NOT NULL,
+	kind varchar(10) NOT NULL,
+	entity_id bigint NOT NULL,
+	change_seq bigint NOT NULL,
+	deleted_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_users_sync_tombstones FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sync_tombstones_user_change_seq ON sync_tombstones (user_id, change_seq);
+CREATE INDEX idx_sync_tombstones_deleted_at ON sync_tombstones (deleted_at);
@@ -0,0 +1,20 @@
+-- Read-only links to a note. Only the SHA-256 hash of the token is stored;
+-- password_hash is empty when the link needs no password.
+CREATE TABLE note_shares (
+	id bigserial,
+	note_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	password_hash text NOT NULL DEFAULT '',
+	created_at timestamptz,
+	expires_at timestamptz,
+	revoked_at timestamptz,
+	views bigint NOT NULL DEFAULT 0,
+	last_viewed_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_notes_shares FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_users_note_shares FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_note_shares_token_hash ON note_shares (token_hash);
+CREATE INDEX idx_note_shares_note_id ON note_shares (note_id);
+CREATE INDEX idx_note_shares_user_id ON note_shares (user_id);
@@ -0,0 +1,14 @@
+-- Access to a note granted by its owner to another user, as a viewer or an
+-- editor. The owner's own access is implied and never stored.
+CREATE TABLE note_permissions (
+	id bigserial,
+	note_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	role varchar(10) NOT NULL,
+	created_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_notes_permissions FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_users_note_permissions FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_note_permissions_note_user ON note_permissions (note_id, user_id);
+CREATE INDEX idx_note_permissions_user_id ON note_permissions (user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notes_user_position;
+ALTER TABLE notes DROP COLUMN IF EXISTS position, DROP COLUMN IF EXISTS pinned;
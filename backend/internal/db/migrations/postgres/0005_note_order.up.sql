@@ -0,0 +1,15 @@
+-- Pinning and manual ordering. Notes are listed pinned first, then by
+-- ascending position. Moving a note puts it halfway between its new
+-- neighbours, so positions are fractional and only rewritten when two of
+-- them get too close to split. Existing notes keep their newest-first order.
+ALTER TABLE notes
+	ADD COLUMN pinned boolean NOT NULL DEFAULT false,
+	ADD COLUMN position double precision NOT NULL DEFAULT 0;
+
+UPDATE notes SET position = 1024 * (
+	SELECT count(*) FROM notes newer
+	WHERE newer.user_id = notes.user_id
+		AND (newer.created_at, newer.id) > (notes.created_at, notes.id)
+);
+
+CREATE INDEX idx_notes_user_position ON notes (user_id, pinned, position);
@@ -0,0 +1,115 @@
+-- The schema of the PostgreSQL migration, in SQLite types. Times are stored
+-- as UTC text in datetime columns. Full-text search needs no index: notes
+-- are ranked by the application.
+
+CREATE TABLE users (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_name varchar(30) NOT NULL,
+	password text NOT NULL,
+	is_admin boolean DEFAULT false,
+	is_disabled boolean DEFAULT false,
+	password_reset_hash varchar(64),
+	password_reset_expires_at datetime,
+	totp_secret varchar(64),
+	totp_enabled boolean DEFAULT false,
+	totp_last_step bigint DEFAULT 0,
+	created_at datetime,
+	updated_at datetime,
+	CONSTRAINT uni_users_user_name UNIQUE (user_name)
+);
+
+CREATE TABLE notes (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	title varchar(50) NOT NULL,
+	content text,
+	user_id bigint NOT NULL,
+	is_archived boolean DEFAULT false,
+	version bigint NOT NULL DEFAULT 1,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	CONSTRAINT fk_users_notes FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- Titles are unique per user among notes outside the trash.
+CREATE UNIQUE INDEX idx_notes_user_title_live ON notes (user_id, title) WHERE deleted_at IS NULL;
+CREATE INDEX idx_notes_deleted_at ON notes (deleted_at);
+
+CREATE TABLE categories (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name varchar(30) NOT NULL,
+	user_id bigint NOT NULL,
+	created_at datetime,
+	updated_at datetime,
+	CONSTRAINT fk_users_categories FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_categories_user_name ON categories (user_id, name);
+
+CREATE TABLE note_categories (
+	note_id bigint,
+	category_id bigint,
+	PRIMARY KEY (note_id, category_id),
+	CONSTRAINT fk_note_categories_note FOREIGN KEY (note_id) REFERENCES notes(id),
+	CONSTRAINT fk_note_categories_category FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+CREATE TABLE note_revisions (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	note_id bigint NOT NULL,
+	revision bigint NOT NULL,
+	title varchar(50) NOT NULL,
+	content text,
+	categories text,
+	is_archived boolean DEFAULT false,
+	actor_id bigint NOT NULL,
+	created_at datetime,
+	CONSTRAINT fk_notes_revisions FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_note_revisions_note_revision ON note_revisions (note_id, revision);
+
+CREATE TABLE sessions (
+	id varchar(32) PRIMARY KEY,
+	user_id bigint NOT NULL,
+	user_agent varchar(255),
+	created_at datetime,
+	last_used_at datetime,
+	expires_at datetime NOT NULL,
+	revoked_at datetime,
+	CONSTRAINT fk_users_sessions FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE refresh_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	session_id varchar(32) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	created_at datetime,
+	used_at datetime,
+	CONSTRAINT fk_sessions_refresh_tokens FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+CREATE TABLE recovery_codes (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id bigint NOT NULL,
+	code_hash varchar(64) NOT NULL,
+	created_at datetime,
+	used_at datetime,
+	CONSTRAINT fk_users_recovery_codes FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE access_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id bigint NOT NULL,
+	name varchar(50) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	scopes varchar(255) NOT NULL,
+	created_at datetime,
+	expires_at datetime NOT NULL,
+	last_used_at datetime,
+	revoked_at datetime,
+	CONSTRAINT fk_users_access_tokens FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_access_tokens_token_hash ON access_tokens (token_hash);
+CREATE INDEX idx_access_tokens_user_id ON access_tokens (user_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS sync_tombstones;
+DROP INDEX IF EXISTS idx_categories_user_change_seq;
+DROP INDEX IF EXISTS idx_notes_user_change_seq;
+ALTER TABLE categories DROP COLUMN change_seq;
+ALTER TABLE notes DROP COLUMN change_seq;
+ALTER TABLE users DROP COLUMN sync_pruned_seq;
+ALTER TABLE users DROP COLUMN change_seq;
@@ -0,0 +1,37 @@
+-- Delta sync, as in the PostgreSQL migration. SQLite runs one writer at a
+-- time, which keeps the change numbers in commit order by itself.
+ALTER TABLE users ADD COLUMN change_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN sync_pruned_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN change_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN change_seq bigint NOT NULL DEFAULT 0;
+
+UPDATE notes SET change_seq = (
+	SELECT count(*) FROM notes older
+	WHERE older.user_id = notes.user_id AND older.id <= notes.id
+);
+UPDATE categories SET change_seq = (
+	SELECT count(*) FROM notes WHERE notes.user_id = categories.user_id
+) + (
+	SELECT count(*) FROM categories older
+	WHERE older.user_id = categories.user_id AND older.id <= categories.id
+);
+UPDATE users SET change_seq = (
+	SELECT count(*) FROM notes WHERE notes.user_id = users.id
+) + (
+	SELECT count(*) FROM categories WHERE categories.user_id = users.id
+);
+
+CREATE INDEX idx_notes_user_change_seq ON notes (user_id, change_seq);
+CREATE INDEX idx_categories_user_change_seq ON categories (user_id, change_seq);
+
+CREATE TABLE sync_tombstones (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id bigint NOT NULL,
+	kind varchar(10) NOT NULL,
+	entity_id bigint NOT NULL,
+	change_seq bigint NOT NULL,
+	deleted_at datetime NOT NULL,
+	CONSTRAINT fk_users_sync_tombstones FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sync_tombstones_user_change_seq ON sync_tombstones (user_id, change_seq);
+CREATE INDEX idx_sync_tombstones_deleted_at ON sync_tombstones (deleted_at);
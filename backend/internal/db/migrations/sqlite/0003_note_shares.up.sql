@@ -0,0 +1,18 @@
+-- Read-only links to a note, as in the PostgreSQL migration.
+CREATE TABLE note_shares (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	note_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	password_hash text NOT NULL DEFAULT '',
+	created_at datetime,
+	expires_at datetime,
+	revoked_at datetime,
+	views bigint NOT NULL DEFAULT 0,
+	last_viewed_at datetime,
+	CONSTRAINT fk_notes_shares FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_users_note_shares FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_note_shares_token_hash ON note_shares (token_hash);
+CREATE INDEX idx_note_shares_note_id ON note_shares (note_id);
+CREATE INDEX idx_note_shares_user_id ON note_shares (user_id);
@@ -0,0 +1,12 @@
+-- Access to a note granted to another user, as in the PostgreSQL migration.
+CREATE TABLE note_permissions (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	note_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	role varchar(10) NOT NULL,
+	created_at datetime,
+	CONSTRAINT fk_notes_permissions FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_users_note_permissions FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_note_permissions_note_user ON note_permissions (note_id, user_id);
+CREATE INDEX idx_note_permissions_user_id ON note_permissions (user_id);
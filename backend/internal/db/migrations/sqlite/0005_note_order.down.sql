@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_user_position;
+ALTER TABLE notes DROP COLUMN position;
+ALTER TABLE notes DROP COLUMN pinned;
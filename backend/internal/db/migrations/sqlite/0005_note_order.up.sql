@@ -0,0 +1,11 @@
+-- Pinning and manual ordering, as in the PostgreSQL migration.
+ALTER TABLE notes ADD COLUMN pinned boolean NOT NULL DEFAULT false;
+ALTER TABLE notes ADD COLUMN position real NOT NULL DEFAULT 0;
+
+UPDATE notes SET position = 1024 * (
+	SELECT count(*) FROM notes newer
+	WHERE newer.user_id = notes.user_id
+		AND (newer.created_at, newer.id) > (notes.created_at, notes.id)
+);
+
+CREATE INDEX idx_notes_user_position ON notes (user_id, pinned, position);
@@ -0,0 +1,53 @@
+-- The schema AutoMigrate created for the first release, before any of the
+-- migrations existed, with a few rows. Both users filed a note under "work",
+-- which was then a single global category.
+
+CREATE TABLE users (
+	id bigserial,
+	user_name varchar(30) NOT NULL,
+	password text NOT NULL,
+	is_admin boolean DEFAULT false,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT uni_users_user_name UNIQUE (user_name)
+);
+
+CREATE TABLE notes (
+	id bigserial,
+	title varchar(50) NOT NULL,
+	content text,
+	user_id bigint NOT NULL,
+	is_archived boolean DEFAULT false,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT uni_notes_title UNIQUE (title),
+	CONSTRAINT fk_users_notes FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE categories (
+	id bigserial,
+	name varchar(30) NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT uni_categories_name UNIQUE (name)
+);
+
+CREATE TABLE note_categories (
+	note_id bigint,
+	category_id bigint,
+	PRIMARY KEY (note_id, category_id),
+	CONSTRAINT fk_note_categories_note FOREIGN KEY (note_id) REFERENCES notes(id),
+	CONSTRAINT fk_note_categories_category FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+INSERT INTO users (user_name, password, created_at) VALUES
+	('alice', '$2a$10$abcdefghijklmnopqrstuv', now()),
+	('bob', '$2a$10$abcdefghijklmnopqrstuv', now());
+INSERT INTO notes (title, content, user_id, created_at) VALUES
+	('standup', 'monday notes', 1, now()),
+	('review', 'pull requests', 2, now());
+INSERT INTO categories (name, created_at) VALUES ('work', now()), ('unused', now());
+INSERT INTO note_categories (note_id, category_id) VALUES (1, 1), (2, 1);
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const sqliteDriverName = "sqlite3_utc"
+
+func init() {
+	sql.Register(sqliteDriverName, utcDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// sqliteOptions are added to SQLite DSNs that do not set them: foreign keys,
+// which SQLite leaves off, cascade deletes, and writers wait for the lock
+// instead of failing at once.
+var sqliteOptions = []string{"_foreign_keys=1", "_busy_timeout=5000"}
+
+func openSQLite(dsn string) gorm.Dialector {
+	for _, option := range sqliteOptions {
+		name, _, _ := strings.Cut(option, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + option
+		} else {
+			dsn += "?" + option
+		}
+	}
+	return sqlite.New(sqlite.Config{DriverName: sqliteDriverName, DSN: dsn})
+}
+
+// utcDriver stores every time in UTC. SQLite keeps times as text, so times
+// written in different zones would neither compare nor sort correctly.
+type utcDriver struct {
+	*sqlite3.SQLiteDriver
+}
+
+func (d utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC()
+	}
+	nv.Value = value
+	return nil
+}
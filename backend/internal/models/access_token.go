@@ -0,0 +1,48 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"strings"
+	"time"
+)
+
+// Scopes a personal access token can be granted. Browser sessions are not
+// scoped and may call every route.
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+)
+
+// AccessTokenScopes lists every valid scope.
+var AccessTokenScopes = []string{ScopeNotesRead, ScopeNotesWrite}
+
+// AccessToken is a personal access token for scripts and CLI clients, sent as
+// "Authorization: Bearer <token>". Only the SHA-256 hash of the token is
+// stored; the token itself is shown once, when it is created.
+type AccessToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"not null;size:50" json:"name"`
+	TokenHash  string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"not null;size:255" json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+func NewAccessToken(userId uint, name, tokenHash string, scopes []string, expiresAt time.Time) *AccessToken {
+	return &AccessToken{
+		UserID:    userId,
+		Name:      name,
+		TokenHash: tokenHash,
+		Scopes:    strings.Join(scopes, " "),
+		CreatedAt: *date.ArgentinaTimeNow(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// ScopeList returns the scopes granted to the token.
+func (t *AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// AccountExport is everything stored about a user, as handed to them by the
+// personal data export.
+type AccountExport struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Profile    AccountProfile     `json:"profile"`
+	Notes      []Note             `json:"notes"`
+	Categories []Category         `json:"categories"`
+	Revisions  []RevisionMetadata `json:"revisions"`
+}
+
+// AccountProfile is the user record without credentials.
+type AccountProfile struct {
+	ID               uint       `json:"id"`
+	UserName         string     `json:"user_name"`
+	IsAdmin          bool       `json:"is_admin"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// RevisionMetadata describes a note revision without its content, which
+// the notes themselves already carry in their current version.
+type RevisionMetadata struct {
+	NoteID     uint      `json:"note_id"`
+	Revision   uint      `json:"revision"`
+	Title      string    `json:"title"`
+	Categories []string  `json:"categories" gorm:"serializer:json"`
+	IsArchived bool      `json:"is_archived"`
+	ActorID    uint      `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -5,18 +5,23 @@ import (
 	"time"
 )
 
-// Category represents a note category
+// Category represents a note category owned by a user
 // @swagger:model
 type Category struct {
 	ID        uint       `gorm:"primaryKey" json:"id,omitempty"`
-	Name      string     `gorm:"not null;unique;size:30" json:"name"`
+	Name      string     `gorm:"not null;size:30;uniqueIndex:idx_categories_user_name,priority:2" json:"name"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_categories_user_name,priority:1" json:"user_id,omitempty"`
 	CreatedAt time.Time  `gorm:"created_at" json:"created_at,omitempty"`
 	UpdatedAt *time.Time `gorm:"updated_at" json:"updated_at,omitempty"`
+	// ChangeSeq is the number the owner's change sequence gave the last
+	// change of the category.
+	ChangeSeq uint64 `gorm:"not null;default:0" json:"-"`
 }
 
-func NewCategory(name string) *Category {
+func NewCategory(name string, userId uint) *Category {
 	return &Category{
 		Name:      name,
+		UserID:    userId,
 		CreatedAt: *date.ArgentinaTimeNow(),
 		UpdatedAt: nil,
 	}
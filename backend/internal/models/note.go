@@ -2,20 +2,40 @@ package models
 
 import (
 	"notes/pkg/date"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Note represents a user's note
 // @swagger:model
 type Note struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
-	Title      string     `gorm:"not null;unique;size:50" json:"title"`
+	Title      string     `gorm:"not null;size:50;uniqueIndex:idx_notes_user_title_live,priority:2,where:deleted_at IS NULL" json:"title"`
 	Content    string     `gorm:"type:text;size:70" json:"content"`
 	Categories []Category `gorm:"many2many:note_categories;" json:"categories"`
-	UserID     uint       `gorm:"not null;foreignKey:UserID" json:"user_id"`
+	UserID     uint       `gorm:"not null;foreignKey:UserID;uniqueIndex:idx_notes_user_title_live,priority:1,where:deleted_at IS NULL" json:"user_id"`
 	IsArchived bool       `gorm:"default:false" json:"is_archived"`
-	CreatedAt  time.Time  `gorm:"created_at" json:"created_at,omitempty"`
-	UpdatedAt  *time.Time `gorm:"updated_at" json:"updated_at,omitempty"`
+	// Pinned notes are listed before the others.
+	Pinned bool `gorm:"not null;default:false" json:"pinned"`
+	// Position orders the owner's notes by hand, lowest first. A moved note
+	// is put halfway between its new neighbours, so positions are fractional.
+	Position float64 `gorm:"not null;default:0" json:"position"`
+	// Version increases with every saved change and is exposed as the ETag
+	// clients must send back in If-Match to update the note.
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// ChangeSeq is the number the owner's change sequence gave the last
+	// change of the note, trashing and restoring included.
+	ChangeSeq uint64     `gorm:"not null;default:0" json:"-"`
+	CreatedAt time.Time  `gorm:"created_at" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `gorm:"updated_at" json:"updated_at,omitempty"`
+	// DeletedAt is set while the note sits in the trash; gorm skips such rows
+	// in every query unless Unscoped is used.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" format:"date-time"`
+
+	Revisions []NoteRevision `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
 }
 
 func NewNote(title, content string, categories []Category, userId uint) *Note {
@@ -25,7 +45,54 @@ func NewNote(title, content string, categories []Category, userId uint) *Note {
 		Categories: categories,
 		UserID:     userId,
 		IsArchived: false,
+		Version:    1,
 		CreatedAt:  *date.ArgentinaTimeNow(),
 		UpdatedAt:  nil,
 	}
 }
+
+// PositionCursor writes where the note is in manual order, pinned or not and
+// its position, as the value of a page cursor.
+func (n *Note) PositionCursor() string {
+	return strconv.FormatBool(n.Pinned) + ":" + strconv.FormatFloat(n.Position, 'g', -1, 64)
+}
+
+// ParsePositionCursor reads the value written by PositionCursor.
+func ParsePositionCursor(value string) (bool, float64, error) {
+	pinned, position, _ := strings.Cut(value, ":")
+	p, err := strconv.ParseBool(pinned)
+	if err != nil {
+		return false, 0, err
+	}
+	f, err := strconv.ParseFloat(position, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return p, f, nil
+}
+
+// NotePatch holds the fields of a partial note update. Nil fields are left
+// untouched; a non-nil Categories replaces the whole category list.
+type NotePatch struct {
+	Title      *string
+	Content    *string
+	Categories []Category
+	IsArchived *bool
+}
+
+// NoteSearchResult is a note matched by full-text search, along with its
+// relevance rank and the highlighted title and content snippets.
+type NoteSearchResult struct {
+	Note           Note
+	Rank           float64
+	TitleSnippet   string
+	ContentSnippet string
+}
+
+// NotePage is one page of a note listing in the given sort order.
+// NextCursor is empty on the last page.
+type NotePage struct {
+	Notes      []Note
+	Sort       string
+	NextCursor string
+}
@@ -0,0 +1,18 @@
+package models
+
+// NoteImportReport lists what happened to each entry of an imported archive.
+type NoteImportReport struct {
+	Created   []NoteImportEntry
+	Skipped   []NoteImportEntry
+	Conflicts []NoteImportEntry
+}
+
+// NoteImportEntry is one file of an imported archive. NoteID is the created
+// note, or for a conflict the existing note with the same title. Reason
+// explains why the file was skipped.
+type NoteImportEntry struct {
+	File   string
+	Title  string
+	NoteID uint
+	Reason string
+}
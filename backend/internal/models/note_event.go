@@ -0,0 +1,52 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"time"
+)
+
+type NoteEventType string
+
+const (
+	NoteCreated         NoteEventType = "created"
+	NoteUpdated         NoteEventType = "updated"
+	NoteArchived        NoteEventType = "archived"
+	NoteUnarchived      NoteEventType = "unarchived"
+	NoteDeleted         NoteEventType = "deleted"
+	NoteRestored        NoteEventType = "restored"
+	NoteCategoryChanged NoteEventType = "category-changed"
+	NotePinned          NoteEventType = "pinned"
+	NoteUnpinned        NoteEventType = "unpinned"
+	// NoteMoved may follow a rebalancing that renumbered the positions of
+	// the owner's other notes too, so clients should reload their order.
+	NoteMoved NoteEventType = "moved"
+)
+
+// NoteEvent is a committed change to one of a user's notes. Note holds the
+// state after the change, nil for deletions. IDs are assigned by the event
+// bus when the event is published.
+type NoteEvent struct {
+	ID     uint64
+	Type   NoteEventType
+	UserID uint
+	NoteID uint
+	Note   *Note
+	At     time.Time
+}
+
+// NewNoteEvent captures the note as it is now, so later changes to it do not
+// leak into the event.
+func NewNoteEvent(eventType NoteEventType, note *Note) NoteEvent {
+	event := NoteEvent{
+		Type:   eventType,
+		UserID: note.UserID,
+		NoteID: note.ID,
+		At:     *date.ArgentinaTimeNow(),
+	}
+	if eventType != NoteDeleted {
+		snapshot := *note
+		snapshot.Categories = append([]Category(nil), note.Categories...)
+		event.Note = &snapshot
+	}
+	return event
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"notes/pkg/diff"
+	"time"
+)
+
+// NoteRevision is an immutable snapshot of a note, recorded every time the
+// note is created or changed
+// @swagger:model
+type NoteRevision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	NoteID     uint      `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision,priority:1" json:"note_id"`
+	Revision   uint      `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision,priority:2" json:"revision"`
+	Title      string    `gorm:"not null;size:50" json:"title"`
+	Content    string    `gorm:"type:text" json:"content"`
+	Categories []string  `gorm:"type:text;serializer:json" json:"categories"`
+	IsArchived bool      `gorm:"default:false" json:"is_archived"`
+	ActorID    uint      `gorm:"not null" json:"actor_id"`
+	CreatedAt  time.Time `gorm:"created_at" json:"created_at"`
+}
+
+func NewNoteRevision(note *Note, revision uint, actorId uint) *NoteRevision {
+	categories := make([]string, 0, len(note.Categories))
+	for _, c := range note.Categories {
+		categories = append(categories, c.Name)
+	}
+	return &NoteRevision{
+		NoteID:     note.ID,
+		Revision:   revision,
+		Title:      note.Title,
+		Content:    note.Content,
+		Categories: categories,
+		IsArchived: note.IsArchived,
+		ActorID:    actorId,
+		CreatedAt:  *date.ArgentinaTimeNow(),
+	}
+}
+
+// NoteRevisionDiff describes what changed between two revisions of a note.
+type NoteRevisionDiff struct {
+	From              uint
+	To                uint
+	Title             []diff.Line
+	Content           []diff.Line
+	CategoriesAdded   []string
+	CategoriesRemoved []string
+	ArchivedFrom      bool
+	ArchivedTo        bool
+}
+
+func NewNoteRevisionDiff(from, to *NoteRevision) *NoteRevisionDiff {
+	fromCats := make(map[string]bool, len(from.Categories))
+	for _, c := range from.Categories {
+		fromCats[c] = true
+	}
+	toCats := make(map[string]bool, len(to.Categories))
+	for _, c := range to.Categories {
+		toCats[c] = true
+	}
+
+	added, removed := []string{}, []string{}
+	for _, c := range to.Categories {
+		if !fromCats[c] {
+			added = append(added, c)
+		}
+	}
+	for _, c := range from.Categories {
+		if !toCats[c] {
+			removed = append(removed, c)
+		}
+	}
+
+	return &NoteRevisionDiff{
+		From:              from.Revision,
+		To:                to.Revision,
+		Title:             diff.Lines(from.Title, to.Title),
+		Content:           diff.Lines(from.Content, to.Content),
+		CategoriesAdded:   added,
+		CategoriesRemoved: removed,
+		ArchivedFrom:      from.IsArchived,
+		ArchivedTo:        to.IsArchived,
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"time"
+)
+
+// NoteRole is the access a user has to a note.
+type NoteRole string
+
+const (
+	// NoteOwner is the role of the user who created the note. It is implied
+	// by Note.UserID and never stored as a permission.
+	NoteOwner NoteRole = "owner"
+	// NoteEditor may read the note and change its title, content and
+	// categories.
+	NoteEditor NoteRole = "editor"
+	// NoteViewer may only read the note.
+	NoteViewer NoteRole = "viewer"
+)
+
+// NotePermission grants a user other than the owner access to a note.
+type NotePermission struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	NoteID    uint      `gorm:"not null;uniqueIndex:idx_note_permissions_note_user,priority:1" json:"note_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_note_permissions_note_user,priority:2;index" json:"-"`
+	Role      NoteRole  `gorm:"not null;size:10" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	// UserName is the grantee's username, read along with the permission
+	// when permissions are listed.
+	UserName string `gorm:"->;-:migration" json:"user_name"`
+}
+
+func NewNotePermission(noteId, userId uint, role NoteRole) *NotePermission {
+	return &NotePermission{
+		NoteID:    noteId,
+		UserID:    userId,
+		Role:      role,
+		CreatedAt: *date.ArgentinaTimeNow(),
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"time"
+)
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the user
+// has lost their authenticator. Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64"`
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+func NewRecoveryCode(userId uint, codeHash string) *RecoveryCode {
+	return &RecoveryCode{
+		UserID:    userId,
+		CodeHash:  codeHash,
+		CreatedAt: *date.ArgentinaTimeNow(),
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"time"
+)
+
+// Session is a login on one device. Access tokens carry the session ID as
+// their jti, so revoking the session invalidates them before they expire.
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:32" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	RefreshTokens []RefreshToken `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func NewSession(id string, userId uint, userAgent string, expiresAt time.Time) *Session {
+	now := *date.ArgentinaTimeNow()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return &Session{
+		ID:         id,
+		UserID:     userId,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  expiresAt,
+	}
+}
+
+// RefreshToken is one link of a session's rotation chain. Only the SHA-256
+// hash of the token is stored; a token can be exchanged once, after which
+// UsedAt is set and presenting it again revokes the whole session.
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	SessionID string `gorm:"not null;size:32;index"`
+	TokenHash string `gorm:"not null;size:64;uniqueIndex"`
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+func NewRefreshToken(sessionId, tokenHash string) *RefreshToken {
+	return &RefreshToken{
+		SessionID: sessionId,
+		TokenHash: tokenHash,
+		CreatedAt: *date.ArgentinaTimeNow(),
+	}
+}
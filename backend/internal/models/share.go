@@ -0,0 +1,39 @@
+package models
+
+import (
+	"notes/pkg/date"
+	"time"
+)
+
+// NoteShare is a read-only link to a note that anyone holding its token can
+// open without an account. Only the SHA-256 hash of the token is stored; the
+// token itself is shown once, when the link is created. PasswordHash is empty
+// when the link needs no password.
+type NoteShare struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	NoteID       uint       `gorm:"not null;index" json:"note_id"`
+	UserID       uint       `gorm:"not null;index" json:"-"`
+	TokenHash    string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	PasswordHash string     `gorm:"not null;default:''" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RevokedAt    *time.Time `json:"-"`
+	Views        int64      `gorm:"not null;default:0" json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+}
+
+func NewNoteShare(noteId, userId uint, tokenHash, passwordHash string, expiresAt *time.Time) *NoteShare {
+	return &NoteShare{
+		NoteID:       noteId,
+		UserID:       userId,
+		TokenHash:    tokenHash,
+		PasswordHash: passwordHash,
+		CreatedAt:    *date.ArgentinaTimeNow(),
+		ExpiresAt:    expiresAt,
+	}
+}
+
+// HasPassword reports whether opening the link needs a password.
+func (s *NoteShare) HasPassword() bool {
+	return s.PasswordHash != ""
+}
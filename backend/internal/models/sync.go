@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// SyncKind names what a tombstone stands for.
+type SyncKind string
+
+const (
+	SyncNote     SyncKind = "note"
+	SyncCategory SyncKind = "category"
+)
+
+// SyncTombstone records that a note was purged from the trash or a category
+// deleted, under the number of the owner's change sequence the deletion took.
+type SyncTombstone struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index:idx_sync_tombstones_user_change_seq,priority:1"`
+	Kind      SyncKind  `gorm:"not null;size:10"`
+	EntityID  uint      `gorm:"not null"`
+	ChangeSeq uint64    `gorm:"not null;index:idx_sync_tombstones_user_change_seq,priority:2"`
+	DeletedAt time.Time `gorm:"not null;index"`
+}
+
+// ChangeSet is what changed in a user's notes and categories after some
+// number of their change sequence, ordered by that number. Notes include
+// those moved to the trash. Latest is the number of the last change
+// committed when the set was read and PrunedThrough the number of the newest
+// tombstone dropped.
+type ChangeSet struct {
+	Notes         []Note
+	Categories    []Category
+	Tombstones    []SyncTombstone
+	Latest        uint64
+	PrunedThrough uint64
+}
+
+// SyncChange is a change made by an offline client to one note. NoteID 0
+// creates a note; otherwise BaseVersion is the version the client changed.
+type SyncChange struct {
+	NoteID      uint
+	BaseVersion uint
+	Deleted     bool
+	Title       string
+	Content     string
+	Categories  []Category
+	IsArchived  bool
+}
+
+type SyncStatus string
+
+const (
+	// SyncApplied changes were saved.
+	SyncApplied SyncStatus = "applied"
+	// SyncUnchanged changes already matched the server, nothing was saved.
+	SyncUnchanged SyncStatus = "unchanged"
+	// SyncConflict changes were made to a version the server has moved on
+	// from; the client resolves them against the server's note.
+	SyncConflict SyncStatus = "conflict"
+	// SyncRejected changes failed validation.
+	SyncRejected SyncStatus = "rejected"
+)
+
+// SyncResult is the outcome of a SyncChange. Note is the server's note after
+// it, nil when the note is deleted; Err says why a change was rejected.
+type SyncResult struct {
+	Status SyncStatus
+	Note   *Note
+	Err    error
+}
+
+// SyncPage is one page of a user's changes. Deleted lists notes moved to the
+// trash as well as those purged from it. Token resumes after the page.
+type SyncPage struct {
+	Notes      []Note
+	Categories []Category
+	Deleted    []SyncTombstone
+	Token      string
+	HasMore    bool
+}
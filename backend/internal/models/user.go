@@ -8,13 +8,30 @@ import (
 // User represents a user
 // @swagger:model
 type User struct {
-	ID        uint       `gorm:"primaryKey" json:"id,omitempty"`
-	UserName  string     `gorm:"unique;not null;size:30" json:"user_name"`
-	Password  string     `gorm:"not null" json:"-"`
-	Notes     []Note     `gorm:"constraint:OnDelete:CASCADE;" json:"notes"`
-	IsAdmin   bool       `gorm:"default:false" json:"is_admin,omitempty"`
-	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at,omitempty"`
-	UpdatedAt *time.Time `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	ID         uint       `gorm:"primaryKey" json:"id,omitempty"`
+	UserName   string     `gorm:"unique;not null;size:30" json:"user_name"`
+	Password   string     `gorm:"not null" json:"-"`
+	Notes      []Note     `gorm:"constraint:OnDelete:CASCADE;" json:"notes"`
+	Categories []Category `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Sessions   []Session  `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	IsAdmin    bool       `gorm:"default:false" json:"is_admin,omitempty"`
+	// IsDisabled locks the user out: PROTECT rejects their requests even
+	// with a token that is still valid.
+	IsDisabled bool `gorm:"default:false" json:"is_disabled,omitempty"`
+	// PasswordResetHash is set when an admin forces a password reset. Until
+	// the user sets a new password with the reset token, logins are refused.
+	PasswordResetHash      string     `gorm:"size:64" json:"-"`
+	PasswordResetExpiresAt *time.Time `json:"-"`
+	// TOTPSecret is set when enrollment starts and TOTPEnabled once the user
+	// confirms it with a code. TOTPLastStep is the last time step accepted,
+	// so a code cannot be used twice.
+	TOTPSecret    string         `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled   bool           `gorm:"column:totp_enabled;default:false" json:"-"`
+	TOTPLastStep  int64          `gorm:"column:totp_last_step;default:0" json:"-"`
+	RecoveryCodes []RecoveryCode `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	AccessTokens  []AccessToken  `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time     `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
 }
 
 func NewUser(user, password string, notes []*Note) *User {
@@ -28,3 +45,20 @@ func NewUser(user, password string, notes []*Note) *User {
 		UpdatedAt: nil,
 	}
 }
+
+// UserSummary is a user as listed to admins.
+type UserSummary struct {
+	ID         uint
+	UserName   string
+	IsAdmin    bool
+	IsDisabled bool
+	NoteCount  int64
+	CreatedAt  time.Time
+}
+
+// UserPage is one page of the admin user listing, ordered by ID.
+// NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []UserSummary
+	NextCursor string
+}
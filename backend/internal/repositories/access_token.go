@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"notes/internal/configs"
+	"notes/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AccessTokenRepository struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+func NewAccessTokenRepository(db *gorm.DB, config *configs.Config) *AccessTokenRepository {
+	return &AccessTokenRepository{
+		db:     db,
+		config: config,
+	}
+}
+
+func (ar *AccessTokenRepository) Create(ctx context.Context, token *models.AccessToken) error {
+	return ar.db.WithContext(ctx).Create(token).Error
+}
+
+// ListForUser returns the user's tokens that are not revoked, newest first.
+// Expired tokens are included so the user can see and clean them up.
+func (ar *AccessTokenRepository) ListForUser(ctx context.Context, userId uint) ([]models.AccessToken, error) {
+	var tokens []models.AccessToken
+	err := ar.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Order("created_at DESC, id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// FindActive returns the token with the given hash if it is neither revoked
+// nor expired.
+func (ar *AccessTokenRepository) FindActive(ctx context.Context, tokenHash string, now time.Time) (*models.AccessToken, error) {
+	var token models.AccessToken
+	if err := ar.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", tokenHash, now).
+		First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Touch records when the token was last used.
+func (ar *AccessTokenRepository) Touch(ctx context.Context, id uint, now time.Time) error {
+	return ar.db.WithContext(ctx).Model(&models.AccessToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", now).Error
+}
+
+// Revoke revokes one of the user's tokens. It reports false when the user
+// has no such token.
+func (ar *AccessTokenRepository) Revoke(ctx context.Context, userId, id uint, now time.Time) (bool, error) {
+	res := ar.db.WithContext(ctx).Model(&models.AccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAllForUser revokes every token of the user.
+func (ar *AccessTokenRepository) RevokeAllForUser(ctx context.Context, userId uint, now time.Time) error {
+	return ar.db.WithContext(ctx).Model(&models.AccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+}
@@ -21,46 +21,119 @@ func NewCategoryRepository(db *gorm.DB, config *configs.Config) *CategoryReposit
 }
 
 func (cr *CategoryRepository) Create(ctx context.Context, category *models.Category) (*models.Category, error) {
-	if err := cr.db.WithContext(ctx).Create(category).Error; err != nil {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, category.UserID)
+		if err != nil {
+			return err
+		}
+		category.ChangeSeq = seq
+		return tx.Create(category).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return category, nil
 }
 
 func (cr *CategoryRepository) Update(ctx context.Context, category *models.Category) (*uint, error) {
-	if err := cr.db.WithContext(ctx).Save(category).Error; err != nil {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, category.UserID)
+		if err != nil {
+			return err
+		}
+		category.ChangeSeq = seq
+		return tx.Save(category).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &category.ID, nil
 }
 
-func (cr *CategoryRepository) Delete(ctx context.Context, id uint) (*uint, error) {
-	if err := cr.db.WithContext(ctx).Delete(&models.Category{}, id).Error; err != nil {
+// Delete removes the category and detaches it from every note in one
+// transaction, leaving a tombstone in its place.
+func (cr *CategoryRepository) Delete(ctx context.Context, userId uint, id uint) (*uint, error) {
+	err := cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM note_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		return deleteCategory(tx, userId, id)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &id, nil
 }
 
-func (cr *CategoryRepository) FindByID(ctx context.Context, id uint) (*models.Category, error) {
+func (cr *CategoryRepository) FindByID(ctx context.Context, userId uint, id uint) (*models.Category, error) {
 	var category models.Category
-	if err := cr.db.WithContext(ctx).First(&category, id).Error; err != nil {
+	if err := cr.db.WithContext(ctx).Where("user_id = ?", userId).First(&category, id).Error; err != nil {
 		return nil, err
 	}
 	return &category, nil
 }
 
-func (cr *CategoryRepository) FindByName(ctx context.Context, name string) (*models.Category, error) {
+func (cr *CategoryRepository) FindByName(ctx context.Context, userId uint, name string) (*models.Category, error) {
 	var category models.Category
-	if err := cr.db.WithContext(ctx).Where("name = ?", name).First(&category).Error; err != nil {
+	if err := cr.db.WithContext(ctx).Where("user_id = ? AND name = ?", userId, name).First(&category).Error; err != nil {
 		return nil, err
 	}
 	return &category, nil
 }
 
-func (cr *CategoryRepository) FindAll(ctx context.Context) ([]models.Category, error) {
+func (cr *CategoryRepository) FindAll(ctx context.Context, userId uint) ([]models.Category, error) {
 	var categories []models.Category
-	if err := cr.db.WithContext(ctx).Find(&categories).Error; err != nil {
+	if err := cr.db.WithContext(ctx).Where("user_id = ?", userId).Order("name").Find(&categories).Error; err != nil {
 		return nil, err
 	}
 	return categories, nil
 }
+
+// CountSoleCategoryNotes counts the notes for which the category is the only
+// one attached, which would be left without categories if it were removed.
+func (cr *CategoryRepository) CountSoleCategoryNotes(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := cr.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) FROM note_categories nc
+		WHERE nc.category_id = ?
+		AND NOT EXISTS (
+			SELECT 1 FROM note_categories other
+			WHERE other.note_id = nc.note_id AND other.category_id <> nc.category_id
+		)`, id).Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Merge re-points every note tagged with the source category to the target
+// category and deletes the source, all inside a single transaction. Notes
+// already tagged with both keep a single association to the target.
+func (cr *CategoryRepository) Merge(ctx context.Context, userId uint, sourceId uint, targetId uint) error {
+	return cr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			INSERT INTO note_categories (note_id, category_id)
+			SELECT nc.note_id, ? FROM note_categories nc
+			WHERE nc.category_id = ?
+			AND NOT EXISTS (
+				SELECT 1 FROM note_categories existing
+				WHERE existing.note_id = nc.note_id AND existing.category_id = ?
+			)`, targetId, sourceId, targetId).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM note_categories WHERE category_id = ?", sourceId).Error; err != nil {
+			return err
+		}
+		return deleteCategory(tx, userId, sourceId)
+	})
+}
+
+// deleteCategory deletes the user's category and leaves a tombstone if there
+// was one to delete.
+func deleteCategory(tx *gorm.DB, userId uint, id uint) error {
+	res := tx.Where("user_id = ?", userId).Delete(&models.Category{}, id)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+	return addTombstone(tx, userId, models.SyncCategory, id)
+}
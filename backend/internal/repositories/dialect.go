@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// isSQLite reports whether db talks to SQLite rather than PostgreSQL.
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// ilike returns a condition matching the column against a pattern, ignoring
+// case. SQLite has no ILIKE, but its LIKE ignores the case of ASCII letters.
+func ilike(db *gorm.DB, column string) string {
+	if isSQLite(db) {
+		return column + " LIKE ?"
+	}
+	return column + " ILIKE ?"
+}
+
+// ilikeAny returns a condition, and its arguments, matching the column
+// against any of the patterns, ignoring case.
+func ilikeAny(db *gorm.DB, column string, patterns []string) (string, []any) {
+	if !isSQLite(db) {
+		return column + " ILIKE ANY (CAST(? AS varchar[]))", []any{pq.Array(patterns)}
+	}
+	conditions := make([]string, len(patterns))
+	args := make([]any, len(patterns))
+	for i, pattern := range patterns {
+		conditions[i] = column + " LIKE ?"
+		args[i] = pattern
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
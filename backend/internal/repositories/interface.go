@@ -3,10 +3,126 @@ package repositories
 import (
 	"context"
 	"notes/internal/models"
+	"time"
 )
 
-type UserRepositoryMethods interface {
+// NoteStore persists notes with their category associations and revision
+// history. Implementations return the validations errors documented on
+// NoteRepository, so services behave the same whatever the backend.
+type NoteStore interface {
+	// Transaction runs fn with a store bound to a single transaction that
+	// commits when fn returns nil. Transactions opened inside it become
+	// savepoints.
+	Transaction(ctx context.Context, fn func(tx NoteStore) error) error
+	// Categories returns the category store taking part in the same
+	// transaction as this one.
+	Categories() CategoryStore
+
+	Create(ctx context.Context, note *models.Note) (*models.Note, error)
+	GetAllNotes(ctx context.Context) ([]*models.Note, error)
+	GetNotesByCategories(ctx context.Context, categoryNames []string) ([]*models.Note, error)
+	GetNoteById(ctx context.Context, id uint) (*models.Note, error)
+	GetByTitle(ctx context.Context, userId uint, title string) (*models.Note, error)
+	UpdateNote(ctx context.Context, note *models.Note, actorId uint) (*uint, error)
+	PatchNote(ctx context.Context, note *models.Note, columns []string, replaceCategories bool, actorId uint) (*uint, error)
+	GetRevisions(ctx context.Context, noteId uint) ([]models.NoteRevision, error)
+	GetRevision(ctx context.Context, noteId uint, revision uint) (*models.NoteRevision, error)
+	Delete(ctx context.Context, note *models.Note) (*uint, error)
+	GetTrash(ctx context.Context, userId uint) ([]models.Note, error)
+	Restore(ctx context.Context, userId uint, noteId uint) (*uint, error)
+	EmptyTrash(ctx context.Context, userId uint) (int64, error)
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error)
+	FilterNotes(ctx context.Context, isArchived *bool, categories []string) ([]models.Note, error)
+	ListNotes(ctx context.Context, opts NoteListOptions) ([]models.Note, error)
+	DeleteNoteByUserId(ctx context.Context, noteId uint, userId uint) (*uint, error)
+	// PinNote pins or unpins the note, keeping its position, and bumps its
+	// version.
+	PinNote(ctx context.Context, note *models.Note, pinned bool) error
+	// MoveNote puts the note right before the target in its owner's manual
+	// order, or right after it with after set, and bumps its version. The
+	// target must be another note of the owner, or ErrMoveTarget is returned,
+	// and pinned or not alike, or ErrMovePinned is. Positions are only
+	// rebalanced when there is no room left next to the target.
+	MoveNote(ctx context.Context, note *models.Note, targetId uint, after bool) error
+	// TouchCategoryNotes bumps the version of every note, trashed ones
+	// included, tagged with the category and returns their IDs.
+	TouchCategoryNotes(ctx context.Context, categoryId uint) ([]uint, error)
+	// Search ranks the live notes the user owns or has been given access
+	// to by relevance to the query.
+	Search(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error)
+	// Changes returns up to limit each of the user's notes, categories and
+	// tombstones changed after the change number since, oldest first.
+	Changes(ctx context.Context, userId uint, since uint64, limit int) (*models.ChangeSet, error)
+	// PruneTombstones deletes the tombstones of deletions made before the
+	// cutoff.
+	PruneTombstones(ctx context.Context, cutoff time.Time) (int64, error)
+	// GetPermission returns the access the user was granted to the note, or
+	// nil when the user has none.
+	GetPermission(ctx context.Context, noteId, userId uint) (*models.NotePermission, error)
+	// SetPermission grants the access, replacing the role of an earlier
+	// grant to the same user.
+	SetPermission(ctx context.Context, permission *models.NotePermission) error
+	// ListPermissions returns the grants of the note with the grantees'
+	// usernames, in username order.
+	ListPermissions(ctx context.Context, noteId uint) ([]models.NotePermission, error)
+	DeletePermission(ctx context.Context, noteId, userId uint) (bool, error)
+}
+
+// CategoryStore persists the users' categories. Lookups of a missing
+// category fail with gorm.ErrRecordNotFound and name clashes with
+// gorm.ErrDuplicatedKey, as the gorm backed repository does.
+type CategoryStore interface {
+	Create(ctx context.Context, category *models.Category) (*models.Category, error)
+	Update(ctx context.Context, category *models.Category) (*uint, error)
+	Delete(ctx context.Context, userId uint, id uint) (*uint, error)
+	FindByID(ctx context.Context, userId uint, id uint) (*models.Category, error)
+	FindByName(ctx context.Context, userId uint, name string) (*models.Category, error)
+	FindAll(ctx context.Context, userId uint) ([]models.Category, error)
+	CountSoleCategoryNotes(ctx context.Context, id uint) (int64, error)
+	Merge(ctx context.Context, userId uint, sourceId uint, targetId uint) error
+}
+
+// UserStore persists users and their credentials. Lookups of a missing user
+// fail with gorm.ErrRecordNotFound and taken usernames with
+// gorm.ErrDuplicatedKey.
+type UserStore interface {
 	CreateUser(ctx context.Context, user *models.User) error
-	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	GetUserByID(ctx context.Context, userId uint) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	SetTOTPSecret(ctx context.Context, userId uint, secret string) error
+	EnableTOTP(ctx context.Context, userId uint, step int64, codes []models.RecoveryCode) error
+	DisableTOTP(ctx context.Context, userId uint) error
+	UseTOTPStep(ctx context.Context, userId uint, step int64) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string, now time.Time) error
+	UpdatePassword(ctx context.Context, userId uint, passwordHash string) error
+	ListUsers(ctx context.Context, afterId uint, limit int) ([]models.UserSummary, error)
+	GetAuthStatus(ctx context.Context, userId uint) (*models.User, error)
+	SetDisabled(ctx context.Context, userId uint, disabled bool) (bool, error)
+	PromoteToAdmin(ctx context.Context, username string) (bool, error)
+	RequirePasswordReset(ctx context.Context, userId uint, tokenHash string, expiresAt time.Time) (bool, error)
+	ResetPassword(ctx context.Context, username, tokenHash, passwordHash string, now time.Time) error
+	ExportData(ctx context.Context, userId uint) (*models.AccountExport, error)
+	DeleteUser(ctx context.Context, userId uint) (bool, error)
 }
+
+// SessionStore persists login sessions and the rotation chains of their
+// refresh tokens. Lookups of a missing session or token fail with
+// gorm.ErrRecordNotFound.
+type SessionStore interface {
+	Create(ctx context.Context, session *models.Session, token *models.RefreshToken) error
+	FindActive(ctx context.Context, id string, now time.Time) (*models.Session, error)
+	FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, *models.Session, error)
+	// Rotate spends used and stores next in its place. A token already
+	// spent, even by a concurrent call, fails with ErrRefreshTokenReuse.
+	Rotate(ctx context.Context, used *models.RefreshToken, next *models.RefreshToken, now time.Time, expiresAt time.Time) error
+	Revoke(ctx context.Context, id string, now time.Time) error
+	RevokeAllForUser(ctx context.Context, userId uint, now time.Time) error
+	RevokeOthersForUser(ctx context.Context, userId uint, keepId string, now time.Time) error
+}
+
+var (
+	_ NoteStore     = (*NoteRepository)(nil)
+	_ CategoryStore = (*CategoryRepository)(nil)
+	_ UserStore     = (*UserRepository)(nil)
+	_ SessionStore  = (*SessionRepository)(nil)
+)
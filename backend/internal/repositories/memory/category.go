@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"notes/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CategoryStore struct {
+	conn *conn
+}
+
+func (cs *CategoryStore) Create(ctx context.Context, category *models.Category) (*models.Category, error) {
+	err := cs.conn.write(func(t *tables) error {
+		return cs.conn.insertCategory(t, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// insertCategory adds the category, giving it an ID unless it has one.
+func (c *conn) insertCategory(t *tables, category *models.Category) error {
+	if _, ok := t.users[category.UserID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if category.ID != 0 {
+		if _, ok := t.categories[category.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if categoryNameTaken(t, category.UserID, category.Name, category.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	if category.ID == 0 {
+		category.ID = c.db.next(&c.db.seq.categories)
+	}
+	ts := now()
+	if category.CreatedAt.IsZero() {
+		category.CreatedAt = ts
+	}
+	if category.UpdatedAt == nil {
+		category.UpdatedAt = &ts
+	}
+	category.ChangeSeq = nextChangeSeq(t, category.UserID)
+	stored := *category
+	stored.CreatedAt = stamp(stored.CreatedAt)
+	stored.UpdatedAt = stampPtr(stored.UpdatedAt)
+	t.categories[category.ID] = stored
+	return nil
+}
+
+func categoryNameTaken(t *tables, userId uint, name string, exceptId uint) bool {
+	for _, c := range t.categories {
+		if c.ID != exceptId && c.UserID == userId && c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Update saves every field of the category, inserting it when there is no
+// category with its ID, as gorm's Save does.
+func (cs *CategoryStore) Update(ctx context.Context, category *models.Category) (*uint, error) {
+	err := cs.conn.write(func(t *tables) error {
+		if _, ok := t.categories[category.ID]; !ok || category.ID == 0 {
+			return cs.conn.insertCategory(t, category)
+		}
+		if _, ok := t.users[category.UserID]; !ok {
+			return gorm.ErrForeignKeyViolated
+		}
+		if categoryNameTaken(t, category.UserID, category.Name, category.ID) {
+			return gorm.ErrDuplicatedKey
+		}
+		ts := now()
+		category.UpdatedAt = &ts
+		category.ChangeSeq = nextChangeSeq(t, category.UserID)
+		stored := *category
+		stored.CreatedAt = stamp(stored.CreatedAt)
+		t.categories[category.ID] = stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category.ID, nil
+}
+
+// Delete removes the category and detaches it from every note, leaving a
+// tombstone in its place.
+func (cs *CategoryStore) Delete(ctx context.Context, userId uint, id uint) (*uint, error) {
+	err := cs.conn.write(func(t *tables) error {
+		detachCategory(t, id)
+		cs.conn.deleteCategory(t, userId, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func detachCategory(t *tables, categoryId uint) {
+	for noteId, ids := range t.noteCategories {
+		t.noteCategories[noteId] = without(ids, categoryId)
+	}
+}
+
+func without(ids []uint, id uint) []uint {
+	kept := ids[:0:0]
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+func contains(ids []uint, id uint) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *CategoryStore) FindByID(ctx context.Context, userId uint, id uint) (*models.Category, error) {
+	var category *models.Category
+	cs.conn.read(func(t *tables) {
+		if c, ok := t.categories[id]; ok && c.UserID == userId {
+			category = &c
+		}
+	})
+	if category == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return category, nil
+}
+
+func (cs *CategoryStore) FindByName(ctx context.Context, userId uint, name string) (*models.Category, error) {
+	var category *models.Category
+	cs.conn.read(func(t *tables) {
+		for _, c := range sortedCategories(t) {
+			if c.UserID == userId && c.Name == name {
+				category = &c
+				return
+			}
+		}
+	})
+	if category == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return category, nil
+}
+
+func (cs *CategoryStore) FindAll(ctx context.Context, userId uint) ([]models.Category, error) {
+	var categories []models.Category
+	cs.conn.read(func(t *tables) {
+		for _, c := range sortedCategories(t) {
+			if c.UserID == userId {
+				categories = append(categories, c)
+			}
+		}
+	})
+	sort.SliceStable(categories, func(i, j int) bool {
+		return strings.Compare(categories[i].Name, categories[j].Name) < 0
+	})
+	return categories, nil
+}
+
+// sortedCategories lists every category by ID.
+func sortedCategories(t *tables) []models.Category {
+	categories := make([]models.Category, 0, len(t.categories))
+	for _, c := range t.categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories
+}
+
+// CountSoleCategoryNotes counts the notes, trashed ones included, for which
+// the category is the only one attached.
+func (cs *CategoryStore) CountSoleCategoryNotes(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	cs.conn.read(func(t *tables) {
+		for _, ids := range t.noteCategories {
+			if contains(ids, id) && len(without(ids, id)) == 0 {
+				count++
+			}
+		}
+	})
+	return count, nil
+}
+
+// Merge re-points every note tagged with the source category to the target
+// and deletes the source.
+func (cs *CategoryStore) Merge(ctx context.Context, userId uint, sourceId uint, targetId uint) error {
+	return cs.conn.write(func(t *tables) error {
+		if _, ok := t.categories[targetId]; !ok {
+			for _, ids := range t.noteCategories {
+				if contains(ids, sourceId) {
+					return gorm.ErrForeignKeyViolated
+				}
+			}
+		}
+		for noteId, ids := range t.noteCategories {
+			if contains(ids, sourceId) && !contains(ids, targetId) {
+				t.noteCategories[noteId] = append(ids, targetId)
+			}
+		}
+		detachCategory(t, sourceId)
+		cs.conn.deleteCategory(t, userId, sourceId)
+		return nil
+	})
+}
+
+// deleteCategory deletes the user's category and leaves a tombstone if there
+// was one to delete.
+func (c *conn) deleteCategory(t *tables, userId uint, id uint) {
+	if cat, ok := t.categories[id]; ok && cat.UserID == userId {
+		delete(t.categories, id)
+		c.addTombstone(t, userId, models.SyncCategory, id)
+	}
+}
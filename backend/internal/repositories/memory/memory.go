@@ -0,0 +1,220 @@
+// Package memory implements the repository stores in process memory, for
+// tests and local experiments that should not need PostgreSQL.
+//
+// The stores follow the gorm backed repositories closely: the same rows are
+// visible to the same queries, constraints fail with the same errors, soft
+// deleted notes stay hidden outside the trash and IDs come from sequences
+// that, as in PostgreSQL, are not rolled back with a transaction. Two things
+// are approximated: text is ordered by byte value rather than by the
+// database collation, and full-text search uses a small English stemmer
+// instead of the PostgreSQL dictionaries.
+package memory
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+)
+
+// DB holds the tables shared by the stores. It is safe for concurrent use:
+// every statement, and every transaction as a whole, runs under one lock.
+type DB struct {
+	mu   sync.Mutex
+	data *tables
+	seq  sequences
+}
+
+func New() *DB {
+	return &DB{data: newTables()}
+}
+
+// Notes returns a note store whose Categories share its transactions.
+func (db *DB) Notes() repositories.NoteStore {
+	return &NoteStore{conn: &conn{db: db}}
+}
+
+func (db *DB) Categories() repositories.CategoryStore {
+	return &CategoryStore{conn: &conn{db: db}}
+}
+
+func (db *DB) Users() repositories.UserStore {
+	return &UserStore{conn: &conn{db: db}}
+}
+
+func (db *DB) Sessions() repositories.SessionStore {
+	return &SessionStore{conn: &conn{db: db}}
+}
+
+type tables struct {
+	users      map[uint]models.User
+	notes      map[uint]models.Note
+	categories map[uint]models.Category
+	// noteCategories is the note_categories join table, by note ID.
+	noteCategories map[uint][]uint
+	revisions      map[uint][]models.NoteRevision
+	recoveryCodes  map[uint][]models.RecoveryCode
+	// changeSeqs and prunedSeqs are the users' change_seq and
+	// sync_pruned_seq columns, by user ID.
+	changeSeqs map[uint]uint64
+	prunedSeqs map[uint]uint64
+	tombstones []models.SyncTombstone
+	// permissions is the note_permissions table, by ID.
+	permissions map[uint]models.NotePermission
+	sessions    map[string]models.Session
+	// refreshTokens is the refresh_tokens table, by ID.
+	refreshTokens map[uint]models.RefreshToken
+}
+
+func newTables() *tables {
+	return &tables{
+		users:          map[uint]models.User{},
+		notes:          map[uint]models.Note{},
+		categories:     map[uint]models.Category{},
+		noteCategories: map[uint][]uint{},
+		revisions:      map[uint][]models.NoteRevision{},
+		recoveryCodes:  map[uint][]models.RecoveryCode{},
+		changeSeqs:     map[uint]uint64{},
+		prunedSeqs:     map[uint]uint64{},
+		permissions:    map[uint]models.NotePermission{},
+		sessions:       map[string]models.Session{},
+		refreshTokens:  map[uint]models.RefreshToken{},
+	}
+}
+
+// clone copies the tables deep enough that changing the copy leaves the
+// original untouched; rows are stored by value and never modified in place.
+func (t *tables) clone() *tables {
+	c := newTables()
+	for id, u := range t.users {
+		c.users[id] = u
+	}
+	for id, n := range t.notes {
+		c.notes[id] = n
+	}
+	for id, cat := range t.categories {
+		c.categories[id] = cat
+	}
+	for id, ids := range t.noteCategories {
+		c.noteCategories[id] = append([]uint(nil), ids...)
+	}
+	for id, revs := range t.revisions {
+		c.revisions[id] = append([]models.NoteRevision(nil), revs...)
+	}
+	for id, codes := range t.recoveryCodes {
+		c.recoveryCodes[id] = append([]models.RecoveryCode(nil), codes...)
+	}
+	for id, seq := range t.changeSeqs {
+		c.changeSeqs[id] = seq
+	}
+	for id, seq := range t.prunedSeqs {
+		c.prunedSeqs[id] = seq
+	}
+	c.tombstones = append([]models.SyncTombstone(nil), t.tombstones...)
+	for id, p := range t.permissions {
+		c.permissions[id] = p
+	}
+	for id, s := range t.sessions {
+		c.sessions[id] = s
+	}
+	for id, rt := range t.refreshTokens {
+		c.refreshTokens[id] = rt
+	}
+	return c
+}
+
+type sequences struct {
+	users, notes, categories, revisions, recoveryCodes, tombstones, permissions, refreshTokens uint
+}
+
+func (db *DB) next(seq *uint) uint {
+	*seq++
+	return *seq
+}
+
+// conn runs statements against the DB. Outside a transaction each statement
+// takes the lock; inside one the lock is already held by the transaction.
+type conn struct {
+	db   *DB
+	inTx bool
+}
+
+// read runs fn with the tables.
+func (c *conn) read(fn func(t *tables)) {
+	if !c.inTx {
+		c.db.mu.Lock()
+		defer c.db.mu.Unlock()
+	}
+	fn(c.db.data)
+}
+
+// write runs fn as one statement: when it fails, the tables are left as they
+// were before it started.
+func (c *conn) write(fn func(t *tables) error) error {
+	if !c.inTx {
+		c.db.mu.Lock()
+		defer c.db.mu.Unlock()
+	}
+	snapshot := c.db.data.clone()
+	if err := fn(c.db.data); err != nil {
+		c.db.data = snapshot
+		return err
+	}
+	return nil
+}
+
+// transaction runs fn with a connection bound to a transaction that holds
+// the lock until fn returns, which makes transactions serializable. Nested
+// calls behave as savepoints. fn must only use stores built on tx, any other
+// store would wait for the lock forever.
+func (c *conn) transaction(fn func(tx *conn) error) error {
+	return c.write(func(*tables) error {
+		return fn(&conn{db: c.db, inTx: true})
+	})
+}
+
+// now returns the current time at the microsecond precision PostgreSQL
+// stores timestamps with.
+func now() time.Time {
+	return stamp(time.Now())
+}
+
+func stamp(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
+
+func stampPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := stamp(*t)
+	return &v
+}
+
+// ilike reports whether s matches the SQL ILIKE pattern, where % matches any
+// run of characters, _ a single one and a backslash escapes the next one.
+func ilike(s, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
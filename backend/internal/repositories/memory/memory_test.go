@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"notes/internal/repositories/memory"
+	"notes/internal/repositories/storetest"
+)
+
+func TestStores(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		db := memory.New()
+		return storetest.Stores{Notes: db.Notes(), Categories: db.Categories(), Users: db.Users()}
+	})
+}
@@ -0,0 +1,621 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+// sortableNoteFields lists the fields ListNotes can order by.
+var sortableNoteFields = map[string]bool{"created_at": true, "updated_at": true, "title": true, "position": true}
+
+type NoteStore struct {
+	conn *conn
+}
+
+func (ns *NoteStore) Transaction(ctx context.Context, fn func(tx repositories.NoteStore) error) error {
+	return ns.conn.transaction(func(tx *conn) error {
+		return fn(&NoteStore{conn: tx})
+	})
+}
+
+func (ns *NoteStore) Categories() repositories.CategoryStore {
+	return &CategoryStore{conn: ns.conn}
+}
+
+func (ns *NoteStore) Create(ctx context.Context, note *models.Note) (*models.Note, error) {
+	if note.UserID == 0 {
+		return nil, validations.ErrUserIdNotSet
+	}
+	err := ns.conn.write(func(t *tables) error {
+		if _, ok := t.users[note.UserID]; !ok {
+			return validations.ErrNoteCreate
+		}
+		if titleTaken(t, note.UserID, note.Title, 0) {
+			return validations.ErrDuplicateTitle
+		}
+		note.ID = ns.conn.db.next(&ns.conn.db.seq.notes)
+		if err := ns.conn.saveNoteCategories(t, note, false); err != nil {
+			return validations.ErrNoteCreate
+		}
+		ts := now()
+		if note.CreatedAt.IsZero() {
+			note.CreatedAt = ts
+		}
+		if note.UpdatedAt == nil {
+			note.UpdatedAt = &ts
+		}
+		if note.Version == 0 {
+			note.Version = 1
+		}
+		note.ChangeSeq = nextChangeSeq(t, note.UserID)
+		note.Position = firstPosition(t, note.UserID)
+		storeNote(t, note)
+		ns.conn.addRevision(t, models.NewNoteRevision(note, 1, note.UserID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// storeNote saves the note's row; its categories are kept in the join table.
+func storeNote(t *tables, note *models.Note) {
+	stored := *note
+	stored.Categories = nil
+	stored.Revisions = nil
+	stored.CreatedAt = stamp(stored.CreatedAt)
+	stored.UpdatedAt = stampPtr(stored.UpdatedAt)
+	if stored.DeletedAt.Valid {
+		stored.DeletedAt.Time = stamp(stored.DeletedAt.Time)
+	}
+	t.notes[note.ID] = stored
+}
+
+// titleTaken reports whether another live note of the user has the title,
+// which the partial unique index on notes forbids.
+func titleTaken(t *tables, userId uint, title string, exceptId uint) bool {
+	for _, n := range t.notes {
+		if n.ID != exceptId && n.UserID == userId && n.Title == title && !n.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// saveNoteCategories attaches the note's categories, creating those without
+// an ID. With replace set, categories no longer listed are detached.
+func (c *conn) saveNoteCategories(t *tables, note *models.Note, replace bool) error {
+	var ids []uint
+	if !replace {
+		ids = append(ids, t.noteCategories[note.ID]...)
+	}
+	for i := range note.Categories {
+		category := &note.Categories[i]
+		if _, ok := t.categories[category.ID]; !ok || category.ID == 0 {
+			if err := c.insertCategory(t, category); err != nil {
+				return err
+			}
+		}
+		if !contains(ids, category.ID) {
+			ids = append(ids, category.ID)
+		}
+	}
+	t.noteCategories[note.ID] = ids
+	return nil
+}
+
+func (c *conn) addRevision(t *tables, revision *models.NoteRevision) {
+	revision.ID = c.db.next(&c.db.seq.revisions)
+	stored := *revision
+	stored.Categories = append([]string{}, revision.Categories...)
+	stored.CreatedAt = stamp(stored.CreatedAt)
+	t.revisions[revision.NoteID] = append(t.revisions[revision.NoteID], stored)
+}
+
+// loadNote returns the stored note with its categories.
+func loadNote(t *tables, n models.Note) models.Note {
+	n.Categories = []models.Category{}
+	for _, id := range t.noteCategories[n.ID] {
+		if c, ok := t.categories[id]; ok {
+			n.Categories = append(n.Categories, c)
+		}
+	}
+	sort.Slice(n.Categories, func(i, j int) bool { return n.Categories[i].ID < n.Categories[j].ID })
+	return n
+}
+
+// liveNotes lists the notes outside the trash that match keep, by ID.
+func liveNotes(t *tables, keep func(n models.Note) bool) []models.Note {
+	return sortedNotes(t, func(n models.Note) bool {
+		return !n.DeletedAt.Valid && keep(n)
+	})
+}
+
+func sortedNotes(t *tables, keep func(n models.Note) bool) []models.Note {
+	var notes []models.Note
+	for _, n := range t.notes {
+		if keep(n) {
+			notes = append(notes, loadNote(t, n))
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes
+}
+
+func all(models.Note) bool { return true }
+
+func pointers(notes []models.Note) []*models.Note {
+	ptrs := make([]*models.Note, 0, len(notes))
+	for i := range notes {
+		ptrs = append(ptrs, &notes[i])
+	}
+	return ptrs
+}
+
+func (ns *NoteStore) GetAllNotes(ctx context.Context) ([]*models.Note, error) {
+	var notes []models.Note
+	ns.conn.read(func(t *tables) {
+		notes = liveNotes(t, all)
+	})
+	return pointers(notes), nil
+}
+
+func (ns *NoteStore) GetNotesByCategories(ctx context.Context, categoryNames []string) ([]*models.Note, error) {
+	var notes []models.Note
+	ns.conn.read(func(t *tables) {
+		notes = liveNotes(t, func(n models.Note) bool {
+			return hasCategory(t, n.ID, func(name string) bool {
+				for _, want := range categoryNames {
+					if name == want {
+						return true
+					}
+				}
+				return false
+			})
+		})
+	})
+	return pointers(notes), nil
+}
+
+// hasCategory reports whether one of the note's categories has a name
+// accepted by match.
+func hasCategory(t *tables, noteId uint, match func(name string) bool) bool {
+	for _, id := range t.noteCategories[noteId] {
+		if c, ok := t.categories[id]; ok && match(c.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyILike is the ILIKE ANY comparison used to filter by category.
+func matchesAnyILike(patterns []string) func(name string) bool {
+	return func(name string) bool {
+		for _, p := range patterns {
+			if ilike(name, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (ns *NoteStore) GetNoteById(ctx context.Context, id uint) (*models.Note, error) {
+	var note *models.Note
+	ns.conn.read(func(t *tables) {
+		if n, ok := t.notes[id]; ok && !n.DeletedAt.Valid {
+			loaded := loadNote(t, n)
+			note = &loaded
+		}
+	})
+	if note == nil {
+		return nil, validations.ErrNoteNotFound
+	}
+	return note, nil
+}
+
+// GetByTitle returns the user's live note with the title. As with the gorm
+// repository, its categories are not loaded.
+func (ns *NoteStore) GetByTitle(ctx context.Context, userId uint, title string) (*models.Note, error) {
+	var note *models.Note
+	ns.conn.read(func(t *tables) {
+		found := liveNotes(t, func(n models.Note) bool { return n.UserID == userId && n.Title == title })
+		if len(found) > 0 {
+			found[0].Categories = nil
+			note = &found[0]
+		}
+	})
+	if note == nil {
+		return nil, validations.ErrNotTitle
+	}
+	return note, nil
+}
+
+func (ns *NoteStore) UpdateNote(ctx context.Context, note *models.Note, actorId uint) (*uint, error) {
+	return ns.update(note, nil, true, actorId)
+}
+
+func (ns *NoteStore) PatchNote(ctx context.Context, note *models.Note, columns []string, replaceCategories bool, actorId uint) (*uint, error) {
+	return ns.update(note, columns, replaceCategories, actorId)
+}
+
+// update saves every column when columns is nil.
+func (ns *NoteStore) update(note *models.Note, columns []string, replaceCategories bool, actorId uint) (*uint, error) {
+	err := ns.conn.write(func(t *tables) error {
+		stored, ok := t.notes[note.ID]
+		if !ok || stored.DeletedAt.Valid || stored.Version != note.Version {
+			return validations.ErrVersionMismatch
+		}
+		note.Version++
+		stored.Version = note.Version
+		note.ChangeSeq = nextChangeSeq(t, stored.UserID)
+		stored.ChangeSeq = note.ChangeSeq
+
+		var lastRevision uint
+		for _, r := range t.revisions[note.ID] {
+			lastRevision = max(lastRevision, r.Revision)
+		}
+		if lastRevision == 0 {
+			lastRevision++
+			before := loadNote(t, stored)
+			ns.conn.addRevision(t, models.NewNoteRevision(&before, lastRevision, stored.UserID))
+		}
+
+		if replaceCategories {
+			if err := ns.conn.saveNoteCategories(t, note, true); err != nil {
+				return validations.ErrCatUpdate
+			}
+		}
+
+		ts := now()
+		if columns == nil {
+			// Pinning and moving are saved on their own, by PinNote and
+			// MoveNote.
+			pinned, position := stored.Pinned, stored.Position
+			stored = *note
+			stored.Pinned, stored.Position = pinned, position
+			stored.UpdatedAt = &ts
+			note.UpdatedAt = &ts
+		} else {
+			for _, column := range columns {
+				switch column {
+				case "title":
+					stored.Title = note.Title
+				case "content":
+					stored.Content = note.Content
+				case "is_archived":
+					stored.IsArchived = note.IsArchived
+				case "version":
+					stored.Version = note.Version
+				case "updated_at":
+					stored.UpdatedAt = &ts
+					note.UpdatedAt = &ts
+				}
+			}
+		}
+		if _, ok := t.users[stored.UserID]; !ok {
+			return validations.ErrNoteUpdate
+		}
+		if titleTaken(t, stored.UserID, stored.Title, stored.ID) {
+			return validations.ErrDuplicateTitle
+		}
+		storeNote(t, &stored)
+
+		ns.conn.addRevision(t, models.NewNoteRevision(note, lastRevision+1, actorId))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note.ID, nil
+}
+
+func (ns *NoteStore) GetRevisions(ctx context.Context, noteId uint) ([]models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	ns.conn.read(func(t *tables) {
+		revs := t.revisions[noteId]
+		for i := len(revs) - 1; i >= 0; i-- {
+			revisions = append(revisions, copyRevision(revs[i]))
+		}
+	})
+	return revisions, nil
+}
+
+func (ns *NoteStore) GetRevision(ctx context.Context, noteId uint, revision uint) (*models.NoteRevision, error) {
+	var found *models.NoteRevision
+	ns.conn.read(func(t *tables) {
+		for _, r := range t.revisions[noteId] {
+			if r.Revision == revision {
+				r = copyRevision(r)
+				found = &r
+				return
+			}
+		}
+	})
+	if found == nil {
+		return nil, validations.ErrRevisionNotFound
+	}
+	return found, nil
+}
+
+func copyRevision(r models.NoteRevision) models.NoteRevision {
+	r.Categories = append([]string{}, r.Categories...)
+	return r
+}
+
+// Delete moves the note to the trash and sets its DeletedAt.
+func (ns *NoteStore) Delete(ctx context.Context, note *models.Note) (*uint, error) {
+	noteId := note.ID
+	if err := ns.conn.write(func(t *tables) error {
+		trashNote(t, note)
+		return nil
+	}); err != nil {
+		return nil, validations.ErrNoteDelete
+	}
+	return &noteId, nil
+}
+
+func trashNote(t *tables, note *models.Note) {
+	stored, ok := t.notes[note.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return
+	}
+	stored.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+	stored.ChangeSeq = nextChangeSeq(t, stored.UserID)
+	t.notes[note.ID] = stored
+	note.DeletedAt = stored.DeletedAt
+	note.ChangeSeq = stored.ChangeSeq
+}
+
+func (ns *NoteStore) GetTrash(ctx context.Context, userId uint) ([]models.Note, error) {
+	var notes []models.Note
+	ns.conn.read(func(t *tables) {
+		notes = sortedNotes(t, func(n models.Note) bool { return n.UserID == userId && n.DeletedAt.Valid })
+	})
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].DeletedAt.Time.After(notes[j].DeletedAt.Time) })
+	return notes, nil
+}
+
+func (ns *NoteStore) Restore(ctx context.Context, userId uint, noteId uint) (*uint, error) {
+	err := ns.conn.write(func(t *tables) error {
+		stored, ok := t.notes[noteId]
+		if !ok || stored.UserID != userId || !stored.DeletedAt.Valid {
+			return validations.ErrTrashedNoteNotFound
+		}
+		if titleTaken(t, userId, stored.Title, noteId) {
+			return validations.ErrDuplicateTitle
+		}
+		ts := now()
+		stored.DeletedAt = gorm.DeletedAt{}
+		stored.UpdatedAt = &ts
+		stored.ChangeSeq = nextChangeSeq(t, userId)
+		t.notes[noteId] = stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &noteId, nil
+}
+
+func (ns *NoteStore) EmptyTrash(ctx context.Context, userId uint) (int64, error) {
+	return ns.purge(func(n models.Note) bool { return n.UserID == userId && n.DeletedAt.Valid })
+}
+
+func (ns *NoteStore) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	return ns.purge(func(n models.Note) bool { return n.DeletedAt.Valid && n.DeletedAt.Time.Before(cutoff) })
+}
+
+// purge leaves a tombstone for every note it deletes, in the order of their
+// IDs as the gorm repository does.
+func (ns *NoteStore) purge(match func(n models.Note) bool) (int64, error) {
+	var purged int64
+	err := ns.conn.write(func(t *tables) error {
+		for _, n := range sortedNotes(t, match) {
+			ns.conn.addTombstone(t, n.UserID, models.SyncNote, n.ID)
+			deleteNote(t, n.ID)
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, validations.ErrTrashDB
+	}
+	return purged, nil
+}
+
+// deleteNote removes the note for good, with its category associations,
+// revisions and permissions.
+func deleteNote(t *tables, id uint) {
+	delete(t.notes, id)
+	delete(t.noteCategories, id)
+	delete(t.revisions, id)
+	for pid, p := range t.permissions {
+		if p.NoteID == id {
+			delete(t.permissions, pid)
+		}
+	}
+}
+
+func (ns *NoteStore) TouchCategoryNotes(ctx context.Context, categoryId uint) ([]uint, error) {
+	var ids []uint
+	err := ns.conn.write(func(t *tables) error {
+		for noteId, categoryIds := range t.noteCategories {
+			if _, ok := t.notes[noteId]; ok && contains(categoryIds, categoryId) {
+				ids = append(ids, noteId)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, noteId := range ids {
+			stored := t.notes[noteId]
+			stored.Version++
+			stored.ChangeSeq = nextChangeSeq(t, stored.UserID)
+			t.notes[noteId] = stored
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, validations.ErrNoteUpdate
+	}
+	return ids, nil
+}
+
+func (ns *NoteStore) FilterNotes(ctx context.Context, isArchived *bool, categories []string) ([]models.Note, error) {
+	var notes []models.Note
+	ns.conn.read(func(t *tables) {
+		notes = liveNotes(t, func(n models.Note) bool {
+			if isArchived != nil && n.IsArchived != *isArchived {
+				return false
+			}
+			return len(categories) == 0 || hasCategory(t, n.ID, matchesAnyILike(categories))
+		})
+	})
+	if len(notes) == 0 {
+		return nil, validations.ErrNoNotesFound
+	}
+	return notes, nil
+}
+
+// ListNotes returns a page of the user's notes using keyset pagination on
+// the sort field with the note id as tie-breaker.
+func (ns *NoteStore) ListNotes(ctx context.Context, opts repositories.NoteListOptions) ([]models.Note, error) {
+	if !sortableNoteFields[opts.SortField] {
+		return nil, validations.ErrInvalidSort
+	}
+	var after time.Time
+	var afterNote models.Note
+	if opts.After != nil {
+		switch opts.SortField {
+		case "title":
+		case "position":
+			pinned, position, err := models.ParsePositionCursor(opts.After.Value)
+			if err != nil {
+				return nil, validations.ErrInvalidCursor
+			}
+			afterNote = models.Note{Pinned: pinned, Position: position}
+		default:
+			parsed, err := time.Parse(time.RFC3339Nano, opts.After.Value)
+			if err != nil {
+				return nil, validations.ErrInvalidCursor
+			}
+			after = parsed
+		}
+	}
+
+	// compare orders two notes by the sort field, then by ID, ascending.
+	compare := func(a, b models.Note) int {
+		var c int
+		switch opts.SortField {
+		case "title":
+			c = strings.Compare(a.Title, b.Title)
+		case "position":
+			c = comparePositions(a, b)
+		default:
+			c = sortTime(a, opts.SortField).Compare(sortTime(b, opts.SortField))
+		}
+		if c != 0 {
+			return c
+		}
+		switch {
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		}
+		return 0
+	}
+	afterCursor := func(n models.Note) bool {
+		if opts.After == nil {
+			return true
+		}
+		var c int
+		switch opts.SortField {
+		case "title":
+			c = strings.Compare(n.Title, opts.After.Value)
+		case "position":
+			c = comparePositions(n, afterNote)
+		default:
+			c = sortTime(n, opts.SortField).Compare(after)
+		}
+		if c == 0 {
+			c = compareIDs(n.ID, opts.After.ID)
+		}
+		if opts.Descending {
+			return c < 0
+		}
+		return c > 0
+	}
+
+	var notes []models.Note
+	ns.conn.read(func(t *tables) {
+		notes = liveNotes(t, func(n models.Note) bool {
+			if opts.SharedWith {
+				if findPermission(t, n.ID, opts.UserID) == nil {
+					return false
+				}
+			} else if n.UserID != opts.UserID {
+				return false
+			}
+			if opts.IsArchived != nil && n.IsArchived != *opts.IsArchived {
+				return false
+			}
+			if len(opts.Categories) > 0 && !hasCategory(t, n.ID, matchesAnyILike(opts.Categories)) {
+				return false
+			}
+			return afterCursor(n)
+		})
+	})
+	sort.SliceStable(notes, func(i, j int) bool {
+		if opts.Descending {
+			return compare(notes[i], notes[j]) > 0
+		}
+		return compare(notes[i], notes[j]) < 0
+	})
+	if opts.Limit > 0 && len(notes) > opts.Limit {
+		notes = notes[:opts.Limit]
+	}
+	return notes, nil
+}
+
+// sortTime is the time a note sorts by; notes never updated sort by their
+// creation time.
+func sortTime(n models.Note, field string) time.Time {
+	if field == "updated_at" && n.UpdatedAt != nil {
+		return *n.UpdatedAt
+	}
+	return n.CreatedAt
+}
+
+func compareIDs(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (ns *NoteStore) DeleteNoteByUserId(ctx context.Context, noteId uint, userId uint) (*uint, error) {
+	err := ns.conn.write(func(t *tables) error {
+		stored, ok := t.notes[noteId]
+		if !ok || stored.UserID != userId || stored.DeletedAt.Valid {
+			return validations.ErrNoteNotOwnedByUser
+		}
+		trashNote(t, &stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &noteId, nil
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"sort"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/validations"
+)
+
+// comparePositions orders two notes of a user manually: pinned first, then
+// by position.
+func comparePositions(a, b models.Note) int {
+	if a.Pinned != b.Pinned {
+		if a.Pinned {
+			return -1
+		}
+		return 1
+	}
+	return cmp.Compare(a.Position, b.Position)
+}
+
+// firstPosition returns the position that puts a new unpinned note of the
+// user before the others.
+func firstPosition(t *tables, userId uint) float64 {
+	first := float64(repositories.PositionStep)
+	for _, n := range t.notes {
+		if n.UserID == userId && !n.Pinned && !n.DeletedAt.Valid {
+			first = min(first, n.Position)
+		}
+	}
+	return first - repositories.PositionStep
+}
+
+func (ns *NoteStore) PinNote(ctx context.Context, note *models.Note, pinned bool) error {
+	return ns.conn.write(func(t *tables) error {
+		stored, ok := t.notes[note.ID]
+		if !ok || stored.DeletedAt.Valid {
+			return validations.ErrNoteUpdate
+		}
+		stored.Pinned = pinned
+		stored.Version++
+		stored.ChangeSeq = nextChangeSeq(t, stored.UserID)
+		t.notes[note.ID] = stored
+		note.Pinned = pinned
+		note.Version = stored.Version
+		note.ChangeSeq = stored.ChangeSeq
+		return nil
+	})
+}
+
+func (ns *NoteStore) MoveNote(ctx context.Context, note *models.Note, targetId uint, after bool) error {
+	return ns.conn.write(func(t *tables) error {
+		stored, ok := t.notes[note.ID]
+		if !ok || stored.DeletedAt.Valid {
+			return validations.ErrNoteNotFound
+		}
+		position, ok, err := positionNextTo(t, stored, targetId, after)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			rebalance(t, stored.UserID)
+			position, _, _ = positionNextTo(t, t.notes[note.ID], targetId, after)
+		}
+		stored = t.notes[note.ID]
+		stored.Position = position
+		stored.Version++
+		stored.ChangeSeq = nextChangeSeq(t, stored.UserID)
+		t.notes[note.ID] = stored
+		note.Pinned = stored.Pinned
+		note.Position = position
+		note.Version = stored.Version
+		note.ChangeSeq = stored.ChangeSeq
+		return nil
+	})
+}
+
+// positionNextTo finds the target's neighbour, leaving out the moved note,
+// and returns the position between them. The target must be another note of
+// the same user, pinned or not alike.
+func positionNextTo(t *tables, note models.Note, targetId uint, after bool) (float64, bool, error) {
+	target, ok := t.notes[targetId]
+	if !ok || target.DeletedAt.Valid || target.ID == note.ID || target.UserID != note.UserID {
+		return 0, false, validations.ErrMoveTarget
+	}
+	if target.Pinned != note.Pinned {
+		return 0, false, validations.ErrMovePinned
+	}
+	// compare orders notes by position, then by ID.
+	compare := func(a, b models.Note) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), compareIDs(a.ID, b.ID))
+	}
+	var neighbour *models.Note
+	for _, n := range t.notes {
+		if n.UserID != note.UserID || n.Pinned != target.Pinned || n.ID == note.ID || n.DeletedAt.Valid {
+			continue
+		}
+		closer := neighbour == nil
+		if after && compare(n, target) > 0 {
+			closer = closer || compare(n, *neighbour) < 0
+		} else if !after && compare(n, target) < 0 {
+			closer = closer || compare(n, *neighbour) > 0
+		} else {
+			continue
+		}
+		if closer {
+			found := n
+			neighbour = &found
+		}
+	}
+	var position *float64
+	if neighbour != nil {
+		position = &neighbour.Position
+	}
+	next, ok := repositories.PositionNextTo(target.Position, position, after)
+	return next, ok, nil
+}
+
+// rebalance spreads the user's notes PositionStep apart again, in the order
+// they are in. Every note it moves takes a new version and a change number
+// of its own.
+func rebalance(t *tables, userId uint) {
+	notes := liveNotes(t, func(n models.Note) bool { return n.UserID == userId })
+	sort.SliceStable(notes, func(i, j int) bool { return comparePositions(notes[i], notes[j]) < 0 })
+	for i, n := range notes {
+		stored := t.notes[n.ID]
+		stored.Position = float64(i) * repositories.PositionStep
+		stored.Version++
+		stored.ChangeSeq = nextChangeSeq(t, userId)
+		t.notes[n.ID] = stored
+	}
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"notes/internal/models"
+	"notes/pkg/validations"
+)
+
+// findPermission returns the user's grant on the note, nil when there is
+// none.
+func findPermission(t *tables, noteId, userId uint) *models.NotePermission {
+	for _, p := range t.permissions {
+		if p.NoteID == noteId && p.UserID == userId {
+			return &p
+		}
+	}
+	return nil
+}
+
+func (ns *NoteStore) GetPermission(ctx context.Context, noteId, userId uint) (*models.NotePermission, error) {
+	var permission *models.NotePermission
+	ns.conn.read(func(t *tables) {
+		permission = findPermission(t, noteId, userId)
+	})
+	return permission, nil
+}
+
+func (ns *NoteStore) SetPermission(ctx context.Context, permission *models.NotePermission) error {
+	return ns.conn.write(func(t *tables) error {
+		_, noteExists := t.notes[permission.NoteID]
+		_, userExists := t.users[permission.UserID]
+		if !noteExists || !userExists {
+			return validations.ErrPermissionDB
+		}
+		if existing := findPermission(t, permission.NoteID, permission.UserID); existing != nil {
+			existing.Role = permission.Role
+			t.permissions[existing.ID] = *existing
+			permission.ID, permission.CreatedAt = existing.ID, existing.CreatedAt
+			return nil
+		}
+		permission.ID = ns.conn.db.next(&ns.conn.db.seq.permissions)
+		permission.CreatedAt = stamp(permission.CreatedAt)
+		stored := *permission
+		stored.UserName = ""
+		t.permissions[permission.ID] = stored
+		return nil
+	})
+}
+
+func (ns *NoteStore) ListPermissions(ctx context.Context, noteId uint) ([]models.NotePermission, error) {
+	var permissions []models.NotePermission
+	ns.conn.read(func(t *tables) {
+		for _, p := range t.permissions {
+			if p.NoteID == noteId {
+				p.UserName = t.users[p.UserID].UserName
+				permissions = append(permissions, p)
+			}
+		}
+	})
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].UserName < permissions[j].UserName })
+	return permissions, nil
+}
+
+func (ns *NoteStore) DeletePermission(ctx context.Context, noteId, userId uint) (bool, error) {
+	var deleted bool
+	err := ns.conn.write(func(t *tables) error {
+		if p := findPermission(t, noteId, userId); p != nil {
+			delete(t.permissions, p.ID)
+			deleted = true
+		}
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"notes/internal/models"
+	"notes/pkg/fulltext"
+	"notes/pkg/validations"
+)
+
+// Search ranks the user's notes, and the notes shared with them, against a
+// web-style query across title, content and category names, highlighting
+// the matched terms.
+func (ns *NoteStore) Search(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error) {
+	q := fulltext.Parse(query)
+	var results []models.NoteSearchResult
+	ns.conn.read(func(t *tables) {
+		readable := func(n models.Note) bool {
+			return n.UserID == userId || findPermission(t, n.ID, userId) != nil
+		}
+		for _, n := range liveNotes(t, readable) {
+			names := make([]string, 0, len(n.Categories))
+			for _, c := range n.Categories {
+				names = append(names, c.Name)
+			}
+			rank, ok := q.Rank(
+				fulltext.Field{Text: n.Title, Weight: fulltext.WeightA},
+				fulltext.Field{Text: n.Content, Weight: fulltext.WeightB},
+				fulltext.Field{Text: strings.Join(names, " "), Weight: fulltext.WeightC},
+			)
+			if !ok {
+				continue
+			}
+			results = append(results, models.NoteSearchResult{
+				Note:           n,
+				Rank:           rank,
+				TitleSnippet:   q.Highlight(n.Title),
+				ContentSnippet: q.Snippet(n.Content),
+			})
+		}
+	})
+	if len(results) == 0 {
+		return nil, validations.ErrNoNotesFound
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if limit >= 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
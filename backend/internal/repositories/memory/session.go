@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"notes/internal/models"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+type SessionStore struct {
+	conn *conn
+}
+
+func (ss *SessionStore) Create(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	return ss.conn.write(func(t *tables) error {
+		if _, ok := t.sessions[session.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		stored := *session
+		stored.RefreshTokens = nil
+		stored.CreatedAt = stamp(stored.CreatedAt)
+		stored.LastUsedAt = stamp(stored.LastUsedAt)
+		stored.ExpiresAt = stamp(stored.ExpiresAt)
+		t.sessions[session.ID] = stored
+		return createRefreshToken(ss.conn.db, t, token)
+	})
+}
+
+func createRefreshToken(db *DB, t *tables, token *models.RefreshToken) error {
+	for _, rt := range t.refreshTokens {
+		if rt.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	token.ID = db.next(&db.seq.refreshTokens)
+	stored := *token
+	stored.CreatedAt = stamp(stored.CreatedAt)
+	t.refreshTokens[token.ID] = stored
+	return nil
+}
+
+func (ss *SessionStore) FindActive(ctx context.Context, id string, now time.Time) (*models.Session, error) {
+	var session *models.Session
+	ss.conn.read(func(t *tables) {
+		if s, ok := t.sessions[id]; ok && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			session = &s
+		}
+	})
+	if session == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (ss *SessionStore) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, *models.Session, error) {
+	var token *models.RefreshToken
+	var session *models.Session
+	ss.conn.read(func(t *tables) {
+		for _, rt := range t.refreshTokens {
+			if rt.TokenHash != tokenHash {
+				continue
+			}
+			if s, ok := t.sessions[rt.SessionID]; ok {
+				token, session = &rt, &s
+			}
+			return
+		}
+	})
+	if token == nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return token, session, nil
+}
+
+func (ss *SessionStore) Rotate(ctx context.Context, used *models.RefreshToken, next *models.RefreshToken, now time.Time, expiresAt time.Time) error {
+	return ss.conn.write(func(t *tables) error {
+		stored, ok := t.refreshTokens[used.ID]
+		if !ok || stored.UsedAt != nil {
+			return validations.ErrRefreshTokenReuse
+		}
+		usedAt := stamp(now)
+		stored.UsedAt = &usedAt
+		t.refreshTokens[used.ID] = stored
+		if err := createRefreshToken(ss.conn.db, t, next); err != nil {
+			return err
+		}
+		if session, ok := t.sessions[used.SessionID]; ok {
+			session.LastUsedAt = stamp(now)
+			session.ExpiresAt = stamp(expiresAt)
+			t.sessions[used.SessionID] = session
+		}
+		return nil
+	})
+}
+
+func (ss *SessionStore) Revoke(ctx context.Context, id string, now time.Time) error {
+	return ss.revokeWhere(now, func(s models.Session) bool { return s.ID == id })
+}
+
+func (ss *SessionStore) RevokeAllForUser(ctx context.Context, userId uint, now time.Time) error {
+	return ss.revokeWhere(now, func(s models.Session) bool { return s.UserID == userId })
+}
+
+func (ss *SessionStore) RevokeOthersForUser(ctx context.Context, userId uint, keepId string, now time.Time) error {
+	return ss.revokeWhere(now, func(s models.Session) bool { return s.UserID == userId && s.ID != keepId })
+}
+
+// revokeWhere revokes the sessions accepted by match that are not revoked
+// yet.
+func (ss *SessionStore) revokeWhere(now time.Time, match func(s models.Session) bool) error {
+	return ss.conn.write(func(t *tables) error {
+		revokedAt := stamp(now)
+		for id, s := range t.sessions {
+			if s.RevokedAt == nil && match(s) {
+				s.RevokedAt = &revokedAt
+				t.sessions[id] = s
+			}
+		}
+		return nil
+	})
+}
+
+// deleteSession deletes the session with its refresh tokens, as the foreign
+// key cascade does.
+func deleteSession(t *tables, id string) {
+	for tokenId, rt := range t.refreshTokens {
+		if rt.SessionID == id {
+			delete(t.refreshTokens, tokenId)
+		}
+	}
+	delete(t.sessions, id)
+}
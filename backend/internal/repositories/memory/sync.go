@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"notes/internal/models"
+)
+
+// nextChangeSeq takes the next number of the user's change sequence, zero
+// when there is no such user, as the UPDATE of the gorm repositories finds
+// no row.
+func nextChangeSeq(t *tables, userId uint) uint64 {
+	if _, ok := t.users[userId]; !ok {
+		return 0
+	}
+	t.changeSeqs[userId]++
+	return t.changeSeqs[userId]
+}
+
+// addTombstone records the deletion of a note or category under the next
+// number of the user's change sequence.
+func (c *conn) addTombstone(t *tables, userId uint, kind models.SyncKind, entityId uint) {
+	t.tombstones = append(t.tombstones, models.SyncTombstone{
+		ID:        c.db.next(&c.db.seq.tombstones),
+		UserID:    userId,
+		Kind:      kind,
+		EntityID:  entityId,
+		ChangeSeq: nextChangeSeq(t, userId),
+		DeletedAt: now(),
+	})
+}
+
+func (ns *NoteStore) Changes(ctx context.Context, userId uint, since uint64, limit int) (*models.ChangeSet, error) {
+	set := &models.ChangeSet{}
+	ns.conn.read(func(t *tables) {
+		set.Latest, set.PrunedThrough = t.changeSeqs[userId], t.prunedSeqs[userId]
+		changed := func(owner uint, seq uint64) bool {
+			return owner == userId && seq > since && seq <= set.Latest
+		}
+
+		for _, n := range t.notes {
+			if changed(n.UserID, n.ChangeSeq) {
+				set.Notes = append(set.Notes, loadNote(t, n))
+			}
+		}
+		sort.Slice(set.Notes, func(i, j int) bool { return set.Notes[i].ChangeSeq < set.Notes[j].ChangeSeq })
+		set.Notes = set.Notes[:min(len(set.Notes), limit)]
+
+		for _, c := range t.categories {
+			if changed(c.UserID, c.ChangeSeq) {
+				set.Categories = append(set.Categories, c)
+			}
+		}
+		sort.Slice(set.Categories, func(i, j int) bool { return set.Categories[i].ChangeSeq < set.Categories[j].ChangeSeq })
+		set.Categories = set.Categories[:min(len(set.Categories), limit)]
+
+		for _, ts := range t.tombstones {
+			if changed(ts.UserID, ts.ChangeSeq) && len(set.Tombstones) < limit {
+				set.Tombstones = append(set.Tombstones, ts)
+			}
+		}
+	})
+	return set, nil
+}
+
+func (ns *NoteStore) PruneTombstones(ctx context.Context, cutoff time.Time) (int64, error) {
+	var pruned int64
+	err := ns.conn.write(func(t *tables) error {
+		kept := t.tombstones[:0:0]
+		for _, ts := range t.tombstones {
+			if !ts.DeletedAt.Before(cutoff) {
+				kept = append(kept, ts)
+				continue
+			}
+			if _, ok := t.users[ts.UserID]; ok {
+				t.prunedSeqs[ts.UserID] = max(t.prunedSeqs[ts.UserID], ts.ChangeSeq)
+			}
+			pruned++
+		}
+		t.tombstones = kept
+		return nil
+	})
+	return pruned, err
+}
@@ -0,0 +1,394 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"notes/internal/models"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+type UserStore struct {
+	conn *conn
+}
+
+func (us *UserStore) CreateUser(ctx context.Context, user *models.User) error {
+	return us.conn.write(func(t *tables) error {
+		for _, u := range t.users {
+			if u.UserName == user.UserName {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+		if user.ID == 0 {
+			user.ID = us.conn.db.next(&us.conn.db.seq.users)
+		} else if _, ok := t.users[user.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		ts := now()
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = ts
+		}
+		if user.UpdatedAt == nil {
+			user.UpdatedAt = &ts
+		}
+		stored := *user
+		stored.Notes, stored.Categories, stored.Sessions = nil, nil, nil
+		stored.RecoveryCodes, stored.AccessTokens = nil, nil
+		stored.CreatedAt = stamp(stored.CreatedAt)
+		stored.UpdatedAt = stampPtr(stored.UpdatedAt)
+		stored.PasswordResetExpiresAt = stampPtr(stored.PasswordResetExpiresAt)
+		t.users[user.ID] = stored
+		return nil
+	})
+}
+
+// sortedUsers lists the users accepted by keep, by ID.
+func sortedUsers(t *tables, keep func(u models.User) bool) []models.User {
+	var users []models.User
+	for _, u := range t.users {
+		if keep(u) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// withNotes returns the user with their live notes and their categories.
+func withNotes(t *tables, u models.User) *models.User {
+	u.Notes = liveNotes(t, func(n models.Note) bool { return n.UserID == u.ID })
+	return &u
+}
+
+func (us *UserStore) GetUserByID(ctx context.Context, userId uint) (*models.User, error) {
+	var user *models.User
+	us.conn.read(func(t *tables) {
+		if u, ok := t.users[userId]; ok {
+			user = withNotes(t, u)
+		}
+	})
+	if user == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// GetUserByUsername matches the name case-insensitively.
+func (us *UserStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user *models.User
+	us.conn.read(func(t *tables) {
+		found := sortedUsers(t, func(u models.User) bool { return ilike(u.UserName, username) })
+		if len(found) > 0 {
+			user = withNotes(t, found[0])
+		}
+	})
+	if user == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// updateUsers applies change to every user accepted by match, as an UPDATE
+// statement would, and returns how many there were.
+func updateUsers(t *tables, match func(u models.User) bool, change func(u *models.User)) int {
+	updated := 0
+	ts := now()
+	for id, u := range t.users {
+		if match(u) {
+			change(&u)
+			u.UpdatedAt = &ts
+			t.users[id] = u
+			updated++
+		}
+	}
+	return updated
+}
+
+func byID(userId uint) func(u models.User) bool {
+	return func(u models.User) bool { return u.ID == userId }
+}
+
+// byName matches the user name case-insensitively, as ILIKE does.
+func byName(username string) func(u models.User) bool {
+	return func(u models.User) bool { return ilike(u.UserName, username) }
+}
+
+// SetTOTPSecret starts a TOTP enrollment, replacing any unconfirmed secret.
+func (us *UserStore) SetTOTPSecret(ctx context.Context, userId uint, secret string) error {
+	return us.conn.write(func(t *tables) error {
+		updateUsers(t, func(u models.User) bool { return u.ID == userId && !u.TOTPEnabled }, func(u *models.User) {
+			u.TOTPSecret = secret
+			u.TOTPLastStep = 0
+		})
+		return nil
+	})
+}
+
+// EnableTOTP turns two-factor authentication on and replaces the user's
+// recovery codes.
+func (us *UserStore) EnableTOTP(ctx context.Context, userId uint, step int64, codes []models.RecoveryCode) error {
+	return us.conn.write(func(t *tables) error {
+		updateUsers(t, byID(userId), func(u *models.User) {
+			u.TOTPEnabled = true
+			u.TOTPLastStep = step
+		})
+		delete(t.recoveryCodes, userId)
+		if len(codes) == 0 {
+			return gorm.ErrEmptySlice
+		}
+		for i := range codes {
+			if _, ok := t.users[codes[i].UserID]; !ok {
+				return gorm.ErrForeignKeyViolated
+			}
+			codes[i].ID = us.conn.db.next(&us.conn.db.seq.recoveryCodes)
+			if codes[i].CreatedAt.IsZero() {
+				codes[i].CreatedAt = now()
+			}
+			stored := codes[i]
+			stored.CreatedAt = stamp(stored.CreatedAt)
+			stored.UsedAt = stampPtr(stored.UsedAt)
+			t.recoveryCodes[stored.UserID] = append(t.recoveryCodes[stored.UserID], stored)
+		}
+		return nil
+	})
+}
+
+// DisableTOTP turns two-factor authentication off and drops the secret and
+// the recovery codes.
+func (us *UserStore) DisableTOTP(ctx context.Context, userId uint) error {
+	return us.conn.write(func(t *tables) error {
+		updateUsers(t, byID(userId), func(u *models.User) {
+			u.TOTPEnabled = false
+			u.TOTPSecret = ""
+			u.TOTPLastStep = 0
+		})
+		delete(t.recoveryCodes, userId)
+		return nil
+	})
+}
+
+// UseTOTPStep records step as the last accepted one, failing with
+// ErrInvalidTOTPCode when a code of that step or a later one was used.
+func (us *UserStore) UseTOTPStep(ctx context.Context, userId uint, step int64) error {
+	return us.conn.write(func(t *tables) error {
+		updated := updateUsers(t, func(u models.User) bool { return u.ID == userId && u.TOTPLastStep < step }, func(u *models.User) {
+			u.TOTPLastStep = step
+		})
+		if updated == 0 {
+			return validations.ErrInvalidTOTPCode
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode spends the user's unused recovery code with the given hash.
+func (us *UserStore) UseRecoveryCode(ctx context.Context, userId uint, codeHash string, now time.Time) error {
+	return us.conn.write(func(t *tables) error {
+		used := stamp(now)
+		spent := false
+		for i, c := range t.recoveryCodes[userId] {
+			if c.CodeHash == codeHash && c.UsedAt == nil {
+				t.recoveryCodes[userId][i].UsedAt = &used
+				spent = true
+			}
+		}
+		if !spent {
+			return validations.ErrInvalidTOTPCode
+		}
+		return nil
+	})
+}
+
+func (us *UserStore) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+	return us.conn.write(func(t *tables) error {
+		updateUsers(t, byID(userId), func(u *models.User) { u.Password = passwordHash })
+		return nil
+	})
+}
+
+// ListUsers returns up to limit users with an ID above afterId, with the
+// number of notes each has outside the trash.
+func (us *UserStore) ListUsers(ctx context.Context, afterId uint, limit int) ([]models.UserSummary, error) {
+	var users []models.UserSummary
+	us.conn.read(func(t *tables) {
+		for _, u := range sortedUsers(t, func(u models.User) bool { return u.ID > afterId }) {
+			if limit >= 0 && len(users) == limit {
+				return
+			}
+			users = append(users, models.UserSummary{
+				ID:         u.ID,
+				UserName:   u.UserName,
+				IsAdmin:    u.IsAdmin,
+				IsDisabled: u.IsDisabled,
+				NoteCount:  int64(len(liveNotes(t, func(n models.Note) bool { return n.UserID == u.ID }))),
+				CreatedAt:  u.CreatedAt,
+			})
+		}
+	})
+	return users, nil
+}
+
+// GetAuthStatus loads only the fields PROTECT needs.
+func (us *UserStore) GetAuthStatus(ctx context.Context, userId uint) (*models.User, error) {
+	var user *models.User
+	us.conn.read(func(t *tables) {
+		if u, ok := t.users[userId]; ok {
+			user = &models.User{ID: u.ID, IsAdmin: u.IsAdmin, IsDisabled: u.IsDisabled}
+		}
+	})
+	if user == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (us *UserStore) SetDisabled(ctx context.Context, userId uint, disabled bool) (bool, error) {
+	var updated int
+	err := us.conn.write(func(t *tables) error {
+		updated = updateUsers(t, byID(userId), func(u *models.User) { u.IsDisabled = disabled })
+		return nil
+	})
+	return updated > 0, err
+}
+
+func (us *UserStore) PromoteToAdmin(ctx context.Context, username string) (bool, error) {
+	var updated int
+	err := us.conn.write(func(t *tables) error {
+		updated = updateUsers(t, byName(username), func(u *models.User) { u.IsAdmin = true })
+		return nil
+	})
+	return updated > 0, err
+}
+
+func (us *UserStore) RequirePasswordReset(ctx context.Context, userId uint, tokenHash string, expiresAt time.Time) (bool, error) {
+	var updated int
+	err := us.conn.write(func(t *tables) error {
+		updated = updateUsers(t, byID(userId), func(u *models.User) {
+			u.PasswordResetHash = tokenHash
+			u.PasswordResetExpiresAt = stampPtr(&expiresAt)
+		})
+		return nil
+	})
+	return updated > 0, err
+}
+
+// ResetPassword replaces the password of the user holding an unexpired reset
+// token with the given hash, failing with ErrResetToken when there is none.
+func (us *UserStore) ResetPassword(ctx context.Context, username, tokenHash, passwordHash string, now time.Time) error {
+	return us.conn.write(func(t *tables) error {
+		match := func(u models.User) bool {
+			return byName(username)(u) && u.PasswordResetHash == tokenHash &&
+				u.PasswordResetExpiresAt != nil && u.PasswordResetExpiresAt.After(now)
+		}
+		updated := updateUsers(t, match, func(u *models.User) {
+			u.Password = passwordHash
+			u.PasswordResetHash = ""
+			u.PasswordResetExpiresAt = nil
+		})
+		if updated == 0 {
+			return validations.ErrResetToken
+		}
+		return nil
+	})
+}
+
+// ExportData collects everything stored about the user: their notes,
+// including those in the trash, categories and revision metadata.
+func (us *UserStore) ExportData(ctx context.Context, userId uint) (*models.AccountExport, error) {
+	var export *models.AccountExport
+	us.conn.read(func(t *tables) {
+		user, ok := t.users[userId]
+		if !ok {
+			return
+		}
+		export = &models.AccountExport{
+			Profile: models.AccountProfile{
+				ID:               user.ID,
+				UserName:         user.UserName,
+				IsAdmin:          user.IsAdmin,
+				TwoFactorEnabled: user.TOTPEnabled,
+				CreatedAt:        user.CreatedAt,
+				UpdatedAt:        user.UpdatedAt,
+			},
+			Notes:      []models.Note{},
+			Categories: []models.Category{},
+			Revisions:  []models.RevisionMetadata{},
+		}
+		export.Notes = append(export.Notes, sortedNotes(t, func(n models.Note) bool { return n.UserID == userId })...)
+		for _, c := range sortedCategories(t) {
+			if c.UserID == userId {
+				export.Categories = append(export.Categories, c)
+			}
+		}
+		sort.SliceStable(export.Categories, func(i, j int) bool {
+			return strings.Compare(export.Categories[i].Name, export.Categories[j].Name) < 0
+		})
+		for _, n := range export.Notes {
+			for _, r := range t.revisions[n.ID] {
+				export.Revisions = append(export.Revisions, models.RevisionMetadata{
+					NoteID:     r.NoteID,
+					Revision:   r.Revision,
+					Title:      r.Title,
+					Categories: append([]string{}, r.Categories...),
+					IsArchived: r.IsArchived,
+					ActorID:    r.ActorID,
+					CreatedAt:  r.CreatedAt,
+				})
+			}
+		}
+	})
+	if export == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return export, nil
+}
+
+// DeleteUser permanently deletes the user with their notes, categories,
+// recovery codes and tombstones. It reports false when there is no such user.
+func (us *UserStore) DeleteUser(ctx context.Context, userId uint) (bool, error) {
+	var deleted bool
+	err := us.conn.write(func(t *tables) error {
+		if _, ok := t.users[userId]; !ok {
+			return nil
+		}
+		for id, n := range t.notes {
+			if n.UserID == userId {
+				deleteNote(t, id)
+			}
+		}
+		for id, c := range t.categories {
+			if c.UserID == userId {
+				detachCategory(t, id)
+				delete(t.categories, id)
+			}
+		}
+		for id, p := range t.permissions {
+			if p.UserID == userId {
+				delete(t.permissions, id)
+			}
+		}
+		for id, s := range t.sessions {
+			if s.UserID == userId {
+				deleteSession(t, id)
+			}
+		}
+		delete(t.recoveryCodes, userId)
+		delete(t.changeSeqs, userId)
+		delete(t.prunedSeqs, userId)
+		kept := t.tombstones[:0:0]
+		for _, ts := range t.tombstones {
+			if ts.UserID != userId {
+				kept = append(kept, ts)
+			}
+		}
+		t.tombstones = kept
+		delete(t.users, userId)
+		deleted = true
+		return nil
+	})
+	return deleted, err
+}
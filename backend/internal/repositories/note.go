@@ -2,15 +2,45 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"notes/internal/configs"
 	"notes/internal/models"
+	"notes/pkg/fulltext"
+	"notes/pkg/pagination"
 	"notes/pkg/validations"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+// NoteListOptions narrows and orders one page of a user's notes. Limit is
+// the number of rows fetched; After, when set, is the last row of the
+// previous page. With SharedWith the page lists the notes other users shared
+// with the user instead of the user's own.
+type NoteListOptions struct {
+	UserID     uint
+	SharedWith bool
+	IsArchived *bool
+	Categories []string
+	SortField  string
+	Descending bool
+	Limit      int
+	After      *pagination.Cursor
+}
+
+// noteSortColumns maps the sortable fields to their SQL expressions. Notes
+// that were never updated sort by their creation time. Manual order puts
+// pinned notes first, then goes by position.
+var noteSortColumns = map[string][]string{
+	"created_at": {"notes.created_at"},
+	"updated_at": {"COALESCE(notes.updated_at, notes.created_at)"},
+	"title":      {"notes.title"},
+	"position":   {"CASE WHEN notes.pinned THEN 0 ELSE 1 END", "notes.position"},
+}
+
 type NoteRepository struct {
 	db           *gorm.DB
 	config       *configs.Config
@@ -25,12 +55,51 @@ func NewNoteRepository(db *gorm.DB, config *configs.Config, categoryRepo *Catego
 	}
 }
 
+// Transaction runs fn with a note repository, and its category repository,
+// bound to a single database transaction that commits when fn returns nil.
+// Transactions opened by their methods become savepoints of it.
+func (nr *NoteRepository) Transaction(ctx context.Context, fn func(tx NoteStore) error) error {
+	return nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&NoteRepository{
+			db:           tx,
+			config:       nr.config,
+			categoryRepo: NewCategoryRepository(tx, nr.config),
+		})
+	})
+}
+
+// Categories returns the category repository sharing this repository's
+// connection, so both take part in the same transaction.
+func (nr *NoteRepository) Categories() CategoryStore {
+	return nr.categoryRepo
+}
+
 func (nr *NoteRepository) Create(ctx context.Context, note *models.Note) (*models.Note, error) {
 	if note.UserID == 0 {
 		return nil, validations.ErrUserIdNotSet
 	}
-	if err := nr.db.WithContext(ctx).Create(&note).Error; err != nil {
-		return nil, validations.ErrNoteCreate
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return validations.ErrNoteCreate
+		}
+		note.ChangeSeq = seq
+		if note.Position, err = firstPosition(tx, note.UserID); err != nil {
+			return validations.ErrNoteCreate
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return validations.ErrDuplicateTitle
+			}
+			return validations.ErrNoteCreate
+		}
+		if err := tx.Create(models.NewNoteRevision(note, 1, note.UserID)).Error; err != nil {
+			return validations.ErrRevisionCreate
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return note, nil
 }
@@ -79,9 +148,9 @@ func (nr *NoteRepository) GetNoteById(ctx context.Context, id uint) (*models.Not
 	return &note, nil
 }
 
-func (nr *NoteRepository) GetByTitle(ctx context.Context, title string) (*models.Note, error) {
+func (nr *NoteRepository) GetByTitle(ctx context.Context, userId uint, title string) (*models.Note, error) {
 	var note models.Note
-	if err := nr.db.WithContext(ctx).Where("title = ?", title).First(&note).Error; err != nil {
+	if err := nr.db.WithContext(ctx).Where("user_id = ? AND title = ?", userId, title).First(&note).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, validations.ErrNotTitle
 		}
@@ -91,33 +160,254 @@ func (nr *NoteRepository) GetByTitle(ctx context.Context, title string) (*models
 	return &note, nil
 }
 
-func (nr *NoteRepository) UpdateNote(ctx context.Context, note *models.Note) (*uint, error) {
-	tx := nr.db.WithContext(ctx).Begin()
-	defer tx.Rollback()
+// UpdateNote saves the note and records the resulting state as a new
+// revision in the same transaction. Notes that predate revision history get
+// their stored state recorded first, so the edit can still be undone.
+//
+// The note's Version must match the stored one; it is bumped in the same
+// statement that checks it, so of two concurrent updates starting from the
+// same version only the first succeeds and the other gets ErrVersionMismatch.
+func (nr *NoteRepository) UpdateNote(ctx context.Context, note *models.Note, actorId uint) (*uint, error) {
+	return nr.update(ctx, note, nil, true, actorId)
+}
 
-	if err := tx.Model(note).Association("Categories").Replace(note.Categories); err != nil {
-		return nil, validations.ErrCatUpdate
-	}
+// PatchNote works like UpdateNote but writes only the given columns, and
+// replaces the note's categories only when replaceCategories is set. The
+// revision still records the full resulting state of the note.
+func (nr *NoteRepository) PatchNote(ctx context.Context, note *models.Note, columns []string, replaceCategories bool, actorId uint) (*uint, error) {
+	return nr.update(ctx, note, columns, replaceCategories, actorId)
+}
 
-	if err := tx.Save(note).Error; err != nil {
-		return nil, validations.ErrNoteUpdate
-	}
+// update saves every column when columns is nil.
+func (nr *NoteRepository) update(ctx context.Context, note *models.Note, columns []string, replaceCategories bool, actorId uint) (*uint, error) {
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return validations.ErrNoteUpdate
+		}
+		bump := tx.Model(&models.Note{}).
+			Where("id = ? AND version = ?", note.ID, note.Version).
+			UpdateColumns(map[string]any{"version": gorm.Expr("version + 1"), "change_seq": seq})
+		if bump.Error != nil {
+			return validations.ErrNoteUpdate
+		}
+		if bump.RowsAffected == 0 {
+			return validations.ErrVersionMismatch
+		}
+		note.Version++
+		note.ChangeSeq = seq
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, validations.ErrNoteUpdate
+		var lastRevision uint
+		if err := tx.Model(&models.NoteRevision{}).
+			Where("note_id = ?", note.ID).
+			Select("COALESCE(MAX(revision), 0)").
+			Scan(&lastRevision).Error; err != nil {
+			return validations.ErrFetchingRevisions
+		}
+		if lastRevision == 0 {
+			var stored models.Note
+			if err := tx.Preload("Categories").First(&stored, note.ID).Error; err != nil {
+				return validations.ErrFetchingNote
+			}
+			lastRevision++
+			if err := tx.Create(models.NewNoteRevision(&stored, lastRevision, stored.UserID)).Error; err != nil {
+				return validations.ErrRevisionCreate
+			}
+		}
+
+		if replaceCategories {
+			if err := tx.Model(note).Association("Categories").Replace(note.Categories); err != nil {
+				return validations.ErrCatUpdate
+			}
+		}
+
+		var saved *gorm.DB
+		if columns == nil {
+			// Pinning and moving are saved on their own, by PinNote and
+			// MoveNote.
+			saved = tx.Omit("pinned", "position").Save(note)
+		} else {
+			saved = tx.Model(note).Select(columns).Updates(note)
+		}
+		if err := saved.Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return validations.ErrDuplicateTitle
+			}
+			return validations.ErrNoteUpdate
+		}
+
+		if err := tx.Create(models.NewNoteRevision(note, lastRevision+1, actorId)).Error; err != nil {
+			return validations.ErrRevisionCreate
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &note.ID, nil
 }
 
+// GetRevisions lists a note's revisions, newest first.
+func (nr *NoteRepository) GetRevisions(ctx context.Context, noteId uint) ([]models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	if err := nr.db.WithContext(ctx).
+		Where("note_id = ?", noteId).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, validations.ErrFetchingRevisions
+	}
+	return revisions, nil
+}
+
+func (nr *NoteRepository) GetRevision(ctx context.Context, noteId uint, revision uint) (*models.NoteRevision, error) {
+	var noteRevision models.NoteRevision
+	if err := nr.db.WithContext(ctx).
+		Where("note_id = ? AND revision = ?", noteId, revision).
+		First(&noteRevision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, validations.ErrRevisionNotFound
+		}
+		return nil, validations.ErrFetchingRevisions
+	}
+	return &noteRevision, nil
+}
+
+// Delete moves the note to the trash. Its categories are kept so that a
+// restore brings it back unchanged.
 func (nr *NoteRepository) Delete(ctx context.Context, note *models.Note) (*uint, error) {
 	noteId := note.ID
-	if err := nr.db.WithContext(ctx).Select("Categories").Delete(&note).Error; err != nil {
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(note).Error; err != nil {
+			return err
+		}
+		note.ChangeSeq = seq
+		return tx.Unscoped().Model(&models.Note{}).Where("id = ?", noteId).UpdateColumn("change_seq", seq).Error
+	})
+	if err != nil {
 		return nil, validations.ErrNoteDelete
 	}
 	return &noteId, nil
 }
 
+func (nr *NoteRepository) GetTrash(ctx context.Context, userId uint) ([]models.Note, error) {
+	var notes []models.Note
+	if err := nr.db.WithContext(ctx).Unscoped().
+		Preload("Categories").
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at DESC").
+		Find(&notes).Error; err != nil {
+		return nil, validations.ErrTrashDB
+	}
+	return notes, nil
+}
+
+func (nr *NoteRepository) Restore(ctx context.Context, userId uint, noteId uint) (*uint, error) {
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, userId)
+		if err != nil {
+			return validations.ErrNoteRestore
+		}
+		res := tx.Unscoped().
+			Model(&models.Note{}).
+			Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", noteId, userId).
+			Updates(map[string]any{"deleted_at": nil, "change_seq": seq})
+		if res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+				return validations.ErrDuplicateTitle
+			}
+			return validations.ErrNoteRestore
+		}
+		if res.RowsAffected == 0 {
+			return validations.ErrTrashedNoteNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &noteId, nil
+}
+
+// EmptyTrash permanently deletes every trashed note of the user.
+func (nr *NoteRepository) EmptyTrash(ctx context.Context, userId uint) (int64, error) {
+	return nr.purge(ctx, nr.db.Where("user_id = ? AND deleted_at IS NOT NULL", userId))
+}
+
+// PurgeTrash permanently deletes notes that were trashed before the cutoff.
+func (nr *NoteRepository) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	return nr.purge(ctx, nr.db.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff))
+}
+
+// purge leaves a tombstone for every note it deletes.
+func (nr *NoteRepository) purge(ctx context.Context, scope *gorm.DB) (int64, error) {
+	var purged int64
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var notes []models.Note
+		if err := tx.Unscoped().Select("id", "user_id").Where(scope).Order("id").Find(&notes).Error; err != nil {
+			return err
+		}
+		if len(notes) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(notes))
+		for _, n := range notes {
+			if err := addTombstone(tx, n.UserID, models.SyncNote, n.ID); err != nil {
+				return err
+			}
+			ids = append(ids, n.ID)
+		}
+		if err := tx.Exec("DELETE FROM note_categories WHERE note_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Delete(&models.Note{}, ids)
+		purged = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, validations.ErrTrashDB
+	}
+	return purged, nil
+}
+
+// TouchCategoryNotes bumps the version of the category's notes, whose
+// representation changes when the category is renamed, merged or deleted, so
+// that ETags handed out before no longer match. Each note also takes a new
+// change number, for clients syncing it to pick up the new category.
+func (nr *NoteRepository) TouchCategoryNotes(ctx context.Context, categoryId uint) ([]uint, error) {
+	var ids []uint
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var notes []models.Note
+		if err := tx.Unscoped().Select("id", "user_id").
+			Where("id IN (SELECT note_id FROM note_categories WHERE category_id = ?)", categoryId).
+			Order("id").
+			Find(&notes).Error; err != nil {
+			return err
+		}
+		for _, n := range notes {
+			seq, err := nextChangeSeq(tx, n.UserID)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Note{}).
+				Where("id = ?", n.ID).
+				UpdateColumns(map[string]any{"version": gorm.Expr("version + 1"), "change_seq": seq}).Error; err != nil {
+				return err
+			}
+			ids = append(ids, n.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, validations.ErrNoteUpdate
+	}
+	return ids, nil
+}
+
 func (nr *NoteRepository) FilterNotes(ctx context.Context, isArchived *bool, categories []string) ([]models.Note, error) {
 	var notes []models.Note
 	query := nr.db.WithContext(ctx).Model(&models.Note{})
@@ -127,14 +417,14 @@ func (nr *NoteRepository) FilterNotes(ctx context.Context, isArchived *bool, cat
 	}
 
 	if len(categories) > 0 {
+		condition, args := ilikeAny(nr.db, "categories.name", categories)
 		query = query.Joins("JOIN note_categories ON notes.id = note_categories.note_id").
 			Joins("JOIN categories ON categories.id = note_categories.category_id").
-			Where("categories.name ILIKE ANY (CAST(? AS varchar[]))", pq.Array(categories)).
+			Where(condition, args...).
 			Group("notes.id")
 	}
 
 	if err := query.Preload("Categories").Find(&notes).Error; err != nil {
-		fmt.Println("Error during filtering:", err)
 		return nil, validations.ErrFilterDB
 	}
 
@@ -145,6 +435,86 @@ func (nr *NoteRepository) FilterNotes(ctx context.Context, isArchived *bool, cat
 	return notes, nil
 }
 
+// ListNotes returns a page of the user's notes using keyset pagination on
+// the sort column with the note id as tie-breaker.
+func (nr *NoteRepository) ListNotes(ctx context.Context, opts NoteListOptions) ([]models.Note, error) {
+	columns, ok := noteSortColumns[opts.SortField]
+	if !ok {
+		return nil, validations.ErrInvalidSort
+	}
+	direction, comparison := "ASC", ">"
+	if opts.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := nr.db.WithContext(ctx).Model(&models.Note{})
+	if opts.SharedWith {
+		query = query.Where("notes.id IN (SELECT note_id FROM note_permissions WHERE user_id = ?)", opts.UserID)
+	} else {
+		query = query.Where("notes.user_id = ?", opts.UserID)
+	}
+
+	if opts.IsArchived != nil {
+		query = query.Where("notes.is_archived = ?", *opts.IsArchived)
+	}
+
+	if len(opts.Categories) > 0 {
+		condition, args := ilikeAny(nr.db, "categories.name", opts.Categories)
+		query = query.Where(`notes.id IN (
+			SELECT note_categories.note_id FROM note_categories
+			JOIN categories ON categories.id = note_categories.category_id
+			WHERE `+condition+`)`, args...)
+	}
+
+	if opts.After != nil {
+		values, err := cursorValues(opts.SortField, opts.After.Value)
+		if err != nil {
+			return nil, err
+		}
+		placeholders := strings.Repeat("?, ", len(values)) + "?"
+		query = query.Where(fmt.Sprintf("(%s, notes.id) %s (%s)", strings.Join(columns, ", "), comparison, placeholders),
+			append(values, opts.After.ID)...)
+	}
+
+	order := make([]string, 0, len(columns)+1)
+	for _, column := range append(columns, "notes.id") {
+		order = append(order, column+" "+direction)
+	}
+	var notes []models.Note
+	if err := query.
+		Preload("Categories").
+		Order(strings.Join(order, ", ")).
+		Limit(opts.Limit).
+		Find(&notes).Error; err != nil {
+		return nil, validations.ErrFetchingNotes
+	}
+	return notes, nil
+}
+
+// cursorValues parses the sort values of the last note of a page, as
+// written by the note service, into the arguments of the keyset condition.
+func cursorValues(sortField, value string) ([]any, error) {
+	switch sortField {
+	case "title":
+		return []any{value}, nil
+	case "position":
+		pinned, position, err := models.ParsePositionCursor(value)
+		if err != nil {
+			return nil, validations.ErrInvalidCursor
+		}
+		rank := 1
+		if pinned {
+			rank = 0
+		}
+		return []any{rank, position}, nil
+	}
+	after, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, validations.ErrInvalidCursor
+	}
+	return []any{after}, nil
+}
+
 func (nr *NoteRepository) DeleteNoteByUserId(ctx context.Context, noteId uint, userId uint) (*uint, error) {
 	var note models.Note
 	if err := nr.db.WithContext(ctx).Where("id = ? AND user_id = ?", noteId, userId).First(&note).Error; err != nil {
@@ -158,3 +528,129 @@ func (nr *NoteRepository) DeleteNoteByUserId(ctx context.Context, noteId uint, u
 	}
 	return &note.ID, nil
 }
+
+// Search ranks the user's notes, and the notes shared with them, against a
+// web-style query across title, content and category names, highlighting
+// the matched terms.
+func (nr *NoteRepository) Search(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error) {
+	if isSQLite(nr.db) {
+		return nr.rankNotes(ctx, userId, query, limit)
+	}
+	var hits []struct {
+		ID             uint
+		Rank           float64
+		TitleSnippet   string
+		ContentSnippet string
+	}
+	// The headlines are built from HTML-escaped text so that only the <mark>
+	// tags they add are markup; the content is cut down to fragments.
+	err := nr.db.WithContext(ctx).Raw(`
+		WITH q AS (SELECT websearch_to_tsquery('english', ?) AS query)
+		SELECT n.id,
+			ts_rank(n.search_vector || cats.vector, q.query) AS rank,
+			ts_headline('english', `+escapeHTML("n.title")+`, q.query,
+				'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_snippet,
+			ts_headline('english', `+escapeHTML("n.content")+`, q.query,
+				'StartSel=<mark>, StopSel=</mark>, `+contentHeadlineOptions+`') AS content_snippet
+		FROM notes n
+		CROSS JOIN q
+		LEFT JOIN LATERAL (
+			SELECT setweight(to_tsvector('english', coalesce(string_agg(c.name, ' '), '')), 'C') AS vector
+			FROM note_categories nc
+			JOIN categories c ON c.id = nc.category_id
+			WHERE nc.note_id = n.id
+		) cats ON true
+		WHERE (n.user_id = ? OR n.id IN (SELECT note_id FROM note_permissions WHERE user_id = ?))
+			AND n.deleted_at IS NULL AND (n.search_vector @@ q.query OR cats.vector @@ q.query)
+		ORDER BY rank DESC, n.id
+		LIMIT ?`, query, userId, userId, limit).Scan(&hits).Error
+	if err != nil {
+		return nil, validations.ErrSearchDB
+	}
+	if len(hits) == 0 {
+		return nil, validations.ErrNoNotesFound
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	var notes []models.Note
+	if err := nr.db.WithContext(ctx).Preload("Categories").Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return nil, validations.ErrSearchDB
+	}
+	byId := make(map[uint]models.Note, len(notes))
+	for _, n := range notes {
+		byId[n.ID] = n
+	}
+
+	results := make([]models.NoteSearchResult, 0, len(hits))
+	for _, h := range hits {
+		note, ok := byId[h.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.NoteSearchResult{
+			Note:           note,
+			Rank:           h.Rank,
+			TitleSnippet:   h.TitleSnippet,
+			ContentSnippet: h.ContentSnippet,
+		})
+	}
+	return results, nil
+}
+
+// contentHeadlineOptions are the ts_headline options of content snippets,
+// shared with the application side highlighting of the fulltext package.
+var contentHeadlineOptions = fmt.Sprintf(`MaxFragments=%d, MaxWords=%d, MinWords=%d, FragmentDelimiter="%s"`,
+	fulltext.MaxFragments, fulltext.MaxWords, fulltext.MinWords, fulltext.FragmentDelimiter)
+
+// escapeHTML is the SQL expression escaping the characters of a text column
+// that start markup or entities. ts_headline copies the resulting entities
+// through unchanged.
+func escapeHTML(column string) string {
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", column)
+}
+
+// rankNotes searches without PostgreSQL full-text search, ranking every live
+// note the user can read in the application. SQLite suits personal servers,
+// whose notes are few enough for that.
+func (nr *NoteRepository) rankNotes(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error) {
+	var notes []models.Note
+	if err := nr.db.WithContext(ctx).Preload("Categories").
+		Where("user_id = ? OR id IN (SELECT note_id FROM note_permissions WHERE user_id = ?)", userId, userId).
+		Order("id").Find(&notes).Error; err != nil {
+		return nil, validations.ErrSearchDB
+	}
+
+	q := fulltext.Parse(query)
+	var results []models.NoteSearchResult
+	for _, n := range notes {
+		names := make([]string, 0, len(n.Categories))
+		for _, c := range n.Categories {
+			names = append(names, c.Name)
+		}
+		rank, ok := q.Rank(
+			fulltext.Field{Text: n.Title, Weight: fulltext.WeightA},
+			fulltext.Field{Text: n.Content, Weight: fulltext.WeightB},
+			fulltext.Field{Text: strings.Join(names, " "), Weight: fulltext.WeightC},
+		)
+		if !ok {
+			continue
+		}
+		results = append(results, models.NoteSearchResult{
+			Note:           n,
+			Rank:           rank,
+			TitleSnippet:   q.Highlight(n.Title),
+			ContentSnippet: q.Snippet(n.Content),
+		})
+	}
+	if len(results) == 0 {
+		return nil, validations.ErrNoNotesFound
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"math"
+	"notes/internal/models"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// PositionStep is the gap between notes given a position of their own:
+	// new notes, which go before all others, and rebalanced ones.
+	PositionStep = 1024
+	// minPositionGap is the smallest gap a move still splits in two. Closer
+	// positions are rebalanced first.
+	minPositionGap = 1e-6
+)
+
+// PositionNextTo returns the position that puts a moved note between the
+// target and its neighbour on the side the note goes to. neighbour is nil when
+// the target is at that end of the list. It reports false when the two are
+// too close to fit a note between them.
+func PositionNextTo(target float64, neighbour *float64, after bool) (float64, bool) {
+	if neighbour == nil {
+		if after {
+			return target + PositionStep, true
+		}
+		return target - PositionStep, true
+	}
+	if math.Abs(*neighbour-target) < minPositionGap {
+		return 0, false
+	}
+	return (target + *neighbour) / 2, true
+}
+
+// firstPosition returns the position that puts a new unpinned note of the
+// user before the others.
+func firstPosition(tx *gorm.DB, userId uint) (float64, error) {
+	var first float64
+	err := tx.Model(&models.Note{}).
+		Where("user_id = ? AND pinned = ?", userId, false).
+		Select("COALESCE(MIN(position), ?)", PositionStep).
+		Scan(&first).Error
+	return first - PositionStep, err
+}
+
+// PinNote pins or unpins the note. Its position is kept, so it goes back to
+// where it was among the others when unpinned. Its version is bumped, since
+// the pinned flag is part of what clients see.
+func (nr *NoteRepository) PinNote(ctx context.Context, note *models.Note, pinned bool) error {
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Note{}).
+			Where("id = ?", note.ID).
+			UpdateColumns(map[string]any{"pinned": pinned, "version": gorm.Expr("version + 1"), "change_seq": seq}).Error; err != nil {
+			return err
+		}
+		if err := refreshVersion(tx, note); err != nil {
+			return err
+		}
+		note.Pinned = pinned
+		note.ChangeSeq = seq
+		return nil
+	})
+	if err != nil {
+		return validations.ErrNoteUpdate
+	}
+	return nil
+}
+
+// MoveNote puts the note right before the target in its owner's manual
+// order, or right after it. It only writes the moved note, unless the target
+// and its neighbour are too close to fit it between them; the owner's
+// positions are then rebalanced first.
+func (nr *NoteRepository) MoveNote(ctx context.Context, note *models.Note, targetId uint, after bool) error {
+	return nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Taking the next change number locks the owner's row, so moves and
+		// pins of the same user's notes never see each other's half-done
+		// work.
+		seq, err := nextChangeSeq(tx, note.UserID)
+		if err != nil {
+			return validations.ErrNoteUpdate
+		}
+		var stored models.Note
+		if err := tx.Select("id", "user_id", "pinned").First(&stored, note.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return validations.ErrNoteNotFound
+			}
+			return validations.ErrFetchingNote
+		}
+		position, ok, err := positionNextTo(tx, &stored, targetId, after)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := rebalance(tx, note.UserID); err != nil {
+				return validations.ErrNoteUpdate
+			}
+			if position, _, err = positionNextTo(tx, &stored, targetId, after); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Note{}).
+			Where("id = ?", note.ID).
+			UpdateColumns(map[string]any{"position": position, "version": gorm.Expr("version + 1"), "change_seq": seq}).Error; err != nil {
+			return validations.ErrNoteUpdate
+		}
+		if err := refreshVersion(tx, note); err != nil {
+			return validations.ErrFetchingNote
+		}
+		note.Pinned = stored.Pinned
+		note.Position = position
+		note.ChangeSeq = seq
+		return nil
+	})
+}
+
+// positionNextTo reads the target and its neighbour, leaving out the moved
+// note, and returns the position between them. The target must be another
+// note of the same user, pinned or not alike; it is read under the lock of
+// the owner's row, so a pin made meanwhile cannot slip in.
+func positionNextTo(tx *gorm.DB, note *models.Note, targetId uint, after bool) (float64, bool, error) {
+	var target models.Note
+	if err := tx.Select("id", "user_id", "pinned", "position").First(&target, targetId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, validations.ErrMoveTarget
+		}
+		return 0, false, validations.ErrFetchingNote
+	}
+	if target.ID == note.ID || target.UserID != note.UserID {
+		return 0, false, validations.ErrMoveTarget
+	}
+	if target.Pinned != note.Pinned {
+		return 0, false, validations.ErrMovePinned
+	}
+	comparison, direction := "<", "DESC"
+	if after {
+		comparison, direction = ">", "ASC"
+	}
+	var neighbours []models.Note
+	if err := tx.Select("id", "position").
+		Where("user_id = ? AND pinned = ? AND id <> ?", note.UserID, target.Pinned, note.ID).
+		Where("(position, id) "+comparison+" (?, ?)", target.Position, target.ID).
+		Order("position " + direction + ", id " + direction).
+		Limit(1).
+		Find(&neighbours).Error; err != nil {
+		return 0, false, validations.ErrFetchingNotes
+	}
+	var neighbour *float64
+	if len(neighbours) > 0 {
+		neighbour = &neighbours[0].Position
+	}
+	position, ok := PositionNextTo(target.Position, neighbour, after)
+	return position, ok, nil
+}
+
+// refreshVersion reads back the version of the note bumped in the
+// transaction.
+func refreshVersion(tx *gorm.DB, note *models.Note) error {
+	return tx.Model(&models.Note{}).Select("version").Where("id = ?", note.ID).Scan(&note.Version).Error
+}
+
+// rebalance spreads the user's notes PositionStep apart again, in the order
+// they are in. Every note it moves takes a new version and a change number
+// of its own.
+func rebalance(tx *gorm.DB, userId uint) error {
+	var notes []models.Note
+	if err := tx.Select("id").
+		Where("user_id = ?", userId).
+		Order("pinned DESC, position, id").
+		Find(&notes).Error; err != nil {
+		return err
+	}
+	for i, n := range notes {
+		seq, err := nextChangeSeq(tx, userId)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Note{}).
+			Where("id = ?", n.ID).
+			UpdateColumns(map[string]any{"position": float64(i) * PositionStep, "version": gorm.Expr("version + 1"), "change_seq": seq}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
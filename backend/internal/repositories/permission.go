@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"notes/internal/models"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetPermission returns the access the user was granted to the note, or nil
+// when the user has none.
+func (nr *NoteRepository) GetPermission(ctx context.Context, noteId, userId uint) (*models.NotePermission, error) {
+	var permission models.NotePermission
+	err := nr.db.WithContext(ctx).Where("note_id = ? AND user_id = ?", noteId, userId).First(&permission).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, validations.ErrPermissionDB
+	}
+	return &permission, nil
+}
+
+// SetPermission grants the access, replacing the role of an earlier grant to
+// the same user.
+func (nr *NoteRepository) SetPermission(ctx context.Context, permission *models.NotePermission) error {
+	err := nr.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "note_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(permission).Error
+	if err != nil {
+		return validations.ErrPermissionDB
+	}
+	return nil
+}
+
+// ListPermissions returns the grants of the note with the grantees'
+// usernames, in username order.
+func (nr *NoteRepository) ListPermissions(ctx context.Context, noteId uint) ([]models.NotePermission, error) {
+	var permissions []models.NotePermission
+	err := nr.db.WithContext(ctx).
+		Select("note_permissions.*, users.user_name").
+		Joins("JOIN users ON users.id = note_permissions.user_id").
+		Where("note_permissions.note_id = ?", noteId).
+		Order("users.user_name").
+		Find(&permissions).Error
+	if err != nil {
+		return nil, validations.ErrPermissionDB
+	}
+	return permissions, nil
+}
+
+// DeletePermission takes the user's access to the note away. It reports
+// false when the user had none.
+func (nr *NoteRepository) DeletePermission(ctx context.Context, noteId, userId uint) (bool, error) {
+	res := nr.db.WithContext(ctx).Where("note_id = ? AND user_id = ?", noteId, userId).Delete(&models.NotePermission{})
+	if res.Error != nil {
+		return false, validations.ErrPermissionDB
+	}
+	return res.RowsAffected > 0, nil
+}
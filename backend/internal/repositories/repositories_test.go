@@ -0,0 +1,39 @@
+package repositories_test
+
+import (
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/repositories"
+	"notes/internal/repositories/storetest"
+
+	"gorm.io/gorm"
+)
+
+// TestStores runs the store contract against PostgreSQL. It needs a
+// disposable database, named by NOTES_TEST_DB_URI, which is migrated up and
+// whose tables are emptied before every test.
+func TestStores(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return newStores(dbtest.Postgres(t))
+	})
+}
+
+// TestSQLiteStores runs the store contract against SQLite, with a new
+// database file for every test.
+func TestSQLiteStores(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return newStores(dbtest.SQLite(t))
+	})
+}
+
+func newStores(gormDB *gorm.DB) storetest.Stores {
+	conf := &configs.Config{}
+	categories := repositories.NewCategoryRepository(gormDB, conf)
+	return storetest.Stores{
+		Notes:      repositories.NewNoteRepository(gormDB, conf, categories),
+		Categories: categories,
+		Users:      repositories.NewUserRepository(gormDB, conf),
+	}
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"notes/internal/configs"
+	"notes/internal/models"
+	"notes/pkg/validations"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+func NewSessionRepository(db *gorm.DB, config *configs.Config) *SessionRepository {
+	return &SessionRepository{
+		db:     db,
+		config: config,
+	}
+}
+
+// Create stores a new session together with its first refresh token.
+func (sr *SessionRepository) Create(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	return sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// FindActive returns the session if it is neither revoked nor expired.
+func (sr *SessionRepository) FindActive(ctx context.Context, id string, now time.Time) (*models.Session, error) {
+	var session models.Session
+	if err := sr.db.WithContext(ctx).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindRefreshToken looks a refresh token up by its hash, along with the
+// session it belongs to.
+func (sr *SessionRepository) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, *models.Session, error) {
+	var token models.RefreshToken
+	if err := sr.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, nil, err
+	}
+	var session models.Session
+	if err := sr.db.WithContext(ctx).First(&session, "id = ?", token.SessionID).Error; err != nil {
+		return nil, nil, err
+	}
+	return &token, &session, nil
+}
+
+// Rotate marks used as spent and stores next in its place, extending the
+// session. The token is only spent if it was still unused, so of two
+// concurrent refreshes with the same token one gets ErrRefreshTokenReuse.
+func (sr *SessionRepository) Rotate(ctx context.Context, used *models.RefreshToken, next *models.RefreshToken, now time.Time, expiresAt time.Time) error {
+	return sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		spend := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", used.ID).
+			Update("used_at", now)
+		if spend.Error != nil {
+			return spend.Error
+		}
+		if spend.RowsAffected == 0 {
+			return validations.ErrRefreshTokenReuse
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("id = ?", used.SessionID).
+			Updates(map[string]any{"last_used_at": now, "expires_at": expiresAt}).Error
+	})
+}
+
+func (sr *SessionRepository) Revoke(ctx context.Context, id string, now time.Time) error {
+	return sr.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now).Error
+}
+
+func (sr *SessionRepository) RevokeAllForUser(ctx context.Context, userId uint, now time.Time) error {
+	return sr.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+}
+
+// RevokeOthersForUser ends every session of the user except keepId.
+func (sr *SessionRepository) RevokeOthersForUser(ctx context.Context, userId uint, keepId string, now time.Time) error {
+	return sr.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, keepId).
+		Update("revoked_at", now).Error
+}
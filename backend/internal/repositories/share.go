@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"notes/internal/configs"
+	"notes/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShareRepository struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+func NewShareRepository(db *gorm.DB, config *configs.Config) *ShareRepository {
+	return &ShareRepository{
+		db:     db,
+		config: config,
+	}
+}
+
+func (sr *ShareRepository) Create(ctx context.Context, share *models.NoteShare) error {
+	return sr.db.WithContext(ctx).Create(share).Error
+}
+
+// ListForNote returns the links to the user's note that are not revoked,
+// newest first. Expired links are included so the user can see and revoke
+// them.
+func (sr *ShareRepository) ListForNote(ctx context.Context, userId, noteId uint) ([]models.NoteShare, error) {
+	var shares []models.NoteShare
+	err := sr.db.WithContext(ctx).
+		Where("note_id = ? AND user_id = ? AND revoked_at IS NULL", noteId, userId).
+		Order("created_at DESC, id DESC").
+		Find(&shares).Error
+	return shares, err
+}
+
+// FindActive returns the link with the given hash if it is neither revoked
+// nor expired.
+func (sr *ShareRepository) FindActive(ctx context.Context, tokenHash string, now time.Time) (*models.NoteShare, error) {
+	var share models.NoteShare
+	if err := sr.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", tokenHash, now).
+		First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// RecordView counts a view of the link.
+func (sr *ShareRepository) RecordView(ctx context.Context, id uint, now time.Time) error {
+	return sr.db.WithContext(ctx).Model(&models.NoteShare{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"views": gorm.Expr("views + 1"), "last_viewed_at": now}).Error
+}
+
+// Revoke revokes a link to the user's note. It reports false when the note
+// has no such link.
+func (sr *ShareRepository) Revoke(ctx context.Context, userId, noteId, id uint, now time.Time) (bool, error) {
+	res := sr.db.WithContext(ctx).Model(&models.NoteShare{}).
+		Where("id = ? AND note_id = ? AND user_id = ? AND revoked_at IS NULL", id, noteId, userId).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,1156 @@
+// Package storetest is the contract every backend of the repository stores
+// must meet. Backends call Run from their own tests, so the in-memory stores
+// and the gorm repositories are held to the same behavior.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/pagination"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+// Stores are the stores of one backend, sharing the same tables.
+type Stores struct {
+	Notes      repositories.NoteStore
+	Categories repositories.CategoryStore
+	Users      repositories.UserStore
+}
+
+// Run runs the contract suite. newStores is called for every test and must
+// return stores over empty tables.
+func Run(t *testing.T, newStores func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Stores)
+	}{
+		{"UserCreateAndFind", testUserCreateAndFind},
+		{"UserTwoFactor", testUserTwoFactor},
+		{"UserAdministration", testUserAdministration},
+		{"UserPasswordReset", testUserPasswordReset},
+		{"UserExport", testUserExport},
+		{"UserDelete", testUserDelete},
+		{"CategoryCRUD", testCategoryCRUD},
+		{"CategoryMerge", testCategoryMerge},
+		{"CategoryTouchNotes", testCategoryTouchNotes},
+		{"NoteCreate", testNoteCreate},
+		{"NoteUpdate", testNoteUpdate},
+		{"NotePatch", testNotePatch},
+		{"NoteConcurrentUpdates", testNoteConcurrentUpdates},
+		{"NoteTrash", testNoteTrash},
+		{"NoteList", testNoteList},
+		{"NoteFilter", testNoteFilter},
+		{"NoteSearch", testNoteSearch},
+		{"NoteOrder", testNoteOrder},
+		{"SyncChanges", testSyncChanges},
+		{"NotePermissions", testNotePermissions},
+		{"Transaction", testTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStores(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+func createUser(t *testing.T, s Stores, name string) *models.User {
+	t.Helper()
+	user := models.NewUser(name, "hash-of-"+name, nil)
+	if err := s.Users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser(%q): %v", name, err)
+	}
+	return user
+}
+
+func createCategory(t *testing.T, s Stores, userId uint, name string) models.Category {
+	t.Helper()
+	category, err := s.Categories.Create(ctx, models.NewCategory(name, userId))
+	if err != nil {
+		t.Fatalf("Create category %q: %v", name, err)
+	}
+	return *category
+}
+
+func createNote(t *testing.T, s Stores, userId uint, title string, categories ...models.Category) *models.Note {
+	t.Helper()
+	note, err := s.Notes.Create(ctx, models.NewNote(title, "content of "+title, categories, userId))
+	if err != nil {
+		t.Fatalf("Create note %q: %v", title, err)
+	}
+	return note
+}
+
+func getNote(t *testing.T, s Stores, id uint) *models.Note {
+	t.Helper()
+	note, err := s.Notes.GetNoteById(ctx, id)
+	if err != nil {
+		t.Fatalf("GetNoteById(%d): %v", id, err)
+	}
+	return note
+}
+
+func wantErr(t *testing.T, what string, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Fatalf("%s: got error %v, want %v", what, got, want)
+	}
+}
+
+func noErr(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func categoryNames(categories []models.Category) string {
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func noteTitles(notes []models.Note) string {
+	titles := make([]string, 0, len(notes))
+	for _, n := range notes {
+		titles = append(titles, n.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func testUserCreateAndFind(t *testing.T, s Stores) {
+	alice := createUser(t, s, "Alice")
+	if alice.ID == 0 {
+		t.Fatal("CreateUser did not assign an ID")
+	}
+	err := s.Users.CreateUser(ctx, models.NewUser("Alice", "other", nil))
+	wantErr(t, "duplicate username", err, gorm.ErrDuplicatedKey)
+
+	byID, err := s.Users.GetUserByID(ctx, alice.ID)
+	noErr(t, "GetUserByID", err)
+	if byID.UserName != "Alice" || byID.Password != "hash-of-Alice" {
+		t.Fatalf("GetUserByID returned %+v", byID)
+	}
+	byName, err := s.Users.GetUserByUsername(ctx, "aLiCe")
+	noErr(t, "GetUserByUsername ignores case", err)
+	if byName.ID != alice.ID {
+		t.Fatalf("GetUserByUsername returned user %d, want %d", byName.ID, alice.ID)
+	}
+
+	_, err = s.Users.GetUserByID(ctx, alice.ID+100)
+	wantErr(t, "GetUserByID of a missing user", err, gorm.ErrRecordNotFound)
+	_, err = s.Users.GetUserByUsername(ctx, "bob")
+	wantErr(t, "GetUserByUsername of a missing user", err, gorm.ErrRecordNotFound)
+
+	work := createCategory(t, s, alice.ID, "work")
+	kept := createNote(t, s, alice.ID, "kept note", work)
+	trashed := createNote(t, s, alice.ID, "trashed note", work)
+	_, err = s.Notes.Delete(ctx, trashed)
+	noErr(t, "Delete", err)
+	withNotes, err := s.Users.GetUserByID(ctx, alice.ID)
+	noErr(t, "GetUserByID", err)
+	if len(withNotes.Notes) != 1 || withNotes.Notes[0].ID != kept.ID || categoryNames(withNotes.Notes[0].Categories) != "work" {
+		t.Fatalf("GetUserByID loaded notes %+v, want only %q with its category", withNotes.Notes, kept.Title)
+	}
+
+	noErr(t, "UpdatePassword", s.Users.UpdatePassword(ctx, alice.ID, "new-hash"))
+	byID, err = s.Users.GetUserByID(ctx, alice.ID)
+	noErr(t, "GetUserByID", err)
+	if byID.Password != "new-hash" {
+		t.Fatalf("password is %q after UpdatePassword", byID.Password)
+	}
+}
+
+func testUserTwoFactor(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	noErr(t, "SetTOTPSecret", s.Users.SetTOTPSecret(ctx, user.ID, "SECRET1"))
+	noErr(t, "SetTOTPSecret again", s.Users.SetTOTPSecret(ctx, user.ID, "SECRET2"))
+
+	codes := []models.RecoveryCode{*models.NewRecoveryCode(user.ID, "code-1"), *models.NewRecoveryCode(user.ID, "code-2")}
+	noErr(t, "EnableTOTP", s.Users.EnableTOTP(ctx, user.ID, 100, codes))
+	noErr(t, "SetTOTPSecret once enabled", s.Users.SetTOTPSecret(ctx, user.ID, "SECRET3"))
+	stored, err := s.Users.GetUserByID(ctx, user.ID)
+	noErr(t, "GetUserByID", err)
+	if !stored.TOTPEnabled || stored.TOTPSecret != "SECRET2" || stored.TOTPLastStep != 100 {
+		t.Fatalf("after EnableTOTP the user has enabled=%v secret=%q step=%d, want true SECRET2 100",
+			stored.TOTPEnabled, stored.TOTPSecret, stored.TOTPLastStep)
+	}
+
+	wantErr(t, "UseTOTPStep of the enrollment step", s.Users.UseTOTPStep(ctx, user.ID, 100), validations.ErrInvalidTOTPCode)
+	noErr(t, "UseTOTPStep of a later step", s.Users.UseTOTPStep(ctx, user.ID, 102))
+	wantErr(t, "UseTOTPStep of the same step", s.Users.UseTOTPStep(ctx, user.ID, 102), validations.ErrInvalidTOTPCode)
+	wantErr(t, "UseTOTPStep of an earlier step", s.Users.UseTOTPStep(ctx, user.ID, 101), validations.ErrInvalidTOTPCode)
+
+	now := time.Now()
+	noErr(t, "UseRecoveryCode", s.Users.UseRecoveryCode(ctx, user.ID, "code-1", now))
+	wantErr(t, "UseRecoveryCode twice", s.Users.UseRecoveryCode(ctx, user.ID, "code-1", now), validations.ErrInvalidTOTPCode)
+	wantErr(t, "UseRecoveryCode of an unknown code", s.Users.UseRecoveryCode(ctx, user.ID, "code-9", now), validations.ErrInvalidTOTPCode)
+
+	noErr(t, "DisableTOTP", s.Users.DisableTOTP(ctx, user.ID))
+	stored, err = s.Users.GetUserByID(ctx, user.ID)
+	noErr(t, "GetUserByID", err)
+	if stored.TOTPEnabled || stored.TOTPSecret != "" || stored.TOTPLastStep != 0 {
+		t.Fatalf("DisableTOTP left enabled=%v secret=%q step=%d", stored.TOTPEnabled, stored.TOTPSecret, stored.TOTPLastStep)
+	}
+	wantErr(t, "UseRecoveryCode after DisableTOTP", s.Users.UseRecoveryCode(ctx, user.ID, "code-2", now), validations.ErrInvalidTOTPCode)
+}
+
+func testUserAdministration(t *testing.T, s Stores) {
+	var users []*models.User
+	for _, name := range []string{"alice", "bob", "carol"} {
+		users = append(users, createUser(t, s, name))
+	}
+	general := createCategory(t, s, users[1].ID, "general")
+	createNote(t, s, users[1].ID, "first note", general)
+	trashed := createNote(t, s, users[1].ID, "second note", general)
+	_, err := s.Notes.Delete(ctx, trashed)
+	noErr(t, "Delete", err)
+
+	page, err := s.Users.ListUsers(ctx, 0, 2)
+	noErr(t, "ListUsers", err)
+	if len(page) != 2 || page[0].UserName != "alice" || page[1].UserName != "bob" {
+		t.Fatalf("first page is %+v, want alice and bob", page)
+	}
+	if page[1].NoteCount != 1 {
+		t.Fatalf("bob has a note count of %d, want 1 as trashed notes do not count", page[1].NoteCount)
+	}
+	page, err = s.Users.ListUsers(ctx, page[1].ID, 2)
+	noErr(t, "ListUsers", err)
+	if len(page) != 1 || page[0].UserName != "carol" {
+		t.Fatalf("second page is %+v, want carol", page)
+	}
+
+	found, err := s.Users.SetDisabled(ctx, users[0].ID, true)
+	noErr(t, "SetDisabled", err)
+	if !found {
+		t.Fatal("SetDisabled did not find the user")
+	}
+	found, err = s.Users.SetDisabled(ctx, users[2].ID+100, true)
+	noErr(t, "SetDisabled of a missing user", err)
+	if found {
+		t.Fatal("SetDisabled found a missing user")
+	}
+
+	found, err = s.Users.PromoteToAdmin(ctx, "BOB")
+	noErr(t, "PromoteToAdmin", err)
+	if !found {
+		t.Fatal("PromoteToAdmin did not find bob")
+	}
+	found, err = s.Users.PromoteToAdmin(ctx, "dave")
+	noErr(t, "PromoteToAdmin of a missing user", err)
+	if found {
+		t.Fatal("PromoteToAdmin found a missing user")
+	}
+
+	status, err := s.Users.GetAuthStatus(ctx, users[0].ID)
+	noErr(t, "GetAuthStatus", err)
+	if !status.IsDisabled || status.IsAdmin {
+		t.Fatalf("alice has disabled=%v admin=%v, want true false", status.IsDisabled, status.IsAdmin)
+	}
+	status, err = s.Users.GetAuthStatus(ctx, users[1].ID)
+	noErr(t, "GetAuthStatus", err)
+	if status.IsDisabled || !status.IsAdmin {
+		t.Fatalf("bob has disabled=%v admin=%v, want false true", status.IsDisabled, status.IsAdmin)
+	}
+	_, err = s.Users.GetAuthStatus(ctx, users[2].ID+100)
+	wantErr(t, "GetAuthStatus of a missing user", err, gorm.ErrRecordNotFound)
+}
+
+func testUserPasswordReset(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	now := time.Now()
+
+	found, err := s.Users.RequirePasswordReset(ctx, user.ID, "reset-hash", now.Add(time.Hour))
+	noErr(t, "RequirePasswordReset", err)
+	if !found {
+		t.Fatal("RequirePasswordReset did not find the user")
+	}
+	found, err = s.Users.RequirePasswordReset(ctx, user.ID+100, "reset-hash", now.Add(time.Hour))
+	noErr(t, "RequirePasswordReset of a missing user", err)
+	if found {
+		t.Fatal("RequirePasswordReset found a missing user")
+	}
+
+	wantErr(t, "ResetPassword with a wrong token",
+		s.Users.ResetPassword(ctx, "alice", "wrong-hash", "new-hash", now), validations.ErrResetToken)
+	wantErr(t, "ResetPassword with an expired token",
+		s.Users.ResetPassword(ctx, "alice", "reset-hash", "new-hash", now.Add(2*time.Hour)), validations.ErrResetToken)
+	noErr(t, "ResetPassword", s.Users.ResetPassword(ctx, "ALICE", "reset-hash", "new-hash", now))
+	wantErr(t, "ResetPassword twice",
+		s.Users.ResetPassword(ctx, "alice", "reset-hash", "newer-hash", now), validations.ErrResetToken)
+
+	stored, err := s.Users.GetUserByID(ctx, user.ID)
+	noErr(t, "GetUserByID", err)
+	if stored.Password != "new-hash" || stored.PasswordResetHash != "" || stored.PasswordResetExpiresAt != nil {
+		t.Fatalf("after ResetPassword the user has password %q and reset %q %v",
+			stored.Password, stored.PasswordResetHash, stored.PasswordResetExpiresAt)
+	}
+}
+
+func testUserExport(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "work")
+	createCategory(t, s, alice.ID, "home")
+	createCategory(t, s, bob.ID, "other")
+	kept := createNote(t, s, alice.ID, "kept note", work)
+	trashed := createNote(t, s, alice.ID, "trashed note", work)
+	createNote(t, s, bob.ID, "bob note")
+
+	kept.Content = "edited content"
+	_, err := s.Notes.UpdateNote(ctx, kept, alice.ID)
+	noErr(t, "UpdateNote", err)
+	_, err = s.Notes.Delete(ctx, trashed)
+	noErr(t, "Delete", err)
+
+	export, err := s.Users.ExportData(ctx, alice.ID)
+	noErr(t, "ExportData", err)
+	if export.Profile.ID != alice.ID || export.Profile.UserName != "alice" {
+		t.Fatalf("exported profile %+v", export.Profile)
+	}
+	if got := noteTitles(export.Notes); got != "kept note,trashed note" {
+		t.Fatalf("exported notes %q, want the kept and the trashed note", got)
+	}
+	if got := categoryNames(export.Notes[0].Categories); got != "work" {
+		t.Fatalf("exported note categories %q, want work", got)
+	}
+	if got := categoryNames(export.Categories); got != "home,work" {
+		t.Fatalf("exported categories %q, want home,work", got)
+	}
+	var revisions []string
+	for _, r := range export.Revisions {
+		revisions = append(revisions, fmt.Sprintf("%d/%d", r.NoteID, r.Revision))
+	}
+	want := fmt.Sprintf("%d/1,%d/2,%d/1", kept.ID, kept.ID, trashed.ID)
+	if got := strings.Join(revisions, ","); got != want {
+		t.Fatalf("exported revisions %s, want %s", got, want)
+	}
+
+	_, err = s.Users.ExportData(ctx, bob.ID+100)
+	wantErr(t, "ExportData of a missing user", err, gorm.ErrRecordNotFound)
+}
+
+func testUserDelete(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "work")
+	bobs := createCategory(t, s, bob.ID, "work")
+	note := createNote(t, s, alice.ID, "alice note", work)
+	trashed := createNote(t, s, alice.ID, "trashed note", work)
+	bobNote := createNote(t, s, bob.ID, "bob note", bobs)
+	_, err := s.Notes.Delete(ctx, trashed)
+	noErr(t, "Delete", err)
+
+	deleted, err := s.Users.DeleteUser(ctx, alice.ID)
+	noErr(t, "DeleteUser", err)
+	if !deleted {
+		t.Fatal("DeleteUser did not find the user")
+	}
+	deleted, err = s.Users.DeleteUser(ctx, alice.ID)
+	noErr(t, "DeleteUser twice", err)
+	if deleted {
+		t.Fatal("DeleteUser deleted the user twice")
+	}
+
+	_, err = s.Users.GetUserByID(ctx, alice.ID)
+	wantErr(t, "GetUserByID of a deleted user", err, gorm.ErrRecordNotFound)
+	_, err = s.Notes.GetNoteById(ctx, note.ID)
+	wantErr(t, "GetNoteById of a deleted user's note", err, validations.ErrNoteNotFound)
+	revisions, err := s.Notes.GetRevisions(ctx, note.ID)
+	noErr(t, "GetRevisions", err)
+	if len(revisions) != 0 {
+		t.Fatalf("a deleted user's note kept %d revisions", len(revisions))
+	}
+	_, err = s.Categories.FindByID(ctx, alice.ID, work.ID)
+	wantErr(t, "FindByID of a deleted user's category", err, gorm.ErrRecordNotFound)
+	count, err := s.Categories.CountSoleCategoryNotes(ctx, work.ID)
+	noErr(t, "CountSoleCategoryNotes", err)
+	if count != 0 {
+		t.Fatalf("a deleted user's category is still attached to %d notes", count)
+	}
+
+	if got := categoryNames(getNote(t, s, bobNote.ID).Categories); got != "work" {
+		t.Fatalf("bob's note has categories %q after alice was deleted", got)
+	}
+}
+
+func testCategoryCRUD(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "work")
+	home := createCategory(t, s, alice.ID, "home")
+	createCategory(t, s, bob.ID, "work")
+
+	_, err := s.Categories.Create(ctx, models.NewCategory("work", alice.ID))
+	wantErr(t, "duplicate category", err, gorm.ErrDuplicatedKey)
+
+	found, err := s.Categories.FindByName(ctx, alice.ID, "work")
+	noErr(t, "FindByName", err)
+	if found.ID != work.ID {
+		t.Fatalf("FindByName returned category %d, want %d", found.ID, work.ID)
+	}
+	_, err = s.Categories.FindByName(ctx, alice.ID, "WORK")
+	wantErr(t, "FindByName is case sensitive", err, gorm.ErrRecordNotFound)
+	_, err = s.Categories.FindByID(ctx, bob.ID, work.ID)
+	wantErr(t, "FindByID of another user's category", err, gorm.ErrRecordNotFound)
+
+	all, err := s.Categories.FindAll(ctx, alice.ID)
+	noErr(t, "FindAll", err)
+	if got := categoryNames(all); got != "home,work" {
+		t.Fatalf("FindAll returned %q, want home,work", got)
+	}
+
+	home.Name = "work"
+	_, err = s.Categories.Update(ctx, &home)
+	wantErr(t, "renaming to a taken name", err, gorm.ErrDuplicatedKey)
+	home.Name = "family"
+	_, err = s.Categories.Update(ctx, &home)
+	noErr(t, "Update", err)
+	found, err = s.Categories.FindByID(ctx, alice.ID, home.ID)
+	noErr(t, "FindByID", err)
+	if found.Name != "family" {
+		t.Fatalf("category is named %q after Update, want family", found.Name)
+	}
+
+	note := createNote(t, s, alice.ID, "tagged note", work, home)
+	count, err := s.Categories.CountSoleCategoryNotes(ctx, work.ID)
+	noErr(t, "CountSoleCategoryNotes", err)
+	if count != 0 {
+		t.Fatalf("CountSoleCategoryNotes = %d for a note with two categories", count)
+	}
+	_, err = s.Categories.Delete(ctx, alice.ID, home.ID)
+	noErr(t, "Delete", err)
+	if got := categoryNames(getNote(t, s, note.ID).Categories); got != "work" {
+		t.Fatalf("note has categories %q after deleting one, want work", got)
+	}
+	count, err = s.Categories.CountSoleCategoryNotes(ctx, work.ID)
+	noErr(t, "CountSoleCategoryNotes", err)
+	if count != 1 {
+		t.Fatalf("CountSoleCategoryNotes = %d, want 1", count)
+	}
+}
+
+func testCategoryMerge(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	source := createCategory(t, s, user.ID, "chores")
+	target := createCategory(t, s, user.ID, "home")
+	onlySource := createNote(t, s, user.ID, "only source", source)
+	both := createNote(t, s, user.ID, "both categories", source, target)
+
+	noErr(t, "Merge", s.Categories.Merge(ctx, user.ID, source.ID, target.ID))
+	for _, id := range []uint{onlySource.ID, both.ID} {
+		if got := categoryNames(getNote(t, s, id).Categories); got != "home" {
+			t.Fatalf("note %d has categories %q after the merge, want home", id, got)
+		}
+	}
+	_, err := s.Categories.FindByID(ctx, user.ID, source.ID)
+	wantErr(t, "FindByID of the merged category", err, gorm.ErrRecordNotFound)
+}
+
+func testCategoryTouchNotes(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	home := createCategory(t, s, user.ID, "home")
+	work := createCategory(t, s, user.ID, "work")
+	tagged := createNote(t, s, user.ID, "tagged", home)
+	trashed := createNote(t, s, user.ID, "trashed", home, work)
+	other := createNote(t, s, user.ID, "other", work)
+	_, err := s.Notes.Delete(ctx, trashed)
+	noErr(t, "Delete", err)
+
+	ids, err := s.Notes.TouchCategoryNotes(ctx, home.ID)
+	noErr(t, "TouchCategoryNotes", err)
+	if fmt.Sprint(ids) != fmt.Sprint([]uint{tagged.ID, trashed.ID}) {
+		t.Fatalf("touched notes %v, want %v", ids, []uint{tagged.ID, trashed.ID})
+	}
+	if got := getNote(t, s, tagged.ID).Version; got != 2 {
+		t.Fatalf("tagged note has version %d, want 2", got)
+	}
+	if got := getNote(t, s, other.ID).Version; got != 1 {
+		t.Fatalf("untagged note has version %d, want 1", got)
+	}
+	_, err = s.Notes.Restore(ctx, user.ID, trashed.ID)
+	noErr(t, "Restore", err)
+	if got := getNote(t, s, trashed.ID).Version; got != 2 {
+		t.Fatalf("trashed note has version %d, want 2", got)
+	}
+}
+
+func testNoteCreate(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "work")
+
+	note := createNote(t, s, alice.ID, "first note", work)
+	if note.ID == 0 || note.Version != 1 {
+		t.Fatalf("created note has ID %d and version %d", note.ID, note.Version)
+	}
+	stored := getNote(t, s, note.ID)
+	if stored.Title != "first note" || stored.UserID != alice.ID || categoryNames(stored.Categories) != "work" {
+		t.Fatalf("GetNoteById returned %+v", stored)
+	}
+	revisions, err := s.Notes.GetRevisions(ctx, note.ID)
+	noErr(t, "GetRevisions", err)
+	if len(revisions) != 1 || revisions[0].Revision != 1 || revisions[0].ActorID != alice.ID {
+		t.Fatalf("a new note has revisions %+v, want revision 1 by its owner", revisions)
+	}
+
+	_, err = s.Notes.Create(ctx, models.NewNote("first note", "again", nil, alice.ID))
+	wantErr(t, "duplicate title", err, validations.ErrDuplicateTitle)
+	createNote(t, s, bob.ID, "first note")
+	_, err = s.Notes.Create(ctx, models.NewNote("no owner", "content", nil, 0))
+	wantErr(t, "note without user", err, validations.ErrUserIdNotSet)
+
+	byTitle, err := s.Notes.GetByTitle(ctx, alice.ID, "first note")
+	noErr(t, "GetByTitle", err)
+	if byTitle.ID != note.ID {
+		t.Fatalf("GetByTitle returned note %d, want %d", byTitle.ID, note.ID)
+	}
+	_, err = s.Notes.GetByTitle(ctx, alice.ID, "missing note")
+	wantErr(t, "GetByTitle of a missing note", err, validations.ErrNotTitle)
+	_, err = s.Notes.GetNoteById(ctx, note.ID+100)
+	wantErr(t, "GetNoteById of a missing note", err, validations.ErrNoteNotFound)
+
+	all, err := s.Notes.GetAllNotes(ctx)
+	noErr(t, "GetAllNotes", err)
+	if len(all) != 2 {
+		t.Fatalf("GetAllNotes returned %d notes, want 2", len(all))
+	}
+	tagged, err := s.Notes.GetNotesByCategories(ctx, []string{"work"})
+	noErr(t, "GetNotesByCategories", err)
+	if len(tagged) != 1 || tagged[0].ID != note.ID {
+		t.Fatalf("GetNotesByCategories returned %d notes, want only note %d", len(tagged), note.ID)
+	}
+}
+
+func testNoteUpdate(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	work := createCategory(t, s, user.ID, "work")
+	home := createCategory(t, s, user.ID, "home")
+	createNote(t, s, user.ID, "other note", work)
+	note := getNote(t, s, createNote(t, s, user.ID, "draft note", work).ID)
+
+	stale := *note
+	note.Title = "final note"
+	note.Categories = []models.Category{home}
+	_, err := s.Notes.UpdateNote(ctx, note, user.ID)
+	noErr(t, "UpdateNote", err)
+	if note.Version != 2 {
+		t.Fatalf("note version is %d after an update, want 2", note.Version)
+	}
+	stored := getNote(t, s, note.ID)
+	if stored.Title != "final note" || stored.Version != 2 || categoryNames(stored.Categories) != "home" {
+		t.Fatalf("updated note is %+v", stored)
+	}
+
+	stale.Title = "stale note"
+	_, err = s.Notes.UpdateNote(ctx, &stale, user.ID)
+	wantErr(t, "UpdateNote of a stale version", err, validations.ErrVersionMismatch)
+
+	taken := getNote(t, s, note.ID)
+	taken.Title = "other note"
+	_, err = s.Notes.UpdateNote(ctx, taken, user.ID)
+	wantErr(t, "UpdateNote to a taken title", err, validations.ErrDuplicateTitle)
+	if stored := getNote(t, s, note.ID); stored.Title != "final note" || stored.Version != 2 {
+		t.Fatalf("a failed update left the note as %q version %d", stored.Title, stored.Version)
+	}
+
+	revisions, err := s.Notes.GetRevisions(ctx, note.ID)
+	noErr(t, "GetRevisions", err)
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[1].Revision != 1 {
+		t.Fatalf("revisions are %+v, want 2 then 1", revisions)
+	}
+	first, err := s.Notes.GetRevision(ctx, note.ID, 1)
+	noErr(t, "GetRevision", err)
+	if first.Title != "draft note" || strings.Join(first.Categories, ",") != "work" {
+		t.Fatalf("revision 1 is %+v", first)
+	}
+	second, err := s.Notes.GetRevision(ctx, note.ID, 2)
+	noErr(t, "GetRevision", err)
+	if second.Title != "final note" || strings.Join(second.Categories, ",") != "home" {
+		t.Fatalf("revision 2 is %+v", second)
+	}
+	_, err = s.Notes.GetRevision(ctx, note.ID, 3)
+	wantErr(t, "GetRevision of a missing revision", err, validations.ErrRevisionNotFound)
+}
+
+func testNotePatch(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	work := createCategory(t, s, user.ID, "work")
+	note := getNote(t, s, createNote(t, s, user.ID, "patched note", work).ID)
+
+	note.Content = "patched content"
+	note.Title = "not saved"
+	_, err := s.Notes.PatchNote(ctx, note, []string{"content", "updated_at"}, false, user.ID)
+	noErr(t, "PatchNote", err)
+
+	stored := getNote(t, s, note.ID)
+	if stored.Content != "patched content" || stored.Title != "patched note" || stored.Version != 2 {
+		t.Fatalf("patched note is %+v, want only the content changed", stored)
+	}
+	if categoryNames(stored.Categories) != "work" {
+		t.Fatalf("patch without categories left %q", categoryNames(stored.Categories))
+	}
+}
+
+func testNoteConcurrentUpdates(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	work := createCategory(t, s, user.ID, "work")
+	note := getNote(t, s, createNote(t, s, user.ID, "contended note", work).ID)
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		edit := *note
+		edit.Categories = append([]models.Category(nil), note.Categories...)
+		edit.Content = fmt.Sprintf("edit number %d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Notes.UpdateNote(ctx, &edit, user.ID)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, validations.ErrVersionMismatch):
+			t.Fatalf("concurrent UpdateNote: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d concurrent updates of the same version succeeded, want 1", succeeded)
+	}
+	if stored := getNote(t, s, note.ID); stored.Version != 2 {
+		t.Fatalf("note version is %d, want 2", stored.Version)
+	}
+}
+
+func testNoteTrash(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "work")
+	note := createNote(t, s, alice.ID, "trashed note", work)
+	old := createNote(t, s, alice.ID, "old note", work)
+
+	_, err := s.Notes.DeleteNoteByUserId(ctx, note.ID, bob.ID)
+	wantErr(t, "DeleteNoteByUserId by another user", err, validations.ErrNoteNotOwnedByUser)
+	_, err = s.Notes.DeleteNoteByUserId(ctx, note.ID, alice.ID)
+	noErr(t, "DeleteNoteByUserId", err)
+	_, err = s.Notes.GetNoteById(ctx, note.ID)
+	wantErr(t, "GetNoteById of a trashed note", err, validations.ErrNoteNotFound)
+	_, err = s.Notes.DeleteNoteByUserId(ctx, note.ID, alice.ID)
+	wantErr(t, "DeleteNoteByUserId of a trashed note", err, validations.ErrNoteNotOwnedByUser)
+
+	trash, err := s.Notes.GetTrash(ctx, alice.ID)
+	noErr(t, "GetTrash", err)
+	if len(trash) != 1 || trash[0].ID != note.ID || categoryNames(trash[0].Categories) != "work" {
+		t.Fatalf("trash is %+v, want the trashed note with its category", trash)
+	}
+
+	replacement := createNote(t, s, alice.ID, "trashed note", work)
+	_, err = s.Notes.Restore(ctx, alice.ID, note.ID)
+	wantErr(t, "Restore over a live title", err, validations.ErrDuplicateTitle)
+	_, err = s.Notes.Delete(ctx, replacement)
+	noErr(t, "Delete", err)
+	_, err = s.Notes.Restore(ctx, bob.ID, note.ID)
+	wantErr(t, "Restore by another user", err, validations.ErrTrashedNoteNotFound)
+	_, err = s.Notes.Restore(ctx, alice.ID, note.ID)
+	noErr(t, "Restore", err)
+	if restored := getNote(t, s, note.ID); categoryNames(restored.Categories) != "work" {
+		t.Fatalf("restored note has categories %q, want work", categoryNames(restored.Categories))
+	}
+	_, err = s.Notes.Restore(ctx, alice.ID, note.ID)
+	wantErr(t, "Restore of a live note", err, validations.ErrTrashedNoteNotFound)
+
+	_, err = s.Notes.Delete(ctx, old)
+	noErr(t, "Delete", err)
+	purged, err := s.Notes.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	noErr(t, "PurgeTrash", err)
+	if purged != 0 {
+		t.Fatalf("PurgeTrash purged %d notes trashed after the cutoff", purged)
+	}
+	purged, err = s.Notes.PurgeTrash(ctx, time.Now().Add(time.Hour))
+	noErr(t, "PurgeTrash", err)
+	if purged != 2 {
+		t.Fatalf("PurgeTrash purged %d notes, want 2", purged)
+	}
+	revisions, err := s.Notes.GetRevisions(ctx, old.ID)
+	noErr(t, "GetRevisions", err)
+	if len(revisions) != 0 {
+		t.Fatalf("a purged note kept %d revisions", len(revisions))
+	}
+
+	_, err = s.Notes.Delete(ctx, getNote(t, s, note.ID))
+	noErr(t, "Delete", err)
+	emptied, err := s.Notes.EmptyTrash(ctx, alice.ID)
+	noErr(t, "EmptyTrash", err)
+	if emptied != 1 {
+		t.Fatalf("EmptyTrash deleted %d notes, want 1", emptied)
+	}
+	count, err := s.Categories.CountSoleCategoryNotes(ctx, work.ID)
+	noErr(t, "CountSoleCategoryNotes", err)
+	if count != 0 {
+		t.Fatalf("purged notes are still attached to their category")
+	}
+}
+
+func testNoteList(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	work := createCategory(t, s, alice.ID, "Work")
+	home := createCategory(t, s, alice.ID, "home")
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, title := range []string{"charlie note", "alpha note", "delta note", "bravo note"} {
+		category := work
+		if i%2 == 1 {
+			category = home
+		}
+		note := models.NewNote(title, "content", []models.Category{category}, alice.ID)
+		note.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		note.IsArchived = title == "delta note"
+		if _, err := s.Notes.Create(ctx, note); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	createNote(t, s, bob.ID, "echo note")
+
+	list := func(opts repositories.NoteListOptions) []models.Note {
+		t.Helper()
+		opts.UserID = alice.ID
+		notes, err := s.Notes.ListNotes(ctx, opts)
+		noErr(t, "ListNotes", err)
+		return notes
+	}
+
+	page := list(repositories.NoteListOptions{SortField: "title", Limit: 2})
+	if got := noteTitles(page); got != "alpha note,bravo note" {
+		t.Fatalf("first page by title is %q", got)
+	}
+	last := page[len(page)-1]
+	page = list(repositories.NoteListOptions{SortField: "title", Limit: 2, After: &pagination.Cursor{Value: last.Title, ID: last.ID}})
+	if got := noteTitles(page); got != "charlie note,delta note" {
+		t.Fatalf("second page by title is %q", got)
+	}
+
+	page = list(repositories.NoteListOptions{SortField: "created_at", Descending: true, Limit: 3})
+	if got := noteTitles(page); got != "bravo note,delta note,alpha note" {
+		t.Fatalf("first page by newest is %q", got)
+	}
+	last = page[len(page)-1]
+	after := &pagination.Cursor{Value: last.CreatedAt.Format(time.RFC3339Nano), ID: last.ID}
+	page = list(repositories.NoteListOptions{SortField: "created_at", Descending: true, Limit: 3, After: after})
+	if got := noteTitles(page); got != "charlie note" {
+		t.Fatalf("second page by newest is %q", got)
+	}
+
+	archived := false
+	page = list(repositories.NoteListOptions{SortField: "title", Limit: 10, IsArchived: &archived, Categories: []string{"work"}})
+	if got := noteTitles(page); got != "charlie note" {
+		t.Fatalf("unarchived notes in work are %q, want the category name matched case-insensitively", got)
+	}
+
+	_, err := s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: alice.ID, SortField: "content", Limit: 10})
+	wantErr(t, "ListNotes with an unknown sort", err, validations.ErrInvalidSort)
+	_, err = s.Notes.ListNotes(ctx, repositories.NoteListOptions{
+		UserID: alice.ID, SortField: "created_at", Limit: 10, After: &pagination.Cursor{Value: "yesterday", ID: 1},
+	})
+	wantErr(t, "ListNotes with a bad cursor", err, validations.ErrInvalidCursor)
+}
+
+func testNoteFilter(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	work := createCategory(t, s, user.ID, "work")
+	home := createCategory(t, s, user.ID, "home")
+	archivedNote := createNote(t, s, user.ID, "archived note", work)
+	createNote(t, s, user.ID, "home note", home)
+
+	archivedNote.IsArchived = true
+	_, err := s.Notes.UpdateNote(ctx, archivedNote, user.ID)
+	noErr(t, "UpdateNote", err)
+
+	archived := true
+	notes, err := s.Notes.FilterNotes(ctx, &archived, nil)
+	noErr(t, "FilterNotes", err)
+	if got := noteTitles(notes); got != "archived note" {
+		t.Fatalf("archived notes are %q", got)
+	}
+	notes, err = s.Notes.FilterNotes(ctx, nil, []string{"HOME"})
+	noErr(t, "FilterNotes", err)
+	if got := noteTitles(notes); got != "home note" {
+		t.Fatalf("notes in home are %q", got)
+	}
+	_, err = s.Notes.FilterNotes(ctx, &archived, []string{"home"})
+	wantErr(t, "FilterNotes without matches", err, validations.ErrNoNotesFound)
+}
+
+func testNoteSearch(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	shopping := createCategory(t, s, alice.ID, "shopping")
+	inContent, err := s.Notes.Create(ctx, models.NewNote("weekly list", "buy milk and bread", []models.Category{shopping}, alice.ID))
+	noErr(t, "Create", err)
+	inTitle, err := s.Notes.Create(ctx, models.NewNote("milk prices", "compare stores", []models.Category{shopping}, alice.ID))
+	noErr(t, "Create", err)
+	createNote(t, s, bob.ID, "milk for bob")
+
+	results, err := s.Notes.Search(ctx, alice.ID, "Milk", 10)
+	noErr(t, "Search", err)
+	if len(results) != 2 || results[0].Note.ID != inTitle.ID || results[1].Note.ID != inContent.ID {
+		t.Fatalf("search results %+v, want the title match ranked above the content match", results)
+	}
+	if results[0].TitleSnippet != "<mark>milk</mark> prices" || results[1].ContentSnippet != "buy <mark>milk</mark> and bread" {
+		t.Fatalf("snippets %q and %q do not highlight the match", results[0].TitleSnippet, results[1].ContentSnippet)
+	}
+
+	results, err = s.Notes.Search(ctx, alice.ID, "milk -bread", 10)
+	noErr(t, "Search", err)
+	if len(results) != 1 || results[0].Note.ID != inTitle.ID {
+		t.Fatalf("search excluding bread returned %+v", results)
+	}
+	results, err = s.Notes.Search(ctx, alice.ID, "shopping", 1)
+	noErr(t, "Search", err)
+	if len(results) != 1 {
+		t.Fatalf("search by category with limit 1 returned %d results", len(results))
+	}
+
+	_, err = s.Notes.Search(ctx, alice.ID, "cheese", 10)
+	wantErr(t, "Search without matches", err, validations.ErrNoNotesFound)
+
+	// Notes shared with the user are searched along with their own.
+	cheese := createNote(t, s, bob.ID, "cheese for bob")
+	noErr(t, "SetPermission", s.Notes.SetPermission(ctx, models.NewNotePermission(cheese.ID, alice.ID, models.NoteViewer)))
+	results, err = s.Notes.Search(ctx, alice.ID, "cheese", 10)
+	noErr(t, "Search", err)
+	if len(results) != 1 || results[0].Note.ID != cheese.ID {
+		t.Fatalf("search of a shared note returned %+v", results)
+	}
+	results, err = s.Notes.Search(ctx, alice.ID, "milk", 10)
+	noErr(t, "Search", err)
+	if len(results) != 2 {
+		t.Fatalf("search returned %d results, want only alice's notes and not bob's unshared one", len(results))
+	}
+
+	_, err = s.Notes.Delete(ctx, inTitle)
+	noErr(t, "Delete", err)
+	results, err = s.Notes.Search(ctx, alice.ID, "prices", 10)
+	wantErr(t, "Search of a trashed note", err, validations.ErrNoNotesFound)
+}
+
+// changes describes a change set as the kind and change number of its rows,
+// in the order of their numbers within each kind.
+func testNoteOrder(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	notes := map[string]*models.Note{}
+	for _, title := range []string{"alpha", "bravo", "charlie", "delta"} {
+		notes[title] = createNote(t, s, alice.ID, title)
+	}
+	order := func() string {
+		t.Helper()
+		list, err := s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: alice.ID, SortField: "position", Limit: 10})
+		noErr(t, "ListNotes", err)
+		return noteTitles(list)
+	}
+	move := func(title, target string, after bool) {
+		t.Helper()
+		noErr(t, "MoveNote "+title, s.Notes.MoveNote(ctx, getNote(t, s, notes[title].ID), notes[target].ID, after))
+	}
+	if got := order(); got != "delta,charlie,bravo,alpha" {
+		t.Fatalf("new notes are in order %q, want newest first", got)
+	}
+
+	// Pinning and moving change what clients see of a note, so both bump
+	// its version.
+	bravo := getNote(t, s, notes["bravo"].ID)
+	noErr(t, "PinNote", s.Notes.PinNote(ctx, bravo, true))
+	if got := getNote(t, s, bravo.ID).Version; got != 2 || bravo.Version != 2 {
+		t.Fatalf("after pinning bravo is at version %d, returned %d, want 2", got, bravo.Version)
+	}
+	if got := order(); got != "bravo,delta,charlie,alpha" {
+		t.Fatalf("after pinning bravo the order is %q", got)
+	}
+	alpha := getNote(t, s, notes["alpha"].ID)
+	noErr(t, "MoveNote alpha", s.Notes.MoveNote(ctx, alpha, notes["charlie"].ID, false))
+	if got := getNote(t, s, alpha.ID).Version; got != 2 || alpha.Version != 2 {
+		t.Fatalf("after moving alpha is at version %d, returned %d, want 2", got, alpha.Version)
+	}
+	if got := order(); got != "bravo,delta,alpha,charlie" {
+		t.Fatalf("after moving alpha before charlie the order is %q", got)
+	}
+	err := s.Notes.MoveNote(ctx, getNote(t, s, notes["alpha"].ID), notes["bravo"].ID, false)
+	wantErr(t, "MoveNote next to a pinned note", err, validations.ErrMovePinned)
+	err = s.Notes.MoveNote(ctx, getNote(t, s, notes["alpha"].ID), notes["alpha"].ID, false)
+	wantErr(t, "MoveNote next to itself", err, validations.ErrMoveTarget)
+	move("delta", "charlie", true)
+	if got := order(); got != "bravo,alpha,charlie,delta" {
+		t.Fatalf("after moving delta after charlie the order is %q", got)
+	}
+
+	page, err := s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: alice.ID, SortField: "position", Limit: 2})
+	noErr(t, "ListNotes", err)
+	last := page[len(page)-1]
+	page, err = s.Notes.ListNotes(ctx, repositories.NoteListOptions{
+		UserID: alice.ID, SortField: "position", Limit: 2, After: &pagination.Cursor{Value: last.PositionCursor(), ID: last.ID},
+	})
+	noErr(t, "ListNotes after a cursor", err)
+	if got := noteTitles(page); got != "charlie,delta" {
+		t.Fatalf("second page in manual order is %q", got)
+	}
+
+	// Moving two notes around each other halves the gap next to alpha every
+	// time, until the positions have to be rebalanced.
+	for i := range 60 {
+		if i%2 == 0 {
+			move("delta", "charlie", false)
+		} else {
+			move("charlie", "delta", false)
+		}
+	}
+	if got := order(); got != "bravo,alpha,charlie,delta" {
+		t.Fatalf("after moving notes around each other the order is %q", got)
+	}
+	if got := getNote(t, s, notes["alpha"].ID).Position; got != repositories.PositionStep {
+		t.Fatalf("alpha is at %v, want %v after rebalancing", got, float64(repositories.PositionStep))
+	}
+
+	// Full updates of a note read before a move are stale, and later ones
+	// leave its position alone.
+	stale := getNote(t, s, notes["alpha"].ID)
+	move("alpha", "delta", true)
+	stale.Title = "alpha renamed"
+	_, err = s.Notes.UpdateNote(ctx, stale, alice.ID)
+	wantErr(t, "UpdateNote read before a move", err, validations.ErrVersionMismatch)
+	fresh := getNote(t, s, notes["alpha"].ID)
+	fresh.Title = "alpha renamed"
+	_, err = s.Notes.UpdateNote(ctx, fresh, alice.ID)
+	noErr(t, "UpdateNote", err)
+	if got := order(); got != "bravo,charlie,delta,alpha renamed" {
+		t.Fatalf("after updating a moved note the order is %q", got)
+	}
+
+	createNote(t, s, alice.ID, "echo")
+	if got := order(); got != "bravo,echo,charlie,delta,alpha renamed" {
+		t.Fatalf("a new note is in order %q, want it first after the pinned ones", got)
+	}
+
+	_, err = s.Notes.Delete(ctx, getNote(t, s, notes["charlie"].ID))
+	noErr(t, "Delete", err)
+	err = s.Notes.MoveNote(ctx, getNote(t, s, notes["delta"].ID), notes["charlie"].ID, false)
+	wantErr(t, "MoveNote next to a note in the trash", err, validations.ErrMoveTarget)
+	_, err = s.Notes.ListNotes(ctx, repositories.NoteListOptions{
+		UserID: alice.ID, SortField: "position", Limit: 10, After: &pagination.Cursor{Value: "first", ID: 1},
+	})
+	wantErr(t, "ListNotes with a bad position cursor", err, validations.ErrInvalidCursor)
+}
+
+func changes(set *models.ChangeSet) string {
+	var parts []string
+	for _, n := range set.Notes {
+		kind := "note"
+		if n.DeletedAt.Valid {
+			kind = "trashed"
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", kind, n.ChangeSeq))
+	}
+	for _, c := range set.Categories {
+		parts = append(parts, fmt.Sprintf("category:%d", c.ChangeSeq))
+	}
+	for _, ts := range set.Tombstones {
+		parts = append(parts, fmt.Sprintf("%s-tombstone:%d", ts.Kind, ts.ChangeSeq))
+	}
+	return strings.Join(parts, ",")
+}
+
+func testSyncChanges(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	home := createCategory(t, s, alice.ID, "home")
+	note := getNote(t, s, createNote(t, s, alice.ID, "synced note", home).ID)
+	createNote(t, s, bob.ID, "bob's note", createCategory(t, s, bob.ID, "home"))
+
+	set, err := s.Notes.Changes(ctx, alice.ID, 0, 10)
+	noErr(t, "Changes", err)
+	if got := changes(set); got != "note:2,category:1" || set.Latest != 2 {
+		t.Fatalf("initial changes are %q up to %d, want note:2,category:1 up to 2", got, set.Latest)
+	}
+
+	stale := *note
+	note.Title = "edited note"
+	_, err = s.Notes.UpdateNote(ctx, note, alice.ID)
+	noErr(t, "UpdateNote", err)
+	stale.Title = "stale note"
+	_, err = s.Notes.UpdateNote(ctx, &stale, alice.ID)
+	wantErr(t, "UpdateNote of a stale version", err, validations.ErrVersionMismatch)
+	_, err = s.Notes.Delete(ctx, note)
+	noErr(t, "Delete", err)
+	set, err = s.Notes.Changes(ctx, alice.ID, 2, 10)
+	noErr(t, "Changes", err)
+	if got := changes(set); got != "trashed:4" || set.Latest != 4 {
+		t.Fatalf("changes after an edit and trashing are %q up to %d, want trashed:4 up to 4", got, set.Latest)
+	}
+
+	_, err = s.Notes.EmptyTrash(ctx, alice.ID)
+	noErr(t, "EmptyTrash", err)
+	_, err = s.Categories.Delete(ctx, alice.ID, home.ID)
+	noErr(t, "Delete category", err)
+	set, err = s.Notes.Changes(ctx, alice.ID, 4, 10)
+	noErr(t, "Changes", err)
+	if got := changes(set); got != "note-tombstone:5,category-tombstone:6" {
+		t.Fatalf("changes after purging are %q, want note-tombstone:5,category-tombstone:6", got)
+	}
+	if set.Tombstones[0].EntityID != note.ID || set.Tombstones[1].EntityID != home.ID {
+		t.Fatalf("tombstones are %+v, want the note's and the category's", set.Tombstones)
+	}
+
+	set, err = s.Notes.Changes(ctx, alice.ID, 0, 1)
+	noErr(t, "Changes", err)
+	if got := changes(set); got != "note-tombstone:5" {
+		t.Fatalf("changes limited to one row each are %q, want note-tombstone:5", got)
+	}
+	set, err = s.Notes.Changes(ctx, bob.ID, 0, 10)
+	noErr(t, "Changes", err)
+	if got := changes(set); got != "note:2,category:1" {
+		t.Fatalf("bob's changes are %q, want note:2,category:1", got)
+	}
+
+	pruned, err := s.Notes.PruneTombstones(ctx, time.Now().Add(-time.Hour))
+	noErr(t, "PruneTombstones", err)
+	if pruned != 0 {
+		t.Fatalf("PruneTombstones pruned %d tombstones made after the cutoff", pruned)
+	}
+	pruned, err = s.Notes.PruneTombstones(ctx, time.Now().Add(time.Hour))
+	noErr(t, "PruneTombstones", err)
+	if pruned != 2 {
+		t.Fatalf("PruneTombstones pruned %d tombstones, want 2", pruned)
+	}
+	set, err = s.Notes.Changes(ctx, alice.ID, 0, 10)
+	noErr(t, "Changes", err)
+	if len(set.Tombstones) != 0 || set.PrunedThrough != 6 || set.Latest != 6 {
+		t.Fatalf("after pruning the change set is %+v, want no tombstones pruned through 6", set)
+	}
+}
+
+func testNotePermissions(t *testing.T, s Stores) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	shared := createNote(t, s, alice.ID, "shared note")
+	createNote(t, s, alice.ID, "private note")
+	createNote(t, s, bob.ID, "bob's note")
+
+	permission, err := s.Notes.GetPermission(ctx, shared.ID, bob.ID)
+	noErr(t, "GetPermission", err)
+	if permission != nil {
+		t.Fatalf("GetPermission before any grant returned %+v", permission)
+	}
+	noErr(t, "SetPermission", s.Notes.SetPermission(ctx, models.NewNotePermission(shared.ID, carol.ID, models.NoteViewer)))
+	noErr(t, "SetPermission", s.Notes.SetPermission(ctx, models.NewNotePermission(shared.ID, bob.ID, models.NoteViewer)))
+	noErr(t, "SetPermission again", s.Notes.SetPermission(ctx, models.NewNotePermission(shared.ID, bob.ID, models.NoteEditor)))
+	permission, err = s.Notes.GetPermission(ctx, shared.ID, bob.ID)
+	noErr(t, "GetPermission", err)
+	if permission == nil || permission.Role != models.NoteEditor {
+		t.Fatalf("GetPermission after granting twice returned %+v, want the editor role", permission)
+	}
+
+	permissions, err := s.Notes.ListPermissions(ctx, shared.ID)
+	noErr(t, "ListPermissions", err)
+	if len(permissions) != 2 || permissions[0].UserName != "bob" || permissions[1].UserName != "carol" ||
+		permissions[0].Role != models.NoteEditor || permissions[1].Role != models.NoteViewer {
+		t.Fatalf("ListPermissions returned %+v, want bob as editor and carol as viewer", permissions)
+	}
+
+	notes, err := s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: bob.ID, SharedWith: true, SortField: "title", Limit: 10})
+	noErr(t, "ListNotes shared with bob", err)
+	if got := noteTitles(notes); got != "shared note" {
+		t.Fatalf("notes shared with bob are %q, want shared note", got)
+	}
+	notes, err = s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: bob.ID, SortField: "title", Limit: 10})
+	noErr(t, "ListNotes of bob", err)
+	if got := noteTitles(notes); got != "bob's note" {
+		t.Fatalf("bob's own notes are %q, want only his", got)
+	}
+
+	deleted, err := s.Notes.DeletePermission(ctx, shared.ID, carol.ID)
+	noErr(t, "DeletePermission", err)
+	if !deleted {
+		t.Fatalf("DeletePermission reported no grant")
+	}
+	deleted, err = s.Notes.DeletePermission(ctx, shared.ID, carol.ID)
+	noErr(t, "DeletePermission again", err)
+	if deleted {
+		t.Fatalf("DeletePermission of a removed grant reported one")
+	}
+
+	_, err = s.Notes.Delete(ctx, getNote(t, s, shared.ID))
+	noErr(t, "Delete", err)
+	notes, err = s.Notes.ListNotes(ctx, repositories.NoteListOptions{UserID: bob.ID, SharedWith: true, SortField: "title", Limit: 10})
+	noErr(t, "ListNotes shared with bob", err)
+	if len(notes) != 0 {
+		t.Fatalf("notes shared with bob after trashing are %q, want none", noteTitles(notes))
+	}
+	_, err = s.Notes.EmptyTrash(ctx, alice.ID)
+	noErr(t, "EmptyTrash", err)
+	permission, err = s.Notes.GetPermission(ctx, shared.ID, bob.ID)
+	noErr(t, "GetPermission", err)
+	if permission != nil {
+		t.Fatalf("GetPermission after the note was purged returned %+v", permission)
+	}
+}
+
+func testTransaction(t *testing.T, s Stores) {
+	user := createUser(t, s, "alice")
+	work := createCategory(t, s, user.ID, "work")
+	errRollback := errors.New("roll back")
+
+	err := s.Notes.Transaction(ctx, func(tx repositories.NoteStore) error {
+		if _, err := tx.Categories().Create(ctx, models.NewCategory("rolled back", user.ID)); err != nil {
+			return err
+		}
+		if _, err := tx.Create(ctx, models.NewNote("rolled back note", "content", []models.Category{work}, user.ID)); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	wantErr(t, "Transaction", err, errRollback)
+	_, err = s.Notes.GetByTitle(ctx, user.ID, "rolled back note")
+	wantErr(t, "note created in a rolled back transaction", err, validations.ErrNotTitle)
+	_, err = s.Categories.FindByName(ctx, user.ID, "rolled back")
+	wantErr(t, "category created in a rolled back transaction", err, gorm.ErrRecordNotFound)
+
+	err = s.Notes.Transaction(ctx, func(tx repositories.NoteStore) error {
+		if _, err := tx.Create(ctx, models.NewNote("committed note", "content", []models.Category{work}, user.ID)); err != nil {
+			return err
+		}
+		nested := tx.Transaction(ctx, func(inner repositories.NoteStore) error {
+			if _, err := inner.Create(ctx, models.NewNote("savepoint note", "content", []models.Category{work}, user.ID)); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		wantErr(t, "nested Transaction", nested, errRollback)
+		return nil
+	})
+	noErr(t, "Transaction", err)
+	_, err = s.Notes.GetByTitle(ctx, user.ID, "committed note")
+	noErr(t, "note created in a committed transaction", err)
+	_, err = s.Notes.GetByTitle(ctx, user.ID, "savepoint note")
+	wantErr(t, "note created in a rolled back savepoint", err, validations.ErrNotTitle)
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"notes/internal/models"
+	"notes/pkg/validations"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// nextChangeSeq takes the next number of the user's change sequence. It runs
+// inside the transaction writing the change: the increment locks the user's
+// row until that transaction ends, so changes commit in the order of their
+// numbers.
+func nextChangeSeq(tx *gorm.DB, userId uint) (uint64, error) {
+	var seq uint64
+	err := tx.Raw("UPDATE users SET change_seq = change_seq + 1 WHERE id = ? RETURNING change_seq", userId).
+		Scan(&seq).Error
+	return seq, err
+}
+
+// addTombstone records the deletion of a note or category under the next
+// number of the user's change sequence.
+func addTombstone(tx *gorm.DB, userId uint, kind models.SyncKind, entityId uint) error {
+	seq, err := nextChangeSeq(tx, userId)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.SyncTombstone{
+		UserID:    userId,
+		Kind:      kind,
+		EntityID:  entityId,
+		ChangeSeq: seq,
+		DeletedAt: time.Now(),
+	}).Error
+}
+
+// Changes returns up to limit notes, categories and tombstones each whose
+// change number is greater than since. Rows changed after the user's counter
+// was read are left to the next call, so none is ever skipped.
+func (nr *NoteRepository) Changes(ctx context.Context, userId uint, since uint64, limit int) (*models.ChangeSet, error) {
+	set := &models.ChangeSet{}
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var counters struct {
+			ChangeSeq     uint64
+			SyncPrunedSeq uint64
+		}
+		if err := tx.Raw("SELECT change_seq, sync_pruned_seq FROM users WHERE id = ?", userId).
+			Scan(&counters).Error; err != nil {
+			return err
+		}
+		set.Latest, set.PrunedThrough = counters.ChangeSeq, counters.SyncPrunedSeq
+
+		window := func(q *gorm.DB) *gorm.DB {
+			return q.Where("user_id = ? AND change_seq > ? AND change_seq <= ?", userId, since, set.Latest).
+				Order("change_seq").
+				Limit(limit)
+		}
+		if err := tx.Unscoped().Preload("Categories").Scopes(window).Find(&set.Notes).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(window).Find(&set.Categories).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(window).Find(&set.Tombstones).Error
+	})
+	if err != nil {
+		return nil, validations.ErrSyncDB
+	}
+	return set, nil
+}
+
+// PruneTombstones deletes the tombstones of deletions made before the
+// cutoff, remembering for every user the newest number dropped.
+func (nr *NoteRepository) PruneTombstones(ctx context.Context, cutoff time.Time) (int64, error) {
+	var pruned int64
+	err := nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var newest []struct {
+			UserID    uint
+			ChangeSeq uint64
+		}
+		if err := tx.Model(&models.SyncTombstone{}).
+			Select("user_id, MAX(change_seq) AS change_seq").
+			Where("deleted_at < ?", cutoff).
+			Group("user_id").
+			Scan(&newest).Error; err != nil {
+			return err
+		}
+		for _, n := range newest {
+			if err := tx.Exec("UPDATE users SET sync_pruned_seq = ? WHERE id = ? AND sync_pruned_seq < ?",
+				n.ChangeSeq, n.UserID, n.ChangeSeq).Error; err != nil {
+				return err
+			}
+		}
+		res := tx.Where("deleted_at < ?", cutoff).Delete(&models.SyncTombstone{})
+		pruned = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, validations.ErrSyncDB
+	}
+	return pruned, nil
+}
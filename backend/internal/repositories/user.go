@@ -4,6 +4,8 @@ import (
 	"context"
 	"notes/internal/configs"
 	"notes/internal/models"
+	"notes/pkg/validations"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -20,7 +22,7 @@ func NewUserRepository(db *gorm.DB, config *configs.Config) *UserRepository {
 	}
 }
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
-	return r.db.Create(user).Error
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 func (ur *UserRepository) GetUserByID(ctx context.Context, userId uint) (*models.User, error) {
@@ -35,9 +37,212 @@ func (ur *UserRepository) GetUserByID(ctx context.Context, userId uint) (*models
 
 func (ur *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := ur.db.WithContext(ctx).Preload("Notes.Categories").Where("user_name ILIKE ?", username).First(&user).Error
+	err := ur.db.WithContext(ctx).Preload("Notes.Categories").Where(ilike(ur.db, "user_name"), username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+// SetTOTPSecret starts a TOTP enrollment, replacing any unconfirmed secret.
+func (ur *UserRepository) SetTOTPSecret(ctx context.Context, userId uint, secret string) error {
+	return ur.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_enabled = false", userId).
+		Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error
+}
+
+// EnableTOTP turns two-factor authentication on and replaces the user's
+// recovery codes, in one transaction.
+func (ur *UserRepository) EnableTOTP(ctx context.Context, userId uint, step int64, codes []models.RecoveryCode) error {
+	return ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]any{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// DisableTOTP turns two-factor authentication off and drops the secret and
+// the recovery codes.
+func (ur *UserRepository) DisableTOTP(ctx context.Context, userId uint) error {
+	return ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records step as the last accepted one. It fails with
+// ErrInvalidTOTPCode when a code of that step or a later one was already
+// used, which makes every code single use.
+func (ur *UserRepository) UseTOTPStep(ctx context.Context, userId uint, step int64) error {
+	res := ur.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userId, step).
+		Update("totp_last_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return validations.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// UseRecoveryCode spends the user's unused recovery code with the given hash.
+// It fails with ErrInvalidTOTPCode when there is none.
+func (ur *UserRepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string, now time.Time) error {
+	res := ur.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return validations.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// UpdatePassword stores a new password hash for the user.
+func (ur *UserRepository) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+	return ur.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userId).
+		Update("password", passwordHash).Error
+}
+
+// ListUsers returns up to limit users with an ID above afterId, with the
+// number of notes each has outside the trash.
+func (ur *UserRepository) ListUsers(ctx context.Context, afterId uint, limit int) ([]models.UserSummary, error) {
+	var users []models.UserSummary
+	err := ur.db.WithContext(ctx).Model(&models.User{}).
+		Select("users.id, users.user_name, users.is_admin, users.is_disabled, users.created_at, "+
+			"(SELECT count(*) FROM notes WHERE notes.user_id = users.id AND notes.deleted_at IS NULL) AS note_count").
+		Where("users.id > ?", afterId).
+		Order("users.id").
+		Limit(limit).
+		Scan(&users).Error
+	return users, err
+}
+
+// GetAuthStatus loads only the columns PROTECT needs on every request.
+func (ur *UserRepository) GetAuthStatus(ctx context.Context, userId uint) (*models.User, error) {
+	var user models.User
+	if err := ur.db.WithContext(ctx).
+		Select("id", "is_admin", "is_disabled").
+		First(&user, userId).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetDisabled disables or enables the user. It reports false when there is
+// no such user.
+func (ur *UserRepository) SetDisabled(ctx context.Context, userId uint, disabled bool) (bool, error) {
+	res := ur.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userId).
+		Update("is_disabled", disabled)
+	return res.RowsAffected > 0, res.Error
+}
+
+// PromoteToAdmin grants the admin role to the user with the given name.
+func (ur *UserRepository) PromoteToAdmin(ctx context.Context, username string) (bool, error) {
+	res := ur.db.WithContext(ctx).Model(&models.User{}).
+		Where(ilike(ur.db, "user_name"), username).
+		Update("is_admin", true)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RequirePasswordReset stores the hash of a reset token. It reports false
+// when there is no such user.
+func (ur *UserRepository) RequirePasswordReset(ctx context.Context, userId uint, tokenHash string, expiresAt time.Time) (bool, error) {
+	res := ur.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{"password_reset_hash": tokenHash, "password_reset_expires_at": expiresAt})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ResetPassword replaces the password of the user holding an unexpired reset
+// token with the given hash, and clears the token. It fails with
+// ErrResetToken when there is none.
+func (ur *UserRepository) ResetPassword(ctx context.Context, username, tokenHash, passwordHash string, now time.Time) error {
+	res := ur.db.WithContext(ctx).Model(&models.User{}).
+		Where(ilike(ur.db, "user_name")+" AND password_reset_hash = ? AND password_reset_expires_at > ?", username, tokenHash, now).
+		Updates(map[string]any{"password": passwordHash, "password_reset_hash": "", "password_reset_expires_at": nil})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return validations.ErrResetToken
+	}
+	return nil
+}
+
+// ExportData collects everything stored about the user: their notes,
+// including those in the trash, categories and revision metadata.
+func (ur *UserRepository) ExportData(ctx context.Context, userId uint) (*models.AccountExport, error) {
+	var user models.User
+	if err := ur.db.WithContext(ctx).First(&user, userId).Error; err != nil {
+		return nil, err
+	}
+	export := &models.AccountExport{
+		Profile: models.AccountProfile{
+			ID:               user.ID,
+			UserName:         user.UserName,
+			IsAdmin:          user.IsAdmin,
+			TwoFactorEnabled: user.TOTPEnabled,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+		},
+		Notes:      []models.Note{},
+		Categories: []models.Category{},
+		Revisions:  []models.RevisionMetadata{},
+	}
+
+	if err := ur.db.WithContext(ctx).Unscoped().
+		Preload("Categories").
+		Where("user_id = ?", userId).
+		Order("id").
+		Find(&export.Notes).Error; err != nil {
+		return nil, err
+	}
+	if err := ur.db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("name").
+		Find(&export.Categories).Error; err != nil {
+		return nil, err
+	}
+	if err := ur.db.WithContext(ctx).Model(&models.NoteRevision{}).
+		Select("note_revisions.note_id, note_revisions.revision, note_revisions.title, note_revisions.categories, "+
+			"note_revisions.is_archived, note_revisions.actor_id, note_revisions.created_at").
+		Joins("JOIN notes ON notes.id = note_revisions.note_id").
+		Where("notes.user_id = ?", userId).
+		Order("note_revisions.note_id, note_revisions.revision").
+		Find(&export.Revisions).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// DeleteUser permanently deletes the user. Notes, including those in the
+// trash, categories, sessions and tokens go with it through the cascading
+// foreign keys; the note_categories join rows have no cascade and are
+// removed first. It reports false when there is no such user.
+func (ur *UserRepository) DeleteUser(ctx context.Context, userId uint) (bool, error) {
+	var deleted bool
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM note_categories WHERE note_id IN (SELECT id FROM notes WHERE user_id = ?) "+
+			"OR category_id IN (SELECT id FROM categories WHERE user_id = ?)", userId, userId).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&models.User{}, userId)
+		deleted = res.RowsAffected > 0
+		return res.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/date"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// AccessTokenPrefix makes personal access tokens recognisable, both in
+	// the Authorization header and when one leaks into a log or a commit.
+	AccessTokenPrefix = "notes_pat_"
+
+	DefaultAccessTokenDays = 30
+	MaxAccessTokenDays     = 365
+
+	// accessTokenTouchInterval is how stale the last use of a token may get
+	// before a request records it again, so that scripts calling in a loop
+	// do not write to the database on every request.
+	accessTokenTouchInterval = time.Minute
+)
+
+type AccessTokenService struct {
+	tokenRepo *repositories.AccessTokenRepository
+}
+
+func NewAccessTokenService(repo *repositories.AccessTokenRepository) *AccessTokenService {
+	return &AccessTokenService{
+		tokenRepo: repo,
+	}
+}
+
+// Create issues a token for the user. The returned string is the only copy
+// of the token; it cannot be recovered later.
+func (as *AccessTokenService) Create(ctx context.Context, userId uint, name string, scopes []string, expiresInDays int) (*models.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 50 {
+		return nil, "", validations.ErrAccessTokenName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresInDays == 0 {
+		expiresInDays = DefaultAccessTokenDays
+	}
+	if expiresInDays < 1 || expiresInDays > MaxAccessTokenDays {
+		return nil, "", validations.ErrAccessTokenExpiry
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", validations.ErrTokenGeneration
+	}
+	raw := AccessTokenPrefix + secret
+	expiresAt := date.ArgentinaTimeNow().Add(time.Duration(expiresInDays) * 24 * time.Hour)
+	token := models.NewAccessToken(userId, name, utils.HashToken(raw), scopes, expiresAt)
+	if err := as.tokenRepo.Create(ctx, token); err != nil {
+		return nil, "", validations.ErrAccessTokenDB
+	}
+	return token, raw, nil
+}
+
+func (as *AccessTokenService) List(ctx context.Context, userId uint) ([]models.AccessToken, error) {
+	tokens, err := as.tokenRepo.ListForUser(ctx, userId)
+	if err != nil {
+		return nil, validations.ErrAccessTokenDB
+	}
+	return tokens, nil
+}
+
+func (as *AccessTokenService) Revoke(ctx context.Context, userId, id uint) error {
+	revoked, err := as.tokenRepo.Revoke(ctx, userId, id, *date.ArgentinaTimeNow())
+	if err != nil {
+		return validations.ErrAccessTokenDB
+	}
+	if !revoked {
+		return validations.ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAll revokes every token of the user.
+func (as *AccessTokenService) RevokeAll(ctx context.Context, userId uint) error {
+	if err := as.tokenRepo.RevokeAllForUser(ctx, userId, *date.ArgentinaTimeNow()); err != nil {
+		return validations.ErrAccessTokenDB
+	}
+	return nil
+}
+
+// Authenticate resolves the raw token from an Authorization header. The
+// last use of the token is recorded at most once per
+// accessTokenTouchInterval.
+func (as *AccessTokenService) Authenticate(ctx context.Context, raw string) (*models.AccessToken, error) {
+	if !strings.HasPrefix(raw, AccessTokenPrefix) {
+		return nil, validations.ErrAccessToken
+	}
+	now := *date.ArgentinaTimeNow()
+	token, err := as.tokenRepo.FindActive(ctx, utils.HashToken(raw), now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, validations.ErrAccessToken
+	}
+	if err != nil {
+		return nil, validations.ErrAccessTokenDB
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenTouchInterval {
+		if err := as.tokenRepo.Touch(ctx, token.ID, now); err != nil {
+			return nil, validations.ErrAccessTokenDB
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// normalizeScopes validates the requested scopes and removes duplicates.
+// notes:write implies notes:read, since writes return the notes they change.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, validations.ErrAccessTokenScopes
+	}
+	var out []string
+	for _, scope := range scopes {
+		if !slices.Contains(models.AccessTokenScopes, scope) {
+			return nil, validations.ErrAccessTokenScopes
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	if slices.Contains(out, models.ScopeNotesWrite) && !slices.Contains(out, models.ScopeNotesRead) {
+		out = append([]string{models.ScopeNotesRead}, out...)
+	}
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+)
+
+func TestAccessTokenScopesAndLastUse(t *testing.T) {
+	ctx := context.Background()
+	gormDB := dbtest.SQLite(t)
+	_, users := sqlStores(gormDB)
+	user := models.NewUser("alice", "hash", nil)
+	if err := users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	as := NewAccessTokenService(repositories.NewAccessTokenRepository(gormDB, &configs.Config{}))
+
+	// Writes return the notes they change, so they imply reading.
+	token, raw, err := as.Create(ctx, user.ID, "backup script", []string{models.ScopeNotesWrite}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := token.ScopeList(); !slices.Equal(got, []string{models.ScopeNotesRead, models.ScopeNotesWrite}) {
+		t.Fatalf("write token has scopes %v, want read and write", got)
+	}
+
+	first, err := as.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if first.LastUsedAt == nil {
+		t.Fatalf("Authenticate did not record the use of the token")
+	}
+	// A second request within the touch interval leaves the recorded use
+	// alone instead of writing it again.
+	if _, err := as.Authenticate(ctx, raw); err != nil {
+		t.Fatalf("Authenticate again: %v", err)
+	}
+	tokens, err := as.List(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil || !tokens[0].LastUsedAt.Equal(*first.LastUsedAt) {
+		t.Fatalf("tokens are %+v, want the first use of the token recorded", tokens)
+	}
+}
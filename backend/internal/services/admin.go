@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/date"
+	"notes/pkg/pagination"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+const (
+	adminUserSort = "id"
+
+	// PasswordResetTTL is how long a reset token issued by an admin is valid.
+	PasswordResetTTL = 24 * time.Hour
+)
+
+// AdminService holds the user management operations reserved to admins.
+type AdminService struct {
+	userRepo     repositories.UserStore
+	sessions     *SessionService
+	accessTokens *AccessTokenService
+}
+
+func NewAdminService(userRepo repositories.UserStore, sessions *SessionService, accessTokens *AccessTokenService) *AdminService {
+	return &AdminService{
+		userRepo:     userRepo,
+		sessions:     sessions,
+		accessTokens: accessTokens,
+	}
+}
+
+// ListUsers returns one page of users ordered by ID, with their note counts.
+func (as *AdminService) ListUsers(ctx context.Context, page pagination.Request) (*models.UserPage, error) {
+	if page.Limit < 1 || page.Limit > 100 {
+		return nil, validations.ErrInvalidPageLimit
+	}
+	var afterId uint
+	if page.Cursor != "" {
+		cursor, err := pagination.Decode(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != adminUserSort {
+			return nil, validations.ErrInvalidCursor
+		}
+		afterId = cursor.ID
+	}
+
+	users, err := as.userRepo.ListUsers(ctx, afterId, page.Limit+1)
+	if err != nil {
+		return nil, validations.ErrUserDB
+	}
+	userPage := &models.UserPage{Users: users}
+	if len(users) > page.Limit {
+		userPage.Users = users[:page.Limit]
+		userPage.NextCursor = pagination.Cursor{
+			Sort: adminUserSort,
+			ID:   userPage.Users[page.Limit-1].ID,
+		}.Encode()
+	}
+	return userPage, nil
+}
+
+// SetDisabled disables or enables a user. Disabling also ends the user's
+// sessions and revokes their access tokens.
+func (as *AdminService) SetDisabled(ctx context.Context, adminId, userId uint, disabled bool) error {
+	if adminId == userId {
+		return validations.ErrAdminSelf
+	}
+	found, err := as.userRepo.SetDisabled(ctx, userId, disabled)
+	if err != nil {
+		return validations.ErrUserDB
+	}
+	if !found {
+		return validations.ErrUserNotFound
+	}
+	if !disabled {
+		return nil
+	}
+	return as.signOutEverywhere(ctx, userId)
+}
+
+// ForcePasswordReset locks the user out until they choose a new password.
+// It returns the reset token, which the admin hands to the user; there is no
+// other way to deliver it.
+func (as *AdminService) ForcePasswordReset(ctx context.Context, userId uint) (string, time.Time, error) {
+	token, err := utils.RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, validations.ErrTokenGeneration
+	}
+	expiresAt := date.ArgentinaTimeNow().Add(PasswordResetTTL)
+	found, err := as.userRepo.RequirePasswordReset(ctx, userId, utils.HashToken(token), expiresAt)
+	if err != nil {
+		return "", time.Time{}, validations.ErrUserDB
+	}
+	if !found {
+		return "", time.Time{}, validations.ErrUserNotFound
+	}
+	if err := as.signOutEverywhere(ctx, userId); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// DeleteUser permanently deletes a user and everything they own.
+func (as *AdminService) DeleteUser(ctx context.Context, adminId, userId uint) error {
+	if adminId == userId {
+		return validations.ErrAdminSelf
+	}
+	found, err := as.userRepo.DeleteUser(ctx, userId)
+	if err != nil {
+		return validations.ErrUserDB
+	}
+	if !found {
+		return validations.ErrUserNotFound
+	}
+	return nil
+}
+
+func (as *AdminService) signOutEverywhere(ctx context.Context, userId uint) error {
+	if err := as.sessions.RevokeAll(ctx, userId); err != nil {
+		return err
+	}
+	return as.accessTokens.RevokeAll(ctx, userId)
+}
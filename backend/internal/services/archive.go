@@ -0,0 +1,267 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"notes/internal/models"
+	"notes/pkg/date"
+	"notes/pkg/markdown"
+	"notes/pkg/pagination"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+const (
+	// exportPageSize is how many notes are loaded at a time while exporting.
+	exportPageSize = 100
+	// maxImportEntries and maxImportEntryBytes bound the work a single
+	// import archive can cause.
+	maxImportEntries    = 500
+	maxImportEntryBytes = 64 << 10
+)
+
+// noteFrontMatter is the YAML front matter of an exported note.
+type noteFrontMatter struct {
+	Title      string     `yaml:"title"`
+	Categories []string   `yaml:"categories"`
+	Archived   bool       `yaml:"archived"`
+	CreatedAt  time.Time  `yaml:"created_at"`
+	UpdatedAt  *time.Time `yaml:"updated_at,omitempty"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFileName builds a unique, filesystem-safe name for the note's file.
+func exportFileName(note *models.Note) string {
+	slug := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(note.Title), "-"), "-")
+	if slug == "" {
+		return fmt.Sprintf("%d.md", note.ID)
+	}
+	return fmt.Sprintf("%d-%s.md", note.ID, slug)
+}
+
+// ExportMarkdown writes a zip archive to w with one markdown file per note
+// of the user outside the trash, oldest first. Notes are loaded a page at a
+// time, so the archive is streamed as it is built.
+func (ns *NoteService) ExportMarkdown(ctx context.Context, userId uint, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	page := pagination.Request{Limit: exportPageSize, Sort: "created_at"}
+	for {
+		notePage, err := ns.ListNotes(ctx, userId, nil, nil, page)
+		if errors.Is(err, validations.ErrNoNotesFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i := range notePage.Notes {
+			if err := writeNoteFile(archive, &notePage.Notes[i]); err != nil {
+				return err
+			}
+		}
+		if notePage.NextCursor == "" {
+			break
+		}
+		page.Cursor = notePage.NextCursor
+	}
+	return archive.Close()
+}
+
+func writeNoteFile(archive *zip.Writer, note *models.Note) error {
+	categories := make([]string, 0, len(note.Categories))
+	for _, c := range note.Categories {
+		categories = append(categories, c.Name)
+	}
+	modified := note.CreatedAt
+	if note.UpdatedAt != nil {
+		modified = *note.UpdatedAt
+	}
+
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     exportFileName(note),
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	return markdown.WriteFrontMatter(file, noteFrontMatter{
+		Title:      note.Title,
+		Categories: categories,
+		Archived:   note.IsArchived,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+	}, note.Content)
+}
+
+// ImportMarkdown creates a note for every markdown file of a zip archive in
+// the format written by ExportMarkdown. Files that fail validation are
+// skipped and files whose title is already used by one of the user's notes
+// are reported as conflicts; neither stops the rest of the import. Files
+// without front matter use their name as title.
+func (ns *NoteService) ImportMarkdown(ctx context.Context, userId uint, archive *zip.Reader) (*models.NoteImportReport, error) {
+	if len(archive.File) > maxImportEntries {
+		return nil, validations.ErrImportTooLarge
+	}
+
+	report := &models.NoteImportReport{}
+	seenTitles := make(map[string]bool)
+	for _, file := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entry := models.NoteImportEntry{File: file.Name}
+		if !strings.EqualFold(path.Ext(file.Name), ".md") {
+			entry.Reason = "not a markdown file"
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+
+		note, err := readNoteFile(file)
+		if err != nil {
+			entry.Reason = err.Error()
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+		entry.Title = note.Title
+
+		if seenTitles[note.Title] {
+			entry.Reason = validations.ErrDuplicateTitle.Error()
+			report.Conflicts = append(report.Conflicts, entry)
+			continue
+		}
+		if existing, _ := ns.noteRepo.GetByTitle(ctx, userId, note.Title); existing != nil {
+			entry.NoteID = existing.ID
+			entry.Reason = validations.ErrDuplicateTitle.Error()
+			report.Conflicts = append(report.Conflicts, entry)
+			continue
+		}
+
+		created, err := ns.importNote(ctx, userId, note)
+		if errors.Is(err, validations.ErrDuplicateTitle) {
+			entry.Reason = err.Error()
+			report.Conflicts = append(report.Conflicts, entry)
+			continue
+		}
+		if err != nil {
+			entry.Reason = err.Error()
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+		seenTitles[note.Title] = true
+		entry.NoteID = created.ID
+		report.Created = append(report.Created, entry)
+	}
+	return report, nil
+}
+
+// importedNote is a validated archive entry; category names are formatted
+// but not yet resolved to the user's categories.
+type importedNote struct {
+	Title      string
+	Content    string
+	Categories []string
+	Archived   bool
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
+
+func readNoteFile(file *zip.File) (*importedNote, error) {
+	if file.UncompressedSize64 > maxImportEntryBytes {
+		return nil, validations.ErrImportEntryTooLarge
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	doc, err := io.ReadAll(io.LimitReader(rc, maxImportEntryBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(doc) > maxImportEntryBytes {
+		return nil, validations.ErrImportEntryTooLarge
+	}
+
+	var meta noteFrontMatter
+	body, err := markdown.ParseFrontMatter(doc, &meta)
+	if err != nil {
+		return nil, validations.ErrImportFrontMatter
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+	}
+
+	_, title, err := utils.ValidateAndFormatTitle(meta.Title)
+	if err != nil {
+		return nil, err
+	}
+	_, content, err := utils.ValidateAndFormatContent(body)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make(map[string]bool)
+	categories := make([]string, 0, len(meta.Categories))
+	for _, name := range meta.Categories {
+		_, formatted, err := utils.ValidateAndFormatCategory(name)
+		if err != nil {
+			return nil, err
+		}
+		if !unique[formatted] {
+			unique[formatted] = true
+			categories = append(categories, formatted)
+		}
+	}
+	if len(categories) > 4 {
+		return nil, validations.ErrTooManyCat
+	}
+	if len(categories) < 1 {
+		return nil, validations.ErrZeroCategory
+	}
+
+	createdAt := meta.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = *date.ArgentinaTimeNow()
+	}
+	return &importedNote{
+		Title:      title,
+		Content:    content,
+		Categories: categories,
+		Archived:   meta.Archived,
+		CreatedAt:  createdAt,
+		UpdatedAt:  meta.UpdatedAt,
+	}, nil
+}
+
+func (ns *NoteService) importNote(ctx context.Context, userId uint, imported *importedNote) (*models.Note, error) {
+	categories := make([]models.Category, 0, len(imported.Categories))
+	for _, name := range imported.Categories {
+		category, err := ns.CategoryService.GetByNameOrCreate(ctx, userId, name)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *category)
+	}
+
+	note := models.NewNote(imported.Title, imported.Content, categories, userId)
+	note.IsArchived = imported.Archived
+	note.CreatedAt = imported.CreatedAt
+	note.UpdatedAt = imported.UpdatedAt
+	if _, err := ns.noteRepo.Create(ctx, note); err != nil {
+		return nil, err
+	}
+	ns.publish(models.NewNoteEvent(models.NoteCreated, note))
+	return note, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"notes/pkg/throttle"
+)
+
+// AttemptThrottle slows down guessing of a secret other than a login
+// password, such as the password of a share link. Failures are counted per
+// key under a single policy; keys that are held back get a ThrottledError
+// wrapping err.
+type AttemptThrottle struct {
+	store  throttle.Store
+	policy throttle.Policy
+	err    error
+	logger *slog.Logger
+	// now is the clock of the throttle, replaced in tests.
+	now func() time.Time
+}
+
+func NewAttemptThrottle(store throttle.Store, policy throttle.Policy, err error, logger *slog.Logger) *AttemptThrottle {
+	return &AttemptThrottle{
+		store:  store,
+		policy: policy,
+		err:    err,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Check returns a ThrottledError when the key must wait before trying
+// again. Like logins, attempts are let through if the store fails.
+func (at *AttemptThrottle) Check(ctx context.Context, key string) error {
+	now := at.now()
+	entry, err := at.store.Get(ctx, key, now)
+	if err != nil {
+		at.logger.Error("attempt throttle store failed", "error", err)
+		return nil
+	}
+	wait := at.policy.Wait(entry, now)
+	if wait <= 0 {
+		return nil
+	}
+	at.logger.Warn("security: attempt throttled", "key", key, "retry_after", wait.Round(time.Second))
+	return &ThrottledError{Err: at.err, RetryAfter: wait}
+}
+
+// Failed records a failed attempt of the key.
+func (at *AttemptThrottle) Failed(ctx context.Context, key string) {
+	entry, err := at.store.Fail(ctx, key, at.now(), at.policy.TTL())
+	if err != nil {
+		at.logger.Error("attempt throttle store failed", "error", err)
+		return
+	}
+	if at.policy.Locked(entry) && entry.Failures == at.policy.LockoutAfter {
+		at.logger.Warn("security: attempts locked out", "key", key, "failures", entry.Failures, "duration", at.policy.LockoutFor)
+	}
+}
+
+// Succeeded clears the failures of the key.
+func (at *AttemptThrottle) Succeeded(ctx context.Context, key string) {
+	if err := at.store.Reset(ctx, key); err != nil {
+		at.logger.Error("attempt throttle store failed", "error", err)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+// maxBulkNotes caps how many notes a single bulk request may touch.
+const maxBulkNotes = 100
+
+type BulkOperation string
+
+const (
+	BulkArchive        BulkOperation = "archive"
+	BulkUnarchive      BulkOperation = "unarchive"
+	BulkDelete         BulkOperation = "delete"
+	BulkAddCategory    BulkOperation = "add_category"
+	BulkRemoveCategory BulkOperation = "remove_category"
+)
+
+// BulkNoteResult is the outcome of a bulk operation on one note. Err is nil
+// when the operation succeeded for that note.
+type BulkNoteResult struct {
+	NoteID uint
+	Err    error
+}
+
+// errBulkRollback aborts the transaction of an atomic bulk run once every
+// note has been tried and at least one failed.
+var errBulkRollback = errors.New("bulk operation rolled back")
+
+// BulkNotesForUser applies op to each of the user's notes and reports the
+// outcome per note, in request order with duplicates removed. Atomic runs
+// share one transaction that is only committed when every note succeeds;
+// each note still runs in its own savepoint so every failure is reported.
+// Best-effort runs keep whatever succeeded. committed reports whether the
+// successful changes were kept.
+func (us *UserService) BulkNotesForUser(ctx context.Context, userId uint, op BulkOperation, noteIds []uint, categoryName string, bestEffort bool) (results []BulkNoteResult, committed bool, err error) {
+	switch op {
+	case BulkArchive, BulkUnarchive, BulkDelete:
+	case BulkAddCategory, BulkRemoveCategory:
+		valid, formattedName, err := utils.ValidateAndFormatCategory(categoryName)
+		if !valid {
+			return nil, false, err
+		}
+		categoryName = formattedName
+	default:
+		return nil, false, validations.ErrBulkOperation
+	}
+
+	ids := make([]uint, 0, len(noteIds))
+	seen := make(map[uint]bool, len(noteIds))
+	for _, id := range noteIds {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxBulkNotes {
+		return nil, false, validations.ErrBulkNoteIds
+	}
+
+	results = make([]BulkNoteResult, 0, len(ids))
+	if bestEffort {
+		for _, id := range ids {
+			results = append(results, BulkNoteResult{NoteID: id, Err: us.applyBulk(ctx, op, userId, id, categoryName)})
+		}
+		return results, true, nil
+	}
+
+	err = us.noteService.InTransaction(ctx, func(txService *NoteService) error {
+		failed := false
+		for _, id := range ids {
+			itemErr := txService.InTransaction(ctx, func(itemService *NoteService) error {
+				itemUserService := &UserService{userRepo: us.userRepo, noteService: itemService, sessionService: us.sessionService}
+				return itemUserService.applyBulk(ctx, op, userId, id, categoryName)
+			})
+			failed = failed || itemErr != nil
+			results = append(results, BulkNoteResult{NoteID: id, Err: itemErr})
+		}
+		if failed {
+			return errBulkRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkRollback) {
+		return nil, false, err
+	}
+	return results, err == nil, nil
+}
+
+// applyBulk applies op to one note under the same access policy as the
+// single-note routes: categories need EditNote, the rest ManageNote.
+func (us *UserService) applyBulk(ctx context.Context, op BulkOperation, userId uint, noteId uint, categoryName string) error {
+	action := ManageNote
+	if op == BulkAddCategory || op == BulkRemoveCategory {
+		action = EditNote
+	}
+	note, err := us.noteService.AuthorizeNote(ctx, userId, noteId, action)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case BulkArchive, BulkUnarchive:
+		if note.IsArchived == (op == BulkArchive) {
+			return nil
+		}
+		_, err = us.noteService.ToggleArchiveStatus(ctx, noteId, 0, userId)
+	case BulkDelete:
+		_, err = us.noteService.DeleteNoteForUser(ctx, noteId, userId)
+	case BulkAddCategory:
+		_, err = us.noteService.AddCategoryToNote(ctx, noteId, categoryName, 0, userId)
+	case BulkRemoveCategory:
+		_, err = us.noteService.RemoveCategoryFromNote(ctx, noteId, categoryName, 0, userId)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"notes/internal/models"
 	"notes/internal/repositories"
 	"notes/pkg/date"
@@ -12,43 +13,46 @@ import (
 )
 
 type CategoryService struct {
-	categoryRepo *repositories.CategoryRepository
+	categoryRepo repositories.CategoryStore
 }
 
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
+func NewCategoryService(repo repositories.CategoryStore) *CategoryService {
 	return &CategoryService{
 		categoryRepo: repo,
 	}
 }
 
-func (cs *CategoryService) Create(ctx context.Context, name string) (*models.Category, error) {
+func (cs *CategoryService) Create(ctx context.Context, userId uint, name string) (*models.Category, error) {
 	valid, formattedName, err := utils.ValidateAndFormatCategory(name)
 	if !valid {
 		return nil, err
 	}
 
-	c, err := cs.categoryRepo.FindByName(ctx, formattedName)
+	c, err := cs.categoryRepo.FindByName(ctx, userId, formattedName)
 	if c != nil {
 		return nil, validations.ErrCatAlreadyExist
 	}
 	if err != gorm.ErrRecordNotFound {
 		return nil, validations.ErrFetchingCategory
 	}
-	category := models.NewCategory(formattedName)
+	category := models.NewCategory(formattedName, userId)
 	createdCategory, err := cs.categoryRepo.Create(ctx, category)
 	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, validations.ErrCatAlreadyExist
+		}
 		return nil, validations.ErrCatCreate
 	}
 	return createdCategory, nil
 }
 
-func (cs *CategoryService) Update(ctx context.Context, id uint, newName string) (*models.Category, error) {
+func (cs *CategoryService) Update(ctx context.Context, userId uint, id uint, newName string) (*models.Category, error) {
 	valid, formattedName, err := utils.ValidateAndFormatCategory(newName)
 	if !valid {
 		return nil, err
 	}
 
-	category, err := cs.categoryRepo.FindByID(ctx, id)
+	category, err := cs.categoryRepo.FindByID(ctx, userId, id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, validations.ErrCatNotFound
@@ -56,27 +60,33 @@ func (cs *CategoryService) Update(ctx context.Context, id uint, newName string)
 		return nil, validations.ErrFetchingCategory
 	}
 
-	existingCategory, err := cs.categoryRepo.FindByName(ctx, formattedName)
+	existingCategory, err := cs.categoryRepo.FindByName(ctx, userId, formattedName)
 	if err == nil && existingCategory.ID != id {
 		return nil, validations.ErrCatAlreadyExist
 	}
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, validations.ErrFetchingCategory
 	}
+	if category.Name == formattedName {
+		return nil, validations.ErrNoChangesDetected
+	}
 
 	category.Name = formattedName
 	category.UpdatedAt = date.ArgentinaTimeNow()
 
 	updatedID, err := cs.categoryRepo.Update(ctx, category)
 	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, validations.ErrCatAlreadyExist
+		}
 		return nil, validations.ErrCatUpdate
 	}
 	category.ID = *updatedID
 	return category, nil
 }
 
-func (cs *CategoryService) Delete(ctx context.Context, id uint) (*uint, error) {
-	_, err := cs.categoryRepo.FindByID(ctx, id)
+func (cs *CategoryService) Delete(ctx context.Context, userId uint, id uint) (*uint, error) {
+	_, err := cs.categoryRepo.FindByID(ctx, userId, id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, validations.ErrCatNotFound
@@ -84,23 +94,51 @@ func (cs *CategoryService) Delete(ctx context.Context, id uint) (*uint, error) {
 		return nil, validations.ErrFetchingCategory
 	}
 
-	deletedID, err := cs.categoryRepo.Delete(ctx, id)
+	soleNotes, err := cs.categoryRepo.CountSoleCategoryNotes(ctx, id)
+	if err != nil {
+		return nil, validations.ErrFetchingCategory
+	}
+	if soleNotes > 0 {
+		return nil, validations.ErrCatInUse
+	}
+
+	deletedID, err := cs.categoryRepo.Delete(ctx, userId, id)
 	if err != nil {
 		return nil, validations.ErrCatDelete
 	}
 	return deletedID, nil
 }
 
-func (cs *CategoryService) GetAll(ctx context.Context) ([]models.Category, error) {
-	categories, err := cs.categoryRepo.FindAll(ctx)
+// Merge moves every note from the source category to the target category and
+// deletes the source. Both categories must belong to the user.
+func (cs *CategoryService) Merge(ctx context.Context, userId uint, sourceId uint, targetId uint) (*models.Category, error) {
+	if sourceId == targetId {
+		return nil, validations.ErrCatMergeSelf
+	}
+	if _, err := cs.GetById(ctx, userId, sourceId); err != nil {
+		return nil, err
+	}
+	target, err := cs.GetById(ctx, userId, targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.categoryRepo.Merge(ctx, userId, sourceId, targetId); err != nil {
+		return nil, validations.ErrCatMerge
+	}
+	return target, nil
+}
+
+func (cs *CategoryService) GetAll(ctx context.Context, userId uint) ([]models.Category, error) {
+	categories, err := cs.categoryRepo.FindAll(ctx, userId)
 	if err != nil {
 		return nil, validations.ErrFetchingCategories
 	}
 	return categories, nil
 }
 
-func (cs *CategoryService) GetById(ctx context.Context, id uint) (*models.Category, error) {
-	category, err := cs.categoryRepo.FindByID(ctx, id)
+func (cs *CategoryService) GetById(ctx context.Context, userId uint, id uint) (*models.Category, error) {
+	category, err := cs.categoryRepo.FindByID(ctx, userId, id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, validations.ErrCatNotFound
@@ -110,13 +148,13 @@ func (cs *CategoryService) GetById(ctx context.Context, id uint) (*models.Catego
 	return category, nil
 }
 
-func (cs *CategoryService) GetByName(ctx context.Context, name string) (*models.Category, error) {
+func (cs *CategoryService) GetByName(ctx context.Context, userId uint, name string) (*models.Category, error) {
 	valid, formattedName, err := utils.ValidateAndFormatCategory(name)
 	if !valid {
 		return nil, err
 	}
 
-	category, err := cs.categoryRepo.FindByName(ctx, formattedName)
+	category, err := cs.categoryRepo.FindByName(ctx, userId, formattedName)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, validations.ErrCatNotFound
@@ -126,13 +164,13 @@ func (cs *CategoryService) GetByName(ctx context.Context, name string) (*models.
 	return category, nil
 }
 
-func (cs *CategoryService) GetByNameOrCreate(ctx context.Context, name string) (*models.Category, error) {
+func (cs *CategoryService) GetByNameOrCreate(ctx context.Context, userId uint, name string) (*models.Category, error) {
 	valid, formattedName, err := utils.ValidateAndFormatCategory(name)
 	if !valid {
 		return nil, err
 	}
 
-	category, err := cs.categoryRepo.FindByName(ctx, formattedName)
+	category, err := cs.categoryRepo.FindByName(ctx, userId, formattedName)
 	if category != nil {
 		return category, nil
 	}
@@ -140,7 +178,7 @@ func (cs *CategoryService) GetByNameOrCreate(ctx context.Context, name string) (
 		return nil, validations.ErrFetchingCategory
 	}
 
-	category = models.NewCategory(formattedName)
+	category = models.NewCategory(formattedName, userId)
 	createdCategory, err := cs.categoryRepo.Create(ctx, category)
 	if err != nil {
 		return nil, validations.ErrCatCreate
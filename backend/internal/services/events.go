@@ -0,0 +1,226 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"notes/internal/models"
+)
+
+// DropPolicy decides what happens to an event published while a
+// subscriber's buffer is full.
+type DropPolicy string
+
+const (
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest DropPolicy = "drop-oldest"
+	// DropNewest discards the event being published.
+	DropNewest DropPolicy = "drop-newest"
+	// Disconnect ends the subscription; the client resumes with Last-Event-ID.
+	Disconnect DropPolicy = "disconnect"
+)
+
+func ParseDropPolicy(policy string) (DropPolicy, error) {
+	switch DropPolicy(policy) {
+	case DropOldest, DropNewest, Disconnect:
+		return DropPolicy(policy), nil
+	}
+	return "", fmt.Errorf("unknown drop policy %q, use %s, %s or %s", policy, DropOldest, DropNewest, Disconnect)
+}
+
+// EventBus fans note events out to the subscriptions of the note's owner
+// within this process. The latest events are kept in a bounded replay log so
+// a reconnecting client can resume from the last event it saw.
+//
+// Event IDs are prefixed with an epoch that changes on every start, so IDs
+// handed out by a previous process are never mistaken for current ones.
+type EventBus struct {
+	mu          sync.Mutex
+	epoch       string
+	bufferSize  int
+	replaySize  int
+	policy      DropPolicy
+	lastID      uint64
+	evictedID   uint64
+	replay      []models.NoteEvent
+	subscribers map[uint]map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewEventBus(bufferSize, replaySize int, policy DropPolicy) *EventBus {
+	return &EventBus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		bufferSize:  max(bufferSize, 1),
+		replaySize:  max(replaySize, 0),
+		policy:      policy,
+		subscribers: map[uint]map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the event its ID, records it in the replay log and hands
+// it to every subscription of its user, applying the drop policy to those
+// whose buffer is full. It never blocks on a slow subscriber.
+func (b *EventBus) Publish(event models.NoteEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.evictedID = b.replay[0].ID
+			b.replay = append(b.replay[1:], event)
+		} else {
+			b.replay = append(b.replay, event)
+		}
+	} else {
+		b.evictedID = event.ID
+	}
+
+	for sub := range b.subscribers[event.UserID] {
+		select {
+		case sub.events <- event:
+			continue
+		default:
+		}
+		switch b.policy {
+		case DropOldest:
+			select {
+			case <-sub.events:
+			default:
+			}
+			select {
+			case sub.events <- event:
+			default:
+			}
+			sub.lagged.Store(true)
+		case DropNewest:
+			sub.lagged.Store(true)
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscription for the user's events. When
+// lastEventID is set, the events of the user the client missed since then
+// are returned for replay; complete is false when some of them can no
+// longer be replayed because the ID is unknown, from another epoch or older
+// than the replay log, and the client must reload its notes.
+func (b *EventBus) Subscribe(userId uint, lastEventID string) (sub *Subscription, missed []models.NoteEvent, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		bus:    b,
+		userID: userId,
+		events: make(chan models.NoteEvent, b.bufferSize),
+		done:   make(chan struct{}),
+		start:  b.lastID,
+	}
+	if b.closed {
+		close(sub.done)
+		return sub, nil, true
+	}
+	if b.subscribers[userId] == nil {
+		b.subscribers[userId] = map[*Subscription]struct{}{}
+	}
+	b.subscribers[userId][sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	since, ok := b.parseID(lastEventID)
+	if !ok || since < b.evictedID || since > b.lastID {
+		return sub, nil, false
+	}
+	for _, event := range b.replay {
+		if event.ID > since && event.UserID == userId {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, true
+}
+
+// FormatID renders an event ID as sent to clients.
+func (b *EventBus) FormatID(id uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(id, 10)
+}
+
+func (b *EventBus) parseID(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// Close ends every subscription, letting open streams finish before the
+// server shuts down.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+// remove must be called with the lock held.
+func (b *EventBus) remove(sub *Subscription) {
+	subs, ok := b.subscribers[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.userID)
+	}
+	close(sub.done)
+}
+
+// Subscription receives the events of one user. Done is closed when the bus
+// drops the subscription, after which no more events are delivered.
+type Subscription struct {
+	bus    *EventBus
+	userID uint
+	events chan models.NoteEvent
+	done   chan struct{}
+	lagged atomic.Bool
+	start  uint64
+}
+
+func (s *Subscription) Events() <-chan models.NoteEvent {
+	return s.events
+}
+
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Lagged reports whether events were dropped since the last call.
+func (s *Subscription) Lagged() bool {
+	return s.lagged.Swap(false)
+}
+
+// Start is the ID of the last event published before the subscription; every
+// later event of the user is delivered to it.
+func (s *Subscription) Start() uint64 {
+	return s.start
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notes/internal/models"
+	"notes/internal/repositories/memory"
+)
+
+func TestEventBusResume(t *testing.T) {
+	bus := NewEventBus(8, 2, Disconnect)
+	first, _, _ := bus.Subscribe(1, "")
+	defer first.Close()
+
+	for noteId := uint(1); noteId <= 3; noteId++ {
+		bus.Publish(models.NoteEvent{Type: models.NoteCreated, UserID: 1, NoteID: noteId})
+	}
+	bus.Publish(models.NoteEvent{Type: models.NoteCreated, UserID: 2, NoteID: 4})
+
+	seen := <-first.Events()
+	if seen.NoteID != 1 {
+		t.Fatalf("first event is for note %d, want 1", seen.NoteID)
+	}
+
+	// The log holds the last two events, of notes 3 and 4; the one of note 2
+	// can no longer be replayed.
+	resumed, missed, complete := bus.Subscribe(1, bus.FormatID(seen.ID))
+	resumed.Close()
+	if complete || len(missed) != 0 {
+		t.Errorf("resuming past the replay log: got %d events, complete %v", len(missed), complete)
+	}
+
+	resumed, missed, complete = bus.Subscribe(1, bus.FormatID(seen.ID+1))
+	resumed.Close()
+	if !complete || len(missed) != 1 || missed[0].NoteID != 3 {
+		t.Errorf("resuming within the replay log: got %+v, complete %v, want the event of note 3", missed, complete)
+	}
+
+	for _, id := range []string{"garbage", "0-1", bus.FormatID(99)} {
+		resumed, _, complete := bus.Subscribe(1, id)
+		resumed.Close()
+		if complete {
+			t.Errorf("resuming from %q was complete", id)
+		}
+	}
+}
+
+func TestEventBusDropPolicies(t *testing.T) {
+	publish := func(bus *EventBus, count int) {
+		for noteId := 1; noteId <= count; noteId++ {
+			bus.Publish(models.NoteEvent{Type: models.NoteUpdated, UserID: 1, NoteID: uint(noteId)})
+		}
+	}
+
+	t.Run("drop-oldest", func(t *testing.T) {
+		bus := NewEventBus(2, 10, DropOldest)
+		sub, _, _ := bus.Subscribe(1, "")
+		defer sub.Close()
+		publish(bus, 3)
+		if got := (<-sub.Events()).NoteID; got != 2 || !sub.Lagged() {
+			t.Errorf("got note %d, want 2 with the subscription lagging", got)
+		}
+	})
+
+	t.Run("drop-newest", func(t *testing.T) {
+		bus := NewEventBus(2, 10, DropNewest)
+		sub, _, _ := bus.Subscribe(1, "")
+		defer sub.Close()
+		publish(bus, 3)
+		if got := (<-sub.Events()).NoteID; got != 1 || !sub.Lagged() {
+			t.Errorf("got note %d, want 1 with the subscription lagging", got)
+		}
+		if sub.Lagged() {
+			t.Error("Lagged did not reset")
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		bus := NewEventBus(2, 10, Disconnect)
+		sub, _, _ := bus.Subscribe(1, "")
+		defer sub.Close()
+		publish(bus, 3)
+		select {
+		case <-sub.Done():
+		default:
+			t.Fatal("subscription still open after overflowing")
+		}
+	})
+}
+
+func TestNoteEventsWaitForCommit(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	user := models.NewUser("alice", "hash", nil)
+	if err := store.Users().CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	bus := NewEventBus(8, 10, Disconnect)
+	sub, _, _ := bus.Subscribe(user.ID, "")
+	defer sub.Close()
+	ns := NewNoteService(store.Notes(), NewCategoryService(store.Notes().Categories()), bus)
+
+	rollback := errors.New("rollback")
+	err := ns.InTransaction(ctx, func(tx *NoteService) error {
+		if _, err := tx.CreateNote(ctx, "draft", "never saved anywhere", []string{"work"}, user.ID); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("InTransaction: %v", err)
+	}
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("got %s event for a rolled back note", event.Type)
+	default:
+	}
+
+	note, err := ns.CreateNote(ctx, "shopping", "buy milk and bread", []string{"home"}, user.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	for range 2 {
+		if _, err := ns.ToggleArchiveStatus(ctx, note.ID, 0, user.ID); err != nil {
+			t.Fatalf("ToggleArchiveStatus: %v", err)
+		}
+	}
+	for _, want := range []models.NoteEventType{models.NoteCreated, models.NoteArchived, models.NoteUnarchived} {
+		select {
+		case event := <-sub.Events():
+			if event.Type != want || event.NoteID != note.ID {
+				t.Errorf("got %s event for note %d, want %s for note %d", event.Type, event.NoteID, want, note.ID)
+			}
+		default:
+			t.Fatalf("no %s event", want)
+		}
+	}
+}
+
+func TestCategoryChangesPublishNoteEvents(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	user := models.NewUser("alice", "hash", nil)
+	if err := store.Users().CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	bus := NewEventBus(8, 10, Disconnect)
+	ns := NewNoteService(store.Notes(), NewCategoryService(store.Notes().Categories()), bus)
+	note, err := ns.CreateNote(ctx, "shopping", "buy milk and bread", []string{"errands"}, user.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	sub, _, _ := bus.Subscribe(user.ID, "")
+	defer sub.Close()
+
+	if _, err := ns.DeleteCategory(ctx, user.ID, note.Categories[0].ID); err == nil {
+		t.Fatal("deleted the only category of a note")
+	}
+	if _, err := ns.RenameCategory(ctx, user.ID, note.Categories[0].ID, "chores"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	select {
+	case event := <-sub.Events():
+		if event.Type != models.NoteCategoryChanged || event.Note.Categories[0].Name != "Chores" || event.Note.Version != 2 {
+			t.Errorf("got %s event with %+v, want category-changed with the renamed category", event.Type, event.Note)
+		}
+	default:
+		t.Fatal("no category-changed event")
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("got an extra %s event", event.Type)
+	default:
+	}
+}
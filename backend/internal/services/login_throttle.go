@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"notes/pkg/throttle"
+	"notes/pkg/validations"
+)
+
+// ThrottledError is returned while an attempt is held back. It wraps the
+// error saying what was throttled, such as ErrLoginThrottled, and tells the
+// client when to retry.
+type ThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %d seconds", e.Err, int(e.RetryAfter.Round(time.Second).Seconds()))
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// LoginThrottle slows down password guessing. Failed logins are counted
+// per username and per IP address, each with its own policy, and the
+// strictest wait of the two applies.
+type LoginThrottle struct {
+	store      throttle.Store
+	userPolicy throttle.Policy
+	ipPolicy   throttle.Policy
+	logger     *slog.Logger
+	// now is the clock of the throttle, replaced in tests.
+	now func() time.Time
+}
+
+func NewLoginThrottle(store throttle.Store, userPolicy, ipPolicy throttle.Policy, logger *slog.Logger) *LoginThrottle {
+	return &LoginThrottle{
+		store:      store,
+		userPolicy: userPolicy,
+		ipPolicy:   ipPolicy,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Check returns a ThrottledError when the username or the IP address must
+// wait before trying again. If the store fails the login is let through,
+// so that an outage of the store does not lock everyone out.
+func (lt *LoginThrottle) Check(ctx context.Context, username, ip string) error {
+	now := lt.now()
+	var wait time.Duration
+	for _, k := range lt.keys(username, ip) {
+		entry, err := lt.store.Get(ctx, k.key, now)
+		if err != nil {
+			lt.logger.Error("login throttle store failed", "error", err)
+			return nil
+		}
+		wait = max(wait, k.policy.Wait(entry, now))
+	}
+	if wait <= 0 {
+		return nil
+	}
+	lt.logger.Warn("security: login throttled", "user", username, "ip", ip, "retry_after", wait.Round(time.Second))
+	return &ThrottledError{Err: validations.ErrLoginThrottled, RetryAfter: wait}
+}
+
+// Failed records a failed login.
+func (lt *LoginThrottle) Failed(ctx context.Context, username, ip string) {
+	now := lt.now()
+	for _, k := range lt.keys(username, ip) {
+		entry, err := lt.store.Fail(ctx, k.key, now, k.policy.TTL())
+		if err != nil {
+			lt.logger.Error("login throttle store failed", "error", err)
+			return
+		}
+		if k.policy.Locked(entry) && entry.Failures == k.policy.LockoutAfter {
+			lt.logger.Warn("security: login locked out", "key", k.key, "failures", entry.Failures, "duration", k.policy.LockoutFor)
+		}
+	}
+	lt.logger.Warn("security: login failed", "user", username, "ip", ip)
+}
+
+// Succeeded clears the failures of the username. The IP address keeps its
+// count, so one valid account does not unlock guessing from that address.
+func (lt *LoginThrottle) Succeeded(ctx context.Context, username string) {
+	if err := lt.store.Reset(ctx, userThrottleKey(username)); err != nil {
+		lt.logger.Error("login throttle store failed", "error", err)
+	}
+}
+
+type throttleKey struct {
+	key    string
+	policy throttle.Policy
+}
+
+func (lt *LoginThrottle) keys(username, ip string) []throttleKey {
+	keys := []throttleKey{{userThrottleKey(username), lt.userPolicy}}
+	if ip != "" {
+		keys = append(keys, throttleKey{"ip:" + ip, lt.ipPolicy})
+	}
+	return keys
+}
+
+// userThrottleKey matches usernames the way logins do, ignoring case.
+func userThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"notes/pkg/throttle"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLoginThrottle(clock *fakeClock) *LoginThrottle {
+	userPolicy := throttle.Policy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutAfter: 5, LockoutFor: 15 * time.Minute, Window: 15 * time.Minute}
+	ipPolicy := throttle.Policy{FreeAttempts: 8, BaseDelay: time.Second, MaxDelay: time.Minute, Window: 15 * time.Minute}
+	lt := NewLoginThrottle(throttle.NewMemoryStore(), userPolicy, ipPolicy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	lt.now = clock.Now
+	return lt
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("got %v, want a ThrottledError", err)
+	}
+	return throttled.RetryAfter
+}
+
+func TestLoginThrottleBackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}
+	lt := newTestLoginThrottle(clock)
+
+	for range 2 {
+		lt.Failed(ctx, "alice", "192.0.2.1")
+	}
+	if err := lt.Check(ctx, "Alice", "192.0.2.1"); err != nil {
+		t.Fatalf("throttled within the free attempts: %v", err)
+	}
+
+	lt.Failed(ctx, "alice", "192.0.2.1")
+	if got := retryAfter(t, lt.Check(ctx, "alice", "192.0.2.1")); got != time.Second {
+		t.Fatalf("first delay is %s, want 1s", got)
+	}
+	clock.Advance(time.Second)
+	if err := lt.Check(ctx, "alice", "192.0.2.1"); err != nil {
+		t.Fatalf("still throttled once the delay passed: %v", err)
+	}
+	lt.Failed(ctx, "alice", "192.0.2.1")
+	if got := retryAfter(t, lt.Check(ctx, "alice", "192.0.2.1")); got != 2*time.Second {
+		t.Fatalf("second delay is %s, want 2s", got)
+	}
+
+	lt.Failed(ctx, "alice", "192.0.2.1")
+	if got := retryAfter(t, lt.Check(ctx, "alice", "198.51.100.7")); got != 15*time.Minute {
+		t.Fatalf("locked out user waits %s from another address, want 15m", got)
+	}
+	if err := lt.Check(ctx, "bob", "198.51.100.7"); err != nil {
+		t.Fatalf("lockout of alice throttled bob: %v", err)
+	}
+	clock.Advance(15 * time.Minute)
+	if err := lt.Check(ctx, "alice", "192.0.2.1"); err != nil {
+		t.Fatalf("still locked out after the lockout: %v", err)
+	}
+}
+
+func TestLoginThrottleResetOnSuccess(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}
+	lt := newTestLoginThrottle(clock)
+
+	for range 9 {
+		lt.Failed(ctx, "alice", "192.0.2.1")
+	}
+	lt.Succeeded(ctx, "alice")
+	if err := lt.Check(ctx, "alice", "198.51.100.7"); err != nil {
+		t.Fatalf("alice throttled after a successful login: %v", err)
+	}
+	// The address keeps its failures, past its own free attempts.
+	if got := retryAfter(t, lt.Check(ctx, "alice", "192.0.2.1")); got != time.Second {
+		t.Fatalf("address waits %s after a success, want 1s", got)
+	}
+	clock.Advance(16 * time.Minute)
+	if err := lt.Check(ctx, "alice", "192.0.2.1"); err != nil {
+		t.Fatalf("address still throttled after the window: %v", err)
+	}
+}
@@ -2,23 +2,87 @@ package services
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
 	"notes/internal/models"
 	"notes/internal/repositories"
 	"notes/pkg/date"
+	"notes/pkg/pagination"
 	"notes/pkg/utils"
 	"notes/pkg/validations"
 )
 
+const (
+	// defaultNoteSort lists the user's notes in manual order, pinned first.
+	defaultNoteSort = "position"
+	// defaultSharedNoteSort lists shared notes newest first, since their
+	// positions belong to different owners.
+	defaultSharedNoteSort = "-created_at"
+)
+
 type NoteService struct {
-	noteRepo        *repositories.NoteRepository
+	noteRepo        repositories.NoteStore
 	CategoryService *CategoryService
+	events          *EventBus
+	// pending collects the events of a transaction until it commits.
+	pending *[]models.NoteEvent
 }
 
-func NewNoteService(noteRepo *repositories.NoteRepository, categoryService *CategoryService) *NoteService {
+// NewNoteService builds the service. Changes to notes are published to
+// events, which may be nil when nothing listens to them.
+func NewNoteService(noteRepo repositories.NoteStore, categoryService *CategoryService, events *EventBus) *NoteService {
 	return &NoteService{
 		noteRepo:        noteRepo,
 		CategoryService: categoryService,
+		events:          events,
+	}
+}
+
+// InTransaction runs fn with a NoteService whose reads and writes all go
+// through one database transaction, committed only when fn returns nil.
+// Calling it on a service handed to fn opens a savepoint. Events are only
+// published once the transaction commits.
+func (ns *NoteService) InTransaction(ctx context.Context, fn func(txService *NoteService) error) error {
+	var pending []models.NoteEvent
+	err := ns.noteRepo.Transaction(ctx, func(tx repositories.NoteStore) error {
+		txService := NewNoteService(tx, NewCategoryService(tx.Categories()), ns.events)
+		txService.pending = &pending
+		return fn(txService)
+	})
+	if err != nil {
+		return err
 	}
+	for _, event := range pending {
+		ns.publish(event)
+	}
+	return nil
+}
+
+func (ns *NoteService) publish(event models.NoteEvent) {
+	switch {
+	case ns.pending != nil:
+		*ns.pending = append(*ns.pending, event)
+	case ns.events != nil:
+		ns.events.Publish(event)
+	}
+}
+
+// changeType names an update of the note after what it changed: archiving,
+// unarchiving and category changes get their own event when nothing else
+// changed.
+func changeType(note *models.Note, edited, archived, categories bool) models.NoteEventType {
+	switch {
+	case archived && !edited && !categories && note.IsArchived:
+		return models.NoteArchived
+	case archived && !edited && !categories:
+		return models.NoteUnarchived
+	case categories && !edited && !archived:
+		return models.NoteCategoryChanged
+	}
+	return models.NoteUpdated
 }
 
 func (ns *NoteService) CreateNote(ctx context.Context, title, content string, categoryNames []string, userID uint) (*models.Note, error) {
@@ -41,14 +105,14 @@ func (ns *NoteService) CreateNote(ctx context.Context, title, content string, ca
 
 	var categories []models.Category
 	for _, categoryName := range categoryNames {
-		category, err := ns.CategoryService.GetByNameOrCreate(ctx, categoryName)
+		category, err := ns.CategoryService.GetByNameOrCreate(ctx, userID, categoryName)
 		if err != nil {
 			return nil, err
 		}
 		categories = append(categories, *category)
 	}
 
-	noteByTitle, _ := ns.noteRepo.GetByTitle(ctx, formattedTitle)
+	noteByTitle, _ := ns.noteRepo.GetByTitle(ctx, userID, formattedTitle)
 	if noteByTitle != nil {
 		return nil, validations.ErrDuplicateTitle
 	}
@@ -58,6 +122,7 @@ func (ns *NoteService) CreateNote(ctx context.Context, title, content string, ca
 	if _, err := ns.noteRepo.Create(ctx, note); err != nil {
 		return nil, err
 	}
+	ns.publish(models.NewNoteEvent(models.NoteCreated, note))
 
 	return note, nil
 }
@@ -96,12 +161,18 @@ func (ns *NoteService) GetNotesByCategories(ctx context.Context, categoryNames [
 	return notes, nil
 }
 
-func (ns *NoteService) UpdateNote(ctx context.Context, noteId uint, updatedNote *models.Note) (*uint, error) {
+// UpdateNote applies updatedNote to the stored note. A non-zero
+// updatedNote.Version must match the stored version; on success it is set to
+// the new one.
+func (ns *NoteService) UpdateNote(ctx context.Context, noteId uint, updatedNote *models.Note, actorId uint) (*uint, error) {
 
 	existingNote, err := ns.GetNoteById(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
+	if updatedNote.Version != 0 && updatedNote.Version != existingNote.Version {
+		return nil, validations.ErrVersionMismatch
+	}
 
 	if existingNote.Categories == nil {
 		existingNote.Categories = []models.Category{}
@@ -111,7 +182,7 @@ func (ns *NoteService) UpdateNote(ctx context.Context, noteId uint, updatedNote
 	if !validTitle {
 		return nil, err
 	}
-	potentialDif, _ := ns.noteRepo.GetByTitle(ctx, formattedTitle)
+	potentialDif, _ := ns.noteRepo.GetByTitle(ctx, existingNote.UserID, formattedTitle)
 	if potentialDif != nil && potentialDif.ID != existingNote.ID {
 		return nil, validations.ErrDuplicateTitle
 	}
@@ -119,13 +190,122 @@ func (ns *NoteService) UpdateNote(ctx context.Context, noteId uint, updatedNote
 	if !validContent {
 		return nil, err
 	}
+	newCats, err := ns.resolveCategories(ctx, existingNote.UserID, updatedNote.Categories)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingNote.Title == formattedTitle &&
+		existingNote.Content == formattedContent &&
+		existingNote.IsArchived == updatedNote.IsArchived &&
+		utils.CompareCategories(existingNote.Categories, newCats) {
+		return nil, validations.ErrNoChangesDetected
+	}
+	eventType := changeType(
+		updatedNote,
+		existingNote.Title != formattedTitle || existingNote.Content != formattedContent,
+		existingNote.IsArchived != updatedNote.IsArchived,
+		!utils.CompareCategories(existingNote.Categories, newCats),
+	)
+	existingNote.Categories = newCats
+	existingNote.Title = formattedTitle
+	existingNote.Content = formattedContent
+	existingNote.IsArchived = updatedNote.IsArchived
+	existingNote.UpdatedAt = date.ArgentinaTimeNow()
+
+	updatedId, err := ns.noteRepo.UpdateNote(ctx, existingNote, actorId)
+	if err != nil {
+		return nil, err
+	}
+	updatedNote.Version = existingNote.Version
+	ns.publish(models.NewNoteEvent(eventType, existingNote))
+
+	return updatedId, nil
+}
+
+// PatchNote applies only the fields set in patch to the stored note and
+// persists only those. A patch that leaves the note as it is returns the note
+// without saving anything. A non-zero expectedVersion must match the stored
+// version.
+func (ns *NoteService) PatchNote(ctx context.Context, noteId uint, patch *models.NotePatch, expectedVersion uint, actorId uint) (*models.Note, error) {
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && note.Version != expectedVersion {
+		return nil, validations.ErrVersionMismatch
+	}
+
+	var columns []string
+	if patch.Title != nil {
+		validTitle, formattedTitle, err := utils.ValidateAndFormatTitle(*patch.Title)
+		if !validTitle {
+			return nil, err
+		}
+		if formattedTitle != note.Title {
+			potentialDif, _ := ns.noteRepo.GetByTitle(ctx, note.UserID, formattedTitle)
+			if potentialDif != nil && potentialDif.ID != note.ID {
+				return nil, validations.ErrDuplicateTitle
+			}
+			note.Title = formattedTitle
+			columns = append(columns, "title")
+		}
+	}
+	if patch.Content != nil {
+		validContent, formattedContent, err := utils.ValidateAndFormatContent(*patch.Content)
+		if !validContent {
+			return nil, err
+		}
+		if formattedContent != note.Content {
+			note.Content = formattedContent
+			columns = append(columns, "content")
+		}
+	}
+	if patch.IsArchived != nil && *patch.IsArchived != note.IsArchived {
+		note.IsArchived = *patch.IsArchived
+		columns = append(columns, "is_archived")
+	}
+	replaceCategories := false
+	if patch.Categories != nil {
+		newCats, err := ns.resolveCategories(ctx, note.UserID, patch.Categories)
+		if err != nil {
+			return nil, err
+		}
+		if !utils.CompareCategories(note.Categories, newCats) {
+			note.Categories = newCats
+			replaceCategories = true
+		}
+	}
+
+	if len(columns) == 0 && !replaceCategories {
+		return note, nil
+	}
+	eventType := changeType(
+		note,
+		slices.Contains(columns, "title") || slices.Contains(columns, "content"),
+		slices.Contains(columns, "is_archived"),
+		replaceCategories,
+	)
+	note.UpdatedAt = date.ArgentinaTimeNow()
+	columns = append(columns, "updated_at")
+
+	if _, err := ns.noteRepo.PatchNote(ctx, note, columns, replaceCategories, actorId); err != nil {
+		return nil, err
+	}
+	ns.publish(models.NewNoteEvent(eventType, note))
+	return note, nil
+}
+
+// resolveCategories validates the category names, creating the user's
+// missing categories, and returns them without duplicates.
+func (ns *NoteService) resolveCategories(ctx context.Context, userId uint, categories []models.Category) ([]models.Category, error) {
 	uniqueCats := make(map[string]models.Category)
-	for _, c := range updatedNote.Categories {
+	for _, c := range categories {
 		valid, name, err := utils.ValidateAndFormatCategory(c.Name)
 		if !valid {
 			return nil, err
 		}
-		nCat, err := ns.CategoryService.GetByNameOrCreate(ctx, name)
+		nCat, err := ns.CategoryService.GetByNameOrCreate(ctx, userId, name)
 		if err != nil {
 			return nil, err
 		}
@@ -143,25 +323,7 @@ func (ns *NoteService) UpdateNote(ctx context.Context, noteId uint, updatedNote
 	if len(newCats) < 1 {
 		return nil, validations.ErrZeroCategory
 	}
-
-	if existingNote.Title == formattedTitle &&
-		existingNote.Content == formattedContent &&
-		existingNote.IsArchived == updatedNote.IsArchived &&
-		utils.CompareCategories(existingNote.Categories, newCats) {
-		return nil, validations.ErrNoChangesDetected
-	}
-	existingNote.Categories = newCats
-	existingNote.Title = formattedTitle
-	existingNote.Content = formattedContent
-	existingNote.IsArchived = updatedNote.IsArchived
-	existingNote.UpdatedAt = date.ArgentinaTimeNow()
-
-	updatedId, err := ns.noteRepo.UpdateNote(ctx, existingNote)
-	if err != nil {
-		return nil, err
-	}
-
-	return updatedId, nil
+	return newCats, nil
 }
 
 func (ns *NoteService) DeleteNote(ctx context.Context, noteId uint) (*uint, error) {
@@ -169,14 +331,22 @@ func (ns *NoteService) DeleteNote(ctx context.Context, noteId uint) (*uint, erro
 	if err != nil {
 		return nil, err
 	}
-	return ns.noteRepo.Delete(ctx, note)
+	deletedId, err := ns.noteRepo.Delete(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+	ns.publish(models.NewNoteEvent(models.NoteDeleted, note))
+	return deletedId, nil
 }
 
-func (ns *NoteService) AddCategoryToNote(ctx context.Context, noteId uint, categoryName string) (*models.Note, error) {
+func (ns *NoteService) AddCategoryToNote(ctx context.Context, noteId uint, categoryName string, expectedVersion uint, actorId uint) (*models.Note, error) {
 	note, err := ns.GetNoteById(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
+	if expectedVersion != 0 && note.Version != expectedVersion {
+		return nil, validations.ErrVersionMismatch
+	}
 	if len(note.Categories) == 4 {
 		return nil, validations.ErrFullCatCount
 	}
@@ -190,24 +360,27 @@ func (ns *NoteService) AddCategoryToNote(ctx context.Context, noteId uint, categ
 			return nil, validations.ErrCatAlreadyAdded
 		}
 	}
-	category, err := ns.CategoryService.GetByNameOrCreate(ctx, formmatedCatname)
+	category, err := ns.CategoryService.GetByNameOrCreate(ctx, note.UserID, formmatedCatname)
 	if err != nil {
 		return nil, err
 	}
 	note.Categories = append(note.Categories, *category)
 	note.UpdatedAt = date.ArgentinaTimeNow()
-	if _, err = ns.UpdateNote(ctx, noteId, note); err != nil {
+	if _, err = ns.UpdateNote(ctx, noteId, note, actorId); err != nil {
 		return nil, err
 	}
 	return note, nil
 }
 
-func (ns *NoteService) RemoveCategoryFromNote(ctx context.Context, noteId uint, categoryName string) (*models.Note, error) {
+func (ns *NoteService) RemoveCategoryFromNote(ctx context.Context, noteId uint, categoryName string, expectedVersion uint, actorId uint) (*models.Note, error) {
 
 	note, err := ns.GetNoteById(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
+	if expectedVersion != 0 && note.Version != expectedVersion {
+		return nil, validations.ErrVersionMismatch
+	}
 	if len(note.Categories) == 1 {
 		return nil, validations.ErrMinCategory
 	}
@@ -235,21 +408,109 @@ func (ns *NoteService) RemoveCategoryFromNote(ctx context.Context, noteId uint,
 	note.Categories = updatedCategories
 	note.UpdatedAt = date.ArgentinaTimeNow()
 
-	if _, err = ns.UpdateNote(ctx, noteId, note); err != nil {
+	if _, err = ns.UpdateNote(ctx, noteId, note, actorId); err != nil {
 		return nil, err
 	}
 
 	return note, nil
 }
 
-func (ns *NoteService) ToggleArchiveStatus(ctx context.Context, noteId uint) (*models.Note, error) {
+// RenameCategory renames one of the user's categories. The notes tagged
+// with it get a new version in the same transaction, since they read
+// differently from then on.
+func (ns *NoteService) RenameCategory(ctx context.Context, userId uint, categoryId uint, newName string) (*models.Category, error) {
+	var category *models.Category
+	err := ns.InTransaction(ctx, func(tx *NoteService) error {
+		var err error
+		if category, err = tx.CategoryService.Update(ctx, userId, categoryId, newName); err != nil {
+			return err
+		}
+		noteIds, err := tx.noteRepo.TouchCategoryNotes(ctx, categoryId)
+		if err != nil {
+			return err
+		}
+		return tx.publishCategoryChanges(ctx, noteIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory deletes one of the user's categories and bumps the version
+// of the notes it is detached from.
+func (ns *NoteService) DeleteCategory(ctx context.Context, userId uint, categoryId uint) (*uint, error) {
+	var deletedId *uint
+	err := ns.InTransaction(ctx, func(tx *NoteService) error {
+		if _, err := tx.CategoryService.GetById(ctx, userId, categoryId); err != nil {
+			return err
+		}
+		noteIds, err := tx.noteRepo.TouchCategoryNotes(ctx, categoryId)
+		if err != nil {
+			return err
+		}
+		if deletedId, err = tx.CategoryService.Delete(ctx, userId, categoryId); err != nil {
+			return err
+		}
+		return tx.publishCategoryChanges(ctx, noteIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletedId, nil
+}
+
+// MergeCategories merges the source category into the target and bumps the
+// version of the notes that were tagged with the source.
+func (ns *NoteService) MergeCategories(ctx context.Context, userId uint, sourceId uint, targetId uint) (*models.Category, error) {
+	var target *models.Category
+	err := ns.InTransaction(ctx, func(tx *NoteService) error {
+		if _, err := tx.CategoryService.GetById(ctx, userId, sourceId); err != nil {
+			return err
+		}
+		noteIds, err := tx.noteRepo.TouchCategoryNotes(ctx, sourceId)
+		if err != nil {
+			return err
+		}
+		if target, err = tx.CategoryService.Merge(ctx, userId, sourceId, targetId); err != nil {
+			return err
+		}
+		return tx.publishCategoryChanges(ctx, noteIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// publishCategoryChanges sends a category-changed event for each of the
+// notes, as they read after a change to one of their categories. Trashed
+// notes are skipped; they are sent again when restored.
+func (ns *NoteService) publishCategoryChanges(ctx context.Context, noteIds []uint) error {
+	for _, noteId := range noteIds {
+		note, err := ns.noteRepo.GetNoteById(ctx, noteId)
+		if errors.Is(err, validations.ErrNoteNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		ns.publish(models.NewNoteEvent(models.NoteCategoryChanged, note))
+	}
+	return nil
+}
+
+func (ns *NoteService) ToggleArchiveStatus(ctx context.Context, noteId uint, expectedVersion uint, actorId uint) (*models.Note, error) {
 	note, err := ns.GetNoteById(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
+	if expectedVersion != 0 && note.Version != expectedVersion {
+		return nil, validations.ErrVersionMismatch
+	}
 	note.IsArchived = !note.IsArchived
 	note.UpdatedAt = date.ArgentinaTimeNow()
-	if _, err := ns.UpdateNote(ctx, noteId, note); err != nil {
+	if _, err := ns.UpdateNote(ctx, noteId, note, actorId); err != nil {
 		return nil, err
 	}
 
@@ -277,5 +538,180 @@ func (ns *NoteService) DeleteNoteForUser(ctx context.Context, noteId uint, userI
 	if err != nil {
 		return nil, err
 	}
+	ns.publish(models.NewNoteEvent(models.NoteDeleted, &models.Note{ID: noteId, UserID: userId}))
 	return deletedNoteId, nil
 }
+
+func (ns *NoteService) SearchNotes(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error) {
+	trimmedQuery := strings.TrimSpace(query)
+	if trimmedQuery == "" || len(trimmedQuery) > 100 {
+		return nil, validations.ErrSearchQuery
+	}
+	if limit < 1 || limit > 50 {
+		return nil, validations.ErrInvalidLimit
+	}
+	return ns.noteRepo.Search(ctx, userId, trimmedQuery, limit)
+}
+
+func (ns *NoteService) GetNoteRevisions(ctx context.Context, noteId uint) ([]models.NoteRevision, error) {
+	return ns.noteRepo.GetRevisions(ctx, noteId)
+}
+
+func (ns *NoteService) DiffNoteRevisions(ctx context.Context, noteId uint, from, to uint) (*models.NoteRevisionDiff, error) {
+	if from == 0 || to == 0 {
+		return nil, validations.ErrInvalidRevision
+	}
+	fromRevision, err := ns.noteRepo.GetRevision(ctx, noteId, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := ns.noteRepo.GetRevision(ctx, noteId, to)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewNoteRevisionDiff(fromRevision, toRevision), nil
+}
+
+// RestoreNoteRevision brings the note back to the state captured by the
+// revision. The restore itself is recorded as a new revision.
+func (ns *NoteService) RestoreNoteRevision(ctx context.Context, noteId uint, revision uint, actorId uint) (*models.Note, error) {
+	if revision == 0 {
+		return nil, validations.ErrInvalidRevision
+	}
+	noteRevision, err := ns.noteRepo.GetRevision(ctx, noteId, revision)
+	if err != nil {
+		return nil, err
+	}
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	// Restoring a revision with another archived status archives or
+	// unarchives the note, which is up to its owner.
+	if noteRevision.IsArchived != note.IsArchived {
+		if err := ns.authorize(ctx, note, actorId, ManageNote); err != nil {
+			return nil, err
+		}
+	}
+
+	categories := make([]models.Category, 0, len(noteRevision.Categories))
+	for _, name := range noteRevision.Categories {
+		categories = append(categories, models.Category{Name: name})
+	}
+	restored := &models.Note{
+		Title:      noteRevision.Title,
+		Content:    noteRevision.Content,
+		Categories: categories,
+		IsArchived: noteRevision.IsArchived,
+	}
+	if _, err := ns.UpdateNote(ctx, noteId, restored, actorId); err != nil {
+		return nil, err
+	}
+	return ns.GetNoteById(ctx, noteId)
+}
+
+func (ns *NoteService) GetTrash(ctx context.Context, userId uint) ([]models.Note, error) {
+	return ns.noteRepo.GetTrash(ctx, userId)
+}
+
+func (ns *NoteService) RestoreNote(ctx context.Context, userId uint, noteId uint) (*models.Note, error) {
+	if _, err := ns.noteRepo.Restore(ctx, userId, noteId); err != nil {
+		return nil, err
+	}
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	ns.publish(models.NewNoteEvent(models.NoteRestored, note))
+	return note, nil
+}
+
+func (ns *NoteService) EmptyTrash(ctx context.Context, userId uint) (int64, error) {
+	return ns.noteRepo.EmptyTrash(ctx, userId)
+}
+
+func (ns *NoteService) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	return ns.noteRepo.PurgeTrash(ctx, cutoff)
+}
+
+// ListNotes returns one page of the user's notes, optionally filtered by
+// archived status and category names. Sort is one of position, the default
+// manual order with pinned notes first, created_at, updated_at or title, with
+// a leading "-" for descending order.
+func (ns *NoteService) ListNotes(ctx context.Context, userId uint, isArchived *bool, categories []string, page pagination.Request) (*models.NotePage, error) {
+	return ns.listNotes(ctx, userId, false, isArchived, categories, page)
+}
+
+// listNotes pages through the user's own notes, or through the notes shared
+// with the user when sharedWith is set.
+func (ns *NoteService) listNotes(ctx context.Context, userId uint, sharedWith bool, isArchived *bool, categories []string, page pagination.Request) (*models.NotePage, error) {
+	if page.Limit < 1 || page.Limit > 100 {
+		return nil, validations.ErrInvalidPageLimit
+	}
+	sort := page.Sort
+	if sort == "" {
+		sort = defaultNoteSort
+		if sharedWith {
+			sort = defaultSharedNoteSort
+		}
+	}
+	sortField := strings.TrimPrefix(sort, "-")
+	if sortField != "created_at" && sortField != "updated_at" && sortField != "title" && sortField != "position" {
+		return nil, validations.ErrInvalidSort
+	}
+
+	var after *pagination.Cursor
+	if page.Cursor != "" {
+		cursor, err := pagination.Decode(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sort {
+			return nil, validations.ErrInvalidCursor
+		}
+		after = cursor
+	}
+
+	notes, err := ns.noteRepo.ListNotes(ctx, repositories.NoteListOptions{
+		UserID:     userId,
+		SharedWith: sharedWith,
+		IsArchived: isArchived,
+		Categories: categories,
+		SortField:  sortField,
+		Descending: strings.HasPrefix(sort, "-"),
+		Limit:      page.Limit + 1,
+		After:      after,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 && after == nil {
+		return nil, validations.ErrNoNotesFound
+	}
+
+	notePage := &models.NotePage{Notes: notes, Sort: sort}
+	if len(notes) > page.Limit {
+		notePage.Notes = notes[:page.Limit]
+		last := notePage.Notes[page.Limit-1]
+		notePage.NextCursor = pagination.Cursor{
+			Sort:  sort,
+			Value: noteSortValue(&last, sortField),
+			ID:    last.ID,
+		}.Encode()
+	}
+	return notePage, nil
+}
+
+func noteSortValue(note *models.Note, sortField string) string {
+	switch sortField {
+	case "title":
+		return note.Title
+	case "position":
+		return note.PositionCursor()
+	case "updated_at":
+		if note.UpdatedAt != nil {
+			return note.UpdatedAt.Format(time.RFC3339Nano)
+		}
+	}
+	return note.CreatedAt.Format(time.RFC3339Nano)
+}
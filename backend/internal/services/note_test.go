@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/internal/repositories/memory"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+// testStores builds, for each backend the service tests run against, an
+// empty note and user store. PostgreSQL is skipped unless NOTES_TEST_DB_URI
+// names a disposable database.
+var testStores = map[string]func(t *testing.T) (repositories.NoteStore, repositories.UserStore){
+	"memory": func(t *testing.T) (repositories.NoteStore, repositories.UserStore) {
+		store := memory.New()
+		return store.Notes(), store.Users()
+	},
+	"postgres": func(t *testing.T) (repositories.NoteStore, repositories.UserStore) {
+		return sqlStores(dbtest.Postgres(t))
+	},
+	"sqlite": func(t *testing.T) (repositories.NoteStore, repositories.UserStore) {
+		return sqlStores(dbtest.SQLite(t))
+	},
+}
+
+func sqlStores(gormDB *gorm.DB) (repositories.NoteStore, repositories.UserStore) {
+	conf := &configs.Config{}
+	categories := repositories.NewCategoryRepository(gormDB, conf)
+	return repositories.NewNoteRepository(gormDB, conf, categories), repositories.NewUserRepository(gormDB, conf)
+}
+
+// eachStore runs test against a note service, and the ID of its user, on
+// every backend.
+func eachStore(t *testing.T, test func(t *testing.T, ns *NoteService, userId uint)) {
+	for name, newStores := range testStores {
+		t.Run(name, func(t *testing.T) {
+			notes, users := newStores(t)
+			user := models.NewUser("alice", "hash", nil)
+			if err := users.CreateUser(context.Background(), user); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			test(t, NewNoteService(notes, NewCategoryService(notes.Categories()), nil), user.ID)
+		})
+	}
+}
+
+func TestCreateNote(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+
+		note, err := ns.CreateNote(ctx, "  shopping list ", "buy milk and bread", []string{"errands", "home"}, userId)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		if note.Title != "Shopping List" {
+			t.Fatalf("title was stored as %q", note.Title)
+		}
+		categories, err := ns.CategoryService.GetAll(ctx, userId)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(categories) != 2 {
+			t.Fatalf("CreateNote created %d categories, want 2", len(categories))
+		}
+
+		_, err = ns.CreateNote(ctx, "Shopping List", "buy more milk", []string{"errands"}, userId)
+		if !errors.Is(err, validations.ErrDuplicateTitle) {
+			t.Fatalf("CreateNote with a taken title: got %v, want ErrDuplicateTitle", err)
+		}
+	})
+}
+
+func TestUpdateNoteRejectsStaleVersion(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		note, err := ns.CreateNote(ctx, "Shopping List", "buy milk and bread", []string{"errands"}, userId)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+
+		edit := &models.Note{Title: note.Title, Content: "buy milk and eggs", Categories: note.Categories, Version: 1}
+		if _, err := ns.UpdateNote(ctx, note.ID, edit, userId); err != nil {
+			t.Fatalf("UpdateNote: %v", err)
+		}
+		stale := &models.Note{Title: note.Title, Content: "buy milk and jam", Categories: note.Categories, Version: 1}
+		if _, err := ns.UpdateNote(ctx, note.ID, stale, userId); !errors.Is(err, validations.ErrVersionMismatch) {
+			t.Fatalf("UpdateNote of a stale version: got %v, want ErrVersionMismatch", err)
+		}
+
+		revisions, err := ns.GetNoteRevisions(ctx, note.ID)
+		if err != nil {
+			t.Fatalf("GetNoteRevisions: %v", err)
+		}
+		if len(revisions) != 2 || revisions[0].Content != "buy milk and eggs" {
+			t.Fatalf("revisions are %+v, want the update on top of the creation", revisions)
+		}
+	})
+}
+
+func TestDeleteSoleCategory(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		note, err := ns.CreateNote(ctx, "Shopping List", "buy milk and bread", []string{"errands"}, userId)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+
+		_, err = ns.CategoryService.Delete(ctx, userId, note.Categories[0].ID)
+		if !errors.Is(err, validations.ErrCatInUse) {
+			t.Fatalf("deleting the only category of a note: got %v, want ErrCatInUse", err)
+		}
+	})
+}
+
+func TestCategoryChangesBumpNoteVersions(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		note, err := ns.CreateNote(ctx, "Shopping List", "buy milk and bread", []string{"errands", "home"}, userId)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		errands, home := note.Categories[0], note.Categories[1]
+		if errands.Name != "Errands" {
+			errands, home = home, errands
+		}
+
+		if _, err := ns.RenameCategory(ctx, userId, errands.ID, "chores"); err != nil {
+			t.Fatalf("RenameCategory: %v", err)
+		}
+		if _, err := ns.MergeCategories(ctx, userId, errands.ID, home.ID); err != nil {
+			t.Fatalf("MergeCategories: %v", err)
+		}
+		if _, err := ns.DeleteCategory(ctx, userId, home.ID); !errors.Is(err, validations.ErrCatInUse) {
+			t.Fatalf("deleting the only category of a note: got %v, want ErrCatInUse", err)
+		}
+
+		stored, err := ns.GetNoteById(ctx, note.ID)
+		if err != nil {
+			t.Fatalf("GetNoteById: %v", err)
+		}
+		if stored.Version != note.Version+2 {
+			t.Fatalf("version is %d after a rename and a merge, want %d", stored.Version, note.Version+2)
+		}
+		stale := &models.Note{Title: note.Title, Content: "buy milk and jam", Categories: stored.Categories, Version: note.Version}
+		if _, err := ns.UpdateNote(ctx, note.ID, stale, userId); !errors.Is(err, validations.ErrVersionMismatch) {
+			t.Fatalf("UpdateNote with the version from before the rename: got %v, want ErrVersionMismatch", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"notes/internal/models"
+)
+
+// PinNote pins or unpins the note. Pinned notes are listed before the
+// others, in the same manual order.
+func (ns *NoteService) PinNote(ctx context.Context, noteId uint, pinned bool) (*models.Note, error) {
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	if note.Pinned == pinned {
+		return note, nil
+	}
+	if err := ns.noteRepo.PinNote(ctx, note, pinned); err != nil {
+		return nil, err
+	}
+	eventType := models.NoteUnpinned
+	if pinned {
+		eventType = models.NotePinned
+	}
+	ns.publish(models.NewNoteEvent(eventType, note))
+	return note, nil
+}
+
+// MoveNote puts the note right before the target in its owner's manual
+// order, or right after it. Both must belong to the same user and be pinned,
+// or not, alike; the repository checks so in the same transaction as the
+// move.
+func (ns *NoteService) MoveNote(ctx context.Context, noteId, targetId uint, after bool) (*models.Note, error) {
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.noteRepo.MoveNote(ctx, note, targetId, after); err != nil {
+		return nil, err
+	}
+	ns.publish(models.NewNoteEvent(models.NoteMoved, note))
+	return note, nil
+}
+
+// PinNoteForUser pins or unpins one of the user's notes. Only the owner may
+// pin a note, since the order is theirs.
+func (us *UserService) PinNoteForUser(ctx context.Context, userId, noteId uint, pinned bool) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, ManageNote); err != nil {
+		return nil, err
+	}
+	return us.noteService.PinNote(ctx, noteId, pinned)
+}
+
+// MoveNoteForUser moves one of the user's notes next to another of theirs.
+// Like pinning, only the owner may move a note.
+func (us *UserService) MoveNoteForUser(ctx context.Context, userId, noteId, targetId uint, after bool) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, ManageNote); err != nil {
+		return nil, err
+	}
+	return us.noteService.MoveNote(ctx, noteId, targetId, after)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notes/internal/models"
+	"notes/pkg/pagination"
+	"notes/pkg/validations"
+)
+
+func TestPinAndMoveNotes(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		var ids []uint
+		for _, title := range []string{"first note", "second note", "third note"} {
+			note, err := ns.CreateNote(ctx, title, "content of "+title, []string{"work"}, userId)
+			if err != nil {
+				t.Fatalf("CreateNote: %v", err)
+			}
+			ids = append(ids, note.ID)
+		}
+		first, second, third := ids[0], ids[1], ids[2]
+		titles := func() string {
+			t.Helper()
+			page, err := ns.ListNotes(ctx, userId, nil, nil, pagination.Request{Limit: 10})
+			if err != nil {
+				t.Fatalf("ListNotes: %v", err)
+			}
+			if page.Sort != "position" {
+				t.Fatalf("notes are listed by %q, want manual order", page.Sort)
+			}
+			var got string
+			for _, n := range page.Notes {
+				got += n.Title + ";"
+			}
+			return got
+		}
+
+		pinned, err := ns.PinNote(ctx, first, true)
+		if err != nil {
+			t.Fatalf("PinNote: %v", err)
+		}
+		if !pinned.Pinned {
+			t.Fatalf("PinNote returned %+v, want it pinned", pinned)
+		}
+		if _, err := ns.MoveNote(ctx, second, first, false); !errors.Is(err, validations.ErrMovePinned) {
+			t.Fatalf("MoveNote next to a pinned note: got %v, want ErrMovePinned", err)
+		}
+		if _, err := ns.MoveNote(ctx, second, second, false); !errors.Is(err, validations.ErrMoveTarget) {
+			t.Fatalf("MoveNote next to itself: got %v, want ErrMoveTarget", err)
+		}
+		if _, err := ns.MoveNote(ctx, second, third+100, false); !errors.Is(err, validations.ErrMoveTarget) {
+			t.Fatalf("MoveNote next to a missing note: got %v, want ErrMoveTarget", err)
+		}
+		if _, err := ns.MoveNote(ctx, second, third, false); err != nil {
+			t.Fatalf("MoveNote: %v", err)
+		}
+		if got := titles(); got != "First Note;Second Note;Third Note;" {
+			t.Fatalf("notes are listed as %q, want the pinned one first, then in manual order", got)
+		}
+
+		if _, err := ns.PinNote(ctx, first, false); err != nil {
+			t.Fatalf("PinNote: %v", err)
+		}
+		if got := titles(); got != "Second Note;Third Note;First Note;" {
+			t.Fatalf("after unpinning notes are listed as %q, want the first back in its place", got)
+		}
+	})
+}
+
+func TestPinAndMoveNeedOwner(t *testing.T) {
+	for name, newStores := range testStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			notes, users := newStores(t)
+			var ids []uint
+			for _, username := range []string{"alice", "bobby"} {
+				user := models.NewUser(username, "hash", nil)
+				if err := users.CreateUser(ctx, user); err != nil {
+					t.Fatalf("CreateUser: %v", err)
+				}
+				ids = append(ids, user.ID)
+			}
+			owner, editor := ids[0], ids[1]
+			ns := NewNoteService(notes, NewCategoryService(notes.Categories()), nil)
+			us := NewUserService(users, ns, nil, nil, nil, nil, nil)
+
+			note, err := us.CreateNote(ctx, "team plan", "ship it this week", []string{"work"}, owner)
+			if err != nil {
+				t.Fatalf("CreateNote: %v", err)
+			}
+			other, err := us.CreateNote(ctx, "bobby plan", "ship it next week", []string{"work"}, editor)
+			if err != nil {
+				t.Fatalf("CreateNote: %v", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "bobby", models.NoteEditor); err != nil {
+				t.Fatalf("ShareNoteWithUser: %v", err)
+			}
+			if _, err := us.PinNoteForUser(ctx, editor, note.ID, true); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("PinNoteForUser by an editor: got %v, want ErrNotePermission", err)
+			}
+			if _, err := us.MoveNoteForUser(ctx, owner, note.ID, other.ID, true); !errors.Is(err, validations.ErrMoveTarget) {
+				t.Fatalf("MoveNoteForUser next to another user's note: got %v, want ErrMoveTarget", err)
+			}
+		})
+	}
+}
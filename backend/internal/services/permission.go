@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+
+	"notes/internal/models"
+	"notes/pkg/pagination"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+// NoteAction is what a user wants to do with a note, as far as access to
+// it is concerned.
+type NoteAction int
+
+const (
+	// ReadNote covers reading the note and its revisions.
+	ReadNote NoteAction = iota
+	// EditNote covers changing its title, content and categories, and
+	// restoring its revisions.
+	EditNote
+	// ManageNote covers archiving, pinning, moving and deleting the note,
+	// sharing it and deciding who else has access to it.
+	ManageNote
+)
+
+// noteRoleAllows is the access policy of notes: owners may do anything,
+// editors may read and edit, and viewers may only read.
+func noteRoleAllows(role models.NoteRole, action NoteAction) bool {
+	switch role {
+	case models.NoteOwner:
+		return true
+	case models.NoteEditor:
+		return action == ReadNote || action == EditNote
+	case models.NoteViewer:
+		return action == ReadNote
+	}
+	return false
+}
+
+// AuthorizeNote returns the note if the user may perform the action on it.
+// Users without any access to the note get ErrNoteNotOwnedByUser, and users
+// whose role falls short get ErrNotePermission.
+func (ns *NoteService) AuthorizeNote(ctx context.Context, userId, noteId uint, action NoteAction) (*models.Note, error) {
+	note, err := ns.GetNoteById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.authorize(ctx, note, userId, action); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// authorize applies the access policy to a note already loaded.
+func (ns *NoteService) authorize(ctx context.Context, note *models.Note, userId uint, action NoteAction) error {
+	role := models.NoteOwner
+	if note.UserID != userId {
+		permission, err := ns.noteRepo.GetPermission(ctx, note.ID, userId)
+		if err != nil {
+			return err
+		}
+		if permission == nil {
+			return validations.ErrNoteNotOwnedByUser
+		}
+		role = permission.Role
+	}
+	if !noteRoleAllows(role, action) {
+		return validations.ErrNotePermission
+	}
+	return nil
+}
+
+// ListSharedNotes returns one page of the notes other users shared with the
+// user, archived ones included.
+func (ns *NoteService) ListSharedNotes(ctx context.Context, userId uint, page pagination.Request) (*models.NotePage, error) {
+	return ns.listNotes(ctx, userId, true, nil, nil, page)
+}
+
+// GrantNotePermission gives the user access to the note, or changes the
+// role of the access already given.
+func (ns *NoteService) GrantNotePermission(ctx context.Context, note *models.Note, userId uint, role models.NoteRole) (*models.NotePermission, error) {
+	if role != models.NoteViewer && role != models.NoteEditor {
+		return nil, validations.ErrNoteRole
+	}
+	if userId == note.UserID {
+		return nil, validations.ErrPermissionSelf
+	}
+	permission := models.NewNotePermission(note.ID, userId, role)
+	if err := ns.noteRepo.SetPermission(ctx, permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (ns *NoteService) ListNotePermissions(ctx context.Context, noteId uint) ([]models.NotePermission, error) {
+	return ns.noteRepo.ListPermissions(ctx, noteId)
+}
+
+func (ns *NoteService) RevokeNotePermission(ctx context.Context, noteId, userId uint) error {
+	deleted, err := ns.noteRepo.DeletePermission(ctx, noteId, userId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return validations.ErrPermissionNotFound
+	}
+	return nil
+}
+
+// ShareNoteWithUser gives the user with the username viewer or editor
+// access to one of the owner's notes.
+func (us *UserService) ShareNoteWithUser(ctx context.Context, ownerId, noteId uint, username string, role models.NoteRole) (*models.NotePermission, error) {
+	note, err := us.noteService.AuthorizeNote(ctx, ownerId, noteId, ManageNote)
+	if err != nil {
+		return nil, err
+	}
+	grantee, err := us.findGrantee(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	permission, err := us.noteService.GrantNotePermission(ctx, note, grantee.ID, role)
+	if err != nil {
+		return nil, err
+	}
+	permission.UserName = grantee.UserName
+	return permission, nil
+}
+
+func (us *UserService) ListNotePermissionsForUser(ctx context.Context, ownerId, noteId uint) ([]models.NotePermission, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, ownerId, noteId, ManageNote); err != nil {
+		return nil, err
+	}
+	return us.noteService.ListNotePermissions(ctx, noteId)
+}
+
+// UnshareNoteWithUser takes away the access the user with the username has
+// to one of the owner's notes.
+func (us *UserService) UnshareNoteWithUser(ctx context.Context, ownerId, noteId uint, username string) error {
+	if _, err := us.noteService.AuthorizeNote(ctx, ownerId, noteId, ManageNote); err != nil {
+		return err
+	}
+	grantee, err := us.findGrantee(ctx, username)
+	if err != nil {
+		return err
+	}
+	return us.noteService.RevokeNotePermission(ctx, noteId, grantee.ID)
+}
+
+func (us *UserService) GetSharedNotesForUser(ctx context.Context, userId uint, page pagination.Request) (*models.NotePage, error) {
+	return us.noteService.ListSharedNotes(ctx, userId, page)
+}
+
+// findGrantee looks up the user a note is shared with by username, which is
+// stored lowercased.
+func (us *UserService) findGrantee(ctx context.Context, username string) (*models.User, error) {
+	valid, formatted, _ := utils.ValidateAndFormatUsername(username)
+	if !valid {
+		return nil, validations.ErrPermissionUser
+	}
+	user, err := us.userRepo.GetUserByUsername(ctx, *formatted)
+	if err != nil || user == nil {
+		return nil, validations.ErrPermissionUser
+	}
+	return user, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notes/internal/models"
+	"notes/pkg/pagination"
+	"notes/pkg/validations"
+)
+
+func TestNoteRoleAllows(t *testing.T) {
+	for _, tc := range []struct {
+		role               models.NoteRole
+		read, edit, manage bool
+	}{
+		{models.NoteOwner, true, true, true},
+		{models.NoteEditor, true, true, false},
+		{models.NoteViewer, true, false, false},
+		{models.NoteRole("admin"), false, false, false},
+	} {
+		got := [3]bool{noteRoleAllows(tc.role, ReadNote), noteRoleAllows(tc.role, EditNote), noteRoleAllows(tc.role, ManageNote)}
+		if got != [3]bool{tc.read, tc.edit, tc.manage} {
+			t.Errorf("%s may read, edit, manage: %v", tc.role, got)
+		}
+	}
+}
+
+func TestNotePermissions(t *testing.T) {
+	for name, newStores := range testStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			notes, users := newStores(t)
+			var ids []uint
+			for _, username := range []string{"alice", "bobby", "carol"} {
+				user := models.NewUser(username, "hash", nil)
+				if err := users.CreateUser(ctx, user); err != nil {
+					t.Fatalf("CreateUser: %v", err)
+				}
+				ids = append(ids, user.ID)
+			}
+			owner, editor, viewer := ids[0], ids[1], ids[2]
+			ns := NewNoteService(notes, NewCategoryService(notes.Categories()), nil)
+			us := NewUserService(users, ns, nil, nil, nil, nil, nil)
+
+			note, err := us.CreateNote(ctx, "team plan", "ship it this week", []string{"work"}, owner)
+			if err != nil {
+				t.Fatalf("CreateNote: %v", err)
+			}
+			if _, err := us.GetNoteById(ctx, note.ID, editor); !errors.Is(err, validations.ErrNoteNotOwnedByUser) {
+				t.Fatalf("GetNoteById before sharing: got %v, want ErrNoteNotOwnedByUser", err)
+			}
+
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "bobby", models.NoteOwner); !errors.Is(err, validations.ErrNoteRole) {
+				t.Fatalf("share as owner: got %v, want ErrNoteRole", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "alice", models.NoteViewer); !errors.Is(err, validations.ErrPermissionSelf) {
+				t.Fatalf("share with the owner: got %v, want ErrPermissionSelf", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "nobody", models.NoteViewer); !errors.Is(err, validations.ErrPermissionUser) {
+				t.Fatalf("share with an unknown user: got %v, want ErrPermissionUser", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "Bobby", models.NoteEditor); err != nil {
+				t.Fatalf("share with the editor: %v", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "carol", models.NoteEditor); err != nil {
+				t.Fatalf("share with the viewer: %v", err)
+			}
+			// Sharing again changes the role.
+			if _, err := us.ShareNoteWithUser(ctx, owner, note.ID, "carol", models.NoteViewer); err != nil {
+				t.Fatalf("share again with the viewer: %v", err)
+			}
+			permissions, err := us.ListNotePermissionsForUser(ctx, owner, note.ID)
+			if err != nil {
+				t.Fatalf("ListNotePermissionsForUser: %v", err)
+			}
+			if len(permissions) != 2 || permissions[0].UserName != "bobby" || permissions[0].Role != models.NoteEditor ||
+				permissions[1].UserName != "carol" || permissions[1].Role != models.NoteViewer {
+				t.Fatalf("permissions are %+v, want bobby as editor and carol as viewer", permissions)
+			}
+
+			// Viewers may read but not edit.
+			if _, err := us.GetNoteById(ctx, note.ID, viewer); err != nil {
+				t.Fatalf("GetNoteById by the viewer: %v", err)
+			}
+			if _, err := us.GetNoteRevisionsForUser(ctx, viewer, note.ID); err != nil {
+				t.Fatalf("GetNoteRevisionsForUser by the viewer: %v", err)
+			}
+			title := "viewer edit"
+			if _, err := us.PatchNoteForUser(ctx, viewer, note.ID, &models.NotePatch{Title: &title}, 0); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("PatchNoteForUser by the viewer: got %v, want ErrNotePermission", err)
+			}
+
+			// Editors may change content and categories, but not archive,
+			// delete or re-share.
+			content := "ship it on friday"
+			if _, err := us.PatchNoteForUser(ctx, editor, note.ID, &models.NotePatch{Content: &content}, 0); err != nil {
+				t.Fatalf("PatchNoteForUser by the editor: %v", err)
+			}
+			edited, err := us.AddCategoryToNoteForUser(ctx, editor, note.ID, "planning", 0)
+			if err != nil {
+				t.Fatalf("AddCategoryToNoteForUser by the editor: %v", err)
+			}
+			if edited.Content != content || len(edited.Categories) != 2 {
+				t.Fatalf("edited note is %+v, want the new content and two categories", edited)
+			}
+			archived := true
+			if _, err := us.PatchNoteForUser(ctx, editor, note.ID, &models.NotePatch{IsArchived: &archived}, 0); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("archive by the editor: got %v, want ErrNotePermission", err)
+			}
+			if _, err := us.ToggleArchiveStatusForUser(ctx, editor, note.ID, 0); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("ToggleArchiveStatusForUser by the editor: got %v, want ErrNotePermission", err)
+			}
+			if _, err := us.DeleteNoteForUser(ctx, editor, note.ID); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("DeleteNoteForUser by the editor: got %v, want ErrNotePermission", err)
+			}
+			if _, err := us.ShareNoteWithUser(ctx, editor, note.ID, "carol", models.NoteEditor); !errors.Is(err, validations.ErrNotePermission) {
+				t.Fatalf("re-share by the editor: got %v, want ErrNotePermission", err)
+			}
+			// Bulk operations follow the same policy, note by note.
+			results, _, err := us.BulkNotesForUser(ctx, editor, BulkDelete, []uint{note.ID}, "", true)
+			if err != nil || !errors.Is(results[0].Err, validations.ErrNotePermission) {
+				t.Fatalf("bulk delete by the editor: got %+v, %v, want ErrNotePermission", results, err)
+			}
+			results, _, err = us.BulkNotesForUser(ctx, editor, BulkAddCategory, []uint{note.ID}, "team", true)
+			if err != nil || results[0].Err != nil {
+				t.Fatalf("bulk add_category by the editor: got %+v, %v, want success", results, err)
+			}
+
+			page := pagination.Request{Limit: 10}
+			shared, err := us.GetSharedNotesForUser(ctx, editor, page)
+			if err != nil {
+				t.Fatalf("GetSharedNotesForUser: %v", err)
+			}
+			if len(shared.Notes) != 1 || shared.Notes[0].ID != note.ID {
+				t.Fatalf("shared notes are %+v, want the shared note", shared.Notes)
+			}
+			if _, err := us.GetSharedNotesForUser(ctx, owner, page); !errors.Is(err, validations.ErrNoNotesFound) {
+				t.Fatalf("GetSharedNotesForUser for the owner: got %v, want ErrNoNotesFound", err)
+			}
+
+			if err := us.UnshareNoteWithUser(ctx, owner, note.ID, "carol"); err != nil {
+				t.Fatalf("UnshareNoteWithUser: %v", err)
+			}
+			if err := us.UnshareNoteWithUser(ctx, owner, note.ID, "carol"); !errors.Is(err, validations.ErrPermissionNotFound) {
+				t.Fatalf("UnshareNoteWithUser twice: got %v, want ErrPermissionNotFound", err)
+			}
+			if _, err := us.GetNoteById(ctx, note.ID, viewer); !errors.Is(err, validations.ErrNoteNotOwnedByUser) {
+				t.Fatalf("GetNoteById after unsharing: got %v, want ErrNoteNotOwnedByUser", err)
+			}
+			if _, err := us.DeleteNoteForUser(ctx, owner, note.ID); err != nil {
+				t.Fatalf("DeleteNoteForUser by the owner: %v", err)
+			}
+		})
+	}
+}
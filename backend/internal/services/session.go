@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/date"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+// SessionTokens are the credentials issued when a session starts or is
+// refreshed.
+type SessionTokens struct {
+	UserID       uint
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+type SessionService struct {
+	sessionRepo repositories.SessionStore
+}
+
+func NewSessionService(repo repositories.SessionStore) *SessionService {
+	return &SessionService{
+		sessionRepo: repo,
+	}
+}
+
+// Start opens a session for the user and issues its first tokens.
+func (ss *SessionService) Start(ctx context.Context, userId uint, userAgent string) (*SessionTokens, error) {
+	sessionId, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, validations.ErrTokenGeneration
+	}
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, validations.ErrTokenGeneration
+	}
+	accessToken, err := utils.GenerateJWT(userId, sessionId)
+	if err != nil {
+		return nil, validations.ErrTokenGeneration
+	}
+
+	session := models.NewSession(sessionId, userId, userAgent, date.ArgentinaTimeNow().Add(utils.RefreshTokenTTL()))
+	if err := ss.sessionRepo.Create(ctx, session, models.NewRefreshToken(sessionId, utils.HashToken(refreshToken))); err != nil {
+		return nil, validations.ErrSessionDB
+	}
+	return &SessionTokens{
+		UserID:       userId,
+		SessionID:    sessionId,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token works once: presenting a spent one means it was
+// copied, so the whole session is revoked.
+func (ss *SessionService) Refresh(ctx context.Context, refreshToken string) (*SessionTokens, error) {
+	if refreshToken == "" {
+		return nil, validations.ErrRefreshToken
+	}
+	used, session, err := ss.sessionRepo.FindRefreshToken(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, validations.ErrRefreshToken
+		}
+		return nil, validations.ErrSessionDB
+	}
+
+	now := *date.ArgentinaTimeNow()
+	if used.UsedAt != nil {
+		if err := ss.sessionRepo.Revoke(ctx, used.SessionID, now); err != nil {
+			return nil, validations.ErrSessionDB
+		}
+		return nil, validations.ErrRefreshTokenReuse
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return nil, validations.ErrRefreshToken
+	}
+
+	nextToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, validations.ErrTokenGeneration
+	}
+	accessToken, err := utils.GenerateJWT(session.UserID, used.SessionID)
+	if err != nil {
+		return nil, validations.ErrTokenGeneration
+	}
+
+	next := models.NewRefreshToken(used.SessionID, utils.HashToken(nextToken))
+	if err := ss.sessionRepo.Rotate(ctx, used, next, now, now.Add(utils.RefreshTokenTTL())); err != nil {
+		if errors.Is(err, validations.ErrRefreshTokenReuse) {
+			if err := ss.sessionRepo.Revoke(ctx, used.SessionID, now); err != nil {
+				return nil, validations.ErrSessionDB
+			}
+			return nil, err
+		}
+		return nil, validations.ErrSessionDB
+	}
+	return &SessionTokens{
+		UserID:       session.UserID,
+		SessionID:    used.SessionID,
+		AccessToken:  accessToken,
+		RefreshToken: nextToken,
+	}, nil
+}
+
+// IsActive reports whether the session exists for the user and is neither
+// revoked nor expired.
+func (ss *SessionService) IsActive(ctx context.Context, sessionId string, userId uint) (bool, error) {
+	if sessionId == "" {
+		return false, nil
+	}
+	session, err := ss.sessionRepo.FindActive(ctx, sessionId, *date.ArgentinaTimeNow())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, validations.ErrSessionDB
+	}
+	return session.UserID == userId, nil
+}
+
+// RevokeByRefreshToken ends the session the refresh token belongs to.
+// Unknown tokens are ignored, so logging out twice is harmless.
+func (ss *SessionService) RevokeByRefreshToken(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	token, _, err := ss.sessionRepo.FindRefreshToken(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return validations.ErrSessionDB
+	}
+	return ss.Revoke(ctx, token.SessionID)
+}
+
+func (ss *SessionService) Revoke(ctx context.Context, sessionId string) error {
+	if err := ss.sessionRepo.Revoke(ctx, sessionId, *date.ArgentinaTimeNow()); err != nil {
+		return validations.ErrSessionDB
+	}
+	return nil
+}
+
+// RevokeAll ends every session of the user, logging out all devices.
+func (ss *SessionService) RevokeAll(ctx context.Context, userId uint) error {
+	if err := ss.sessionRepo.RevokeAllForUser(ctx, userId, *date.ArgentinaTimeNow()); err != nil {
+		return validations.ErrSessionDB
+	}
+	return nil
+}
+
+// RevokeOthers ends every session of the user except keepId, logging out
+// the other devices.
+func (ss *SessionService) RevokeOthers(ctx context.Context, userId uint, keepId string) error {
+	if err := ss.sessionRepo.RevokeOthersForUser(ctx, userId, keepId, *date.ArgentinaTimeNow()); err != nil {
+		return validations.ErrSessionDB
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/internal/repositories/memory"
+	"notes/pkg/date"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+// sessionStores builds, for each backend, an empty session store and the
+// user store its sessions belong to.
+var sessionStores = map[string]func(t *testing.T) (repositories.SessionStore, repositories.UserStore){
+	"memory": func(t *testing.T) (repositories.SessionStore, repositories.UserStore) {
+		store := memory.New()
+		return store.Sessions(), store.Users()
+	},
+	"postgres": func(t *testing.T) (repositories.SessionStore, repositories.UserStore) {
+		gormDB := dbtest.Postgres(t)
+		return repositories.NewSessionRepository(gormDB, &configs.Config{}), repositories.NewUserRepository(gormDB, &configs.Config{})
+	},
+	"sqlite": func(t *testing.T) (repositories.SessionStore, repositories.UserStore) {
+		gormDB := dbtest.SQLite(t)
+		return repositories.NewSessionRepository(gormDB, &configs.Config{}), repositories.NewUserRepository(gormDB, &configs.Config{})
+	},
+}
+
+// eachSessionStore runs test against a session service, and the ID of its
+// user, on every backend.
+func eachSessionStore(t *testing.T, test func(t *testing.T, ss *SessionService, sessions repositories.SessionStore, userId uint)) {
+	t.Setenv("JWT_STRING", "test-secret")
+	for name, newStores := range sessionStores {
+		t.Run(name, func(t *testing.T) {
+			sessions, users := newStores(t)
+			user := models.NewUser("alice", "hash", nil)
+			if err := users.CreateUser(context.Background(), user); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			test(t, NewSessionService(sessions), sessions, user.ID)
+		})
+	}
+}
+
+func TestRefreshRotatesAndRevokesOnReuse(t *testing.T) {
+	eachSessionStore(t, func(t *testing.T, ss *SessionService, _ repositories.SessionStore, userId uint) {
+		ctx := context.Background()
+		started, err := ss.Start(ctx, userId, "test agent")
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		refreshed, err := ss.Refresh(ctx, started.RefreshToken)
+		if err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		if refreshed.SessionID != started.SessionID || refreshed.RefreshToken == started.RefreshToken {
+			t.Fatalf("Refresh returned %+v, want a new refresh token for the same session", refreshed)
+		}
+		if active, err := ss.IsActive(ctx, started.SessionID, userId); err != nil || !active {
+			t.Fatalf("IsActive after a refresh: %v, %v, want true", active, err)
+		}
+
+		// The spent token was copied: spending it again ends the session,
+		// and with it the token that replaced it.
+		if _, err := ss.Refresh(ctx, started.RefreshToken); !errors.Is(err, validations.ErrRefreshTokenReuse) {
+			t.Fatalf("Refresh with a spent token: got %v, want ErrRefreshTokenReuse", err)
+		}
+		if active, err := ss.IsActive(ctx, started.SessionID, userId); err != nil || active {
+			t.Fatalf("IsActive after a reuse: %v, %v, want false", active, err)
+		}
+		if _, err := ss.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, validations.ErrRefreshToken) {
+			t.Fatalf("Refresh of a revoked session: got %v, want ErrRefreshToken", err)
+		}
+		if _, err := ss.Refresh(ctx, "unknown"); !errors.Is(err, validations.ErrRefreshToken) {
+			t.Fatalf("Refresh with an unknown token: got %v, want ErrRefreshToken", err)
+		}
+	})
+}
+
+func TestRotateSpendsTokenOnce(t *testing.T) {
+	eachSessionStore(t, func(t *testing.T, ss *SessionService, sessions repositories.SessionStore, userId uint) {
+		ctx := context.Background()
+		started, err := ss.Start(ctx, userId, "test agent")
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		// Two refreshes racing on one token both find it unused; only the
+		// first to rotate it may go on.
+		hash := utils.HashToken(started.RefreshToken)
+		first, _, err := sessions.FindRefreshToken(ctx, hash)
+		if err != nil {
+			t.Fatalf("FindRefreshToken: %v", err)
+		}
+		second, _, err := sessions.FindRefreshToken(ctx, hash)
+		if err != nil {
+			t.Fatalf("FindRefreshToken: %v", err)
+		}
+		now := *date.ArgentinaTimeNow()
+		expiresAt := now.Add(utils.RefreshTokenTTL())
+		next := models.NewRefreshToken(started.SessionID, utils.HashToken("first next"))
+		if err := sessions.Rotate(ctx, first, next, now, expiresAt); err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		other := models.NewRefreshToken(started.SessionID, utils.HashToken("second next"))
+		if err := sessions.Rotate(ctx, second, other, now, expiresAt); !errors.Is(err, validations.ErrRefreshTokenReuse) {
+			t.Fatalf("Rotate of a token spent meanwhile: got %v, want ErrRefreshTokenReuse", err)
+		}
+		if _, _, err := sessions.FindRefreshToken(ctx, other.TokenHash); err == nil {
+			t.Fatalf("the losing rotation stored its token")
+		}
+		if _, _, err := sessions.FindRefreshToken(ctx, next.TokenHash); err != nil {
+			t.Fatalf("FindRefreshToken of the winning rotation: %v", err)
+		}
+	})
+}
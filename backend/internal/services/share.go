@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/date"
+	"notes/pkg/password"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxShareDays = 365
+
+	minSharePasswordLength = 8
+	maxSharePasswordLength = 128
+)
+
+type ShareService struct {
+	shareRepo      *repositories.ShareRepository
+	noteService    *NoteService
+	passwordHasher *password.Hasher
+	// passwordThrottle holds back guessing of link passwords, per link and
+	// address.
+	passwordThrottle *AttemptThrottle
+}
+
+func NewShareService(repo *repositories.ShareRepository, noteService *NoteService, passwordHasher *password.Hasher, passwordThrottle *AttemptThrottle) *ShareService {
+	return &ShareService{
+		shareRepo:        repo,
+		noteService:      noteService,
+		passwordHasher:   passwordHasher,
+		passwordThrottle: passwordThrottle,
+	}
+}
+
+// Create makes a read-only link to the user's note. expiresInDays of zero
+// makes a link that works until it is revoked; an empty password makes one
+// that needs none. The returned string is the only copy of the token; it
+// cannot be recovered later.
+func (ss *ShareService) Create(ctx context.Context, userId, noteId uint, expiresInDays int, pw string) (*models.NoteShare, string, error) {
+	if _, err := ss.ownedNote(ctx, userId, noteId); err != nil {
+		return nil, "", err
+	}
+	if expiresInDays < 0 || expiresInDays > MaxShareDays {
+		return nil, "", validations.ErrShareExpiry
+	}
+	var expiresAt *time.Time
+	if expiresInDays > 0 {
+		t := date.ArgentinaTimeNow().Add(time.Duration(expiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	var passwordHash string
+	if pw != "" {
+		if n := utf8.RuneCountInString(pw); n < minSharePasswordLength || n > maxSharePasswordLength {
+			return nil, "", validations.ErrSharePasswordLength
+		}
+		hash, err := ss.passwordHasher.Hash(pw)
+		if err != nil {
+			return nil, "", validations.ErrHashingPwd
+		}
+		passwordHash = hash
+	}
+
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", validations.ErrTokenGeneration
+	}
+	share := models.NewNoteShare(noteId, userId, utils.HashToken(raw), passwordHash, expiresAt)
+	if err := ss.shareRepo.Create(ctx, share); err != nil {
+		return nil, "", validations.ErrShareDB
+	}
+	return share, raw, nil
+}
+
+func (ss *ShareService) List(ctx context.Context, userId, noteId uint) ([]models.NoteShare, error) {
+	if _, err := ss.ownedNote(ctx, userId, noteId); err != nil {
+		return nil, err
+	}
+	shares, err := ss.shareRepo.ListForNote(ctx, userId, noteId)
+	if err != nil {
+		return nil, validations.ErrShareDB
+	}
+	return shares, nil
+}
+
+func (ss *ShareService) Revoke(ctx context.Context, userId, noteId, id uint) error {
+	if _, err := ss.ownedNote(ctx, userId, noteId); err != nil {
+		return err
+	}
+	revoked, err := ss.shareRepo.Revoke(ctx, userId, noteId, id, *date.ArgentinaTimeNow())
+	if err != nil {
+		return validations.ErrShareDB
+	}
+	if !revoked {
+		return validations.ErrShareNotFound
+	}
+	return nil
+}
+
+// Open returns the note a link points to and counts the view. Revoked and
+// expired links, and links to notes in the trash, are not found. Links with
+// a password fail with ErrSharePassword unless pw matches it; repeated
+// failures from the IP address hold its attempts back with a ThrottledError.
+func (ss *ShareService) Open(ctx context.Context, raw, pw, ip string) (*models.Note, *models.NoteShare, error) {
+	now := *date.ArgentinaTimeNow()
+	share, err := ss.shareRepo.FindActive(ctx, utils.HashToken(raw), now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, validations.ErrShareNotFound
+	}
+	if err != nil {
+		return nil, nil, validations.ErrShareDB
+	}
+	if share.HasPassword() {
+		// No password is not a guess: it is how the HTML page asks for one.
+		if pw == "" {
+			return nil, nil, validations.ErrSharePassword
+		}
+		// Checked before hashing, so that guesses cost the server nothing
+		// once the address is held back.
+		key := fmt.Sprintf("share:%d:ip:%s", share.ID, ip)
+		if err := ss.passwordThrottle.Check(ctx, key); err != nil {
+			return nil, nil, err
+		}
+		if ok, _ := ss.passwordHasher.Verify(pw, share.PasswordHash); !ok {
+			ss.passwordThrottle.Failed(ctx, key)
+			return nil, nil, validations.ErrSharePassword
+		}
+		ss.passwordThrottle.Succeeded(ctx, key)
+	}
+
+	note, err := ss.noteService.GetNoteById(ctx, share.NoteID)
+	if errors.Is(err, validations.ErrNoteNotFound) {
+		return nil, nil, validations.ErrShareNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ss.shareRepo.RecordView(ctx, share.ID, now); err != nil {
+		return nil, nil, validations.ErrShareDB
+	}
+	return note, share, nil
+}
+
+// ownedNote returns the note if the user may manage it. Only owners may
+// share a note or manage its links.
+func (ss *ShareService) ownedNote(ctx context.Context, userId, noteId uint) (*models.Note, error) {
+	return ss.noteService.AuthorizeNote(ctx, userId, noteId, ManageNote)
+}
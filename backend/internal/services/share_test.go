@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"notes/internal/configs"
+	"notes/internal/db/dbtest"
+	"notes/internal/models"
+	"notes/internal/repositories"
+	"notes/pkg/password"
+	"notes/pkg/throttle"
+	"notes/pkg/validations"
+)
+
+func newShareService(t *testing.T) (*ShareService, *NoteService, uint, uint) {
+	t.Helper()
+	policy := throttle.Policy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutAfter: 5, LockoutFor: 15 * time.Minute, Window: 15 * time.Minute}
+	passwordThrottle := NewAttemptThrottle(throttle.NewMemoryStore(), policy, validations.ErrShareThrottled, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return newShareServiceWithThrottle(t, passwordThrottle)
+}
+
+func newShareServiceWithThrottle(t *testing.T, passwordThrottle *AttemptThrottle) (*ShareService, *NoteService, uint, uint) {
+	t.Helper()
+	gormDB := dbtest.SQLite(t)
+	notes, users := sqlStores(gormDB)
+	var ids []uint
+	for _, name := range []string{"alice", "bob"} {
+		user := models.NewUser(name, "hash", nil)
+		if err := users.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+	ns := NewNoteService(notes, NewCategoryService(notes.Categories()), nil)
+	hasher := &password.Hasher{Algorithm: password.Argon2id, Argon2: password.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}}
+	return NewShareService(repositories.NewShareRepository(gormDB, &configs.Config{}), ns, hasher, passwordThrottle), ns, ids[0], ids[1]
+}
+
+func TestShareLinks(t *testing.T) {
+	ss, ns, alice, bob := newShareService(t)
+	ctx := context.Background()
+	note, err := ns.CreateNote(ctx, "trip plan", "pack the tent", []string{"travel"}, alice)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	if _, _, err := ss.Create(ctx, bob, note.ID, 0, ""); !errors.Is(err, validations.ErrNoteNotOwnedByUser) {
+		t.Fatalf("Create by another user: got %v, want ErrNoteNotOwnedByUser", err)
+	}
+	if _, _, err := ss.Create(ctx, alice, note.ID, MaxShareDays+1, ""); !errors.Is(err, validations.ErrShareExpiry) {
+		t.Fatalf("Create with a long expiry: got %v, want ErrShareExpiry", err)
+	}
+	if _, _, err := ss.Create(ctx, alice, note.ID, 0, "short"); !errors.Is(err, validations.ErrSharePasswordLength) {
+		t.Fatalf("Create with a short password: got %v, want ErrSharePasswordLength", err)
+	}
+
+	open, raw, err := ss.Create(ctx, alice, note.ID, 0, "")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if open.ExpiresAt != nil || open.TokenHash == raw {
+		t.Fatalf("share is %+v, want no expiry and a hashed token", open)
+	}
+	shared, _, err := ss.Open(ctx, raw, "", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if shared.ID != note.ID || shared.Content != "pack the tent" {
+		t.Fatalf("Open returned %+v, want the shared note", shared)
+	}
+	if _, _, err := ss.Open(ctx, raw+"0", "", "192.0.2.1"); !errors.Is(err, validations.ErrShareNotFound) {
+		t.Fatalf("Open with a wrong token: got %v, want ErrShareNotFound", err)
+	}
+
+	locked, lockedRaw, err := ss.Create(ctx, alice, note.ID, 7, "correct horse")
+	if err != nil {
+		t.Fatalf("Create with a password: %v", err)
+	}
+	if locked.ExpiresAt == nil {
+		t.Fatalf("share with an expiry has none")
+	}
+	for _, pw := range []string{"", "wrong horse"} {
+		if _, _, err := ss.Open(ctx, lockedRaw, pw, "192.0.2.1"); !errors.Is(err, validations.ErrSharePassword) {
+			t.Fatalf("Open with password %q: got %v, want ErrSharePassword", pw, err)
+		}
+	}
+	if _, _, err := ss.Open(ctx, lockedRaw, "correct horse", "192.0.2.1"); err != nil {
+		t.Fatalf("Open with the password: %v", err)
+	}
+
+	shares, err := ss.List(ctx, alice, note.ID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(shares) != 2 || shares[0].ID != locked.ID || shares[0].Views != 1 || shares[1].Views != 1 {
+		t.Fatalf("List returned %+v, want both shares, newest first, viewed once each", shares)
+	}
+
+	if err := ss.Revoke(ctx, bob, note.ID, open.ID); !errors.Is(err, validations.ErrNoteNotOwnedByUser) {
+		t.Fatalf("Revoke by another user: got %v, want ErrNoteNotOwnedByUser", err)
+	}
+	if err := ss.Revoke(ctx, alice, note.ID, open.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, _, err := ss.Open(ctx, raw, "", "192.0.2.1"); !errors.Is(err, validations.ErrShareNotFound) {
+		t.Fatalf("Open a revoked share: got %v, want ErrShareNotFound", err)
+	}
+	if err := ss.Revoke(ctx, alice, note.ID, open.ID); !errors.Is(err, validations.ErrShareNotFound) {
+		t.Fatalf("Revoke twice: got %v, want ErrShareNotFound", err)
+	}
+
+	if _, err := ns.DeleteNote(ctx, note.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if _, _, err := ss.Open(ctx, lockedRaw, "correct horse", "192.0.2.1"); !errors.Is(err, validations.ErrShareNotFound) {
+		t.Fatalf("Open a share of a trashed note: got %v, want ErrShareNotFound", err)
+	}
+}
+
+func TestSharePasswordThrottle(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}
+	policy := throttle.Policy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutAfter: 3, LockoutFor: 15 * time.Minute, Window: 15 * time.Minute}
+	passwordThrottle := NewAttemptThrottle(throttle.NewMemoryStore(), policy, validations.ErrShareThrottled, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	passwordThrottle.now = clock.Now
+	ss, ns, alice, _ := newShareServiceWithThrottle(t, passwordThrottle)
+	note, err := ns.CreateNote(ctx, "trip plan", "pack the tent", []string{"travel"}, alice)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	_, raw, err := ss.Create(ctx, alice, note.ID, 0, "correct horse")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Asking for the password is not a guess.
+	for range 5 {
+		if _, _, err := ss.Open(ctx, raw, "", "192.0.2.1"); !errors.Is(err, validations.ErrSharePassword) {
+			t.Fatalf("Open without a password: got %v, want ErrSharePassword", err)
+		}
+	}
+	for range 3 {
+		if _, _, err := ss.Open(ctx, raw, "wrong horse", "192.0.2.1"); !errors.Is(err, validations.ErrSharePassword) {
+			t.Fatalf("Open with a wrong password: got %v, want ErrSharePassword", err)
+		}
+	}
+	_, _, err = ss.Open(ctx, raw, "correct horse", "192.0.2.1")
+	if !errors.Is(err, validations.ErrShareThrottled) {
+		t.Fatalf("Open once locked out: got %v, want ErrShareThrottled", err)
+	}
+	if got := retryAfter(t, err); got != 15*time.Minute {
+		t.Fatalf("locked out address waits %s, want 15m", got)
+	}
+	if _, _, err := ss.Open(ctx, raw, "correct horse", "198.51.100.7"); err != nil {
+		t.Fatalf("Open from another address: %v", err)
+	}
+
+	clock.Advance(15 * time.Minute)
+	if _, _, err := ss.Open(ctx, raw, "correct horse", "192.0.2.1"); err != nil {
+		t.Fatalf("Open after the lockout: %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"notes/internal/models"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+// maxSyncChanges caps how many changes a single sync request may apply.
+const maxSyncChanges = 100
+
+// maxSyncLimit caps how many changes a single sync page may list.
+const maxSyncLimit = 1000
+
+// encodeSyncToken hands a change number to clients as an opaque token.
+func encodeSyncToken(seq uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(seq, 10)))
+}
+
+// decodeSyncToken returns the change number of a token, zero for no token.
+func decodeSyncToken(token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, validations.ErrSyncToken
+	}
+	seq, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, validations.ErrSyncToken
+	}
+	return seq, nil
+}
+
+// syncEntry is one row of a change set.
+type syncEntry struct {
+	seq       uint64
+	note      *models.Note
+	category  *models.Category
+	tombstone *models.SyncTombstone
+}
+
+// Changes returns up to limit of the user's changes made after the token,
+// oldest first. Without a token it returns everything the user has. Tokens
+// older than the newest pruned tombstone fail with ErrSyncTokenExpired, as
+// deletions the client never saw may be gone.
+func (ns *NoteService) Changes(ctx context.Context, userId uint, token string, limit int) (*models.SyncPage, error) {
+	if limit < 1 || limit > maxSyncLimit {
+		return nil, validations.ErrSyncLimit
+	}
+	since, err := decodeSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+	set, err := ns.noteRepo.Changes(ctx, userId, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if since > set.Latest || (since > 0 && since < set.PrunedThrough) {
+		return nil, validations.ErrSyncTokenExpired
+	}
+
+	entries := make([]syncEntry, 0, len(set.Notes)+len(set.Categories)+len(set.Tombstones))
+	for i := range set.Notes {
+		entries = append(entries, syncEntry{seq: set.Notes[i].ChangeSeq, note: &set.Notes[i]})
+	}
+	for i := range set.Categories {
+		entries = append(entries, syncEntry{seq: set.Categories[i].ChangeSeq, category: &set.Categories[i]})
+	}
+	for i := range set.Tombstones {
+		entries = append(entries, syncEntry{seq: set.Tombstones[i].ChangeSeq, tombstone: &set.Tombstones[i]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	page := &models.SyncPage{
+		Notes:      []models.Note{},
+		Categories: []models.Category{},
+		Deleted:    []models.SyncTombstone{},
+		Token:      encodeSyncToken(set.Latest),
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.HasMore = true
+		page.Token = encodeSyncToken(entries[limit-1].seq)
+	}
+	for _, e := range entries {
+		switch {
+		case e.note != nil && e.note.DeletedAt.Valid:
+			page.Deleted = append(page.Deleted, models.SyncTombstone{
+				UserID:    userId,
+				Kind:      models.SyncNote,
+				EntityID:  e.note.ID,
+				ChangeSeq: e.seq,
+				DeletedAt: e.note.DeletedAt.Time,
+			})
+		case e.note != nil:
+			page.Notes = append(page.Notes, *e.note)
+		case e.category != nil:
+			page.Categories = append(page.Categories, *e.category)
+		default:
+			page.Deleted = append(page.Deleted, *e.tombstone)
+		}
+	}
+	return page, nil
+}
+
+// PruneTombstones forgets deletions made before the cutoff.
+func (ns *NoteService) PruneTombstones(ctx context.Context, cutoff time.Time) (int64, error) {
+	return ns.noteRepo.PruneTombstones(ctx, cutoff)
+}
+
+// ApplySyncChange applies a change made offline to one of the user's notes,
+// in a transaction of its own. Changes made to the note's current version
+// are saved; changes made to an older one are not, and come back as a
+// conflict with the server's note unless they already match it. Validation
+// failures come back as rejected results; other errors are returned.
+func (ns *NoteService) ApplySyncChange(ctx context.Context, userId uint, change models.SyncChange) (models.SyncResult, error) {
+	var result models.SyncResult
+	err := ns.InTransaction(ctx, func(tx *NoteService) error {
+		var err error
+		if change.NoteID == 0 {
+			result, err = tx.createSyncNote(ctx, userId, change)
+		} else {
+			result, err = tx.updateSyncNote(ctx, userId, change)
+		}
+		return err
+	})
+	if err != nil && isSyncRejection(err) {
+		return models.SyncResult{Status: models.SyncRejected, Err: err}, nil
+	}
+	return result, err
+}
+
+func (ns *NoteService) createSyncNote(ctx context.Context, userId uint, change models.SyncChange) (models.SyncResult, error) {
+	// A client that never got the response to a create sends it again.
+	if _, title, err := utils.ValidateAndFormatTitle(change.Title); err == nil {
+		if existing, _ := ns.noteRepo.GetByTitle(ctx, userId, title); existing != nil {
+			note, err := ns.GetNoteById(ctx, existing.ID)
+			if err != nil {
+				return models.SyncResult{}, err
+			}
+			if matchesNote(note, change) {
+				return models.SyncResult{Status: models.SyncUnchanged, Note: note}, nil
+			}
+		}
+	}
+
+	names := make([]string, 0, len(change.Categories))
+	for _, c := range change.Categories {
+		names = append(names, c.Name)
+	}
+	note, err := ns.CreateNote(ctx, change.Title, change.Content, names, userId)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	if change.IsArchived {
+		if note, err = ns.ToggleArchiveStatus(ctx, note.ID, note.Version, userId); err != nil {
+			return models.SyncResult{}, err
+		}
+	}
+	return models.SyncResult{Status: models.SyncApplied, Note: note}, nil
+}
+
+func (ns *NoteService) updateSyncNote(ctx context.Context, userId uint, change models.SyncChange) (models.SyncResult, error) {
+	note, err := ns.GetNoteById(ctx, change.NoteID)
+	if errors.Is(err, validations.ErrNoteNotFound) {
+		// The note was deleted on the server, which the client may not know.
+		if change.Deleted {
+			return models.SyncResult{Status: models.SyncUnchanged}, nil
+		}
+		return models.SyncResult{Status: models.SyncConflict}, nil
+	}
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	// Sync covers the user's own notes, which it may also archive and delete.
+	if err := ns.authorize(ctx, note, userId, ManageNote); err != nil {
+		return models.SyncResult{}, err
+	}
+	if note.Version != change.BaseVersion {
+		return ns.resolveSyncConflict(note, change), nil
+	}
+
+	if change.Deleted {
+		if _, err := ns.DeleteNote(ctx, note.ID); err != nil {
+			return models.SyncResult{}, err
+		}
+		return models.SyncResult{Status: models.SyncApplied}, nil
+	}
+	_, err = ns.UpdateNote(ctx, note.ID, &models.Note{
+		Title:      change.Title,
+		Content:    change.Content,
+		Categories: change.Categories,
+		IsArchived: change.IsArchived,
+		Version:    change.BaseVersion,
+	}, userId)
+	if errors.Is(err, validations.ErrNoChangesDetected) {
+		return models.SyncResult{Status: models.SyncUnchanged, Note: note}, nil
+	}
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	if note, err = ns.GetNoteById(ctx, note.ID); err != nil {
+		return models.SyncResult{}, err
+	}
+	return models.SyncResult{Status: models.SyncApplied, Note: note}, nil
+}
+
+// resolveSyncConflict settles a change made to an outdated version of the
+// note. Edits that already match the note need nothing saved; anything else,
+// deletions included, is left for the client to merge with the note.
+func (ns *NoteService) resolveSyncConflict(note *models.Note, change models.SyncChange) models.SyncResult {
+	if !change.Deleted && matchesNote(note, change) {
+		return models.SyncResult{Status: models.SyncUnchanged, Note: note}
+	}
+	return models.SyncResult{Status: models.SyncConflict, Note: note}
+}
+
+// matchesNote reports whether saving the change would leave the note as it is.
+func matchesNote(note *models.Note, change models.SyncChange) bool {
+	_, title, _ := utils.ValidateAndFormatTitle(change.Title)
+	_, content, _ := utils.ValidateAndFormatContent(change.Content)
+	if note.Title != title || note.Content != content || note.IsArchived != change.IsArchived {
+		return false
+	}
+	names := make(map[string]bool, len(change.Categories))
+	for _, c := range change.Categories {
+		_, name, _ := utils.ValidateAndFormatCategory(c.Name)
+		names[name] = true
+	}
+	if len(names) != len(note.Categories) {
+		return false
+	}
+	for _, c := range note.Categories {
+		if !names[c.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSyncRejection reports whether err is the client's fault, a change the
+// server refuses rather than fails to save.
+func isSyncRejection(err error) bool {
+	for _, target := range []error{
+		validations.ErreEmptyTitle, validations.ErrCharactersExcess, validations.ErrEmptyContent,
+		validations.ErrCharactersContentExcess, validations.ErrEmptyCategory, validations.ErrCharactersExcessCat,
+		validations.ErrRepeatedLetters, validations.ErrTooManyCategories, validations.ErrTooManyCat,
+		validations.ErrZeroCategory, validations.ErrDuplicateTitle, validations.ErrNoteNotOwnedByUser, validations.ErrNotePermission,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (us *UserService) SyncChangesForUser(ctx context.Context, userId uint, token string, limit int) (*models.SyncPage, error) {
+	return us.noteService.Changes(ctx, userId, token, limit)
+}
+
+// ApplySyncChangesForUser applies the changes in order, each on its own, and
+// reports the outcome of every one. An error stops the run, keeping the
+// changes applied before it; sending them again is safe, as changes that
+// were saved come back unchanged.
+func (us *UserService) ApplySyncChangesForUser(ctx context.Context, userId uint, changes []models.SyncChange) ([]models.SyncResult, error) {
+	if len(changes) == 0 || len(changes) > maxSyncChanges {
+		return nil, validations.ErrSyncChanges
+	}
+	results := make([]models.SyncResult, 0, len(changes))
+	for _, change := range changes {
+		result, err := us.noteService.ApplySyncChange(ctx, userId, change)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
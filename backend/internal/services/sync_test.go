@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"notes/internal/models"
+	"notes/pkg/validations"
+)
+
+func TestSyncChanges(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		var notes []*models.Note
+		for _, title := range []string{"first note", "second note", "third note"} {
+			note, err := ns.CreateNote(ctx, title, "content of "+title, []string{"home"}, userId)
+			if err != nil {
+				t.Fatalf("CreateNote: %v", err)
+			}
+			notes = append(notes, note)
+		}
+
+		page, err := ns.Changes(ctx, userId, "", 3)
+		if err != nil {
+			t.Fatalf("Changes: %v", err)
+		}
+		if len(page.Categories) != 1 || len(page.Notes) != 2 || !page.HasMore {
+			t.Fatalf("first page has %d categories and %d notes, more %v; want 1 and 2, more true",
+				len(page.Categories), len(page.Notes), page.HasMore)
+		}
+		page, err = ns.Changes(ctx, userId, page.Token, 3)
+		if err != nil {
+			t.Fatalf("Changes: %v", err)
+		}
+		if len(page.Notes) != 1 || page.Notes[0].ID != notes[2].ID || page.HasMore {
+			t.Fatalf("second page is %+v, want the third note alone", page)
+		}
+
+		if _, err := ns.DeleteNote(ctx, notes[0].ID); err != nil {
+			t.Fatalf("DeleteNote: %v", err)
+		}
+		beforePurge := page.Token
+		page, err = ns.Changes(ctx, userId, beforePurge, 3)
+		if err != nil {
+			t.Fatalf("Changes: %v", err)
+		}
+		if len(page.Notes) != 0 || len(page.Deleted) != 1 || page.Deleted[0].EntityID != notes[0].ID {
+			t.Fatalf("changes after trashing are %+v, want the trashed note as deleted", page)
+		}
+
+		if _, err := ns.EmptyTrash(ctx, userId); err != nil {
+			t.Fatalf("EmptyTrash: %v", err)
+		}
+		if _, err := ns.PruneTombstones(ctx, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("PruneTombstones: %v", err)
+		}
+		if _, err := ns.Changes(ctx, userId, beforePurge, 3); !errors.Is(err, validations.ErrSyncTokenExpired) {
+			t.Fatalf("Changes with a token older than the pruned tombstones: got %v, want ErrSyncTokenExpired", err)
+		}
+		page, err = ns.Changes(ctx, userId, "", 10)
+		if err != nil {
+			t.Fatalf("Changes without a token after pruning: %v", err)
+		}
+		if len(page.Notes) != 2 || len(page.Deleted) != 0 {
+			t.Fatalf("full sync after pruning is %+v, want the two remaining notes", page)
+		}
+
+		if _, err := ns.Changes(ctx, userId, "not a token", 10); !errors.Is(err, validations.ErrSyncToken) {
+			t.Fatalf("Changes with a bad token: got %v, want ErrSyncToken", err)
+		}
+		if _, err := ns.Changes(ctx, userId, "", 0); !errors.Is(err, validations.ErrSyncLimit) {
+			t.Fatalf("Changes with limit 0: got %v, want ErrSyncLimit", err)
+		}
+	})
+}
+
+func TestApplySyncChange(t *testing.T) {
+	eachStore(t, func(t *testing.T, ns *NoteService, userId uint) {
+		ctx := context.Background()
+		note, err := ns.CreateNote(ctx, "draft note", "first draft", []string{"work"}, userId)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		apply := func(what string, change models.SyncChange, want models.SyncStatus) models.SyncResult {
+			t.Helper()
+			result, err := ns.ApplySyncChange(ctx, userId, change)
+			if err != nil {
+				t.Fatalf("%s: %v", what, err)
+			}
+			if result.Status != want {
+				t.Fatalf("%s: status %s (%v), want %s", what, result.Status, result.Err, want)
+			}
+			return result
+		}
+		edit := models.SyncChange{
+			NoteID:      note.ID,
+			BaseVersion: 1,
+			Title:       "Draft Note",
+			Content:     "second draft",
+			Categories:  []models.Category{{Name: "work"}},
+		}
+
+		result := apply("edit of the current version", edit, models.SyncApplied)
+		if result.Note.Version != 2 || result.Note.Content != "second draft" {
+			t.Fatalf("edited note is %+v", result.Note)
+		}
+		apply("the same edit sent again", edit, models.SyncUnchanged)
+
+		other := edit
+		other.Content = "another draft"
+		result = apply("edit of an outdated version", other, models.SyncConflict)
+		if result.Note == nil || result.Note.Content != "second draft" {
+			t.Fatalf("conflict carries %+v, want the server's note", result.Note)
+		}
+		apply("deletion of an outdated version", models.SyncChange{NoteID: note.ID, BaseVersion: 1, Deleted: true}, models.SyncConflict)
+
+		apply("deletion of the current version", models.SyncChange{NoteID: note.ID, BaseVersion: 2, Deleted: true}, models.SyncApplied)
+		apply("the same deletion sent again", models.SyncChange{NoteID: note.ID, BaseVersion: 2, Deleted: true}, models.SyncUnchanged)
+		if result = apply("edit of a deleted note", other, models.SyncConflict); result.Note != nil {
+			t.Fatalf("conflict over a deleted note carries %+v, want no note", result.Note)
+		}
+
+		create := models.SyncChange{Title: "offline note", Content: "written offline", Categories: []models.Category{{Name: "home"}}, IsArchived: true}
+		result = apply("creation", create, models.SyncApplied)
+		if !result.Note.IsArchived {
+			t.Fatalf("created note is %+v, want it archived", result.Note)
+		}
+		if again := apply("the same creation sent again", create, models.SyncUnchanged); again.Note.ID != result.Note.ID {
+			t.Fatalf("creation sent again returned note %d, want %d", again.Note.ID, result.Note.ID)
+		}
+
+		create.Title = " "
+		if result = apply("creation without a title", create, models.SyncRejected); result.Err == nil {
+			t.Fatalf("rejected change has no error")
+		}
+	})
+}
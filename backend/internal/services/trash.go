@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// TrashPurger permanently deletes notes that have been in the trash for
+// longer than the retention period, checking once per interval. It also
+// prunes the sync tombstones older than their own retention period.
+type TrashPurger struct {
+	noteService        *NoteService
+	retention          time.Duration
+	tombstoneRetention time.Duration
+	interval           time.Duration
+	logger             *slog.Logger
+}
+
+func NewTrashPurger(noteService *NoteService, retention, tombstoneRetention, interval time.Duration, logger *slog.Logger) *TrashPurger {
+	return &TrashPurger{
+		noteService:        noteService,
+		retention:          retention,
+		tombstoneRetention: tombstoneRetention,
+		interval:           interval,
+		logger:             logger,
+	}
+}
+
+// Run purges on start and then on every tick until ctx is cancelled.
+func (tp *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(tp.interval)
+	defer ticker.Stop()
+
+	for {
+		tp.purge(ctx)
+		select {
+		case <-ctx.Done():
+			tp.logger.Info("trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tp *TrashPurger) purge(ctx context.Context) {
+	cutoff := time.Now().Add(-tp.retention)
+	purged, err := tp.noteService.PurgeTrash(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			tp.logger.Error("trash purge failed", "error", err)
+		}
+		return
+	}
+	if purged > 0 {
+		tp.logger.Info("trash purged", "notes", purged, "cutoff", cutoff)
+	}
+
+	cutoff = time.Now().Add(-tp.tombstoneRetention)
+	pruned, err := tp.noteService.PruneTombstones(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			tp.logger.Error("tombstone pruning failed", "error", err)
+		}
+		return
+	}
+	if pruned > 0 {
+		tp.logger.Info("tombstones pruned", "tombstones", pruned, "cutoff", cutoff)
+	}
+}
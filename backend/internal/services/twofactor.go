@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"notes/internal/models"
+	"notes/pkg/date"
+	"notes/pkg/totp"
+	"notes/pkg/utils"
+	"notes/pkg/validations"
+)
+
+const (
+	totpIssuer        = "Notes"
+	recoveryCodeCount = 10
+)
+
+// TOTPEnrollment is what an authenticator app needs to start generating
+// codes for the user.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// EnrollTOTP generates a new secret for the user. Two-factor authentication
+// stays off until ConfirmTOTP receives a valid code for it.
+func (us *UserService) EnrollTOTP(ctx context.Context, userId uint) (*TOTPEnrollment, error) {
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil {
+		return nil, validations.ErrInvalidUserID
+	}
+	if user.TOTPEnabled {
+		return nil, validations.ErrTOTPEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, validations.ErrTwoFactorDB
+	}
+	if err := us.userRepo.SetTOTPSecret(ctx, userId, secret); err != nil {
+		return nil, validations.ErrTwoFactorDB
+	}
+	return &TOTPEnrollment{Secret: secret, URI: totp.URI(totpIssuer, user.UserName, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their
+// authenticator works. It returns the recovery codes, which are only ever
+// shown here.
+func (us *UserService) ConfirmTOTP(ctx context.Context, userId uint, code string) ([]string, error) {
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil {
+		return nil, validations.ErrInvalidUserID
+	}
+	if user.TOTPEnabled {
+		return nil, validations.ErrTOTPEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, validations.ErrTOTPNotEnrolled
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, *date.ArgentinaTimeNow())
+	if !ok {
+		return nil, validations.ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		raw, err := utils.RandomToken(5)
+		if err != nil {
+			return nil, validations.ErrTwoFactorDB
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = *models.NewRecoveryCode(userId, utils.HashToken(normalizeRecoveryCode(codes[i])))
+	}
+	if err := us.userRepo.EnableTOTP(ctx, userId, step, records); err != nil {
+		return nil, validations.ErrTwoFactorDB
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It takes a current code,
+// or a recovery code, so a stolen session alone is not enough.
+func (us *UserService) DisableTOTP(ctx context.Context, userId uint, code, recoveryCode string) error {
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil {
+		return validations.ErrInvalidUserID
+	}
+	if !user.TOTPEnabled {
+		return validations.ErrTOTPNotEnabled
+	}
+	if err := us.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := us.userRepo.DisableTOTP(ctx, userId); err != nil {
+		return validations.ErrTwoFactorDB
+	}
+	return nil
+}
+
+// CompleteLogin is the second step of a login with two-factor
+// authentication: it checks the challenge token from LoginUser and the code,
+// then starts the session.
+func (us *UserService) CompleteLogin(ctx context.Context, w http.ResponseWriter, challengeToken, code, recoveryCode, userAgent, ip string) (*models.User, error) {
+	userId, err := utils.ParseChallengeJWT(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil || !user.TOTPEnabled {
+		return nil, validations.ErrChallengeToken
+	}
+	if user.IsDisabled {
+		return nil, validations.ErrUserDisabled
+	}
+	if err := us.loginThrottle.Check(ctx, user.UserName, ip); err != nil {
+		return nil, err
+	}
+	if err := us.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+		if errors.Is(err, validations.ErrInvalidTOTPCode) {
+			us.loginThrottle.Failed(ctx, user.UserName, ip)
+		}
+		return nil, err
+	}
+	if err := us.startSession(ctx, w, user.ID, userAgent); err != nil {
+		return nil, err
+	}
+	us.loginThrottle.Succeeded(ctx, user.UserName)
+	return user, nil
+}
+
+// verifySecondFactor spends either a TOTP code or a recovery code. Wrong
+// codes are throttled per user, so a stolen session cannot guess its way to
+// disabling two-factor authentication or deleting the account.
+func (us *UserService) verifySecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	key := fmt.Sprintf("2fa:user:%d", user.ID)
+	if err := us.codeThrottle.Check(ctx, key); err != nil {
+		return err
+	}
+	err := us.spendSecondFactor(ctx, user, code, recoveryCode)
+	switch {
+	case errors.Is(err, validations.ErrInvalidTOTPCode):
+		us.codeThrottle.Failed(ctx, key)
+	case err == nil:
+		us.codeThrottle.Succeeded(ctx, key)
+	}
+	return err
+}
+
+func (us *UserService) spendSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		hash := utils.HashToken(normalizeRecoveryCode(recoveryCode))
+		return mapTwoFactorErr(us.userRepo.UseRecoveryCode(ctx, user.ID, hash, *date.ArgentinaTimeNow()))
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, *date.ArgentinaTimeNow())
+	if !ok {
+		return validations.ErrInvalidTOTPCode
+	}
+	return mapTwoFactorErr(us.userRepo.UseTOTPStep(ctx, user.ID, step))
+}
+
+func mapTwoFactorErr(err error) error {
+	if err == nil || errors.Is(err, validations.ErrInvalidTOTPCode) {
+		return err
+	}
+	return validations.ErrTwoFactorDB
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"notes/internal/models"
+	"notes/internal/repositories/memory"
+	"notes/pkg/throttle"
+	"notes/pkg/totp"
+	"notes/pkg/validations"
+)
+
+// newTwoFactorUser builds a UserService on a memory store holding alice,
+// with two-factor authentication confirmed, and returns it with her ID and
+// TOTP secret.
+func newTwoFactorUser(t *testing.T, codeThrottle *AttemptThrottle) (*UserService, uint, string) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	user := models.NewUser("alice", "hash", nil)
+	if err := store.Users().CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	us := NewUserService(store.Users(), nil, nil, nil, codeThrottle, nil, nil)
+
+	enrollment, err := us.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	if _, err := us.ConfirmTOTP(ctx, user.ID, code); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	return us, user.ID, enrollment.Secret
+}
+
+func newTestCodeThrottle(clock *fakeClock) *AttemptThrottle {
+	policy := throttle.Policy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutAfter: 3, LockoutFor: 15 * time.Minute, Window: 15 * time.Minute}
+	codeThrottle := NewAttemptThrottle(throttle.NewMemoryStore(), policy, validations.ErrTwoFactorThrottled, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	codeThrottle.now = clock.Now
+	return codeThrottle
+}
+
+func TestTOTPCodesAreSingleUse(t *testing.T) {
+	ctx := context.Background()
+	us, userId, secret := newTwoFactorUser(t, newTestCodeThrottle(&fakeClock{now: time.Now()}))
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	if err := us.DisableTOTP(ctx, userId, code, ""); !errors.Is(err, validations.ErrInvalidTOTPCode) {
+		t.Fatalf("reusing the confirmation code: got %v, want ErrInvalidTOTPCode", err)
+	}
+	previous, err := totp.Code(secret, totp.Step(time.Now())-1)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	if err := us.DisableTOTP(ctx, userId, previous, ""); !errors.Is(err, validations.ErrInvalidTOTPCode) {
+		t.Fatalf("using a code older than the last one: got %v, want ErrInvalidTOTPCode", err)
+	}
+}
+
+func TestSecondFactorThrottle(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	us, userId, secret := newTwoFactorUser(t, newTestCodeThrottle(clock))
+
+	// Wrong codes and wrong recovery codes count alike; the third locks the
+	// user out for 15 minutes.
+	for i, wrong := range []struct{ code, recoveryCode string }{{"000000", ""}, {"", "aaaaa-bbbbb"}, {"000000", ""}} {
+		if err := us.DisableTOTP(ctx, userId, wrong.code, wrong.recoveryCode); !errors.Is(err, validations.ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidTOTPCode", i+1, err)
+		}
+		clock.Advance(5 * time.Second)
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now())+1)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	err = us.DisableTOTP(ctx, userId, code, "")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || !errors.Is(err, validations.ErrTwoFactorThrottled) {
+		t.Fatalf("a valid code while locked out: got %v, want ErrTwoFactorThrottled", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > 15*time.Minute {
+		t.Fatalf("locked out for %v, want up to 15 minutes", throttled.RetryAfter)
+	}
+
+	clock.Advance(15 * time.Minute)
+	if err := us.DisableTOTP(ctx, userId, code, ""); err != nil {
+		t.Fatalf("a valid code once the lockout is over: %v", err)
+	}
+}
@@ -1,25 +1,44 @@
 package services
 
 import (
+	"archive/zip"
 	"context"
+	"errors"
+	"io"
 	"net/http"
 
 	"notes/internal/models"
 	"notes/internal/repositories"
+	"notes/pkg/date"
+	"notes/pkg/pagination"
+	"notes/pkg/password"
 	"notes/pkg/utils"
 	"notes/pkg/validations"
-	"time"
+
+	"gorm.io/gorm"
 )
 
 type UserService struct {
-	userRepo    *repositories.UserRepository
-	noteService *NoteService
+	userRepo       repositories.UserStore
+	noteService    *NoteService
+	sessionService *SessionService
+	loginThrottle  *LoginThrottle
+	// codeThrottle counts the wrong second factors of each user outside of
+	// a login, where the login throttle does not apply.
+	codeThrottle   *AttemptThrottle
+	passwordPolicy *password.Policy
+	passwordHasher *password.Hasher
 }
 
-func NewUserService(userRepo *repositories.UserRepository, noteService *NoteService) *UserService {
+func NewUserService(userRepo repositories.UserStore, noteService *NoteService, sessionService *SessionService, loginThrottle *LoginThrottle, codeThrottle *AttemptThrottle, passwordPolicy *password.Policy, passwordHasher *password.Hasher) *UserService {
 	return &UserService{
-		userRepo:    userRepo,
-		noteService: noteService,
+		userRepo:       userRepo,
+		noteService:    noteService,
+		sessionService: sessionService,
+		loginThrottle:  loginThrottle,
+		codeThrottle:   codeThrottle,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
 	}
 }
 
@@ -30,17 +49,21 @@ func (us *UserService) CreateUser(ctx context.Context, username, password string
 		return nil, err
 	}
 
+	if err := us.passwordPolicy.Validate(password, *formattedUsername); err != nil {
+		return nil, err
+	}
+
 	_, err = us.GetUserByUsername(ctx, *formattedUsername)
 	if err == nil {
 		return nil, validations.ErrUserAlreadyExists
 	}
 
-	hashedPassword, err := utils.HashPassword(password)
+	hashedPassword, err := us.hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
-	user := models.NewUser(*formattedUsername, *hashedPassword, nil)
+	user := models.NewUser(*formattedUsername, hashedPassword, nil)
 
 	if err := us.userRepo.CreateUser(ctx, user); err != nil {
 		return nil, err
@@ -62,12 +85,96 @@ func (us *UserService) AuthenticateUser(ctx context.Context, username, password
 	if err != nil || user == nil {
 		return nil, validations.ErrInvalidCredentials
 	}
-	if !utils.CheckPasswordHash(password, user.Password) {
+	ok, rehash := us.passwordHasher.Verify(password, user.Password)
+	if !ok {
 		return nil, validations.ErrInvalidCredentials
 	}
+	if rehash {
+		// Upgrade hashes made with an older algorithm or a lower cost while
+		// the plain password is at hand. A failure only delays the upgrade
+		// to the next login.
+		if hashedPassword, err := us.hashPassword(password); err == nil {
+			_ = us.userRepo.UpdatePassword(ctx, user.ID, hashedPassword)
+		}
+	}
+	if user.IsDisabled {
+		return nil, validations.ErrUserDisabled
+	}
+	if user.PasswordResetHash != "" {
+		return nil, validations.ErrPasswordReset
+	}
+	return user, nil
+}
+
+// CheckActive is called by PROTECT on every request. It fails with
+// ErrUserDisabled once the user is disabled, whatever their token says.
+func (us *UserService) CheckActive(ctx context.Context, userId uint) (*models.User, error) {
+	user, err := us.userRepo.GetAuthStatus(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, validations.ErrInvalidUserID
+	}
+	if err != nil {
+		return nil, validations.ErrUserDB
+	}
+	if user.IsDisabled {
+		return nil, validations.ErrUserDisabled
+	}
 	return user, nil
 }
 
+// ResetPassword sets a new password with the token an admin issued through
+// a forced reset.
+func (us *UserService) ResetPassword(ctx context.Context, username, resetToken, newPassword string) error {
+	if err := us.passwordPolicy.Validate(newPassword, username); err != nil {
+		return err
+	}
+	hashedPassword, err := us.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = us.userRepo.ResetPassword(ctx, username, utils.HashToken(resetToken), hashedPassword, *date.ArgentinaTimeNow())
+	if err != nil && !errors.Is(err, validations.ErrResetToken) {
+		return validations.ErrUserDB
+	}
+	return err
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one, and ends every other session of the user. The session the change is
+// made from stays logged in.
+func (us *UserService) ChangePassword(ctx context.Context, userId uint, sessionId, currentPassword, newPassword string) error {
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil {
+		return validations.ErrInvalidUserID
+	}
+	if ok, _ := us.passwordHasher.Verify(currentPassword, user.Password); !ok {
+		return validations.ErrCurrentPassword
+	}
+	if newPassword == currentPassword {
+		return validations.ErrPasswordUnchanged
+	}
+	if err := us.passwordPolicy.Validate(newPassword, user.UserName); err != nil {
+		return err
+	}
+
+	hashedPassword, err := us.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := us.userRepo.UpdatePassword(ctx, userId, hashedPassword); err != nil {
+		return validations.ErrUserDB
+	}
+	return us.sessionService.RevokeOthers(ctx, userId, sessionId)
+}
+
+func (us *UserService) hashPassword(plain string) (string, error) {
+	hashed, err := us.passwordHasher.Hash(plain)
+	if err != nil {
+		return "", validations.ErrHashingPwd
+	}
+	return hashed, nil
+}
+
 func (us *UserService) CreateNote(ctx context.Context, title, content string, categoryNames []string, userID uint) (*models.Note, error) {
 	note, err := us.noteService.CreateNote(ctx, title, content, categoryNames, userID)
 	if err != nil {
@@ -77,37 +184,55 @@ func (us *UserService) CreateNote(ctx context.Context, title, content string, ca
 }
 
 func (us *UserService) GetNoteById(ctx context.Context, noteId uint, userId uint) (*models.Note, error) {
+	return us.noteService.AuthorizeNote(ctx, userId, noteId, ReadNote)
+}
 
-	note, err := us.noteService.GetNoteById(ctx, noteId)
+// UpdateNoteForUser replaces the note's title, content, categories and
+// archived status. Changing the archived status is left to the owner.
+func (us *UserService) UpdateNoteForUser(ctx context.Context, userId uint, noteId uint, updatedNote *models.Note) (*uint, error) {
+	existingNote, err := us.noteService.AuthorizeNote(ctx, userId, noteId, EditNote)
 	if err != nil {
 		return nil, err
 	}
-	if note.UserID != userId {
-		return nil, validations.ErrNoteNotOwnedByUser
+	if existingNote.IsArchived != updatedNote.IsArchived {
+		if err := us.noteService.authorize(ctx, existingNote, userId, ManageNote); err != nil {
+			return nil, err
+		}
 	}
-	return note, nil
-}
 
-func (us *UserService) UpdateNoteForUser(ctx context.Context, userId uint, noteId uint, updatedNote *models.Note) (*uint, error) {
-	existingNote, err := us.noteService.GetNoteById(ctx, noteId)
+	updatedNoteId, err := us.noteService.UpdateNote(ctx, noteId, updatedNote, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	if existingNote.UserID != userId {
-		return nil, validations.ErrNoteNotOwnedByUser
-	}
+	return updatedNoteId, nil
+}
 
-	updatedNoteId, err := us.noteService.UpdateNote(ctx, noteId, updatedNote)
+func (us *UserService) PatchNoteForUser(ctx context.Context, userId uint, noteId uint, patch *models.NotePatch, expectedVersion uint) (*models.Note, error) {
+	note, err := us.noteService.AuthorizeNote(ctx, userId, noteId, EditNote)
 	if err != nil {
 		return nil, err
 	}
+	if patch.IsArchived != nil && *patch.IsArchived != note.IsArchived {
+		if err := us.noteService.authorize(ctx, note, userId, ManageNote); err != nil {
+			return nil, err
+		}
+	}
+	return us.noteService.PatchNote(ctx, noteId, patch, expectedVersion, userId)
+}
 
-	return updatedNoteId, nil
+func (us *UserService) ExportNotesForUser(ctx context.Context, userId uint, w io.Writer) error {
+	return us.noteService.ExportMarkdown(ctx, userId, w)
 }
 
-func (us *UserService) DeleteNoteForUser(ctx context.Context, userId uint, noteId uint) (*uint, error) {
+func (us *UserService) ImportNotesForUser(ctx context.Context, userId uint, archive *zip.Reader) (*models.NoteImportReport, error) {
+	return us.noteService.ImportMarkdown(ctx, userId, archive)
+}
 
+func (us *UserService) DeleteNoteForUser(ctx context.Context, userId uint, noteId uint) (*uint, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, ManageNote); err != nil {
+		return nil, err
+	}
 	id, err := us.noteService.DeleteNoteForUser(ctx, noteId, userId)
 	if err != nil {
 		return nil, err
@@ -115,123 +240,212 @@ func (us *UserService) DeleteNoteForUser(ctx context.Context, userId uint, noteI
 	return id, nil
 }
 
-func (us *UserService) GetAllNotesByUserID(ctx context.Context, userId uint) ([]models.Note, error) {
-	user, err := us.GetUserByID(ctx, userId)
-	if err != nil {
-		return nil, err
-	}
-	if len(user.Notes) > 0 {
-		return user.Notes, nil
-	}
-	return nil, validations.ErrNoNotesFound
+func (us *UserService) GetAllNotesByUserID(ctx context.Context, userId uint, page pagination.Request) (*models.NotePage, error) {
+	return us.noteService.ListNotes(ctx, userId, nil, nil, page)
 }
 
-func (us *UserService) AddCategoryToNoteForUser(ctx context.Context, userId uint, noteId uint, categoryName string) (*models.Note, error) {
-	note, err := us.noteService.GetNoteById(ctx, noteId)
-	if err != nil {
+func (us *UserService) AddCategoryToNoteForUser(ctx context.Context, userId uint, noteId uint, categoryName string, expectedVersion uint) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, EditNote); err != nil {
 		return nil, err
 	}
-	if note.UserID != userId {
-		return nil, validations.ErrNoteNotOwnedByUser
-	}
-	updatedNote, err := us.noteService.AddCategoryToNote(ctx, noteId, categoryName)
+	updatedNote, err := us.noteService.AddCategoryToNote(ctx, noteId, categoryName, expectedVersion, userId)
 	if err != nil {
 		return nil, err
 	}
 	return updatedNote, nil
 }
 
-func (us *UserService) RemoveCategoryFromNoteForUser(ctx context.Context, userId uint, noteId uint, categoryName string) (*models.Note, error) {
-	note, err := us.noteService.GetNoteById(ctx, noteId)
-	if err != nil {
+func (us *UserService) RemoveCategoryFromNoteForUser(ctx context.Context, userId uint, noteId uint, categoryName string, expectedVersion uint) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, EditNote); err != nil {
 		return nil, err
 	}
-	if note.UserID != userId {
-		return nil, validations.ErrNoteNotOwnedByUser
-	}
-	updatedNote, err := us.noteService.RemoveCategoryFromNote(ctx, noteId, categoryName)
+	updatedNote, err := us.noteService.RemoveCategoryFromNote(ctx, noteId, categoryName, expectedVersion, userId)
 	if err != nil {
 		return nil, err
 	}
 	return updatedNote, nil
 }
 
-func (us *UserService) ToggleArchiveStatusForUser(ctx context.Context, userId uint, noteId uint) (*models.Note, error) {
-	note, err := us.noteService.GetNoteById(ctx, noteId)
-	if err != nil {
+func (us *UserService) ToggleArchiveStatusForUser(ctx context.Context, userId uint, noteId uint, expectedVersion uint) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, ManageNote); err != nil {
 		return nil, err
 	}
-	if note.UserID != userId {
-		return nil, validations.ErrNoteNotOwnedByUser
-	}
-	updatedNote, err := us.noteService.ToggleArchiveStatus(ctx, noteId)
+	updatedNote, err := us.noteService.ToggleArchiveStatus(ctx, noteId, expectedVersion, userId)
 	if err != nil {
 		return nil, err
 	}
 	return updatedNote, nil
 }
 
-func (us *UserService) FilterNotesForUser(ctx context.Context, userId uint, isArchived *bool, categories []string) ([]models.Note, error) {
+func (us *UserService) FilterNotesForUser(ctx context.Context, userId uint, isArchived *bool, categories []string, page pagination.Request) (*models.NotePage, error) {
+	return us.noteService.ListNotes(ctx, userId, isArchived, categories, page)
+}
 
-	notes, err := us.noteService.FilterNotes(ctx, isArchived, categories)
+func (us *UserService) SearchNotesForUser(ctx context.Context, userId uint, query string, limit int) ([]models.NoteSearchResult, error) {
+	results, err := us.noteService.SearchNotes(ctx, userId, query, limit)
 	if err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
-	var userNotes []models.Note
-	for _, note := range notes {
-		if note.UserID == userId {
-			userNotes = append(userNotes, note)
-		}
+func (us *UserService) GetNoteRevisionsForUser(ctx context.Context, userId uint, noteId uint) ([]models.NoteRevision, error) {
+	if _, err := us.GetNoteById(ctx, noteId, userId); err != nil {
+		return nil, err
 	}
+	return us.noteService.GetNoteRevisions(ctx, noteId)
+}
 
-	if len(userNotes) == 0 {
-		return nil, validations.ErrNoNotesFound
+func (us *UserService) DiffNoteRevisionsForUser(ctx context.Context, userId uint, noteId uint, from, to uint) (*models.NoteRevisionDiff, error) {
+	if _, err := us.GetNoteById(ctx, noteId, userId); err != nil {
+		return nil, err
 	}
+	return us.noteService.DiffNoteRevisions(ctx, noteId, from, to)
+}
 
-	return userNotes, nil
+func (us *UserService) RestoreNoteRevisionForUser(ctx context.Context, userId uint, noteId uint, revision uint) (*models.Note, error) {
+	if _, err := us.noteService.AuthorizeNote(ctx, userId, noteId, EditNote); err != nil {
+		return nil, err
+	}
+	return us.noteService.RestoreNoteRevision(ctx, noteId, revision, userId)
+}
+
+func (us *UserService) GetTrashForUser(ctx context.Context, userId uint) ([]models.Note, error) {
+	return us.noteService.GetTrash(ctx, userId)
+}
+
+func (us *UserService) RestoreNoteForUser(ctx context.Context, userId uint, noteId uint) (*models.Note, error) {
+	return us.noteService.RestoreNote(ctx, userId, noteId)
+}
+
+func (us *UserService) EmptyTrashForUser(ctx context.Context, userId uint) (int64, error) {
+	return us.noteService.EmptyTrash(ctx, userId)
 }
 
 // Regular User
-func (us *UserService) RegisterUser(ctx context.Context, w http.ResponseWriter, username, password string) (*models.User, error) {
+func (us *UserService) RegisterUser(ctx context.Context, w http.ResponseWriter, username, password, userAgent string) (*models.User, error) {
 	user, err := us.CreateUser(ctx, username, password)
 	if err != nil {
 		return nil, err
 	}
-
-	token, err := utils.GenerateJWT(user.ID)
-	if err != nil {
-		return nil, validations.ErrTokenGeneration
+	if err := us.startSession(ctx, w, user.ID, userAgent); err != nil {
+		return nil, err
 	}
-	utils.SetJWTAsCookie(w, token)
 	return user, nil
 
 }
 
-func (us *UserService) LoginUser(ctx context.Context, w http.ResponseWriter, username, password string) (*models.User, error) {
+// LoginUser checks the password and starts a session. For users with
+// two-factor authentication it returns a challenge token instead, to be
+// redeemed together with a code through CompleteLogin. Repeated failures
+// from the same username or IP address are throttled.
+func (us *UserService) LoginUser(ctx context.Context, w http.ResponseWriter, username, password, userAgent, ip string) (*models.User, string, error) {
+	if err := us.loginThrottle.Check(ctx, username, ip); err != nil {
+		return nil, "", err
+	}
 	user, err := us.AuthenticateUser(ctx, username, password)
+	if errors.Is(err, validations.ErrInvalidCredentials) {
+		us.loginThrottle.Failed(ctx, username, ip)
+	}
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if user.TOTPEnabled {
+		// The failures are only cleared once the second factor is verified
+		// too, so the code cannot be guessed with a known password.
+		challenge, err := utils.GenerateChallengeJWT(user.ID)
+		if err != nil {
+			return nil, "", validations.ErrTokenGeneration
+		}
+		return user, challenge, nil
+	}
+	if err := us.startSession(ctx, w, user.ID, userAgent); err != nil {
+		return nil, "", err
+	}
+	us.loginThrottle.Succeeded(ctx, user.UserName)
+	return user, "", nil
+}
+
+func (us *UserService) startSession(ctx context.Context, w http.ResponseWriter, userId uint, userAgent string) error {
+	tokens, err := us.sessionService.Start(ctx, userId, userAgent)
+	if err != nil {
+		return err
+	}
+	utils.SetJWTAsCookie(w, tokens.AccessToken)
+	utils.SetRefreshCookie(w, tokens.RefreshToken)
+	return nil
+}
+
+// ExportAccount returns everything stored about the user.
+func (us *UserService) ExportAccount(ctx context.Context, userId uint) (*models.AccountExport, error) {
+	export, err := us.userRepo.ExportData(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, validations.ErrInvalidUserID
 	}
-	token, err := utils.GenerateJWT(user.ID)
 	if err != nil {
-		return nil, validations.ErrTokenGeneration
+		return nil, validations.ErrAccountExport
 	}
-	utils.SetJWTAsCookie(w, token)
-	return user, nil
+	export.ExportedAt = *date.ArgentinaTimeNow()
+	return export, nil
 }
 
-// REFACTORED
-func (us *UserService) LogoutUser(w http.ResponseWriter) error {
+// DeleteAccount permanently deletes the user and all their data once they
+// confirm with their password, and with a second factor when two-factor
+// authentication is enabled. The auth cookies are cleared.
+func (us *UserService) DeleteAccount(ctx context.Context, w http.ResponseWriter, userId uint, password, code, recoveryCode string) error {
+	user, err := us.userRepo.GetUserByID(ctx, userId)
+	if err != nil {
+		return validations.ErrInvalidUserID
+	}
+	if ok, _ := us.passwordHasher.Verify(password, user.Password); !ok {
+		return validations.ErrCurrentPassword
+	}
+	if user.TOTPEnabled {
+		if err := us.verifySecondFactor(ctx, user, code, recoveryCode); err != nil {
+			return err
+		}
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "notes_jwt",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Unix(0, 0),
-		SameSite: http.SameSiteNoneMode,
-	})
+	deleted, err := us.userRepo.DeleteUser(ctx, userId)
+	if err != nil {
+		return validations.ErrAccountDelete
+	}
+	if !deleted {
+		return validations.ErrInvalidUserID
+	}
+	utils.ClearAuthCookies(w)
+	return nil
+}
+
+// RefreshSession rotates the session's tokens. When the refresh token is
+// rejected the cookies are cleared, so the client logs in again.
+func (us *UserService) RefreshSession(ctx context.Context, w http.ResponseWriter, refreshToken string) (uint, error) {
+	tokens, err := us.sessionService.Refresh(ctx, refreshToken)
+	if err != nil {
+		if !errors.Is(err, validations.ErrSessionDB) {
+			utils.ClearAuthCookies(w)
+		}
+		return 0, err
+	}
+	utils.SetJWTAsCookie(w, tokens.AccessToken)
+	utils.SetRefreshCookie(w, tokens.RefreshToken)
+	return tokens.UserID, nil
+}
+
+// LogoutUser revokes the session of the refresh token and clears the cookies.
+func (us *UserService) LogoutUser(ctx context.Context, w http.ResponseWriter, refreshToken string) error {
+	if err := us.sessionService.RevokeByRefreshToken(ctx, refreshToken); err != nil {
+		return err
+	}
+	utils.ClearAuthCookies(w)
+	return nil
+}
+
+// LogoutAllSessions revokes every session of the user, on every device.
+func (us *UserService) LogoutAllSessions(ctx context.Context, w http.ResponseWriter, userId uint) error {
+	if err := us.sessionService.RevokeAll(ctx, userId); err != nil {
+		return err
+	}
+	utils.ClearAuthCookies(w)
 	return nil
 }
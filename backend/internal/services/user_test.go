@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"notes/internal/models"
+	"notes/internal/repositories/memory"
+	"notes/pkg/password"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticateUserUpgradesHash(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	old := &password.Hasher{Algorithm: password.Bcrypt, BcryptCost: bcrypt.MinCost}
+	hash, err := old.Hash("Correct horse 42")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := models.NewUser("alice", hash, nil)
+	if err := store.Users().CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	hasher := &password.Hasher{Algorithm: password.Argon2id, Argon2: password.Argon2Params{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}}
+	us := NewUserService(store.Users(), nil, nil, nil, nil, nil, hasher)
+
+	if _, err := us.AuthenticateUser(ctx, "alice", "wrong password"); err == nil {
+		t.Fatal("AuthenticateUser accepted a wrong password")
+	}
+	stored, _ := store.Users().GetUserByID(ctx, user.ID)
+	if stored.Password != hash {
+		t.Fatal("a failed login replaced the hash")
+	}
+
+	if _, err := us.AuthenticateUser(ctx, "alice", "Correct horse 42"); err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	stored, _ = store.Users().GetUserByID(ctx, user.ID)
+	if ok, rehash := hasher.Verify("Correct horse 42", stored.Password); !ok || rehash {
+		t.Errorf("hash after login %q is not a current argon2id hash", stored.Password)
+	}
+
+	upgraded := stored.Password
+	if _, err := us.AuthenticateUser(ctx, "alice", "Correct horse 42"); err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if stored, _ = store.Users().GetUserByID(ctx, user.ID); stored.Password != upgraded {
+		t.Error("a login with a current hash replaced it")
+	}
+}
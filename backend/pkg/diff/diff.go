@@ -0,0 +1,68 @@
+package diff
+
+import "strings"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line is a single line of a line-based diff.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines returns the line diff turning a into b, computed from their longest
+// common subsequence. Note fields are short, so the quadratic table is fine.
+func Lines(a, b string) []Line {
+	from, to := splitLines(a), splitLines(b)
+
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(from)+len(to))
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, Line{Op: Equal, Text: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: from[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, Line{Op: Delete, Text: from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, Line{Op: Insert, Text: to[j]})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,355 @@
+// Package fulltext approximates PostgreSQL full-text search with the english
+// configuration, for the stores that have none: websearch_to_tsquery parsing,
+// ts_rank weights and ts_headline highlighting.
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The ts_rank defaults for the A, B, C and D weight labels.
+const (
+	WeightA = 1.0
+	WeightB = 0.4
+	WeightC = 0.2
+	WeightD = 0.1
+)
+
+// stopWords are common English words left out of the index, as the english
+// text search configuration does.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "he": true, "her": true,
+	"his": true, "i": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"my": true, "no": true, "not": true, "of": true, "on": true, "or": true, "our": true, "she": true,
+	"so": true, "that": true, "the": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "to": true, "was": true, "we": true, "were": true,
+	"will": true, "with": true, "you": true, "your": true,
+}
+
+// word is a word of a text with its byte offsets and position, as counted
+// by to_tsvector, stop words included.
+type word struct {
+	text       string
+	start, end int
+	position   int
+}
+
+func words(text string) []word {
+	var found []word
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			found = append(found, word{text: text[start:end], start: start, end: end, position: len(found)})
+			start = -1
+		}
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return found
+}
+
+// lexeme normalizes a word the way the english configuration roughly does;
+// stop words have none.
+func lexeme(w string) string {
+	w = strings.ToLower(w)
+	if stopWords[w] {
+		return ""
+	}
+	return stem(w)
+}
+
+// stem strips the most common English inflections.
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "i"
+	case len(w) > 3 && (strings.HasSuffix(w, "sses") || strings.HasSuffix(w, "xes") ||
+		strings.HasSuffix(w, "zes") || strings.HasSuffix(w, "ches") || strings.HasSuffix(w, "shes")):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") &&
+		!strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		return w[:len(w)-1]
+	case len(w) > 5 && strings.HasSuffix(w, "ing"):
+		return undouble(w[:len(w)-3])
+	case len(w) > 4 && strings.HasSuffix(w, "ed"):
+		return undouble(w[:len(w)-2])
+	case len(w) > 2 && strings.HasSuffix(w, "y") && !strings.ContainsRune("aeiou", rune(w[len(w)-2])):
+		return w[:len(w)-1] + "i"
+	}
+	return w
+}
+
+// undouble turns the doubled consonant left by a stripped suffix, as in
+// "running", back into a single one.
+func undouble(w string) string {
+	n := len(w)
+	if n > 2 && w[n-1] == w[n-2] && !strings.ContainsRune("aeiouls", rune(w[n-1])) {
+		return w[:n-1]
+	}
+	return w
+}
+
+// term is a word or a quoted phrase of a search query. A phrase matches
+// when its lexemes appear next to each other.
+type term struct {
+	lexemes   []string
+	positions []int
+	negated   bool
+}
+
+// Query is a parsed search query: alternatives of ANDed terms.
+type Query struct {
+	groups [][]term
+}
+
+// Field is a weighted text of a document, such as a title.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Parse reads a web-style query as websearch_to_tsquery does: terms are
+// ANDed, "or" separates alternatives, a leading - negates a term and double
+// quotes group a phrase.
+func Parse(query string) Query {
+	return Query{groups: parseGroups(query)}
+}
+
+func parseGroups(query string) [][]term {
+	var groups [][]term
+	var current []term
+	for i := 0; i < len(query); {
+		r := rune(query[i])
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+		negated := false
+		if r == '-' {
+			negated = true
+			i++
+		}
+		var text string
+		if i < len(query) && query[i] == '"' {
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				text, i = query[i+1:], len(query)
+			} else {
+				text, i = query[i+1:i+1+end], i+2+end
+			}
+		} else {
+			end := strings.IndexFunc(query[i:], unicode.IsSpace)
+			if end < 0 {
+				end = len(query) - i
+			}
+			text, i = query[i:i+end], i+end
+		}
+		if !negated && strings.EqualFold(text, "or") {
+			if len(current) > 0 {
+				groups = append(groups, current)
+				current = nil
+			}
+			continue
+		}
+		t := term{negated: negated}
+		for _, w := range words(text) {
+			if l := lexeme(w.text); l != "" {
+				t.lexemes = append(t.lexemes, l)
+				t.positions = append(t.positions, w.position)
+			}
+		}
+		if len(t.lexemes) > 0 {
+			current = append(current, t)
+		}
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// document is the indexed text of the fields, each weighted.
+type document struct {
+	fields []field
+}
+
+type field struct {
+	weight float64
+	// lexemes holds the positions of every lexeme of the field.
+	lexemes map[string][]int
+}
+
+func newField(text string, weight float64) field {
+	f := field{weight: weight, lexemes: map[string][]int{}}
+	for _, w := range words(text) {
+		if l := lexeme(w.text); l != "" {
+			f.lexemes[l] = append(f.lexemes[l], w.position)
+		}
+	}
+	return f
+}
+
+// score is the weight of the fields where the term appears, zero when it
+// appears in none.
+func (d document) score(t term) float64 {
+	var score float64
+	for _, f := range d.fields {
+		for _, start := range f.lexemes[t.lexemes[0]] {
+			if f.hasPhrase(t, start) {
+				score += f.weight
+			}
+		}
+	}
+	return score
+}
+
+func (f field) hasPhrase(t term, start int) bool {
+	for i := 1; i < len(t.lexemes); i++ {
+		want := start + t.positions[i] - t.positions[0]
+		found := false
+		for _, p := range f.lexemes[t.lexemes[i]] {
+			if p == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Rank scores the fields against the query and reports whether they match.
+func (q Query) Rank(fields ...Field) (float64, bool) {
+	d := document{}
+	for _, f := range fields {
+		d.fields = append(d.fields, newField(f.Text, f.Weight))
+	}
+	return d.rank(q.groups)
+}
+
+func (d document) rank(groups [][]term) (float64, bool) {
+	var best float64
+	matchedAny := false
+	for _, group := range groups {
+		var rank float64
+		matched := true
+		for _, t := range group {
+			score := d.score(t)
+			if t.negated {
+				if score > 0 {
+					matched = false
+					break
+				}
+				continue
+			}
+			if score == 0 {
+				matched = false
+				break
+			}
+			rank += score
+		}
+		if matched {
+			matchedAny = true
+			best = max(best, rank)
+		}
+	}
+	return best, matchedAny
+}
+
+// The ts_headline options search snippets of note content are built with.
+const (
+	MaxFragments = 2
+	MaxWords     = 20
+	MinWords     = 5
+	// FragmentDelimiter is the ts_headline default.
+	FragmentDelimiter = " ... "
+)
+
+// wanted lists the lexemes a headline highlights: those of the terms that are
+// not negated.
+func (q Query) wanted() map[string]bool {
+	wanted := map[string]bool{}
+	for _, group := range q.groups {
+		for _, t := range group {
+			if !t.negated {
+				for _, l := range t.lexemes {
+					wanted[l] = true
+				}
+			}
+		}
+	}
+	return wanted
+}
+
+// Highlight HTML-escapes the text and wraps the words matching a query lexeme
+// in <mark> tags, as ts_headline with HighlightAll does on escaped text.
+func (q Query) Highlight(text string) string {
+	ws := words(text)
+	return mark(text, ws, 0, len(text), q.wanted())
+}
+
+// Snippet is the headline of a longer text: up to MaxFragments fragments of
+// at most MaxWords words around the matches, highlighted like Highlight does
+// and joined by FragmentDelimiter. Without any match it is the first
+// MinWords words of the text.
+func (q Query) Snippet(text string) string {
+	ws := words(text)
+	if len(ws) == 0 {
+		return mark(text, ws, 0, len(text), nil)
+	}
+	wanted := q.wanted()
+
+	var fragments []string
+	covered := -1
+	for i, w := range ws {
+		if len(fragments) == MaxFragments {
+			break
+		}
+		if i <= covered || !wanted[lexeme(w.text)] {
+			continue
+		}
+		start := max(i-MaxWords/2, covered+1)
+		end := min(start+MaxWords, len(ws)) - 1
+		start = max(min(start, end-MaxWords+1), covered+1, 0)
+		fragments = append(fragments, mark(text, ws, ws[start].start, ws[end].end, wanted))
+		covered = end
+	}
+	if len(fragments) == 0 {
+		end := min(MinWords, len(ws)) - 1
+		return mark(text, ws, ws[0].start, ws[end].end, nil)
+	}
+	return strings.Join(fragments, FragmentDelimiter)
+}
+
+// escaper escapes the characters that start markup or entities, as the
+// PostgreSQL search does before building headlines.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// mark renders text[from:to] HTML-escaped, with the wanted words among ws
+// wrapped in <mark> tags.
+func mark(text string, ws []word, from, to int, wanted map[string]bool) string {
+	var out strings.Builder
+	last := from
+	for _, w := range ws {
+		if w.start < from || w.end > to || !wanted[lexeme(w.text)] {
+			continue
+		}
+		out.WriteString(escaper.Replace(text[last:w.start]))
+		out.WriteString("<mark>" + escaper.Replace(w.text) + "</mark>")
+		last = w.end
+	}
+	out.WriteString(escaper.Replace(text[last:to]))
+	return out.String()
+}
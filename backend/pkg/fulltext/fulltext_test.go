@@ -0,0 +1,121 @@
+package fulltext
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLexeme(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"Notes", "note"},
+		{"categories", "categori"},
+		{"category", "categori"},
+		{"boxes", "box"},
+		{"classes", "class"},
+		{"running", "run"},
+		{"shopping", "shop"},
+		{"called", "call"},
+		{"stopped", "stop"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"glass", "glass"},
+		{"day", "day"},
+		{"The", ""},
+		{"and", ""},
+	}
+	for _, tt := range tests {
+		if got := lexeme(tt.word); got != tt.want {
+			t.Errorf("lexeme(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	title := func(text string) Field { return Field{Text: text, Weight: WeightA} }
+	content := func(text string) Field { return Field{Text: text, Weight: WeightB} }
+
+	tests := []struct {
+		name      string
+		query     string
+		fields    []Field
+		wantRank  float64
+		wantMatch bool
+	}{
+		{"title", "milk", []Field{title("Milk prices"), content("buy bread")}, WeightA, true},
+		{"content", "milk", []Field{title("Shopping"), content("buy milk")}, WeightB, true},
+		{"every occurrence", "milk", []Field{title("Milk"), content("milk and more milk")}, WeightA + 2*WeightB, true},
+		{"stemmed", "shopping lists", []Field{title("Shop list")}, 2 * WeightA, true},
+		{"all terms", "milk bread", []Field{content("buy milk")}, 0, false},
+		{"alternative", "milk or eggs", []Field{content("buy eggs")}, WeightB, true},
+		{"negated", "milk -bread", []Field{content("milk and bread")}, 0, false},
+		{"phrase", `"buy milk"`, []Field{content("buy milk today")}, WeightB, true},
+		{"phrase out of order", `"milk buy"`, []Field{content("buy milk today")}, 0, false},
+		{"stop words only", "the and", []Field{content("the cat and the dog")}, 0, false},
+	}
+	for _, tt := range tests {
+		rank, ok := Parse(tt.query).Rank(tt.fields...)
+		if ok != tt.wantMatch || math.Abs(rank-tt.wantRank) > 1e-9 {
+			t.Errorf("%s: Rank(%q) = %v, %v, want %v, %v", tt.name, tt.query, rank, ok, tt.wantRank, tt.wantMatch)
+		}
+	}
+}
+
+func TestRankOrder(t *testing.T) {
+	q := Parse("milk")
+	inTitle, _ := q.Rank(Field{Text: "Milk", Weight: WeightA}, Field{Text: "buy it", Weight: WeightB})
+	inContent, _ := q.Rank(Field{Text: "Groceries", Weight: WeightA}, Field{Text: "buy milk", Weight: WeightB})
+	inCategory, _ := q.Rank(Field{Text: "Groceries", Weight: WeightA}, Field{Text: "milk", Weight: WeightC})
+	if !(inTitle > inContent && inContent > inCategory) {
+		t.Errorf("ranks title %v, content %v, category %v, want them in decreasing order", inTitle, inContent, inCategory)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		query, text, want string
+	}{
+		{"milk", "Milk prices", "<mark>Milk</mark> prices"},
+		{"shopping", "shops and shopping", "<mark>shops</mark> and <mark>shopping</mark>"},
+		{"milk -bread", "milk and bread", "<mark>milk</mark> and bread"},
+		{"milk", "no match here", "no match here"},
+		{"script", `<script>alert("milk")</script>`, `&lt;<mark>script</mark>&gt;alert("milk")&lt;/<mark>script</mark>&gt;`},
+		{"milk", "<mark>milk</mark> & <b>eggs</b>", "&lt;mark&gt;<mark>milk</mark>&lt;/mark&gt; &amp; &lt;b&gt;eggs&lt;/b&gt;"},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.query).Highlight(tt.text); got != tt.want {
+			t.Errorf("Highlight(%q) of %q = %q, want %q", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	numbered := func(from, to int) string {
+		var ws []string
+		for i := from; i <= to; i++ {
+			ws = append(ws, "w"+strings.Repeat("x", i))
+		}
+		return strings.Join(ws, " ")
+	}
+	long := numbered(0, 29) + " milk " + numbered(31, 79) + " milk " + numbered(81, 99)
+
+	tests := []struct {
+		name, query, text, want string
+	}{
+		{"short text", "milk", "buy <milk> & bread", "buy &lt;<mark>milk</mark>&gt; &amp; bread"},
+		{"no match", "eggs", long, numbered(0, 4)},
+		{
+			"fragments", "milk", long,
+			numbered(20, 29) + " <mark>milk</mark> " + numbered(31, 39) + FragmentDelimiter +
+				numbered(70, 79) + " <mark>milk</mark> " + numbered(81, 89),
+		},
+		{"empty", "milk", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.query).Snippet(tt.text); got != tt.want {
+			t.Errorf("%s: Snippet(%q) = %q, want %q", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package markdown reads and writes markdown documents that start with a
+// YAML front matter block.
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const delimiter = "---"
+
+var ErrUnclosedFrontMatter = errors.New("front matter is not closed by a --- line")
+
+// WriteFrontMatter writes meta as a YAML front matter block followed by body.
+func WriteFrontMatter(w io.Writer, meta any, body string) error {
+	header, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	var doc bytes.Buffer
+	doc.WriteString(delimiter + "\n")
+	doc.Write(header)
+	doc.WriteString(delimiter + "\n\n")
+	doc.WriteString(body)
+	doc.WriteString("\n")
+	_, err = w.Write(doc.Bytes())
+	return err
+}
+
+// ParseFrontMatter decodes the front matter of doc into meta and returns the
+// body that follows it. A document without front matter is returned whole,
+// leaving meta untouched.
+func ParseFrontMatter(doc []byte, meta any) (string, error) {
+	text := strings.ReplaceAll(string(doc), "\r\n", "\n")
+	if !strings.HasPrefix(text, delimiter+"\n") {
+		return text, nil
+	}
+	rest := text[len(delimiter)+1:]
+
+	var header, body string
+	if strings.HasPrefix(rest, delimiter+"\n") || rest == delimiter {
+		body = strings.TrimPrefix(rest, delimiter)
+	} else {
+		end := strings.Index(rest, "\n"+delimiter+"\n")
+		switch {
+		case end >= 0:
+			header, body = rest[:end], rest[end+len(delimiter)+2:]
+		case strings.HasSuffix(rest, "\n"+delimiter):
+			header = strings.TrimSuffix(rest, "\n"+delimiter)
+		default:
+			return "", ErrUnclosedFrontMatter
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte(header), meta); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(body, "\n"), nil
+}
@@ -0,0 +1,39 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"notes/pkg/validations"
+)
+
+// Request holds the paging parameters of a listing endpoint.
+type Request struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Cursor marks the last row of a page so the next page starts right after it.
+// It is handed to clients as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func Decode(s string) (*Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, validations.ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(js, &c); err != nil || c.ID == 0 {
+		return nil, validations.ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,85 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap keeps the tests fast; the costs only matter relative to each other.
+var cheap = Argon2Params{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashVerify(t *testing.T) {
+	for _, h := range []*Hasher{
+		{Algorithm: Argon2id, Argon2: cheap},
+		{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost},
+	} {
+		hash, err := h.Hash("Correct horse 42")
+		if err != nil {
+			t.Fatalf("%s: Hash: %v", h.Algorithm, err)
+		}
+		if ok, rehash := h.Verify("Correct horse 42", hash); !ok || rehash {
+			t.Errorf("%s: Verify of a current hash = %v, %v, want true, false", h.Algorithm, ok, rehash)
+		}
+		if ok, _ := h.Verify("correct horse 42", hash); ok {
+			t.Errorf("%s: Verify accepted a wrong password", h.Algorithm)
+		}
+		again, _ := h.Hash("Correct horse 42")
+		if again == hash {
+			t.Errorf("%s: two hashes of a password are equal, the salt is not random", h.Algorithm)
+		}
+	}
+
+	h := &Hasher{Algorithm: Argon2id, Argon2: cheap}
+	hash, _ := h.Hash("Correct horse 42")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=2,p=1$") {
+		t.Errorf("hash %q is not in the PHC format", hash)
+	}
+	for _, bad := range []string{"", "plain", "$argon2id$v=19$m=1024,t=2,p=1$salt", "$argon2id$v=16$m=1024,t=2,p=1$c2FsdA$a2V5", hash[:len(hash)-4] + "!!!!"} {
+		if ok, _ := h.Verify("Correct horse 42", bad); ok {
+			t.Errorf("Verify accepted the malformed hash %q", bad)
+		}
+	}
+}
+
+func TestVerifyRehash(t *testing.T) {
+	argon := func(p Argon2Params) *Hasher { return &Hasher{Algorithm: Argon2id, Argon2: p} }
+	bcryptAt := func(cost int) *Hasher { return &Hasher{Algorithm: Bcrypt, BcryptCost: cost} }
+	with := func(change func(*Argon2Params)) Argon2Params {
+		p := cheap
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		hashedWith *Hasher
+		configured *Hasher
+		wantRehash bool
+	}{
+		{"same argon2id settings", argon(cheap), argon(cheap), false},
+		{"more argon2id memory", argon(cheap), argon(with(func(p *Argon2Params) { p.Memory = 2048 })), true},
+		{"more argon2id iterations", argon(cheap), argon(with(func(p *Argon2Params) { p.Iterations = 3 })), true},
+		{"more argon2id parallelism", argon(cheap), argon(with(func(p *Argon2Params) { p.Parallelism = 2 })), true},
+		{"lower argon2id cost", argon(with(func(p *Argon2Params) { p.Memory = 2048 })), argon(cheap), false},
+		{"bcrypt to argon2id", bcryptAt(bcrypt.MinCost), argon(cheap), true},
+		{"argon2id to bcrypt", argon(cheap), bcryptAt(bcrypt.MinCost), true},
+		{"same bcrypt cost", bcryptAt(bcrypt.MinCost), bcryptAt(bcrypt.MinCost), false},
+		{"higher bcrypt cost", bcryptAt(bcrypt.MinCost), bcryptAt(bcrypt.MinCost + 1), true},
+		{"lower bcrypt cost", bcryptAt(bcrypt.MinCost + 1), bcryptAt(bcrypt.MinCost), false},
+	}
+	for _, tt := range tests {
+		hash, err := tt.hashedWith.Hash("Correct horse 42")
+		if err != nil {
+			t.Fatalf("%s: Hash: %v", tt.name, err)
+		}
+		ok, rehash := tt.configured.Verify("Correct horse 42", hash)
+		if !ok || rehash != tt.wantRehash {
+			t.Errorf("%s: Verify = %v, %v, want true, %v", tt.name, ok, rehash, tt.wantRehash)
+		}
+		if _, rehash := tt.configured.Verify("wrong", hash); rehash {
+			t.Errorf("%s: Verify asked to rehash after a wrong password", tt.name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package password validates new passwords against a policy and hashes them,
+// recognising the hashes of older settings so they can be upgraded.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"notes/pkg/validations"
+)
+
+// commonPasswords is the deny-list used when no file is configured.
+//
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// Policy is what a new password must satisfy. Existing passwords are never
+// checked against it, so tightening the policy does not lock anyone out.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lowercase letters, uppercase letters,
+	// digits and symbols the password must mix.
+	MinClasses int
+	denied     map[string]struct{}
+}
+
+// NewPolicy builds a policy with the deny-list read from denyListPath, one
+// password per line with # starting a comment. An empty path uses the
+// built-in list of common passwords.
+func NewPolicy(minLength, maxLength, minClasses int, denyListPath string) (*Policy, error) {
+	var list io.Reader = strings.NewReader(commonPasswords)
+	if denyListPath != "" {
+		f, err := os.Open(denyListPath)
+		if err != nil {
+			return nil, fmt.Errorf("password deny-list: %w", err)
+		}
+		defer f.Close()
+		list = f
+	}
+
+	denied := make(map[string]struct{})
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denied[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("password deny-list: %w", err)
+	}
+
+	return &Policy{
+		MinLength:  minLength,
+		MaxLength:  maxLength,
+		MinClasses: minClasses,
+		denied:     denied,
+	}, nil
+}
+
+// Validate checks a new password for the given user.
+func (p *Policy) Validate(password, username string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength || length > p.MaxLength {
+		return fmt.Errorf("%w: min %d - max %d characters", validations.ErrPasswordLength, p.MinLength, p.MaxLength)
+	}
+	if classes(password) < p.MinClasses {
+		return fmt.Errorf("%w: use at least %d of lowercase letters, uppercase letters, digits and symbols", validations.ErrPasswordClasses, p.MinClasses)
+	}
+	lower := strings.ToLower(password)
+	if _, ok := p.denied[lower]; ok {
+		return validations.ErrPasswordCommon
+	}
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return validations.ErrPasswordUsername
+	}
+	return nil
+}
+
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,70 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notes/pkg/validations"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy, err := NewPolicy(10, 64, 3, "")
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	tests := []struct {
+		name, password, username string
+		want                     error
+	}{
+		{"valid", "Correct horse 42", "alice", nil},
+		{"too short", "Ab1!xyz", "alice", validations.ErrPasswordLength},
+		{"shortest", "Abcdefgh1!", "alice", nil},
+		{"too long", "Aa1" + strings.Repeat("x", 62), "alice", validations.ErrPasswordLength},
+		{"longest", "Aa1" + strings.Repeat("x", 61), "alice", nil},
+		{"length in characters", "Ää1ääääääää", "alice", nil},
+		{"two classes", "lowercase123", "alice", validations.ErrPasswordClasses},
+		{"symbols count as a class", "lowercase-123", "alice", nil},
+		{"spaces are symbols", "lower case 123", "alice", nil},
+		{"common", "Password123", "alice", validations.ErrPasswordCommon},
+		{"common in another case", "pASSWORD123", "alice", validations.ErrPasswordCommon},
+		{"contains the username", "xAlice-2025x", "alice", validations.ErrPasswordUsername},
+		{"no username", "xAlice-2025x", "", nil},
+	}
+	for _, tt := range tests {
+		err := policy.Validate(tt.password, tt.username)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate(%q) = %v, want %v", tt.name, tt.password, err, tt.want)
+		}
+	}
+}
+
+func TestPolicyDenyListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denied.txt")
+	list := "# Local-List-1\n\n  Summer-2025  \nWinter-2025\n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	policy, err := NewPolicy(8, 64, 3, path)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	if err := policy.Validate("summer-2025", ""); !errors.Is(err, validations.ErrPasswordCommon) {
+		t.Errorf("listed password: got %v, want ErrPasswordCommon", err)
+	}
+	// The file replaces the built-in list.
+	if err := policy.Validate("Password123", ""); err != nil {
+		t.Errorf("password of the built-in list: got %v, want it accepted", err)
+	}
+	if err := policy.Validate("# Local-List-1", ""); err != nil {
+		t.Errorf("comment line: got %v, want it accepted", err)
+	}
+
+	if _, err := NewPolicy(8, 64, 3, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("NewPolicy accepted a missing deny-list")
+	}
+}
@@ -0,0 +1,87 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP finds the address of the client behind a request. The
+// X-Forwarded-For and X-Real-IP headers are set by whoever sends the
+// request, so they are only believed when the connection comes from one of
+// the trusted proxies; otherwise the peer address is the client.
+type ClientIP struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIP parses a comma separated list of trusted proxy addresses or
+// CIDR ranges. An empty list trusts no proxy.
+func NewClientIP(trustedProxies string) (*ClientIP, error) {
+	c := &ClientIP{}
+	for _, entry := range strings.Split(trustedProxies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			c.trusted = append(c.trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		addr = addr.Unmap()
+		c.trusted = append(c.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return c, nil
+}
+
+// FromRequest returns the client address. Behind trusted proxies it is the
+// right-most X-Forwarded-For entry that is not a trusted proxy itself, or
+// X-Real-IP when there is no such entry.
+func (c *ClientIP) FromRequest(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !c.isTrusted(peer) {
+		return peer
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		if !c.isTrusted(hop) {
+			return hop
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+	return peer
+}
+
+func (c *ClientIP) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
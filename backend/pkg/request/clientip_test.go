@@ -0,0 +1,52 @@
+package request
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	behindProxy, err := NewClientIP("10.0.0.0/8, 192.0.2.10")
+	if err != nil {
+		t.Fatalf("NewClientIP: %v", err)
+	}
+	direct, err := NewClientIP("")
+	if err != nil {
+		t.Fatalf("NewClientIP: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		resolver     *ClientIP
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		want         string
+	}{
+		{"no proxy", direct, "203.0.113.5:4321", "", "", "203.0.113.5"},
+		{"spoofed forwarded for", direct, "203.0.113.5:4321", "198.51.100.1", "198.51.100.2", "203.0.113.5"},
+		{"untrusted peer", behindProxy, "203.0.113.5:4321", "198.51.100.1", "", "203.0.113.5"},
+		{"trusted proxy", behindProxy, "10.1.2.3:4321", "198.51.100.1", "", "198.51.100.1"},
+		{"chain of proxies", behindProxy, "10.1.2.3:4321", "198.51.100.9, 198.51.100.1, 192.0.2.10", "", "198.51.100.1"},
+		{"real ip", behindProxy, "192.0.2.10:80", "", "198.51.100.1", "198.51.100.1"},
+		{"malformed header", behindProxy, "10.1.2.3:4321", "not-an-ip", "", "10.1.2.3"},
+		{"ipv6 peer", direct, "[2001:db8::1]:443", "198.51.100.1", "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := tt.resolver.FromRequest(r); got != tt.want {
+			t.Errorf("%s: FromRequest = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewClientIP("10.0.0.0/8, proxy.local"); err == nil {
+		t.Error("NewClientIP accepted a host name")
+	}
+}
@@ -0,0 +1,21 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// DecodeMergePatch decodes an RFC 7396 JSON merge patch. The document must be
+// an object; its members are returned undecoded so the caller can tell a
+// member that is absent from one explicitly set to null.
+func DecodeMergePatch(w http.ResponseWriter, r *http.Request) (map[string]json.RawMessage, error) {
+	var patch map[string]json.RawMessage
+	if err := DecodeJSON(w, r, &patch); err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return nil, errors.New("merge patch must be a json object")
+	}
+	return patch, nil
+}
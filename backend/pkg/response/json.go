@@ -16,6 +16,22 @@ func JSONWithHeaders(w http.ResponseWriter, status int, data any, headers http.H
 		"data":   data,
 		"status": status,
 	}
+	return writeJSON(w, status, res, headers)
+}
+
+// JSONWithMetadata adds a metadata object, such as paging information,
+// next to the data of the standard envelope.
+func JSONWithMetadata(w http.ResponseWriter, status int, data any, metadata any) error {
+	res := map[string]any{
+		"error":    nil,
+		"data":     data,
+		"metadata": metadata,
+		"status":   status,
+	}
+	return writeJSON(w, status, res, nil)
+}
+
+func writeJSON(w http.ResponseWriter, status int, res map[string]any, headers http.Header) error {
 	js, err := json.MarshalIndent(res, "", "\t")
 	if err != nil {
 		return err
@@ -36,25 +52,16 @@ func JSONWithHeaders(w http.ResponseWriter, status int, data any, headers http.H
 }
 
 func ErrJSONWithHeaders(w http.ResponseWriter, status int, errMsg any, headers http.Header) error {
-	response := map[string]any{
+	return ErrJSONWithData(w, status, errMsg, nil, headers)
+}
+
+// ErrJSONWithData writes an error envelope that also carries data, such as
+// the current state of a resource a conditional request failed against.
+func ErrJSONWithData(w http.ResponseWriter, status int, errMsg any, data any, headers http.Header) error {
+	res := map[string]any{
 		"error":  errMsg,
-		"data":   nil,
+		"data":   data,
 		"status": status,
 	}
-	js, err := json.MarshalIndent(response, "", "\t")
-	if err != nil {
-		return err
-	}
-	js = append(js, '\n')
-	for k, v := range headers {
-		w.Header()[k] = v
-	}
-	w.Header().Set("Content-Type", "application/json, charset=UTF-8")
-	w.WriteHeader(status)
-	_, err = w.Write(js)
-	if err != nil {
-		log.Printf("Error writing json error response: %v", err)
-		return err
-	}
-	return nil
+	return writeJSON(w, status, res, headers)
 }
@@ -0,0 +1,74 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	Entry
+	expiresAt time.Time
+}
+
+// MemoryStore keeps the counters in process memory. Expired entries are
+// swept while recording failures, so the map only holds recent keys.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string, now time.Time) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		return Entry{}, nil
+	}
+	return e.Entry, nil
+}
+
+func (s *MemoryStore) Fail(_ context.Context, key string, now time.Time, ttl time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now, ttl)
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.Failures++
+	e.LastFailure = now
+	e.expiresAt = now.Add(ttl)
+	return e.Entry, nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired entries, at most once per ttl.
+func (s *MemoryStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(s.lastSweep) < ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
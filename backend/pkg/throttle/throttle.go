@@ -0,0 +1,75 @@
+// Package throttle counts failed attempts per key, such as a username or an
+// IP address, and turns the counts into exponential backoff and temporary
+// lockouts.
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is the failure history of one key.
+type Entry struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps the failure counters. MemoryStore suits a single node; a
+// deployment with several nodes needs a shared implementation so that all of
+// them see the same counts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the entry of key, or a zero Entry if there is none.
+	Get(ctx context.Context, key string, now time.Time) (Entry, error)
+	// Fail records a failure for key and returns the updated entry. An entry
+	// without failures for ttl is forgotten.
+	Fail(ctx context.Context, key string, now time.Time, ttl time.Duration) (Entry, error)
+	// Reset forgets key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy turns a failure count into the time the key must wait before its
+// next attempt.
+type Policy struct {
+	// FreeAttempts failures are allowed before any delay applies.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure past FreeAttempts; it
+	// doubles with every further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutFor. Zero disables the
+	// lockout.
+	LockoutAfter int
+	LockoutFor   time.Duration
+	// Window is how long failures are remembered without new ones.
+	Window time.Duration
+}
+
+// Wait returns how long the key must wait at now before its next attempt.
+func (p Policy) Wait(e Entry, now time.Time) time.Duration {
+	var delay time.Duration
+	switch {
+	case e.Failures == 0:
+		return 0
+	case p.LockoutAfter > 0 && e.Failures >= p.LockoutAfter:
+		delay = p.LockoutFor
+	case e.Failures > p.FreeAttempts:
+		delay = p.MaxDelay
+		if shift := e.Failures - p.FreeAttempts - 1; shift < 30 {
+			delay = min(p.BaseDelay<<shift, p.MaxDelay)
+		}
+	default:
+		return 0
+	}
+	return max(e.LastFailure.Add(delay).Sub(now), 0)
+}
+
+// Locked reports whether the entry has reached the lockout threshold.
+func (p Policy) Locked(e Entry) bool {
+	return p.LockoutAfter > 0 && e.Failures >= p.LockoutAfter
+}
+
+// TTL is how long a store must keep an entry: long enough to cover both the
+// window and a lockout.
+func (p Policy) TTL() time.Duration {
+	return max(p.Window, p.LockoutFor, p.MaxDelay)
+}
@@ -0,0 +1,109 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var policy = Policy{
+	FreeAttempts: 2,
+	BaseDelay:    time.Second,
+	MaxDelay:     8 * time.Second,
+	LockoutAfter: 10,
+	LockoutFor:   15 * time.Minute,
+	Window:       time.Hour,
+}
+
+func TestPolicyWait(t *testing.T) {
+	last := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		failures int
+		elapsed  time.Duration
+		want     time.Duration
+	}{
+		{0, 0, 0},
+		{2, 0, 0},
+		{3, 0, time.Second},
+		{4, 0, 2 * time.Second},
+		{5, 0, 4 * time.Second},
+		{6, 0, 8 * time.Second},
+		{9, 0, 8 * time.Second},
+		{5, 3 * time.Second, time.Second},
+		{5, time.Minute, 0},
+		{10, 0, 15 * time.Minute},
+		{12, 5 * time.Minute, 10 * time.Minute},
+		{10, 15 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		e := Entry{Failures: tt.failures, LastFailure: last}
+		if got := policy.Wait(e, last.Add(tt.elapsed)); got != tt.want {
+			t.Errorf("Wait after %d failures and %s = %s, want %s", tt.failures, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyLocked(t *testing.T) {
+	if policy.Locked(Entry{Failures: 9}) || !policy.Locked(Entry{Failures: 10}) {
+		t.Error("Locked does not start at LockoutAfter failures")
+	}
+	if (Policy{}).Locked(Entry{Failures: 100}) {
+		t.Error("a policy without LockoutAfter locked a key")
+	}
+	if got := policy.TTL(); got != time.Hour {
+		t.Errorf("TTL = %s, want the window of an hour", got)
+	}
+}
+
+func TestMemoryStoreWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	start := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	ttl := policy.TTL()
+
+	for i := range 3 {
+		if _, err := store.Fail(ctx, "user:alice", start.Add(time.Duration(i)*time.Minute), ttl); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	e, _ := store.Get(ctx, "user:alice", start.Add(30*time.Minute))
+	if e.Failures != 3 || !e.LastFailure.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("got %+v, want 3 failures, the last at 12:02", e)
+	}
+
+	// Every failure extends the window from its own time.
+	expiry := start.Add(2*time.Minute + ttl)
+	if e, _ := store.Get(ctx, "user:alice", expiry.Add(-time.Second)); e.Failures != 3 {
+		t.Errorf("entry forgotten before the window ended: %+v", e)
+	}
+	if e, _ := store.Get(ctx, "user:alice", expiry); e.Failures != 0 {
+		t.Errorf("entry kept after the window ended: %+v", e)
+	}
+	e, _ = store.Fail(ctx, "user:alice", expiry, ttl)
+	if e.Failures != 1 {
+		t.Errorf("a failure after the window counted %d failures, want a fresh count", e.Failures)
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	for range 10 {
+		store.Fail(ctx, "user:alice", now, policy.TTL())
+		store.Fail(ctx, "ip:192.0.2.1", now, policy.TTL())
+	}
+	if e, _ := store.Get(ctx, "user:alice", now); !policy.Locked(e) {
+		t.Fatalf("alice is not locked after %d failures", e.Failures)
+	}
+
+	if err := store.Reset(ctx, "user:alice"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if e, _ := store.Get(ctx, "user:alice", now); e.Failures != 0 || policy.Wait(e, now) != 0 {
+		t.Errorf("alice still throttled after a reset: %+v", e)
+	}
+	if e, _ := store.Get(ctx, "ip:192.0.2.1", now); e.Failures != 10 {
+		t.Errorf("resetting alice changed the address: %+v", e)
+	}
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, base32 encoded.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B gives 8 digit codes; ours are their last 6 digits.
+	vectors := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, v := range vectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", v.unix, err)
+		}
+		if want := v.want[len(v.want)-Digits:]; got != want {
+			t.Errorf("Code at %d = %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseSecrets(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil || got != "287082" {
+		t.Errorf("Code with a lowercase secret = %q, %v, want 287082", got, err)
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, err := Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		step, ok := Validate(rfcSecret, " "+code+" ", now)
+		wantOk := offset >= -Skew && offset <= Skew
+		if ok != wantOk {
+			t.Errorf("code %d steps away: accepted %v, want %v", offset, ok, wantOk)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code %d steps away matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "287082", now); ok {
+		t.Error("Validate accepted a code for an invalid secret")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Notes", "alice", rfcSecret)
+	for _, part := range []string{"otpauth://totp/Notes:alice?", "secret=" + rfcSecret, "issuer=Notes", "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %q lacks %q", uri, part)
+		}
+	}
+}
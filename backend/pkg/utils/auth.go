@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"notes/internal/configs"
 	"notes/pkg/date"
@@ -8,32 +11,79 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (*string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, validations.ErrHashingPwd
-	}
-	hashed := string(hash)
-	return &hashed, nil
+const RefreshCookieName = "notes_refresh"
+
+// AccessTokenTTL is how long an access token, and its cookie, stays valid.
+func AccessTokenTTL() time.Duration {
+	return time.Minute * time.Duration(configs.GetInt("JWT_TIME_COUNT", 15))
 }
 
-func CheckPasswordHash(password, hash string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+// RefreshTokenTTL is how long a session survives without being refreshed.
+func RefreshTokenTTL() time.Duration {
+	return time.Hour * time.Duration(configs.GetInt("REFRESH_TOKEN_TTL_HOURS", 720))
 }
 
-func GenerateJWT(userID uint) (string, error) {
-	jwtExpirationDuration := time.Minute * time.Duration(configs.GetInt("JWT_TIME_COUNT", 60))
+// GenerateJWT issues an access token for the user's session; the session ID
+// is the token's jti.
+func GenerateJWT(userID uint, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     date.ArgentinaTimeNow().Add(jwtExpirationDuration).Unix(),
+		"jti":     sessionID,
+		"exp":     date.ArgentinaTimeNow().Add(AccessTokenTTL()).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(configs.GetString("JWT_STRING", "")))
 }
 
+// challengePurpose marks the tokens handed out between the password and the
+// TOTP step of a login, so they cannot be mistaken for anything else.
+const challengePurpose = "login-2fa"
+
+// GenerateChallengeJWT issues the short-lived token that proves a user passed
+// the password check and may complete the login with a TOTP code.
+func GenerateChallengeJWT(userID uint) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": challengePurpose,
+		"exp":     date.ArgentinaTimeNow().Add(5 * time.Minute).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(configs.GetString("JWT_STRING", "")))
+}
+
+// ParseChallengeJWT returns the user a login challenge token was issued to.
+func ParseChallengeJWT(tokenStr string) (uint, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(configs.GetString("JWT_STRING", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims["purpose"] != challengePurpose {
+		return 0, validations.ErrChallengeToken
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return 0, validations.ErrChallengeToken
+	}
+	return uint(userID), nil
+}
+
+// RandomToken returns n random bytes, hex encoded.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of a token, which is what gets stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // DEBUG START
 
 func SetJWTAsCookie(w http.ResponseWriter, token string) {
@@ -44,6 +94,35 @@ func SetJWTAsCookie(w http.ResponseWriter, token string) {
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteNoneMode,
-		MaxAge:   309600,
+		MaxAge:   int(AccessTokenTTL().Seconds()),
 	})
 }
+
+// SetRefreshCookie stores the refresh token in a cookie only sent to the
+// /user routes that exchange or revoke it.
+func SetRefreshCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    token,
+		Path:     "/user",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   int(RefreshTokenTTL().Seconds()),
+	})
+}
+
+// ClearAuthCookies removes the access and refresh token cookies.
+func ClearAuthCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{"notes_jwt": "/", RefreshCookieName: "/user"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			HttpOnly: true,
+			Secure:   true,
+			Expires:  time.Unix(0, 0),
+			SameSite: http.SameSiteNoneMode,
+		})
+	}
+}
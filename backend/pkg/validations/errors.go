@@ -10,7 +10,7 @@ var (
 	ErrMinCategory             = errors.New("a note must have at least one category. add a category to delete existing")
 	ErrEmptyCategoryFilter     = errors.New("categories filter can't be empty")
 	ErrCategoryNotFound        = errors.New("unable to find the specified category")
-	ErrDuplicateTitle          = errors.New("duplicate title")
+	ErrDuplicateTitle          = errors.New("duplicate title: you already have a note with this title")
 	ErreEmptyTitle             = errors.New("title cannot be empty")
 	ErrRepeatedLetters         = errors.New("fields cannot contain 3 consecutive same letters")
 	ErrCharactersExcess        = errors.New("title min 5 max - 50 characters")
@@ -29,28 +29,90 @@ var (
 	ErrNoteNotOwnedByUser      = errors.New("note doesn't belong to the logged used")
 	ErrUserNamePassLength      = errors.New("username and password: min 5 - max 20")
 	ErrCategoryName            = errors.New("errors parsing category name")
+	ErrCatInUse                = errors.New("category is the only category of one or more notes, merge it into another category instead")
+	ErrCatMergeSelf            = errors.New("cannot merge a category into itself")
+	ErrInvalidRevision         = errors.New("invalid revision, must be a positive number")
+	ErrInvalidPageLimit        = errors.New("invalid limit, must be a number between 1 and 100")
+	ErrInvalidCursor           = errors.New("invalid cursor, it must come from a previous page with the same sort")
+	ErrInvalidSort             = errors.New("invalid sort, must be one of created_at, updated_at or title, optionally prefixed with - for descending order")
+	ErrSearchQuery             = errors.New("search query min 1 - max 100 characters")
+	ErrInvalidLimit            = errors.New("invalid limit, must be a number between 1 and 50")
+	ErrIfMatchRequired         = errors.New("missing If-Match header: fetch the note and send its ETag to update it")
+	ErrInvalidIfMatch          = errors.New("invalid If-Match header, must be the ETag returned for the note")
+	ErrVersionMismatch         = errors.New("the note was modified since it was fetched, review the current version and retry")
+	ErrPatchRemoveField        = errors.New("title, content, categories and is_archived cannot be removed, omit them to keep the current value")
+	ErrPatchContentType        = errors.New("unsupported content type, send the patch as application/merge-patch+json")
+	ErrBulkOperation           = errors.New("invalid operation, must be one of archive, unarchive, delete, add_category or remove_category")
+	ErrBulkNoteIds             = errors.New("note_ids must list between 1 and 100 note ids")
+	ErrExportFormat            = errors.New("invalid export format, must be markdown")
+	ErrSyncToken               = errors.New("invalid sync token, it must come from a previous sync")
+	ErrSyncTokenExpired        = errors.New("sync token expired, deletions made since are no longer known: sync again without a token")
+	ErrSyncLimit               = errors.New("invalid limit, must be a number between 1 and 1000")
+	ErrSyncChanges             = errors.New("changes must list between 1 and 100 changes")
+	ErrImportArchive           = errors.New("invalid import, the body must be a zip archive of at most 10 MB")
+	ErrImportTooLarge          = errors.New("import archive has too many files, the maximum is 500")
+	ErrImportEntryTooLarge     = errors.New("file exceeds the maximum size of 64 KB")
+	ErrImportFrontMatter       = errors.New("invalid YAML front matter")
+	ErrAccessTokenName         = errors.New("token name min 1 - max 50 characters")
+	ErrAccessTokenScopes       = errors.New("invalid scopes, must be one or more of notes:read and notes:write")
+	ErrAccessTokenExpiry       = errors.New("expires_in_days must be a number between 1 and 365")
+	ErrShareExpiry             = errors.New("expires_in_days must be a number between 1 and 365, or 0 for a link that never expires")
+	ErrSharePasswordLength     = errors.New("share password min 8 - max 128 characters")
+	ErrNoteRole                = errors.New("invalid role, must be viewer or editor")
+	ErrPermissionSelf          = errors.New("notes cannot be shared with their owner")
+	ErrNoteScope               = errors.New("invalid scope, must be own or shared")
+	ErrNoteMove                = errors.New("exactly one of before and after must be set")
+	ErrMoveTarget              = errors.New("notes can only be moved next to another of your notes")
+	ErrMovePinned              = errors.New("pinned and unpinned notes cannot be moved among each other")
+	ErrAdminSelf               = errors.New("admins cannot disable or delete their own account")
+	ErrUserNotFound            = errors.New("no user matches the provided id")
+	ErrPasswordLength          = errors.New("invalid password length")
+	ErrPasswordClasses         = errors.New("password is too simple")
+	ErrPasswordCommon          = errors.New("password is too common, choose another one")
+	ErrPasswordUsername        = errors.New("password cannot contain the username")
+	ErrPasswordUnchanged       = errors.New("the new password must differ from the current one")
+	ErrCurrentPassword         = errors.New("current password is incorrect")
 
 	// DB
-	ErrUserIdNotSet       = errors.New("user id not set for the note")
-	ErrFilterDB           = errors.New("error during filtering from db")
-	ErrNoteCreate         = errors.New("error during creating note")
-	ErrFetchingCategory   = errors.New("error feching categories")
-	ErrCatAlreadyExist    = errors.New("conflict: duplicate category name")
-	ErrCatCreate          = errors.New("err during category creation")
-	ErrCatNotFound        = errors.New("no category matches the provided id")
-	ErrCatUpdate          = errors.New("error during updating category")
-	ErrCatDelete          = errors.New("error deleting category")
-	ErrFetchingCategories = errors.New("error during fetching all categories")
-	ErrTooManyCategories  = errors.New("too many categories. one note can be associated to 4 categories or less")
-	ErrNoNotesFound       = errors.New("no notes were found")
-	ErrFetchingNotes      = errors.New("error fetching notes")
-	ErrZeroCategory       = errors.New("required at least one category")
-	ErrNoteNotFound       = errors.New("no note matches the provided id")
-	ErrFetchingNote       = errors.New("error during fetching note")
-	ErrAddNewCatToNote    = errors.New("error during updating note adding a category")
-	ErrNoteUpdate         = errors.New("error updating note")
-	ErrNoteDelete         = errors.New("error deleting note")
-	ErrNotTitle           = errors.New("cannot find note with the specified title")
+	ErrUserIdNotSet        = errors.New("user id not set for the note")
+	ErrFilterDB            = errors.New("error during filtering from db")
+	ErrNoteCreate          = errors.New("error during creating note")
+	ErrFetchingCategory    = errors.New("error feching categories")
+	ErrCatAlreadyExist     = errors.New("conflict: duplicate category name")
+	ErrCatCreate           = errors.New("err during category creation")
+	ErrCatNotFound         = errors.New("no category matches the provided id")
+	ErrCatUpdate           = errors.New("error during updating category")
+	ErrCatDelete           = errors.New("error deleting category")
+	ErrCatMerge            = errors.New("error merging categories")
+	ErrFetchingCategories  = errors.New("error during fetching all categories")
+	ErrTooManyCategories   = errors.New("too many categories. one note can be associated to 4 categories or less")
+	ErrNoNotesFound        = errors.New("no notes were found")
+	ErrFetchingNotes       = errors.New("error fetching notes")
+	ErrZeroCategory        = errors.New("required at least one category")
+	ErrNoteNotFound        = errors.New("no note matches the provided id")
+	ErrFetchingNote        = errors.New("error during fetching note")
+	ErrAddNewCatToNote     = errors.New("error during updating note adding a category")
+	ErrNoteUpdate          = errors.New("error updating note")
+	ErrNoteDelete          = errors.New("error deleting note")
+	ErrNotTitle            = errors.New("cannot find note with the specified title")
+	ErrSearchDB            = errors.New("error during searching notes")
+	ErrRevisionCreate      = errors.New("error recording note revision")
+	ErrRevisionNotFound    = errors.New("no revision matches the provided number")
+	ErrFetchingRevisions   = errors.New("error fetching note revisions")
+	ErrTrashDB             = errors.New("error accessing the trash")
+	ErrNoteRestore         = errors.New("error restoring note from the trash")
+	ErrTrashedNoteNotFound = errors.New("no trashed note matches the provided id")
+	ErrAccessTokenNotFound = errors.New("no access token matches the provided id")
+	ErrAccessTokenDB       = errors.New("error accessing access tokens")
+	ErrShareNotFound       = errors.New("no share link matches the provided token or id")
+	ErrShareDB             = errors.New("error accessing share links")
+	ErrPermissionUser      = errors.New("no user matches the provided username")
+	ErrPermissionNotFound  = errors.New("the user has no access to this note")
+	ErrPermissionDB        = errors.New("error accessing note permissions")
+	ErrUserDB              = errors.New("error accessing users")
+	ErrAccountExport       = errors.New("error exporting account data")
+	ErrAccountDelete       = errors.New("error deleting account")
+	ErrSyncDB              = errors.New("error reading note changes")
 
 	// API
 	ErrJsonResponse    = errors.New("cannot parse response to json")
@@ -66,4 +128,26 @@ var (
 	ErrTokenExpiry        = errors.New("error getting token expiration")
 	ErrInvalidUserID      = errors.New("invalid user id")
 	ErrUnauthorized       = errors.New("cannot retreive user id")
+	ErrRefreshToken       = errors.New("unauthorized: invalid or expired refresh token, log in again")
+	ErrRefreshTokenReuse  = errors.New("unauthorized: refresh token already used, the session has been revoked")
+	ErrSessionRevoked     = errors.New("unauthorized: session expired or revoked, log in again")
+	ErrSessionDB          = errors.New("error accessing sessions")
+	ErrInvalidTOTPCode    = errors.New("unauthorized: invalid or already used two-factor code")
+	ErrChallengeToken     = errors.New("unauthorized: invalid or expired login challenge, log in again")
+	ErrTOTPEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("start two-factor enrollment before confirming it")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorDB        = errors.New("error updating two-factor settings")
+	ErrAccessToken        = errors.New("unauthorized: invalid, expired or revoked access token")
+	ErrSharePassword      = errors.New("unauthorized: this link needs a password, or the password is incorrect")
+	ErrInsufficientScope  = errors.New("forbidden: the access token does not have the scope this route requires")
+	ErrSessionRequired    = errors.New("forbidden: this route needs a browser session, access tokens cannot use it")
+	ErrAdminRequired      = errors.New("forbidden: admin role required")
+	ErrNotePermission     = errors.New("forbidden: your access to this note does not allow this, ask its owner")
+	ErrUserDisabled       = errors.New("forbidden: this account has been disabled")
+	ErrPasswordReset      = errors.New("forbidden: a password reset was requested for this account, set a new password with the reset token")
+	ErrResetToken         = errors.New("unauthorized: invalid or expired password reset token")
+	ErrLoginThrottled     = errors.New("too many failed login attempts")
+	ErrShareThrottled     = errors.New("too many incorrect passwords for this link")
+	ErrTwoFactorThrottled = errors.New("too many incorrect two-factor codes")
 )
@@ -10,6 +10,10 @@ const Login = () => {
   const initialForm = { user_name: "", password: "" };
   const [form, setForm] = useState(initialForm);
   const [loading, setLoading] = useState(false);
+  // Set when the account has two-factor authentication: the password was
+  // accepted and the login is completed with a code from the authenticator.
+  const [challenge, setChallenge] = useState(null);
+  const [code, setCode] = useState("");
   const navigate = useNavigate();
   const { checkAuth } = useAuth();
 
@@ -24,7 +28,17 @@ const Login = () => {
     setLoading(true);
 
     try {
-      const { data } = await axiosInstance.post("/user/login", form);
+      const { data, status } = challenge
+        ? await axiosInstance.post("/user/login/2fa", {
+            challenge_token: challenge,
+            ...(code.includes("-") ? { recovery_code: code } : { code }),
+          })
+        : await axiosInstance.post("/user/login", form);
+      if (status === 202) {
+        setChallenge(data.data?.challenge_token);
+        return;
+      }
+      setChallenge(null);
       const successMsg = `${data.data?.user_name} successfully logged in`;
       await checkAuth().catch(error => {
         console.error("AUTH CHECK FAILED:", error);
@@ -40,6 +54,7 @@ const Login = () => {
     } finally {
       setLoading(false);
       setForm(initialForm);
+      setCode("");
     }
   };
 
@@ -54,6 +69,22 @@ const Login = () => {
           {/* Title */}
           <h2 className="text-2xl font-bold mb-4 text-base-content">Log In</h2>
 
+          {challenge ? (
+            <div className="mb-6">
+              <input
+                type="text"
+                id="code"
+                name="code"
+                className="input input-bordered input-primary w-full"
+                placeholder="Authenticator or recovery code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+                autoComplete="one-time-code"
+              />
+            </div>
+          ) : (
+          <>
           {/* Username Field */}
           <div className="mb-6">
             <label className="flex items-center gap-2 label">
@@ -107,6 +138,8 @@ const Login = () => {
               />
             </label>
           </div>
+          </>
+          )}
 
           {/* Submit Section */}
           <div className="flex items-center justify-between mt-8">
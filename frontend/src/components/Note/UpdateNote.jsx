@@ -16,6 +16,7 @@ function UpdateNote() {
   const [error, setError] = useState("");
   const [success, setSuccess] = useState("");
   const [loading, setLoading] = useState(false);
+  const [etag, setEtag] = useState("");
 
 
   useEffect(() => {
@@ -25,6 +26,7 @@ function UpdateNote() {
         const response = await axiosInstance.get(`/notes/${id}`);
         const note = response.data.data;
         const categoriesString = note.categories.map(cat => cat.name).join(", ");
+        setEtag(response.headers.etag || `"${note.version}"`);
 
         setFormData({
           title: note.title,
@@ -67,6 +69,8 @@ function UpdateNote() {
         title: formData.title,
         content: formData.content,
         categories: categoriesArray,
+      }, {
+        headers: { "If-Match": etag },
       });
 
       const successMessage = `🟢 Note updated successfully with ID: ${id}`;
@@ -74,6 +78,10 @@ function UpdateNote() {
       showToast(true, successMessage);
       setTimeout(() => navigate("/notes"), 100);
     } catch (err) {
+      const current = err.response?.status === 412 ? err.response.data.data : null;
+      if (current) {
+        setEtag(err.response.headers.etag || `"${current.version}"`);
+      }
       const errorMessage =
         `🔴 ${Object.values(err.response?.data?.error || {}).join(' ')}` ||
         "🔴 Something went wrong. Try again.";
@@ -7,4 +7,35 @@ const axiosInstance = axios.create({
   withCredentials: true,
 });
 
-export default axiosInstance;
\ No newline at end of file
+// Access tokens are short-lived: on a 401, exchange the refresh token cookie
+// for new tokens once and replay the request. Concurrent 401s share a single
+// refresh, since each refresh token can only be used once.
+const NO_REFRESH = ["/user/login", "/user/login/2fa", "/user/register", "/user/refresh", "/user/logout"];
+let refreshing = null;
+
+axiosInstance.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    if (
+      error.response?.status !== 401 ||
+      !original ||
+      original._retried ||
+      NO_REFRESH.includes(original.url)
+    ) {
+      return Promise.reject(error);
+    }
+    original._retried = true;
+    try {
+      refreshing = refreshing || axiosInstance.post("/user/refresh");
+      await refreshing;
+    } catch {
+      return Promise.reject(error);
+    } finally {
+      refreshing = null;
+    }
+    return axiosInstance(original);
+  }
+);
+
+export default axiosInstance;
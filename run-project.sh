@@ -1,6 +1,9 @@
 #!/bin/bash
 
 cd backend
+echo "Applying database migrations..."
+go run ./cmd/migrate up || exit 1
+
 echo "Starting backend server..."
 go run ./cmd/api/ &
 